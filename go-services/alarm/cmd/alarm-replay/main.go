@@ -0,0 +1,143 @@
+// alarm-replay republishes recorded tag values to NATS, or evaluates them
+// offline against a definition set to preview the alarms they would raise.
+//
+//	alarm-replay -file trend.csv -speed 60
+//	alarm-replay -stream SENSORS -filter 'enterprise.site1.>' -speed 0
+//	alarm-replay -file trend.jsonl -dry-run -definitions limits.json
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/replay"
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+)
+
+func main() {
+	file := flag.String("file", "", "CSV or JSONL recording of timestamp,tag,value[,quality]")
+	format := flag.String("format", "", "Recording format: csv or jsonl (default: from the file extension)")
+	stream := flag.String("stream", "", "JetStream stream to replay instead of a file")
+	filter := flag.String("filter", "", "Subject filter within -stream")
+	natsURL := flag.String("nats", "nats://localhost:4222", "NATS URL")
+	prefix := flag.String("subject-prefix", "enterprise.site1.area1.line1.device1", "Subject prefix; the tag is appended")
+	speed := flag.Float64("speed", 1, "Replay rate: 1 is real time, 10 is ten times faster, 0 is as fast as possible")
+	restamp := flag.Bool("restamp", false, "Move timestamps onto the replay timeline")
+
+	dryRun := flag.Bool("dry-run", false, "Evaluate offline and print alarm transitions instead of publishing")
+	definitions := flag.String("definitions", "", "Definition set for -dry-run, as exported by the alarm service (JSON or CSV)")
+	jsonOut := flag.Bool("json", false, "Print dry-run transitions as JSON lines")
+	chatter := core.DefaultChatterConfig()
+	flag.IntVar(&chatter.Threshold, "chatter-threshold", chatter.Threshold, "Activations within -chatter-window that count as chattering")
+	flag.DurationVar(&chatter.Window, "chatter-window", chatter.Window, "Chattering detection window")
+	flag.StringVar(&chatter.Action, "chatter-action", "", `Chattering mitigation: "", "deadband" or "delay"`)
+	flag.Float64Var(&chatter.Deadband, "chatter-deadband", 0, "Clearing deadband applied to chattering alarms")
+	flag.DurationVar(&chatter.Delay, "chatter-delay", 0, "On-delay applied to chattering alarms")
+	flag.Parse()
+
+	if (*file == "") == (*stream == "") {
+		log.Fatal("Exactly one of -file or -stream is required")
+	}
+	if *dryRun && *definitions == "" {
+		log.Fatal("-dry-run requires -definitions")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var nc *nats.Conn
+	if *stream != "" || !*dryRun {
+		var err error
+		nc, err = nats.Connect(*natsURL)
+		if err != nil {
+			log.Fatalf("Failed to connect to NATS: %v", err)
+		}
+		defer nc.Close()
+	}
+
+	src, closeSrc, err := openSource(ctx, nc, *file, *format, *stream, *filter)
+	if err != nil {
+		log.Fatalf("Failed to open recording: %v", err)
+	}
+	defer closeSrc()
+
+	if *dryRun {
+		if err := runDryRun(src, *definitions, chatter, *jsonOut); err != nil {
+			log.Fatalf("Dry run failed: %v", err)
+		}
+		return
+	}
+
+	player := &replay.Player{Speed: *speed, Restamp: *restamp}
+	sent, err := player.Play(ctx, src, func(data *pb.SensorData) error {
+		payload, err := proto.Marshal(data)
+		if err != nil {
+			return err
+		}
+		return nc.Publish(fmt.Sprintf("%s.%s", *prefix, data.SensorId), payload)
+	})
+	if err := nc.Flush(); err != nil {
+		log.Printf("Failed to flush NATS connection: %v", err)
+	}
+	log.Printf("Published %d samples", sent)
+	if err != nil {
+		log.Fatalf("Replay stopped: %v", err)
+	}
+}
+
+func openSource(ctx context.Context, nc *nats.Conn, file, format, stream, filter string) (replay.Source, func(), error) {
+	if stream != "" {
+		src, err := replay.NewStreamSource(ctx, nc, stream, filter)
+		return src, func() {}, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if format == "" {
+		format = replay.FormatFromPath(file)
+	}
+	src, err := replay.NewFileSource(f, format)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return src, func() { f.Close() }, nil
+}
+
+func runDryRun(src replay.Source, path string, chatter core.ChatterConfig, jsonOut bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	format := core.FormatJSON
+	if strings.HasSuffix(strings.ToLower(path), ".csv") {
+		format = core.FormatCSV
+	}
+	defs, err := core.DecodeDefinitions(f, format)
+	if err != nil {
+		return fmt.Errorf("failed to read definitions: %w", err)
+	}
+
+	result, err := replay.DryRun(src, defs, chatter)
+	if err != nil {
+		return err
+	}
+	if err := replay.WriteTransitions(os.Stdout, jsonOut, result.Transitions); err != nil {
+		return err
+	}
+	log.Printf("Evaluated %d samples against %d definitions: %d transitions", result.Samples, len(defs), len(result.Transitions))
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/config"
 	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/metrics"
 	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
 	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/transport"
 )
@@ -43,8 +44,7 @@ func run(ctx context.Context) error {
 
 	log.Printf("Starting Alarm Service on port %s", cfg.Port)
 
-	// Initialize Postgres Repository
-	repo, err := repository.NewPostgresRepository(cfg.DbUrl)
+	repo, err := openRepository(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -59,7 +59,53 @@ func run(ctx context.Context) error {
 
 	// Initialize Alarm Service
 	// NatsTransport implements EventPublisher
-	svc := core.NewAlarmService(repo, natsTransport)
+	serviceMetrics := metrics.New()
+	svc := core.NewAlarmService(serviceMetrics.InstrumentRepository(repo), natsTransport)
+	svc.SetMetrics(serviceMetrics)
+	serviceMetrics.RegisterActiveAlarms(svc)
+	serviceMetrics.RegisterConsumerBacklog(natsTransport.Pending)
+	svc.SetChatterConfig(core.ChatterConfig{
+		Threshold: cfg.ChatterThreshold,
+		Window:    cfg.ChatterWindow,
+		Action:    cfg.ChatterAction,
+		Deadband:  cfg.ChatterDeadband,
+		Delay:     cfg.ChatterDelay,
+	})
+
+	anomalyCfg := core.DefaultAnomalyConfig()
+	anomalyCfg.QuietPeriod = cfg.AnomalyQuietPeriod
+	if cfg.AnomalyPriorities != "" {
+		priorities, err := core.ParseSeverityPriorities(cfg.AnomalyPriorities)
+		if err != nil {
+			return fmt.Errorf("invalid ANOMALY_PRIORITIES: %w", err)
+		}
+		anomalyCfg.PriorityBySeverity = priorities
+	}
+	svc.SetAnomalyConfig(anomalyCfg)
+
+	shiftCalendar, err := core.ParseShiftCalendar(cfg.ShiftStarts, cfg.ShiftTimezone)
+	if err != nil {
+		return fmt.Errorf("invalid SHIFT_STARTS: %w", err)
+	}
+	svc.SetShiftCalendar(shiftCalendar)
+
+	if cfg.PriorityMatrixFile != "" {
+		matrix, err := loadPriorityMatrix(cfg.PriorityMatrixFile)
+		if err != nil {
+			return fmt.Errorf("invalid PRIORITY_MATRIX_FILE: %w", err)
+		}
+		svc.SetPriorityMatrix(matrix)
+	}
+
+	// Electronic signatures for GMP areas
+	if cfg.SigningPublicKey != "" {
+		verifier, err := transport.NewJWTSignatureVerifier(cfg.SigningPublicKey)
+		if err != nil {
+			return err
+		}
+		svc.SetSignatureVerifier(verifier)
+		log.Printf("Shelving or suppressing %s alarms requires an electronic signature", svc.SignedPriority())
+	}
 
 	// Set service in NatsTransport (for consumer)
 	natsTransport.SetService(svc)
@@ -69,9 +115,31 @@ func run(ctx context.Context) error {
 		return fmt.Errorf("failed to load definitions: %w", err)
 	}
 
+	// Correct alarms that changed while we were down
+	if cfg.HistorianAddr != "" {
+		historian, err := transport.NewHistorianClient(cfg.HistorianAddr, cfg.ReconcileLookback)
+		if err != nil {
+			log.Printf("Skipping startup reconciliation: %v", err)
+		} else {
+			svc.ReconcileOnStartup(ctx, historian, 5*time.Second)
+			historian.Close()
+		}
+	}
+
 	// Start Service Background Tasks (Unshelving)
 	svc.StartBackgroundTasks()
 
+	// Generate a handover report at every shift change
+	var reportSink core.ShiftReportSink
+	if cfg.ShiftReportDir != "" {
+		dir, err := repository.NewShiftReportDir(cfg.ShiftReportDir)
+		if err != nil {
+			return err
+		}
+		reportSink = dir
+	}
+	svc.StartShiftReports(reportSink)
+
 	// Start NATS Consumer
 	go func() {
 		if err := natsTransport.Start(); err != nil {
@@ -80,9 +148,24 @@ func run(ctx context.Context) error {
 	}()
 	// Initialize HTTP Handler
 	httpHandler := transport.NewHttpHandler(svc)
+	if cfg.AuthPublicKey != "" {
+		auth, err := transport.NewAuthenticator(cfg.AuthPublicKey)
+		if err != nil {
+			return err
+		}
+		httpHandler.SetAuthenticator(auth)
+	} else {
+		log.Printf("AUTH_PUBLIC_KEY is not set; operator actions are disabled")
+	}
 	mux := http.NewServeMux()
 	httpHandler.RegisterRoutes(mux)
 
+	health := transport.NewHealthHandler()
+	health.AddCheck("database", repo.Ping)
+	health.AddCheck("nats", func(context.Context) error { return natsTransport.Ready() })
+	health.RegisterRoutes(mux)
+	mux.Handle("GET /metrics", serviceMetrics.Handler())
+
 	server := &http.Server{
 		Addr:    ":" + cfg.Port,
 		Handler: mux,
@@ -106,6 +189,35 @@ func run(ctx context.Context) error {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Printf("HTTP server shutdown failed: %v", err)
 	}
+	svc.FlushHistory()
 
 	return nil
 }
+
+func loadPriorityMatrix(path string) (*core.PriorityMatrix, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return core.ParsePriorityMatrix(f)
+}
+
+type closableRepository interface {
+	core.AlarmRepository
+	Close()
+	Ping(ctx context.Context) error
+}
+
+func openRepository(cfg *config.Config) (closableRepository, error) {
+	switch cfg.Repository {
+	case config.RepositorySQLite:
+		log.Printf("Using SQLite repository at %s", cfg.SQLitePath)
+		return repository.NewSQLiteRepository(cfg.SQLitePath)
+	case config.RepositoryMemory:
+		log.Println("Using in-memory repository; alarm state is lost on restart")
+		return repository.NewMemoryRepository(), nil
+	default:
+		return repository.NewPostgresRepository(cfg.DbUrl)
+	}
+}
@@ -3,18 +3,89 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	RepositoryPostgres = "postgres"
+	RepositorySQLite   = "sqlite"
+	RepositoryMemory   = "memory"
 )
 
 type Config struct {
-	DbUrl   string
-	NatsUrl string
-	Port    string
+	// Repository selects the alarm store: postgres (DbUrl), sqlite
+	// (SQLitePath) or memory.
+	Repository string
+	DbUrl      string
+	SQLitePath string
+	NatsUrl    string
+	Port       string
+
+	// Chattering detection
+	ChatterThreshold int
+	ChatterWindow    time.Duration
+	ChatterAction    string // "", "deadband" or "delay"
+	ChatterDeadband  float64
+	ChatterDelay     time.Duration
+
+	// Analytics anomaly alarms
+	AnomalyPriorities  string // e.g. "CRITICAL=High,WARNING=Medium"
+	AnomalyQuietPeriod time.Duration
+
+	// Startup reconciliation against the engine's HistorianQuery gRPC
+	// service. Reconciliation is skipped when HistorianAddr is empty.
+	HistorianAddr     string
+	ReconcileLookback time.Duration
+
+	// Shift reports. ShiftStarts lists local shift start times, e.g.
+	// "06:00,14:00,22:00". Scheduled reports are written to ShiftReportDir
+	// when set.
+	ShiftStarts    string
+	ShiftTimezone  *time.Location
+	ShiftReportDir string
+
+	// SigningPublicKey is a PEM file holding the auth service's RSA public
+	// key. When set, shelving or suppressing an alarm of the priority
+	// matrix's signed priority requires a signing token from the auth
+	// service's re-auth flow.
+	SigningPublicKey string
+
+	// AuthPublicKey is a PEM file holding the auth service's RSA public key
+	// for access tokens. Operator actions are recorded under the token's
+	// username and refused without one. Defaults to SigningPublicKey.
+	AuthPublicKey string
+
+	// PriorityMatrixFile is a JSON priority matrix replacing the default
+	// three priority matrix.
+	PriorityMatrixFile string
 }
 
 func LoadConfig() (*Config, error) {
+	authPublicKey := os.Getenv("AUTH_PUBLIC_KEY")
+	if authPublicKey == "" {
+		authPublicKey = os.Getenv("SIGNING_PUBLIC_KEY")
+	}
+
+	repository := os.Getenv("REPOSITORY")
+	if repository == "" {
+		repository = RepositoryPostgres
+	}
+
 	dbUrl := os.Getenv("DB_URL")
-	if dbUrl == "" {
-		return nil, fmt.Errorf("DB_URL environment variable is required")
+	sqlitePath := os.Getenv("SQLITE_PATH")
+	switch repository {
+	case RepositoryPostgres:
+		if dbUrl == "" {
+			return nil, fmt.Errorf("DB_URL environment variable is required")
+		}
+	case RepositorySQLite:
+		if sqlitePath == "" {
+			sqlitePath = "alarms.db"
+		}
+	case RepositoryMemory:
+	default:
+		return nil, fmt.Errorf("REPOSITORY must be postgres, sqlite or memory, got %q", repository)
 	}
 
 	natsUrl := os.Getenv("NATS_URL")
@@ -27,9 +98,92 @@ func LoadConfig() (*Config, error) {
 		port = "8083"
 	}
 
+	chatterThreshold, err := intEnv("CHATTER_THRESHOLD", 3)
+	if err != nil {
+		return nil, err
+	}
+	chatterWindow, err := intEnv("CHATTER_WINDOW_SECONDS", 60)
+	if err != nil {
+		return nil, err
+	}
+	chatterDelay, err := intEnv("CHATTER_DELAY_SECONDS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	chatterAction := os.Getenv("CHATTER_ACTION")
+	switch chatterAction {
+	case "", "deadband", "delay":
+	default:
+		return nil, fmt.Errorf("CHATTER_ACTION must be empty, deadband or delay, got %q", chatterAction)
+	}
+
+	var chatterDeadband float64
+	if v := os.Getenv("CHATTER_DEADBAND"); v != "" {
+		chatterDeadband, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("CHATTER_DEADBAND must be a number: %w", err)
+		}
+	}
+
+	anomalyQuiet, err := intEnv("ANOMALY_QUIET_SECONDS", 300)
+	if err != nil {
+		return nil, err
+	}
+
+	reconcileLookback, err := intEnv("RECONCILE_LOOKBACK_SECONDS", 3600)
+	if err != nil {
+		return nil, err
+	}
+
+	shiftStarts := os.Getenv("SHIFT_STARTS")
+	if shiftStarts == "" {
+		shiftStarts = "06:00,14:00,22:00"
+	}
+	shiftTimezone := time.UTC
+	if v := os.Getenv("SHIFT_TIMEZONE"); v != "" {
+		shiftTimezone, err = time.LoadLocation(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHIFT_TIMEZONE: %w", err)
+		}
+	}
+
 	return &Config{
-		DbUrl:   dbUrl,
-		NatsUrl: natsUrl,
-		Port:    port,
+		Repository:       repository,
+		DbUrl:            dbUrl,
+		SQLitePath:       sqlitePath,
+		NatsUrl:          natsUrl,
+		Port:             port,
+		ChatterThreshold: chatterThreshold,
+		ChatterWindow:    time.Duration(chatterWindow) * time.Second,
+		ChatterAction:    chatterAction,
+		ChatterDeadband:  chatterDeadband,
+		ChatterDelay:     time.Duration(chatterDelay) * time.Second,
+
+		AnomalyPriorities:  os.Getenv("ANOMALY_PRIORITIES"),
+		AnomalyQuietPeriod: time.Duration(anomalyQuiet) * time.Second,
+
+		HistorianAddr:     os.Getenv("HISTORIAN_GRPC_ADDR"),
+		ReconcileLookback: time.Duration(reconcileLookback) * time.Second,
+
+		ShiftStarts:    shiftStarts,
+		ShiftTimezone:  shiftTimezone,
+		ShiftReportDir: os.Getenv("SHIFT_REPORT_DIR"),
+
+		SigningPublicKey:   os.Getenv("SIGNING_PUBLIC_KEY"),
+		AuthPublicKey:      authPublicKey,
+		PriorityMatrixFile: os.Getenv("PRIORITY_MATRIX_FILE"),
 	}, nil
 }
+
+func intEnv(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return n, nil
+}
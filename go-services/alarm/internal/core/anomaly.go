@@ -0,0 +1,232 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+// AnomalyType is the definition type of alarms raised from analytics
+// AnomalyEvents. These definitions are created on demand, one per source
+// tag, and are never evaluated against sensor values.
+const AnomalyType = "Anomaly"
+
+type AnomalyConfig struct {
+	// PriorityBySeverity maps AnomalyEvent severities (e.g. CRITICAL) to
+	// alarm priorities. Severities are matched case-insensitively.
+	PriorityBySeverity map[string]string
+	DefaultPriority    string
+	// QuietPeriod is how long a tag must go without anomalies before its
+	// alarm clears.
+	QuietPeriod time.Duration
+}
+
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		PriorityBySeverity: map[string]string{"CRITICAL": "High", "WARNING": "Medium"},
+		DefaultPriority:    "Medium",
+		QuietPeriod:        5 * time.Minute,
+	}
+}
+
+// ParseSeverityPriorities parses rules of the form "CRITICAL=High,WARNING=Medium".
+func ParseSeverityPriorities(rules string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		severity, priority, ok := strings.Cut(rule, "=")
+		if !ok || severity == "" || priority == "" {
+			return nil, fmt.Errorf("invalid severity rule %q, expected SEVERITY=Priority", rule)
+		}
+		mapping[strings.ToUpper(strings.TrimSpace(severity))] = strings.TrimSpace(priority)
+	}
+	return mapping, nil
+}
+
+func (c AnomalyConfig) priorityFor(severity string) string {
+	if p, ok := c.PriorityBySeverity[strings.ToUpper(severity)]; ok {
+		return p
+	}
+	return c.DefaultPriority
+}
+
+func (s *AlarmService) SetAnomalyConfig(cfg AnomalyConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.anomalyCfg = cfg
+}
+
+// anomalyDefinitionLocked finds or creates the anomaly definition for a
+// tag. Callers hold s.mu.
+func (s *AlarmService) anomalyDefinitionLocked(tag, severity string) (*AlarmDefinition, error) {
+	for _, def := range s.definitions[tag] {
+		if def.Type == AnomalyType {
+			return def, nil
+		}
+	}
+
+	def := &AlarmDefinition{
+		Tag:       tag,
+		Type:      AnomalyType,
+		Priority:  s.anomalyCfg.priorityFor(severity),
+		Rationale: "Raised by analytics anomaly detection",
+	}
+	if err := s.repo.CreateDefinition(def); err != nil {
+		return nil, err
+	}
+	s.definitions[tag] = append(s.definitions[tag], def)
+	s.definitionsByID[def.ID] = def
+	return def, nil
+}
+
+// setAnomalyPriorityLocked gives an anomaly definition the priority of the
+// severity its alarm is being raised with. It is persisted, so signature
+// checks, views and the priority distribution agree with the event.
+// Callers hold s.mu.
+func (s *AlarmService) setAnomalyPriorityLocked(def *AlarmDefinition, priority string) error {
+	if def.Priority == priority {
+		return nil
+	}
+	if err := s.repo.UpdateDefinitionPriority(def.ID, priority); err != nil {
+		return fmt.Errorf("failed to update anomaly priority for %s: %w", def.Tag, err)
+	}
+	def.Priority = priority
+	def.UpdatedAt = s.clock()
+	return nil
+}
+
+// ProcessAnomaly raises or refreshes the managed alarm for an analytics
+// anomaly. Repeated anomalies on a tag whose alarm is still standing only
+// extend its quiet-period timer.
+func (s *AlarmService) ProcessAnomaly(event *pb.AnomalyEvent) error {
+	if event.SourceTag == "" {
+		return fmt.Errorf("anomaly event without source tag")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	def, err := s.anomalyDefinitionLocked(event.SourceTag, event.Severity)
+	if err != nil {
+		return err
+	}
+	if s.isSuppressedLocked(def) {
+		return nil
+	}
+
+	now := s.clock()
+	s.anomalyLastSeen[def.ID] = now
+
+	detectedAt := now
+	if event.TimestampMs > 0 {
+		detectedAt = time.UnixMilli(event.TimestampMs)
+	}
+	s.recordOnsetLocked(def, true, detectedAt)
+	defer s.reconcileGroupOfLocked(def, now)
+
+	active, exists := s.activeAlarms[def.ID]
+	currentState := StateNormal
+	if exists {
+		currentState = AlarmState(active.State)
+	}
+
+	switch currentState {
+	case StateUnackActive, StateAckActive, StateShelved, StateSuppressed:
+		// Dedup: the alarm is already standing
+		active.Value = event.Actual
+		return nil
+	}
+
+	fsm := NewAlarmFSM(currentState)
+	newState, err := fsm.Transition(EventTrigger)
+	if err != nil {
+		return nil
+	}
+	if err := s.setAnomalyPriorityLocked(def, s.anomalyCfg.priorityFor(event.Severity)); err != nil {
+		return err
+	}
+
+	var alarm *ActiveAlarm
+	if !exists {
+		alarm = &ActiveAlarm{
+			DefinitionID:   def.ID,
+			State:          string(newState),
+			ActivationTime: now,
+			Value:          event.Actual,
+		}
+		if err := s.repo.CreateActiveAlarm(alarm); err != nil {
+			return err
+		}
+		s.activeAlarms[def.ID] = alarm
+	} else {
+		active.State = string(newState)
+		active.Value = event.Actual
+		active.UpdatedAt = now
+		if err := s.repo.UpdateActiveAlarmState(active.ID, string(newState)); err != nil {
+			return err
+		}
+		alarm = active
+	}
+
+	alarmEvent := s.newEvent(def.ID, alarm, currentState, newState, ActorSystem, "",
+		fmt.Sprintf("Anomaly on %s: actual=%g predicted=%g residual=%g severity=%s",
+			event.SourceTag, event.Actual, event.Predicted, event.Residual, event.Severity))
+	s.publish(alarmEvent)
+
+	s.recordActivationLocked(def, now)
+	return nil
+}
+
+// checkAnomalies runs on the background ticker and clears anomaly alarms
+// that have been quiet for the configured period.
+func (s *AlarmService) checkAnomalies() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock()
+	for defID, active := range s.activeAlarms {
+		def, ok := s.definitionsByID[defID]
+		if !ok || def.Type != AnomalyType {
+			continue
+		}
+
+		lastSeen, ok := s.anomalyLastSeen[defID]
+		if !ok {
+			// Loaded from the repository after a restart: start the quiet timer now
+			s.anomalyLastSeen[defID] = now
+			continue
+		}
+		if now.Sub(lastSeen) < s.anomalyCfg.QuietPeriod {
+			continue
+		}
+
+		currentState := AlarmState(active.State)
+		if currentState != StateUnackActive && currentState != StateAckActive {
+			continue
+		}
+
+		fsm := NewAlarmFSM(currentState)
+		newState, err := fsm.Transition(EventClear)
+		if err != nil {
+			continue
+		}
+		if err := s.repo.UpdateActiveAlarmState(active.ID, string(newState)); err != nil {
+			log.Printf("Failed to clear anomaly alarm %d: %v", active.ID, err)
+			continue
+		}
+		active.State = string(newState)
+		active.UpdatedAt = now
+		if newState == StateNormal {
+			delete(s.activeAlarms, defID)
+		}
+
+		s.publish(s.newEvent(defID, active, currentState, newState, ActorSystem, "",
+			fmt.Sprintf("Anomaly on %s cleared after %s without recurrence", def.Tag, s.anomalyCfg.QuietPeriod)))
+	}
+}
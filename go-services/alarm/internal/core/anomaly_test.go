@@ -0,0 +1,87 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+func TestAlarmService_ProcessAnomaly(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repo, publisher)
+	svc.LoadDefinitions()
+
+	anomaly := &pb.AnomalyEvent{SourceTag: "reactor.temp", Actual: 80, Predicted: 60, Residual: 20, Severity: "CRITICAL"}
+	if err := svc.ProcessAnomaly(anomaly); err != nil {
+		t.Fatalf("Failed to process anomaly: %v", err)
+	}
+
+	alarms := svc.GetActiveAlarms()
+	if len(alarms) != 1 || alarms[0].State != string(core.StateUnackActive) {
+		t.Fatalf("Expected one UnackActive anomaly alarm, got %+v", alarms)
+	}
+	defs, _ := repo.ListDefinitions()
+	if len(defs) != 1 || defs[0].ID != alarms[0].DefinitionID || defs[0].Type != core.AnomalyType {
+		t.Errorf("Expected an Anomaly definition to be created for the tag")
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Priority != "High" {
+		t.Fatalf("Expected one High event, got %+v", publisher.events)
+	}
+
+	// Repeated anomalies are deduplicated
+	svc.ProcessAnomaly(anomaly)
+	svc.ProcessAnomaly(&pb.AnomalyEvent{SourceTag: "reactor.temp", Actual: 81, Severity: "WARNING"})
+	if defs, _ := repo.ListDefinitions(); len(publisher.events) != 1 || len(defs) != 1 {
+		t.Errorf("Expected repeated anomalies to be deduplicated, got %d events", len(publisher.events))
+	}
+
+	// Sensor values don't clear anomaly alarms
+	svc.ProcessValue("reactor.temp", 60)
+	if len(svc.GetActiveAlarms()) != 1 {
+		t.Error("Expected sensor values to leave the anomaly alarm alone")
+	}
+
+	// Operators manage it through the normal FSM
+	if err := svc.Acknowledge(alarms[0].ID, "operator1", ""); err != nil {
+		t.Fatalf("Failed to acknowledge anomaly alarm: %v", err)
+	}
+
+	// Quiet period elapses: AckActive -> Normal
+	svc.BackdateAnomaly(alarms[0].DefinitionID, time.Hour)
+	svc.CheckAnomalies()
+
+	if len(svc.GetActiveAlarms()) != 0 {
+		t.Error("Expected the anomaly alarm to clear after the quiet period")
+	}
+
+	// The next anomaly raises it with its own severity's priority
+	svc.ProcessAnomaly(&pb.AnomalyEvent{SourceTag: "reactor.temp", Actual: 70, Severity: "WARNING"})
+	last := publisher.events[len(publisher.events)-1]
+	if last.State != string(core.StateUnackActive) || last.Priority != "Medium" {
+		t.Errorf("Expected a Medium alarm to be raised, got %+v", last)
+	}
+	if def, _ := repo.GetDefinition(alarms[0].DefinitionID); def.Priority != "Medium" {
+		t.Errorf("Expected the definition priority to be persisted, got %s", def.Priority)
+	}
+	if views := svc.GetActiveAlarmViews(); len(views) != 1 || views[0].Priority != "Medium" {
+		t.Errorf("Expected the active alarm to show Medium, got %+v", views)
+	}
+}
+
+func TestParseSeverityPriorities(t *testing.T) {
+	mapping, err := core.ParseSeverityPriorities("critical=Critical, WARNING=Low")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if mapping["CRITICAL"] != "Critical" || mapping["WARNING"] != "Low" {
+		t.Errorf("Unexpected mapping: %v", mapping)
+	}
+
+	if _, err := core.ParseSeverityPriorities("CRITICAL"); err == nil {
+		t.Error("Expected error for rule without priority")
+	}
+}
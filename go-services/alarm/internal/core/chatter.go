@@ -0,0 +1,182 @@
+package core
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	ChatterActionNone     = ""
+	ChatterActionDeadband = "deadband"
+	ChatterActionDelay    = "delay"
+)
+
+// ChatterConfig flags a definition as chattering once it activates
+// Threshold times within Window. While chattering, Action optionally
+// applies a clearing deadband or an on-delay to the definition.
+type ChatterConfig struct {
+	Threshold int
+	Window    time.Duration
+	Action    string
+	Deadband  float64       // Engineering units the value must clear the threshold by
+	Delay     time.Duration // How long the condition must persist before re-activating
+}
+
+func DefaultChatterConfig() ChatterConfig {
+	return ChatterConfig{Threshold: 3, Window: 60 * time.Second}
+}
+
+// ChatteringAlarm is a definition currently flagged as chattering.
+type ChatteringAlarm struct {
+	DefinitionID int       `json:"definition_id"`
+	Tag          string    `json:"tag"`
+	Activations  int       `json:"activations"`
+	Since        time.Time `json:"since"`
+	Mitigation   string    `json:"mitigation,omitempty"`
+}
+
+type chatterTracker struct {
+	activations    []time.Time
+	chattering     bool
+	since          time.Time
+	conditionSince time.Time // When the alarm condition last became true
+}
+
+func (s *AlarmService) SetChatterConfig(cfg ChatterConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chatterCfg = cfg
+}
+
+func (s *AlarmService) trackerLocked(defID int) *chatterTracker {
+	t, ok := s.chatter[defID]
+	if !ok {
+		t = &chatterTracker{}
+		s.chatter[defID] = t
+	}
+	return t
+}
+
+// prune drops activations that have left the sliding window.
+func (t *chatterTracker) prune(now time.Time, window time.Duration) {
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(t.activations) && t.activations[i].Before(cutoff) {
+		i++
+	}
+	t.activations = t.activations[i:]
+}
+
+// mitigateLocked adjusts the raw evaluation result of a chattering
+// definition according to the configured action. Callers hold s.mu.
+func (s *AlarmService) mitigateLocked(def *AlarmDefinition, currentState AlarmState, value float64, shouldFire bool, now time.Time) bool {
+	t := s.trackerLocked(def.ID)
+	if !shouldFire {
+		t.conditionSince = time.Time{}
+	} else if t.conditionSince.IsZero() {
+		t.conditionSince = now
+	}
+
+	if !t.chattering {
+		return shouldFire
+	}
+
+	active := currentState == StateUnackActive || currentState == StateAckActive
+	switch s.chatterCfg.Action {
+	case ChatterActionDeadband:
+		// Hold the alarm until the value clears the threshold by the deadband
+		if active && !shouldFire {
+			switch def.Type {
+			case "High":
+				return value > def.Threshold-s.chatterCfg.Deadband
+			case "Low":
+				return value < def.Threshold+s.chatterCfg.Deadband
+			}
+		}
+	case ChatterActionDelay:
+		// Only re-activate once the condition has persisted for the delay
+		if !active && shouldFire && now.Sub(t.conditionSince) < s.chatterCfg.Delay {
+			return false
+		}
+	}
+	return shouldFire
+}
+
+// recordActivationLocked counts an activation coming out of
+// evaluateDefinition and flags the definition once it crosses the
+// chatter threshold. Callers hold s.mu.
+func (s *AlarmService) recordActivationLocked(def *AlarmDefinition, now time.Time) {
+	if s.chatterCfg.Threshold <= 0 {
+		return
+	}
+	t := s.trackerLocked(def.ID)
+	t.activations = append(t.activations, now)
+	t.prune(now, s.chatterCfg.Window)
+
+	if t.chattering || len(t.activations) < s.chatterCfg.Threshold {
+		return
+	}
+	t.chattering = true
+	t.since = now
+	s.publishAudit(ActorSystem, "alarm_chattering_detected", map[string]interface{}{
+		"definition_id":  def.ID,
+		"tag":            def.Tag,
+		"activations":    len(t.activations),
+		"window_seconds": s.chatterCfg.Window.Seconds(),
+		"mitigation":     s.chatterCfg.Action,
+	})
+}
+
+// checkChattering runs on the background ticker and clears the flag once a
+// definition has gone a full window without activating. Trackers with
+// nothing left to remember are dropped.
+func (s *AlarmService) checkChattering() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock()
+	for defID, t := range s.chatter {
+		t.prune(now, s.chatterCfg.Window)
+		if len(t.activations) > 0 {
+			continue
+		}
+		if t.chattering {
+			t.chattering = false
+			details := map[string]interface{}{
+				"definition_id":    defID,
+				"chattering_since": t.since,
+			}
+			if def, ok := s.definitionsByID[defID]; ok {
+				details["tag"] = def.Tag
+			}
+			s.publishAudit(ActorSystem, "alarm_chattering_cleared", details)
+		}
+		if t.conditionSince.IsZero() {
+			delete(s.chatter, defID)
+		}
+	}
+}
+
+func (s *AlarmService) GetChatteringAlarms() []*ChatteringAlarm {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	alarms := make([]*ChatteringAlarm, 0)
+	for defID, t := range s.chatter {
+		if !t.chattering {
+			continue
+		}
+		alarm := &ChatteringAlarm{
+			DefinitionID: defID,
+			Activations:  len(t.activations),
+			Since:        t.since,
+			Mitigation:   s.chatterCfg.Action,
+		}
+		if def, ok := s.definitionsByID[defID]; ok {
+			alarm.Tag = def.Tag
+		}
+		alarms = append(alarms, alarm)
+	}
+	sort.Slice(alarms, func(i, j int) bool { return alarms[i].DefinitionID < alarms[j].DefinitionID })
+	return alarms
+}
@@ -0,0 +1,117 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+)
+
+func TestAlarmService_Chattering(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repo, publisher)
+
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High"})
+	svc.LoadDefinitions()
+
+	// Three activations: Normal -> UnackActive -> UnackRTN -> UnackActive ...
+	for i := 0; i < 3; i++ {
+		svc.ProcessValue("sensor1", 101)
+		svc.ProcessValue("sensor1", 99)
+	}
+
+	chattering := svc.GetChatteringAlarms()
+	if len(chattering) != 1 || chattering[0].Tag != "sensor1" || chattering[0].Activations != 3 {
+		t.Fatalf("Expected sensor1 to be chattering, got %+v", chattering)
+	}
+	if len(publisher.records) != 1 || publisher.records[0].Action != "alarm_chattering_detected" {
+		t.Fatalf("Expected a chattering audit record, got %+v", publisher.records)
+	}
+
+	// A quiet window clears the flag
+	svc.BackdateChatter(2 * time.Minute)
+	svc.CheckChattering()
+
+	if len(svc.GetChatteringAlarms()) != 0 {
+		t.Error("Expected chattering to clear after a quiet window")
+	}
+	if publisher.records[len(publisher.records)-1].Action != "alarm_chattering_cleared" {
+		t.Errorf("Expected a cleared audit record, got %s", publisher.records[len(publisher.records)-1].Action)
+	}
+}
+
+func TestAlarmService_ChatterFollowsClock(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repo, publisher)
+	now := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	svc.SetClock(func() time.Time { return now })
+
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High"})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor2", Threshold: 100, Type: "High"})
+	svc.LoadDefinitions()
+
+	for i := 0; i < 3; i++ {
+		svc.ProcessValue("sensor1", 101)
+		svc.ProcessValue("sensor1", 99)
+	}
+	svc.ProcessValue("sensor2", 99)
+
+	// The wall clock is far ahead of the service clock; only the latter counts
+	svc.CheckChattering()
+	if len(svc.GetChatteringAlarms()) != 1 {
+		t.Fatal("Expected chattering to hold within the window of the service clock")
+	}
+
+	// A quiet window on the service clock clears the flag and the trackers
+	now = now.Add(2 * time.Minute)
+	svc.CheckChattering()
+	if len(svc.GetChatteringAlarms()) != 0 || svc.ChatterTrackers() != 0 {
+		t.Errorf("Expected quiet trackers to be dropped, %d left", svc.ChatterTrackers())
+	}
+	if publisher.records[len(publisher.records)-1].Action != "alarm_chattering_cleared" {
+		t.Errorf("Expected a cleared audit record, got %s", publisher.records[len(publisher.records)-1].Action)
+	}
+
+	// Deleting a definition drops its tracker
+	svc.ProcessValue("sensor2", 101)
+	svc.ProcessValue("sensor2", 99)
+	for _, alarm := range svc.GetActiveAlarms() {
+		svc.Acknowledge(alarm.ID, "alice", "")
+	}
+	if _, err := svc.ImportDefinitions([]*core.AlarmDefinition{{Tag: "sensor1", Threshold: 100, Type: "High"}}, "alice", false); err != nil {
+		t.Fatalf("ImportDefinitions failed: %v", err)
+	}
+	if svc.ChatterTrackers() != 0 {
+		t.Errorf("Expected the deleted definition's tracker to be dropped, %d left", svc.ChatterTrackers())
+	}
+}
+
+func TestAlarmService_ChatterDeadband(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := core.NewAlarmService(repo, &MockPublisher{})
+	svc.SetChatterConfig(core.ChatterConfig{Threshold: 2, Window: time.Minute, Action: core.ChatterActionDeadband, Deadband: 5})
+
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High"})
+	svc.LoadDefinitions()
+
+	svc.ProcessValue("sensor1", 101)
+	svc.Acknowledge(svc.GetActiveAlarms()[0].ID, "operator1", "")
+	svc.ProcessValue("sensor1", 99)  // AckActive -> Normal
+	svc.ProcessValue("sensor1", 101) // Second activation: chattering
+
+	// Inside the deadband the alarm holds
+	svc.Acknowledge(svc.GetActiveAlarms()[0].ID, "operator1", "")
+	svc.ProcessValue("sensor1", 97)
+	if len(svc.GetActiveAlarms()) != 1 {
+		t.Fatal("Expected the alarm to hold inside the deadband")
+	}
+
+	// Beyond the deadband it clears
+	svc.ProcessValue("sensor1", 94)
+	if len(svc.GetActiveAlarms()) != 0 {
+		t.Error("Expected the alarm to clear beyond the deadband")
+	}
+}
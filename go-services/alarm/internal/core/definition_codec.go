@@ -0,0 +1,202 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// definitionColumn maps one CSV column onto an AlarmDefinition field.
+type definitionColumn struct {
+	name string
+	get  func(d *AlarmDefinition) string
+	set  func(d *AlarmDefinition, v string) error
+}
+
+// definitionColumns is the column order used for export. Imports match
+// columns by header name, so spreadsheets may reorder or omit optional ones.
+var definitionColumns = []definitionColumn{
+	{
+		name: "tag",
+		get:  func(d *AlarmDefinition) string { return d.Tag },
+		set:  func(d *AlarmDefinition, v string) error { d.Tag = v; return nil },
+	},
+	{
+		name: "type",
+		get:  func(d *AlarmDefinition) string { return d.Type },
+		set:  func(d *AlarmDefinition, v string) error { d.Type = v; return nil },
+	},
+	{
+		name: "threshold",
+		get:  func(d *AlarmDefinition) string { return strconv.FormatFloat(d.Threshold, 'g', -1, 64) },
+		set: func(d *AlarmDefinition, v string) error {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("invalid threshold %q", v)
+			}
+			d.Threshold = f
+			return nil
+		},
+	},
+	{
+		name: "priority",
+		get:  func(d *AlarmDefinition) string { return d.Priority },
+		set:  func(d *AlarmDefinition, v string) error { d.Priority = v; return nil },
+	},
+	{
+		name: "rationale",
+		get:  func(d *AlarmDefinition) string { return d.Rationale },
+		set:  func(d *AlarmDefinition, v string) error { d.Rationale = v; return nil },
+	},
+	{
+		name: "cause",
+		get:  func(d *AlarmDefinition) string { return d.Cause },
+		set:  func(d *AlarmDefinition, v string) error { d.Cause = v; return nil },
+	},
+	{
+		name: "consequence",
+		get:  func(d *AlarmDefinition) string { return d.Consequence },
+		set:  func(d *AlarmDefinition, v string) error { d.Consequence = v; return nil },
+	},
+	{
+		name: "severity",
+		get:  func(d *AlarmDefinition) string { return d.Severity },
+		set:  func(d *AlarmDefinition, v string) error { d.Severity = v; return nil },
+	},
+	{
+		name: "corrective_action",
+		get:  func(d *AlarmDefinition) string { return d.CorrectiveAction },
+		set:  func(d *AlarmDefinition, v string) error { d.CorrectiveAction = v; return nil },
+	},
+	{
+		name: "response_time_seconds",
+		get:  func(d *AlarmDefinition) string { return strconv.Itoa(d.ResponseTimeSeconds) },
+		set: func(d *AlarmDefinition, v string) error {
+			if v == "" {
+				d.ResponseTimeSeconds = 0
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid response_time_seconds %q", v)
+			}
+			d.ResponseTimeSeconds = n
+			return nil
+		},
+	},
+	{
+		name: "class",
+		get:  func(d *AlarmDefinition) string { return d.Class },
+		set:  func(d *AlarmDefinition, v string) error { d.Class = v; return nil },
+	},
+}
+
+// requiredDefinitionColumns must be present in every CSV import.
+var requiredDefinitionColumns = []string{"tag", "type", "threshold"}
+
+func EncodeDefinitions(w io.Writer, format string, defs []*AlarmDefinition) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(defs)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		header := make([]string, len(definitionColumns))
+		for i, col := range definitionColumns {
+			header[i] = col.name
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, def := range defs {
+			record := make([]string, len(definitionColumns))
+			for i, col := range definitionColumns {
+				record[i] = col.get(def)
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+func DecodeDefinitions(r io.Reader, format string) ([]*AlarmDefinition, error) {
+	switch format {
+	case FormatJSON:
+		var defs []*AlarmDefinition
+		if err := json.NewDecoder(r).Decode(&defs); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return defs, nil
+	case FormatCSV:
+		return decodeDefinitionsCSV(r)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+func decodeDefinitionsCSV(r io.Reader) ([]*AlarmDefinition, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	byName := make(map[string]definitionColumn, len(definitionColumns))
+	for _, col := range definitionColumns {
+		byName[col.name] = col
+	}
+
+	columns := make([]*definitionColumn, len(header))
+	seen := make(map[string]bool)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if col, ok := byName[name]; ok {
+			columns[i] = &col
+			seen[name] = true
+		}
+	}
+	for _, name := range requiredDefinitionColumns {
+		if !seen[name] {
+			return nil, fmt.Errorf("missing required CSV column %q", name)
+		}
+	}
+
+	var defs []*AlarmDefinition
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		def := &AlarmDefinition{}
+		for i, value := range record {
+			if columns[i] == nil {
+				continue
+			}
+			if err := columns[i].set(def, strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
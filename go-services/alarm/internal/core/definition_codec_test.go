@@ -0,0 +1,61 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDefinitionCodec_RoundTrip(t *testing.T) {
+	defs := []*AlarmDefinition{
+		{
+			Tag: "sensor1", Type: "High", Threshold: 100.5, Priority: "Critical", Rationale: "Protects the pump seal, see HAZOP 12",
+			Rationalization: Rationalization{
+				Cause:               "Cooling water valve closed",
+				Consequence:         "Seal damage, product leak",
+				Severity:            "major",
+				CorrectiveAction:    "Open CW valve, reduce pump speed",
+				ResponseTimeSeconds: 300,
+				Class:               "Safety",
+			},
+		},
+		{Tag: "sensor2", Type: "Low", Threshold: -3, Priority: "Warning"},
+	}
+
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeDefinitions(&buf, format, defs); err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			decoded, err := DecodeDefinitions(&buf, format)
+			if err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+			if len(decoded) != len(defs) {
+				t.Fatalf("Expected %d definitions, got %d", len(defs), len(decoded))
+			}
+			for i := range defs {
+				if decoded[i].Key() != defs[i].Key() || !sameSettings(decoded[i], defs[i]) {
+					t.Errorf("Definition %d mismatch: expected %+v, got %+v", i, defs[i], decoded[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeDefinitions_CSVColumnsByHeader(t *testing.T) {
+	input := "Priority,Threshold,Tag,Type\nCritical,100,sensor1,High\n"
+	defs, err := DecodeDefinitions(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if len(defs) != 1 || defs[0].Tag != "sensor1" || defs[0].Threshold != 100 || defs[0].Priority != "Critical" {
+		t.Errorf("Unexpected definitions: %+v", defs[0])
+	}
+
+	_, err = DecodeDefinitions(strings.NewReader("tag,type\nsensor1,High\n"), FormatCSV)
+	if err == nil {
+		t.Error("Expected error for missing threshold column")
+	}
+}
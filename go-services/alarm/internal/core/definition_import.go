@@ -0,0 +1,184 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrDefinitionInUse is returned when an import would delete a definition
+// that still has an active alarm. The alarm has to return to normal or be
+// shelved out of the way first, so that it never disappears from the HMI
+// without a trace.
+var ErrDefinitionInUse = errors.New("definition has an active alarm")
+
+type DefinitionUpdate struct {
+	Before *AlarmDefinition `json:"before"`
+	After  *AlarmDefinition `json:"after"`
+}
+
+// ImportReport describes what an import changes relative to the stored
+// definitions. Definitions are matched by tag and type.
+type ImportReport struct {
+	DryRun    bool               `json:"dry_run"`
+	Added     []*AlarmDefinition `json:"added"`
+	Changed   []DefinitionUpdate `json:"changed"`
+	Deleted   []*AlarmDefinition `json:"deleted"`
+	Unchanged int                `json:"unchanged"`
+	// InUse lists deleted definitions that still have an active alarm;
+	// an import with any of them is refused.
+	InUse []*AlarmDefinition `json:"in_use"`
+	// Managed counts anomaly definitions in the import. The service
+	// creates those itself, so imports neither add, change nor delete them.
+	Managed int `json:"managed"`
+
+	// PriorityDistribution describes the imported set as a whole.
+	PriorityDistribution *PriorityDistribution `json:"priority_distribution"`
+}
+
+// auditDetails describes an applied import for the audit trail, naming
+// the definitions it touched by key.
+func (r *ImportReport) auditDetails() map[string]interface{} {
+	keys := func(defs []*AlarmDefinition) []string {
+		out := make([]string, len(defs))
+		for i, def := range defs {
+			out[i] = def.Key()
+		}
+		return out
+	}
+	changed := make([]string, len(r.Changed))
+	for i, u := range r.Changed {
+		changed[i] = u.After.Key()
+	}
+	return map[string]interface{}{
+		"added":                 keys(r.Added),
+		"changed":               changed,
+		"deleted":               keys(r.Deleted),
+		"unchanged":             r.Unchanged,
+		"managed":               r.Managed,
+		"priority_distribution": r.PriorityDistribution,
+	}
+}
+
+// sameSettings reports whether two definitions with the same key would
+// evaluate and display identically.
+func sameSettings(a, b *AlarmDefinition) bool {
+	return a.Threshold == b.Threshold &&
+		a.Priority == b.Priority &&
+		a.Rationale == b.Rationale &&
+		a.Rationalization == b.Rationalization
+}
+
+func (s *AlarmService) ExportDefinitions() ([]*AlarmDefinition, error) {
+	return s.repo.ListDefinitions()
+}
+
+// ImportDefinitions treats defs as the complete master alarm database:
+// stored definitions that are missing from defs are deleted, unless they
+// have an active alarm, in which case the import is refused. Anomaly
+// definitions are left out on both sides. With dryRun set only the report
+// is produced. Otherwise the changes are applied in
+// one repository transaction, the in-memory state is reloaded and the
+// import is recorded in the audit trail on behalf of actor.
+func (s *AlarmService) ImportDefinitions(defs []*AlarmDefinition, actor string, dryRun bool) (*ImportReport, error) {
+	s.importMu.Lock()
+	defer s.importMu.Unlock()
+
+	s.mu.RLock()
+	matrix := s.priorityMatrix
+	s.mu.RUnlock()
+
+	incoming := make(map[string]*AlarmDefinition, len(defs))
+	var imported []*AlarmDefinition
+	managed := 0
+	for i, def := range defs {
+		if err := def.Validate(); err != nil {
+			return nil, fmt.Errorf("definition %d: %w", i+1, err)
+		}
+		if def.Type == AnomalyType {
+			managed++
+			continue
+		}
+		if err := matrix.Apply(def); err != nil {
+			return nil, fmt.Errorf("definition %d: %w", i+1, err)
+		}
+		if _, dup := incoming[def.Key()]; dup {
+			return nil, fmt.Errorf("definition %d: duplicate definition for %s", i+1, def.Key())
+		}
+		incoming[def.Key()] = def
+		imported = append(imported, def)
+	}
+
+	stored, err := s.repo.ListDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{
+		DryRun:               dryRun,
+		Added:                []*AlarmDefinition{},
+		Changed:              []DefinitionUpdate{},
+		Deleted:              []*AlarmDefinition{},
+		InUse:                []*AlarmDefinition{},
+		Managed:              managed,
+		PriorityDistribution: matrix.Distribution(imported),
+	}
+	var updates []*AlarmDefinition
+	var deleteIDs []int
+
+	s.mu.RLock()
+	existing := make(map[string]bool, len(stored))
+	for _, old := range stored {
+		if old.Type == AnomalyType {
+			continue
+		}
+		existing[old.Key()] = true
+		def, ok := incoming[old.Key()]
+		if !ok {
+			report.Deleted = append(report.Deleted, old)
+			deleteIDs = append(deleteIDs, old.ID)
+			if _, active := s.activeAlarms[old.ID]; active {
+				report.InUse = append(report.InUse, old)
+			}
+			continue
+		}
+		if sameSettings(old, def) {
+			report.Unchanged++
+			continue
+		}
+		updated := *def
+		updated.ID = old.ID
+		updated.CreatedAt = old.CreatedAt
+		report.Changed = append(report.Changed, DefinitionUpdate{Before: old, After: &updated})
+		updates = append(updates, &updated)
+	}
+	s.mu.RUnlock()
+	for _, def := range imported {
+		if !existing[def.Key()] {
+			def.ID = 0
+			report.Added = append(report.Added, def)
+		}
+	}
+
+	sort.Slice(report.Deleted, func(i, j int) bool { return report.Deleted[i].Key() < report.Deleted[j].Key() })
+	sort.Slice(report.InUse, func(i, j int) bool { return report.InUse[i].Key() < report.InUse[j].Key() })
+
+	if dryRun {
+		return report, nil
+	}
+	if len(report.InUse) > 0 {
+		return nil, fmt.Errorf("%w: cannot delete %s", ErrDefinitionInUse, report.InUse[0].Key())
+	}
+
+	if err := s.repo.ApplyDefinitionImport(report.Added, updates, deleteIDs); err != nil {
+		return nil, err
+	}
+
+	// Hot-reload so evaluation picks up the new set immediately
+	reloadErr := s.LoadDefinitions()
+	s.publishAudit(actor, "alarm_definitions_imported", report.auditDetails())
+	if reloadErr != nil {
+		return nil, fmt.Errorf("import applied but reload failed: %w", reloadErr)
+	}
+	return report, nil
+}
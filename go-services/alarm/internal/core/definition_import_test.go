@@ -0,0 +1,143 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+func TestAlarmService_ImportDefinitions(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repo, publisher)
+
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor1", Type: "High", Threshold: 100, Priority: "Critical"})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor2", Type: "Low", Threshold: 10, Priority: "Warning"})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor3", Type: "High", Threshold: 50, Priority: "Warning"})
+	svc.LoadDefinitions()
+
+	imported := []*core.AlarmDefinition{
+		{Tag: "sensor1", Type: "High", Threshold: 100, Priority: "Critical"},                   // unchanged
+		{Tag: "sensor2", Type: "Low", Threshold: 5, Priority: "Warning", Rationale: "Dry run"}, // changed
+		{Tag: "sensor4", Type: "High", Threshold: 80, Priority: "Warning"},                     // added
+	}
+
+	// Dry run reports without touching the repository
+	report, err := svc.ImportDefinitions(imported, "alice", true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(report.Added) != 1 || len(report.Changed) != 1 || len(report.Deleted) != 1 || report.Unchanged != 1 {
+		t.Fatalf("Unexpected report: added=%d changed=%d deleted=%d unchanged=%d",
+			len(report.Added), len(report.Changed), len(report.Deleted), report.Unchanged)
+	}
+	if report.Deleted[0].Tag != "sensor3" {
+		t.Errorf("Expected sensor3 to be deleted, got %s", report.Deleted[0].Tag)
+	}
+	if len(storedDefinitions(t, repo)) != 3 {
+		t.Errorf("Dry run must not modify the repository, got %d definitions", len(storedDefinitions(t, repo)))
+	}
+
+	// Apply and verify the service was reloaded
+	if _, err := svc.ImportDefinitions(imported, "alice", false); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(storedDefinitions(t, repo)) != 3 {
+		t.Errorf("Expected 3 definitions after import, got %d", len(storedDefinitions(t, repo)))
+	}
+	var records []*core.AuditRecord
+	for _, r := range publisher.records {
+		if r.Action == "alarm_definitions_imported" {
+			records = append(records, r)
+		}
+	}
+	if len(records) != 1 || records[0].Actor != "alice" {
+		t.Fatalf("Expected only the applied import to be audited, got %+v", records)
+	}
+	if deleted, _ := records[0].Details["deleted"].([]string); len(deleted) != 1 || deleted[0] != "sensor3/High" {
+		t.Errorf("Expected the audit record to name the deleted definition, got %+v", records[0].Details)
+	}
+
+	svc.ProcessValue("sensor3", 60)
+	svc.ProcessValue("sensor4", 81)
+	svc.ProcessValue("sensor2", 7)
+	alarms := svc.GetActiveAlarms()
+	if len(alarms) != 1 {
+		t.Fatalf("Expected only the new sensor4 definition to fire, got %d alarms", len(alarms))
+	}
+}
+
+func TestAlarmService_ImportDefinitions_Invalid(t *testing.T) {
+	svc := core.NewAlarmService(repository.NewMemoryRepository(), &MockPublisher{})
+
+	_, err := svc.ImportDefinitions([]*core.AlarmDefinition{
+		{Tag: "sensor1", Type: "High", Threshold: 100},
+		{Tag: "sensor1", Type: "High", Threshold: 90},
+	}, "alice", true)
+	if err == nil {
+		t.Error("Expected error for duplicate definitions")
+	}
+
+	_, err = svc.ImportDefinitions([]*core.AlarmDefinition{{Tag: "sensor1", Type: "Rate", Threshold: 1}}, "alice", true)
+	if err == nil {
+		t.Error("Expected error for unsupported alarm type")
+	}
+}
+
+func TestAlarmService_ImportDefinitions_KeepsLiveAlarms(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := core.NewAlarmService(repo, &MockPublisher{})
+
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor1", Type: "High", Threshold: 100, Priority: "High"})
+	sensor2 := &core.AlarmDefinition{Tag: "sensor2", Type: "High", Threshold: 50, Priority: "Low"}
+	repo.CreateDefinition(sensor2)
+	svc.LoadDefinitions()
+	svc.ProcessValue("sensor2", 60)
+	svc.ProcessAnomaly(&pb.AnomalyEvent{SourceTag: "reactor.temp", Actual: 80, Predicted: 60, Severity: "CRITICAL"})
+
+	imported := []*core.AlarmDefinition{{Tag: "sensor1", Type: "High", Threshold: 100, Priority: "High"}}
+
+	// Anomaly definitions are the service's own and are not deleted
+	report, err := svc.ImportDefinitions(imported, "alice", true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(report.Deleted) != 1 || len(report.InUse) != 1 || report.InUse[0].Tag != "sensor2" {
+		t.Fatalf("Expected only sensor2 to be deleted and reported in use, got %+v", report)
+	}
+
+	if _, err := svc.ImportDefinitions(imported, "alice", false); !errors.Is(err, core.ErrDefinitionInUse) {
+		t.Fatalf("Expected ErrDefinitionInUse, got %v", err)
+	}
+	if len(storedDefinitions(t, repo)) != 3 || len(svc.GetActiveAlarms()) != 2 {
+		t.Errorf("Expected refused import to leave definitions and alarms alone")
+	}
+
+	// Once sensor2 returns to normal it can go, and the anomaly stays
+	svc.ProcessValue("sensor2", 40)
+	for _, a := range svc.GetActiveAlarms() {
+		if a.DefinitionID == sensor2.ID {
+			svc.Acknowledge(a.ID, "operator", "")
+		}
+	}
+
+	imported = append(imported, &core.AlarmDefinition{Tag: "reactor.temp", Type: core.AnomalyType, Priority: "Low"})
+	report, err = svc.ImportDefinitions(imported, "alice", false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(report.Deleted) != 1 || report.Managed != 1 || len(report.Added) != 0 {
+		t.Errorf("Expected sensor2 deleted and the anomaly definition skipped, got %+v", report)
+	}
+	for _, def := range storedDefinitions(t, repo) {
+		if def.Type == core.AnomalyType && def.Priority != "High" {
+			t.Errorf("Expected the anomaly definition to be left alone, got %+v", def)
+		}
+	}
+	if len(storedDefinitions(t, repo)) != 2 {
+		t.Errorf("Expected sensor1 and the anomaly definition to remain, got %d", len(storedDefinitions(t, repo)))
+	}
+}
@@ -1,15 +1,50 @@
 package core
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type AlarmDefinition struct {
 	ID        int       `json:"id"`
 	Tag       string    `json:"tag"`
 	Threshold float64   `json:"threshold"`
-	Type      string    `json:"type"`     // High, Low
-	Priority  string    `json:"priority"` // Critical, Warning
+	Type      string    `json:"type"`     // High, Low, Anomaly
+	Priority  string    `json:"priority"` // from the priority matrix, e.g. High, Medium, Low
+	Rationale string    `json:"rationale"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	Rationalization
+}
+
+// Rationalization is the operator guidance recorded for a definition
+// during alarm rationalization.
+type Rationalization struct {
+	Cause               string `json:"cause"`
+	Consequence         string `json:"consequence"`
+	Severity            string `json:"severity"` // Consequence severity, an axis of the priority matrix
+	CorrectiveAction    string `json:"corrective_action"`
+	ResponseTimeSeconds int    `json:"response_time_seconds"` // Maximum allowed time to respond
+	Class               string `json:"class"`
+}
+
+// Key identifies a definition independently of its database ID.
+// It mirrors the unique (tag, alarm_type) index.
+func (d *AlarmDefinition) Key() string {
+	return d.Tag + "/" + d.Type
+}
+
+func (d *AlarmDefinition) Validate() error {
+	if d.Tag == "" {
+		return fmt.Errorf("tag is required")
+	}
+	switch d.Type {
+	case "High", "Low", AnomalyType:
+	default:
+		return fmt.Errorf("unsupported alarm type %q for tag %s", d.Type, d.Tag)
+	}
+	return nil
 }
 
 type ActiveAlarm struct {
@@ -17,6 +52,7 @@ type ActiveAlarm struct {
 	DefinitionID   int        `json:"definition_id"`
 	State          string     `json:"state"`
 	ActivationTime time.Time  `json:"activation_time"`
+	OnsetTime      *time.Time `json:"onset_time,omitempty"` // Source time the condition started
 	AckTime        *time.Time `json:"ack_time,omitempty"`
 	ShelvedUntil   *time.Time `json:"shelved_until,omitempty"`
 	Value          float64    `json:"value"`
@@ -24,15 +60,86 @@ type ActiveAlarm struct {
 	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
+// Onset returns the source time the alarm's condition started. Alarms
+// stored before onsets were recorded fall back to their activation time.
+func (a *ActiveAlarm) Onset() time.Time {
+	if a.OnsetTime != nil {
+		return *a.OnsetTime
+	}
+	return a.ActivationTime
+}
+
+// ActiveAlarmView is an active alarm together with the definition context
+// the HMI needs to display it.
+type ActiveAlarmView struct {
+	*ActiveAlarm
+	Tag      string           `json:"tag"`
+	Type     string           `json:"type"`
+	Priority string           `json:"priority"`
+	Guidance *Rationalization `json:"guidance,omitempty"`
+
+	GroupID       int  `json:"group_id,omitempty"`
+	FirstOut      bool `json:"first_out,omitempty"`
+	Consequential bool `json:"consequential,omitempty"`
+}
+
+// AlarmHistoryEntry is one recorded alarm state transition.
+type AlarmHistoryEntry struct {
+	ID            int       `json:"id"`
+	AlarmID       int       `json:"alarm_id"`
+	DefinitionID  int       `json:"definition_id"`
+	Tag           string    `json:"tag"`
+	Priority      string    `json:"priority"`
+	PreviousState string    `json:"previous_state"`
+	State         string    `json:"state"`
+	Actor         string    `json:"actor"`
+	Comment       string    `json:"comment,omitempty"`
+	Message       string    `json:"message"`
+	Value         float64   `json:"value"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// IsAck reports whether the transition was an operator acknowledgement.
+func (e *AlarmHistoryEntry) IsAck() bool {
+	if e.Actor == ActorSystem {
+		return false
+	}
+	prev, next := AlarmState(e.PreviousState), AlarmState(e.State)
+	return (prev == StateUnackActive && next == StateAckActive) ||
+		(prev == StateUnackRTN && next == StateNormal)
+}
+
+// IsActivation reports whether the transition raised the alarm.
+func (e *AlarmHistoryEntry) IsActivation() bool {
+	return AlarmState(e.State) == StateUnackActive && AlarmState(e.PreviousState) != StateUnackActive
+}
+
 type AlarmRepository interface {
 	CreateDefinition(def *AlarmDefinition) error
 	GetDefinition(id int) (*AlarmDefinition, error)
 	ListDefinitions() ([]*AlarmDefinition, error)
 	GetDefinitionsByTag(tag string) ([]*AlarmDefinition, error)
+	// ApplyDefinitionImport applies an import atomically: either every
+	// add, update and delete succeeds or none does.
+	ApplyDefinitionImport(adds, updates []*AlarmDefinition, deleteIDs []int) error
+	UpdateDefinitionPriority(id int, priority string) error
 
 	CreateActiveAlarm(alarm *ActiveAlarm) error
 	UpdateActiveAlarmState(id int, state string) error
 	AckActiveAlarm(id int, ackTime time.Time) error
 	ShelveActiveAlarm(id int, shelvedUntil time.Time) error
 	GetActiveAlarms() ([]*ActiveAlarm, error)
+
+	CreateSuppressionWindow(w *SuppressionWindow) error
+	// ListSuppressionWindows returns windows that have not expired.
+	ListSuppressionWindows() ([]*SuppressionWindow, error)
+	ExpireSuppressionWindow(id int, expiredAt time.Time) error
+
+	AppendAlarmHistory(entry *AlarmHistoryEntry) error
+	// ListAlarmHistory returns transitions in [from, to), oldest first.
+	ListAlarmHistory(from, to time.Time) ([]*AlarmHistoryEntry, error)
+
+	CreateAlarmGroup(g *AlarmGroup) error
+	ListAlarmGroups() ([]*AlarmGroup, error)
+	DeleteAlarmGroup(id int) error
 }
@@ -0,0 +1,48 @@
+package core
+
+import "time"
+
+// Hooks for the tests in core_test, which run against the real in-memory
+// repository and so can't live in this package.
+
+func (s *AlarmService) CheckAnomalies()   { s.checkAnomalies() }
+func (s *AlarmService) CheckChattering()  { s.checkChattering() }
+func (s *AlarmService) CheckAlarmGroups() { s.checkAlarmGroups() }
+
+// BackdateAnomaly moves the last anomaly seen on a definition back by d.
+func (s *AlarmService) BackdateAnomaly(defID int, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.anomalyLastSeen[defID] = s.anomalyLastSeen[defID].Add(-d)
+}
+
+// BackdateChatter moves every activation counted for chattering back by d.
+func (s *AlarmService) BackdateChatter(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tracker := range s.chatter {
+		for i := range tracker.activations {
+			tracker.activations[i] = tracker.activations[i].Add(-d)
+		}
+	}
+}
+
+// ChatterTrackers returns how many definitions have chatter state.
+func (s *AlarmService) ChatterTrackers() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.chatter)
+}
+
+// ForgetDefinition drops a definition from the evaluation state while its
+// alarm and groups stay loaded, as when it goes missing underneath them.
+func (s *AlarmService) ForgetDefinition(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	def, ok := s.definitionsByID[id]
+	if !ok {
+		return
+	}
+	delete(s.definitionsByID, id)
+	delete(s.definitions, def.Tag)
+}
@@ -19,11 +19,13 @@ const (
 type AlarmEvent string
 
 const (
-	EventTrigger  AlarmEvent = "Trigger"
-	EventClear    AlarmEvent = "Clear"
-	EventAck      AlarmEvent = "Ack"
-	EventShelve   AlarmEvent = "Shelve"
-	EventUnshelve AlarmEvent = "Unshelve"
+	EventTrigger    AlarmEvent = "Trigger"
+	EventClear      AlarmEvent = "Clear"
+	EventAck        AlarmEvent = "Ack"
+	EventShelve     AlarmEvent = "Shelve"
+	EventUnshelve   AlarmEvent = "Unshelve"
+	EventSuppress   AlarmEvent = "Suppress"
+	EventUnsuppress AlarmEvent = "Unsuppress"
 )
 
 type AlarmFSM struct {
@@ -45,6 +47,8 @@ func (fsm *AlarmFSM) Transition(event AlarmEvent) (AlarmState, error) {
 			fsm.State = StateUnackActive
 		case EventShelve:
 			fsm.State = StateShelved
+		case EventSuppress:
+			fsm.State = StateSuppressed
 		default:
 			return fsm.State, fmt.Errorf("invalid transition from %s with event %s", fsm.State, event)
 		}
@@ -57,6 +61,8 @@ func (fsm *AlarmFSM) Transition(event AlarmEvent) (AlarmState, error) {
 			fsm.State = StateUnackRTN
 		case EventShelve:
 			fsm.State = StateShelved
+		case EventSuppress:
+			fsm.State = StateSuppressed
 		default:
 			return fsm.State, fmt.Errorf("invalid transition from %s with event %s", fsm.State, event)
 		}
@@ -67,6 +73,8 @@ func (fsm *AlarmFSM) Transition(event AlarmEvent) (AlarmState, error) {
 			fsm.State = StateNormal
 		case EventShelve:
 			fsm.State = StateShelved
+		case EventSuppress:
+			fsm.State = StateSuppressed
 		default:
 			return fsm.State, fmt.Errorf("invalid transition from %s with event %s", fsm.State, event)
 		}
@@ -79,6 +87,8 @@ func (fsm *AlarmFSM) Transition(event AlarmEvent) (AlarmState, error) {
 			fsm.State = StateUnackActive
 		case EventShelve:
 			fsm.State = StateShelved
+		case EventSuppress:
+			fsm.State = StateSuppressed
 		default:
 			return fsm.State, fmt.Errorf("invalid transition from %s with event %s", fsm.State, event)
 		}
@@ -92,8 +102,18 @@ func (fsm *AlarmFSM) Transition(event AlarmEvent) (AlarmState, error) {
 			return fsm.State, fmt.Errorf("invalid transition from %s with event %s", fsm.State, event)
 		}
 
+	case StateSuppressed:
+		switch event {
+		case EventUnsuppress:
+			fsm.State = StateNormal
+		case EventShelve:
+			fsm.State = StateShelved
+		default:
+			return fsm.State, fmt.Errorf("invalid transition from %s with event %s", fsm.State, event)
+		}
+
 	default:
-		// Fallback for unknown states, e.g. Suppressed
+		// Fallback for unknown states
 		if event == EventShelve {
 			fsm.State = StateShelved
 			return fsm.State, nil
@@ -21,6 +21,8 @@ func TestFSM_Transitions(t *testing.T) {
 		// Shelving logic might be separate or part of FSM
 		{"Normal -> Shelve -> Shelved", StateNormal, EventShelve, StateShelved},
 		{"Shelved -> Unshelve -> Normal", StateShelved, EventUnshelve, StateNormal},
+		{"UnackActive -> Suppress -> Suppressed", StateUnackActive, EventSuppress, StateSuppressed},
+		{"Suppressed -> Unsuppress -> Normal", StateSuppressed, EventUnsuppress, StateNormal},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,447 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	// GroupModeFirstOut only tracks which member alarmed first.
+	GroupModeFirstOut = "first_out"
+	// GroupModeParentChild also treats members alarming after the parent
+	// as consequential and applies ChildAction to them.
+	GroupModeParentChild = "parent_child"
+
+	ChildActionSuppress     = "suppress"
+	ChildActionDeprioritize = "deprioritize"
+)
+
+// AlarmGroup ties definitions together for cause analysis. Every group
+// reports its first-out member: the one whose condition started earliest by
+// SensorData timestamp since the group last went quiet.
+type AlarmGroup struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	Mode          string    `json:"mode"`
+	ParentID      int       `json:"parent_id,omitempty"` // Parent definition, parent_child only
+	MemberIDs     []int     `json:"member_ids"`          // Children in parent_child mode
+	ChildAction   string    `json:"child_action,omitempty"`
+	ChildPriority string    `json:"child_priority,omitempty"` // Used by the deprioritize action
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (g *AlarmGroup) Validate() error {
+	if g.Name == "" {
+		return fmt.Errorf("alarm group name is required")
+	}
+	switch g.Mode {
+	case GroupModeFirstOut:
+		if len(g.MemberIDs) < 2 {
+			return fmt.Errorf("first_out group needs at least two members")
+		}
+		if g.ParentID != 0 {
+			return fmt.Errorf("parent_id is only valid for parent_child groups")
+		}
+	case GroupModeParentChild:
+		if g.ParentID == 0 || len(g.MemberIDs) == 0 {
+			return fmt.Errorf("parent_child group needs a parent_id and at least one member")
+		}
+		if g.ChildAction == "" {
+			g.ChildAction = ChildActionSuppress
+		}
+		switch g.ChildAction {
+		case ChildActionSuppress:
+		case ChildActionDeprioritize:
+			if g.ChildPriority == "" {
+				g.ChildPriority = "Low"
+			}
+		default:
+			return fmt.Errorf("unsupported child_action %q", g.ChildAction)
+		}
+	default:
+		return fmt.Errorf("unsupported alarm group mode %q", g.Mode)
+	}
+
+	seen := make(map[int]bool)
+	for _, id := range g.Definitions() {
+		if seen[id] {
+			return fmt.Errorf("definition %d listed twice in group", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// Definitions returns every definition in the group, parent first.
+func (g *AlarmGroup) Definitions() []int {
+	if g.ParentID == 0 {
+		return g.MemberIDs
+	}
+	return append([]int{g.ParentID}, g.MemberIDs...)
+}
+
+func (g *AlarmGroup) isChild(defID int) bool {
+	return g.Mode == GroupModeParentChild && defID != g.ParentID
+}
+
+func (g *AlarmGroup) auditDetails() map[string]interface{} {
+	details := map[string]interface{}{
+		"group_id":   g.ID,
+		"name":       g.Name,
+		"mode":       g.Mode,
+		"member_ids": g.MemberIDs,
+	}
+	if g.Mode == GroupModeParentChild {
+		details["parent_id"] = g.ParentID
+		details["child_action"] = g.ChildAction
+	}
+	return details
+}
+
+// groupEpisode covers one burst of alarms in a group, from the first
+// member alarming until every member is back to normal.
+type groupEpisode struct {
+	onsets       map[int]time.Time // Definition ID -> source time its condition started
+	firstOut     int
+	firstOutTime time.Time
+}
+
+func (e *groupEpisode) record(defID int, at time.Time) {
+	if _, ok := e.onsets[defID]; ok {
+		return
+	}
+	e.onsets[defID] = at
+	if e.firstOut == 0 || at.Before(e.firstOutTime) {
+		e.firstOut, e.firstOutTime = defID, at
+	}
+}
+
+// AlarmGroupMember is one definition in a group view.
+type AlarmGroupMember struct {
+	DefinitionID  int        `json:"definition_id"`
+	Tag           string     `json:"tag"`
+	Parent        bool       `json:"parent,omitempty"`
+	State         string     `json:"state"`
+	Onset         *time.Time `json:"onset,omitempty"`
+	FirstOut      bool       `json:"first_out,omitempty"`
+	Consequential bool       `json:"consequential,omitempty"`
+}
+
+// AlarmGroupView is a group with the live state of its members, ordered by
+// onset so the first-out member comes first.
+type AlarmGroupView struct {
+	*AlarmGroup
+	Active       bool                `json:"active"`
+	FirstOutID   int                 `json:"first_out_definition_id,omitempty"`
+	FirstOutTime *time.Time          `json:"first_out_time,omitempty"`
+	Members      []*AlarmGroupMember `json:"members"`
+}
+
+// isStanding reports whether an alarm in state keeps its group's episode open.
+func isStanding(state AlarmState) bool {
+	return state == StateUnackActive || state == StateAckActive || state == StateUnackRTN
+}
+
+func (s *AlarmService) CreateAlarmGroup(g *AlarmGroup) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	for _, id := range g.Definitions() {
+		if _, ok := s.definitionsByID[id]; !ok {
+			s.mu.RUnlock()
+			return fmt.Errorf("definition %d not found", id)
+		}
+		if other, ok := s.groupOf[id]; ok {
+			s.mu.RUnlock()
+			return fmt.Errorf("definition %d already belongs to group %q", id, other.Name)
+		}
+	}
+	s.mu.RUnlock()
+
+	if err := s.repo.CreateAlarmGroup(g); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.addGroupLocked(g)
+	s.reconcileGroupLocked(g, time.Now())
+	s.mu.Unlock()
+
+	s.publishAudit(g.CreatedBy, "alarm_group_created", g.auditDetails())
+	return nil
+}
+
+func (s *AlarmService) DeleteAlarmGroup(id int, actor string) error {
+	s.mu.Lock()
+	g, ok := s.groups[id]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("alarm group %d not found", id)
+	}
+	if err := s.repo.DeleteAlarmGroup(id); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	delete(s.groups, id)
+	delete(s.episodes, id)
+	for _, defID := range g.Definitions() {
+		delete(s.groupOf, defID)
+	}
+	// Release children this group was holding back
+	s.releaseSuppressedLocked(g, time.Now())
+	s.mu.Unlock()
+
+	s.publishAudit(actor, "alarm_group_deleted", g.auditDetails())
+	return nil
+}
+
+// addGroupLocked indexes a group and seeds its episode from alarms that are
+// already standing, e.g. after a restart, using their stored onsets.
+// Callers hold s.mu.
+func (s *AlarmService) addGroupLocked(g *AlarmGroup) {
+	s.groups[g.ID] = g
+	for _, defID := range g.Definitions() {
+		s.groupOf[defID] = g
+	}
+	for _, defID := range g.Definitions() {
+		if active, ok := s.activeAlarms[defID]; ok && (isStanding(AlarmState(active.State)) || active.State == string(StateSuppressed)) {
+			s.episodeLocked(g).record(defID, active.Onset())
+		}
+	}
+}
+
+func (s *AlarmService) episodeLocked(g *AlarmGroup) *groupEpisode {
+	ep, ok := s.episodes[g.ID]
+	if !ok {
+		ep = &groupEpisode{onsets: make(map[int]time.Time)}
+		s.episodes[g.ID] = ep
+	}
+	return ep
+}
+
+// recordOnsetLocked notes the source time at which a member's condition
+// started and returns the onset for the current episode. Callers hold s.mu.
+func (s *AlarmService) recordOnsetLocked(def *AlarmDefinition, firing bool, at time.Time) time.Time {
+	g, ok := s.groupOf[def.ID]
+	if !ok {
+		return at
+	}
+	ep, ok := s.episodes[g.ID]
+	if firing {
+		ep = s.episodeLocked(g)
+		ep.record(def.ID, at)
+		ok = true
+	}
+	if ok {
+		if onset, recorded := ep.onsets[def.ID]; recorded {
+			return onset
+		}
+	}
+	return at
+}
+
+// consequentialLocked reports whether def is a child whose condition began
+// at or after its parent's while the parent is alarming. Callers hold s.mu.
+func (s *AlarmService) consequentialLocked(def *AlarmDefinition, onset time.Time) (*AlarmGroup, bool) {
+	g, ok := s.groupOf[def.ID]
+	if !ok || !g.isChild(def.ID) {
+		return nil, false
+	}
+	parent, ok := s.activeAlarms[g.ParentID]
+	if !ok {
+		return nil, false
+	}
+	if state := AlarmState(parent.State); state != StateUnackActive && state != StateAckActive {
+		return nil, false
+	}
+	ep, ok := s.episodes[g.ID]
+	if !ok {
+		return nil, false
+	}
+	parentOnset, ok := ep.onsets[g.ParentID]
+	if !ok {
+		return nil, false
+	}
+	if childOnset, ok := ep.onsets[def.ID]; ok {
+		onset = childOnset
+	}
+	return g, !onset.Before(parentOnset)
+}
+
+// groupSuppressesLocked reports whether def should be held in Suppressed
+// as a consequence of its parent. Callers hold s.mu.
+func (s *AlarmService) groupSuppressesLocked(def *AlarmDefinition, onset time.Time) bool {
+	g, consequential := s.consequentialLocked(def, onset)
+	return consequential && g.ChildAction == ChildActionSuppress
+}
+
+// groupPriorityLocked returns the priority alarms of def are raised with,
+// lowered for consequential children of deprioritize groups. Callers hold s.mu.
+func (s *AlarmService) groupPriorityLocked(def *AlarmDefinition) string {
+	g, consequential := s.consequentialLocked(def, time.Time{})
+	if consequential && g.ChildAction == ChildActionDeprioritize {
+		return g.ChildPriority
+	}
+	return def.Priority
+}
+
+// annotateGroupLocked returns the group marks for a definition's alarm.
+// Callers hold s.mu.
+func (s *AlarmService) annotateGroupLocked(def *AlarmDefinition) (groupID int, firstOut, consequential bool) {
+	g, ok := s.groupOf[def.ID]
+	if !ok {
+		return 0, false, false
+	}
+	if ep, ok := s.episodes[g.ID]; ok {
+		firstOut = ep.firstOut == def.ID
+	}
+	_, consequential = s.consequentialLocked(def, time.Time{})
+	return g.ID, firstOut, consequential
+}
+
+// parentTagLocked names g's parent in event messages. The parent definition
+// can be gone, e.g. deleted by an import, in which case its ID is used.
+// Callers hold s.mu.
+func (s *AlarmService) parentTagLocked(g *AlarmGroup) string {
+	if parent, ok := s.definitionsByID[g.ParentID]; ok {
+		return parent.Tag
+	}
+	return fmt.Sprintf("definition %d", g.ParentID)
+}
+
+// reconcileGroupLocked suppresses consequential children of an alarming
+// parent, releases them once the parent clears, and closes the episode when
+// no member is standing any more. Callers hold s.mu.
+func (s *AlarmService) reconcileGroupLocked(g *AlarmGroup, now time.Time) {
+	if g.Mode == GroupModeParentChild && g.ChildAction == ChildActionSuppress {
+		parent := s.parentTagLocked(g)
+		for _, defID := range g.MemberIDs {
+			def, ok := s.definitionsByID[defID]
+			active, exists := s.activeAlarms[defID]
+			if !ok || !exists || !isStanding(AlarmState(active.State)) {
+				continue
+			}
+			// Raised before its parent's onset was known, e.g. out-of-order samples
+			if s.groupSuppressesLocked(def, active.Onset()) {
+				s.transitionGroupMemberLocked(def, active, EventSuppress, now,
+					fmt.Sprintf("Alarm %s suppressed as a consequence of %s", def.Tag, parent))
+			}
+		}
+		s.releaseSuppressedLocked(g, now)
+	}
+
+	for _, defID := range g.Definitions() {
+		if active, ok := s.activeAlarms[defID]; ok && isStanding(AlarmState(active.State)) {
+			return
+		}
+	}
+	delete(s.episodes, g.ID)
+}
+
+// releaseSuppressedLocked returns children held back by g to Normal once
+// nothing suppresses them any more, so the next value re-evaluates them.
+// Callers hold s.mu.
+func (s *AlarmService) releaseSuppressedLocked(g *AlarmGroup, now time.Time) {
+	for _, defID := range g.MemberIDs {
+		def, ok := s.definitionsByID[defID]
+		active, exists := s.activeAlarms[defID]
+		if !ok || !exists || active.State != string(StateSuppressed) {
+			continue
+		}
+		if s.isSuppressedLocked(def) {
+			continue // Still covered by a maintenance window
+		}
+		if s.groupSuppressesLocked(def, time.Time{}) {
+			continue
+		}
+		s.transitionGroupMemberLocked(def, active, EventUnsuppress, now,
+			fmt.Sprintf("Alarm %s released: parent alarm cleared", def.Tag))
+	}
+}
+
+func (s *AlarmService) transitionGroupMemberLocked(def *AlarmDefinition, active *ActiveAlarm, event AlarmEvent, now time.Time, message string) {
+	currentState := AlarmState(active.State)
+	newState, err := NewAlarmFSM(currentState).Transition(event)
+	if err != nil {
+		return
+	}
+	if err := s.repo.UpdateActiveAlarmState(active.ID, string(newState)); err != nil {
+		log.Printf("Failed to update grouped alarm %d: %v", active.ID, err)
+		return
+	}
+	active.State = string(newState)
+	active.UpdatedAt = now
+	if newState == StateNormal {
+		delete(s.activeAlarms, def.ID)
+	}
+	s.publish(s.newEvent(def.ID, active, currentState, newState, ActorSystem, "", message))
+}
+
+// reconcileGroupOfLocked reconciles the group def belongs to, if any.
+// Callers hold s.mu.
+func (s *AlarmService) reconcileGroupOfLocked(def *AlarmDefinition, now time.Time) {
+	if g, ok := s.groupOf[def.ID]; ok {
+		s.reconcileGroupLocked(g, now)
+	}
+}
+
+// checkAlarmGroups runs on the background ticker to catch transitions made
+// outside evaluation, such as acknowledgements and unshelving.
+func (s *AlarmService) checkAlarmGroups() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, g := range s.groups {
+		s.reconcileGroupLocked(g, now)
+	}
+}
+
+func (s *AlarmService) GetAlarmGroups() []*AlarmGroupView {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	views := make([]*AlarmGroupView, 0, len(s.groups))
+	for _, g := range s.groups {
+		view := &AlarmGroupView{AlarmGroup: g, Members: make([]*AlarmGroupMember, 0, len(g.Definitions()))}
+		ep, active := s.episodes[g.ID]
+		view.Active = active
+		if active && ep.firstOut != 0 {
+			firstOutTime := ep.firstOutTime
+			view.FirstOutID = ep.firstOut
+			view.FirstOutTime = &firstOutTime
+		}
+
+		for _, defID := range g.Definitions() {
+			member := &AlarmGroupMember{DefinitionID: defID, Parent: g.ParentID == defID, State: string(StateNormal)}
+			if def, ok := s.definitionsByID[defID]; ok {
+				member.Tag = def.Tag
+				_, member.FirstOut, member.Consequential = s.annotateGroupLocked(def)
+			}
+			if a, ok := s.activeAlarms[defID]; ok {
+				member.State = a.State
+			}
+			if active {
+				if onset, ok := ep.onsets[defID]; ok {
+					member.Onset = &onset
+				}
+			}
+			view.Members = append(view.Members, member)
+		}
+		sort.SliceStable(view.Members, func(i, j int) bool {
+			a, b := view.Members[i].Onset, view.Members[j].Onset
+			if a == nil || b == nil {
+				return a != nil
+			}
+			return a.Before(*b)
+		})
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].ID < views[j].ID })
+	return views
+}
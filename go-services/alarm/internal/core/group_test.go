@@ -0,0 +1,192 @@
+package core_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+func sample(tag string, value float64, timestampMs int64) *pb.SensorData {
+	return &pb.SensorData{SensorId: tag, Value: value, TimestampMs: timestampMs}
+}
+
+func newGroupedService(t *testing.T, g *core.AlarmGroup) (*core.AlarmService, *MockPublisher) {
+	t.Helper()
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repo, publisher)
+
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "pump.trip", Threshold: 0.5, Type: "High", Priority: "Critical"})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "pump.flow", Threshold: 10, Type: "Low", Priority: "High"})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "pump.pressure", Threshold: 2, Type: "Low", Priority: "High"})
+	svc.LoadDefinitions()
+
+	if err := svc.CreateAlarmGroup(g); err != nil {
+		t.Fatalf("CreateAlarmGroup failed: %v", err)
+	}
+	return svc, publisher
+}
+
+func TestAlarmGroup_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		group core.AlarmGroup
+	}{
+		{"no name", core.AlarmGroup{Mode: core.GroupModeFirstOut, MemberIDs: []int{1, 2}}},
+		{"single first-out member", core.AlarmGroup{Name: "g", Mode: core.GroupModeFirstOut, MemberIDs: []int{1}}},
+		{"missing parent", core.AlarmGroup{Name: "g", Mode: core.GroupModeParentChild, MemberIDs: []int{2}}},
+		{"parent is a member", core.AlarmGroup{Name: "g", Mode: core.GroupModeParentChild, ParentID: 1, MemberIDs: []int{1, 2}}},
+		{"unknown action", core.AlarmGroup{Name: "g", Mode: core.GroupModeParentChild, ParentID: 1, MemberIDs: []int{2}, ChildAction: "hide"}},
+		{"unknown mode", core.AlarmGroup{Name: "g", Mode: "cascade", MemberIDs: []int{1, 2}}},
+	}
+	for _, tt := range tests {
+		if err := tt.group.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", tt.name)
+		}
+	}
+
+	g := core.AlarmGroup{Name: "g", Mode: core.GroupModeParentChild, ParentID: 1, MemberIDs: []int{2}, ChildAction: core.ChildActionDeprioritize}
+	if err := g.Validate(); err != nil || g.ChildPriority != "Low" {
+		t.Errorf("Expected default child priority, got %q (%v)", g.ChildPriority, err)
+	}
+}
+
+func TestAlarmService_FirstOutUsesSourceTimestamp(t *testing.T) {
+	svc, publisher := newGroupedService(t, &core.AlarmGroup{Name: "Pump", Mode: core.GroupModeFirstOut, MemberIDs: []int{2, 3}})
+
+	// Flow arrives first but was sampled after pressure
+	svc.ProcessSensorData(sample("pump.flow", 5, 2000))
+	svc.ProcessSensorData(sample("pump.pressure", 1, 1000))
+
+	groups := svc.GetAlarmGroups()
+	if len(groups) != 1 || !groups[0].Active || groups[0].FirstOutID != 3 {
+		t.Fatalf("Expected pressure to be first out, got %+v", groups)
+	}
+	if groups[0].Members[0].DefinitionID != 3 || !groups[0].Members[0].FirstOut {
+		t.Errorf("Expected members ordered by onset, got %+v", groups[0].Members)
+	}
+
+	last := publisher.events[len(publisher.events)-1]
+	if !last.FirstOut || last.GroupId != int32(groups[0].ID) || last.SourceTimestampMs != 1000 {
+		t.Errorf("Expected first-out marks on the event, got %+v", last)
+	}
+	for _, v := range svc.GetActiveAlarmViews() {
+		if v.FirstOut != (v.DefinitionID == 3) {
+			t.Errorf("Unexpected first-out mark on %s", v.Tag)
+		}
+	}
+
+	// The episode ends once every member is back to normal
+	svc.ProcessSensorData(sample("pump.flow", 20, 3000))
+	svc.ProcessSensorData(sample("pump.pressure", 5, 3000))
+	for _, v := range svc.GetActiveAlarmViews() {
+		svc.Acknowledge(v.ID, "alice", "")
+	}
+	svc.CheckAlarmGroups()
+	if groups := svc.GetAlarmGroups(); groups[0].Active {
+		t.Errorf("Expected the episode to close, got %+v", groups[0])
+	}
+}
+
+func TestAlarmService_FirstOutSurvivesRestart(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := core.NewAlarmService(repo, &MockPublisher{})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "pump.flow", Threshold: 10, Type: "Low", Priority: "High"})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "pump.pressure", Threshold: 2, Type: "Low", Priority: "High"})
+	svc.LoadDefinitions()
+	if err := svc.CreateAlarmGroup(&core.AlarmGroup{Name: "Pump", Mode: core.GroupModeFirstOut, MemberIDs: []int{1, 2}}); err != nil {
+		t.Fatalf("CreateAlarmGroup failed: %v", err)
+	}
+
+	// Flow is processed first but was sampled after pressure
+	svc.ProcessSensorData(sample("pump.flow", 5, 2000))
+	svc.ProcessSensorData(sample("pump.pressure", 1, 1000))
+
+	restarted := core.NewAlarmService(repo, &MockPublisher{})
+	if err := restarted.LoadDefinitions(); err != nil {
+		t.Fatalf("LoadDefinitions failed: %v", err)
+	}
+	groups := restarted.GetAlarmGroups()
+	if len(groups) != 1 || groups[0].FirstOutID != 2 || groups[0].FirstOutTime.UnixMilli() != 1000 {
+		t.Errorf("Expected pressure to stay first out by sample time, got %+v", groups)
+	}
+}
+
+func TestAlarmService_ParentSuppressesChildren(t *testing.T) {
+	svc, publisher := newGroupedService(t, &core.AlarmGroup{
+		Name: "Pump trip", Mode: core.GroupModeParentChild, ParentID: 1, MemberIDs: []int{2, 3},
+	})
+
+	svc.ProcessSensorData(sample("pump.trip", 1, 1000))
+	// Flow drops after the trip: consequential
+	svc.ProcessSensorData(sample("pump.flow", 5, 1500))
+	// Pressure dropped before the trip: a cause, not a consequence
+	svc.ProcessSensorData(sample("pump.pressure", 1, 900))
+
+	states := make(map[string]string)
+	for _, v := range svc.GetActiveAlarmViews() {
+		states[v.Tag] = v.State
+	}
+	if states["pump.flow"] != string(core.StateSuppressed) {
+		t.Errorf("Expected consequential child to be suppressed, got %s", states["pump.flow"])
+	}
+	if states["pump.pressure"] != string(core.StateUnackActive) {
+		t.Errorf("Expected earlier child to alarm, got %s", states["pump.pressure"])
+	}
+	if groups := svc.GetAlarmGroups(); groups[0].FirstOutID != 3 {
+		t.Errorf("Expected pressure to be first out, got %d", groups[0].FirstOutID)
+	}
+
+	// Parent clears: the suppressed child is released for re-evaluation
+	svc.ProcessSensorData(sample("pump.trip", 0, 2000))
+	for _, v := range svc.GetActiveAlarmViews() {
+		if v.Tag == "pump.flow" {
+			t.Errorf("Expected flow to be released, still %s", v.State)
+		}
+	}
+	last := publisher.events[len(publisher.events)-1]
+	if last.Tag != "pump.flow" || last.State != string(core.StateNormal) || last.PreviousState != string(core.StateSuppressed) {
+		t.Errorf("Expected a release event for flow, got %+v", last)
+	}
+}
+
+func TestAlarmService_ParentDefinitionMissing(t *testing.T) {
+	svc, publisher := newGroupedService(t, &core.AlarmGroup{
+		Name: "Pump trip", Mode: core.GroupModeParentChild, ParentID: 1, MemberIDs: []int{2, 3},
+	})
+
+	svc.ProcessSensorData(sample("pump.trip", 1, 1000))
+	svc.ForgetDefinition(1)
+	svc.ProcessSensorData(sample("pump.flow", 5, 1500))
+
+	last := publisher.events[len(publisher.events)-1]
+	if last.Tag != "pump.flow" || last.State != string(core.StateSuppressed) || !strings.Contains(last.Message, "definition 1") {
+		t.Errorf("Expected flow to be suppressed naming the parent by ID, got %+v", last)
+	}
+	if groups := svc.GetAlarmGroups(); len(groups) != 1 || len(groups[0].Members) != 3 {
+		t.Errorf("Expected the group to still list its members, got %+v", groups)
+	}
+}
+
+func TestAlarmService_ParentDeprioritizesChildren(t *testing.T) {
+	svc, publisher := newGroupedService(t, &core.AlarmGroup{
+		Name: "Pump trip", Mode: core.GroupModeParentChild, ParentID: 1, MemberIDs: []int{2},
+		ChildAction: core.ChildActionDeprioritize, ChildPriority: "Low",
+	})
+
+	svc.ProcessSensorData(sample("pump.trip", 1, 1000))
+	svc.ProcessSensorData(sample("pump.flow", 5, 1500))
+
+	last := publisher.events[len(publisher.events)-1]
+	if last.Tag != "pump.flow" || last.State != string(core.StateUnackActive) || last.Priority != "Low" || !last.Consequential {
+		t.Errorf("Expected flow raised at low priority, got %+v", last)
+	}
+	for _, v := range svc.GetActiveAlarmViews() {
+		if v.Tag == "pump.flow" && (v.Priority != "Low" || !v.Consequential) {
+			t.Errorf("Expected deprioritized view, got %+v", v)
+		}
+	}
+}
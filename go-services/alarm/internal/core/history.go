@@ -0,0 +1,87 @@
+package core
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+func historyFromEvent(event *pb.AlarmEvent) *AlarmHistoryEntry {
+	return &AlarmHistoryEntry{
+		AlarmID:       int(event.AlarmId),
+		DefinitionID:  int(event.DefinitionId),
+		Tag:           event.Tag,
+		Priority:      event.Priority,
+		PreviousState: event.PreviousState,
+		State:         event.State,
+		Actor:         event.Actor,
+		Comment:       event.Comment,
+		Message:       event.Message,
+		Value:         event.Value,
+		Timestamp:     time.UnixMilli(event.TimestampMs),
+	}
+}
+
+// historyWriter records alarm history off the evaluation path. Entries are
+// queued while the service holds its lock and written in queue order by a
+// single goroutine, so a slow history table never stalls processing.
+type historyWriter struct {
+	repo    AlarmRepository
+	mu      sync.Mutex
+	idle    *sync.Cond
+	pending []*AlarmHistoryEntry
+	writing bool
+}
+
+func newHistoryWriter(repo AlarmRepository) *historyWriter {
+	w := &historyWriter{repo: repo}
+	w.idle = sync.NewCond(&w.mu)
+	return w
+}
+
+// enqueue queues entry and starts a writer if none is running.
+func (w *historyWriter) enqueue(entry *AlarmHistoryEntry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, entry)
+	if !w.writing {
+		w.writing = true
+		go w.run()
+	}
+}
+
+func (w *historyWriter) run() {
+	w.mu.Lock()
+	for len(w.pending) > 0 {
+		batch := w.pending
+		w.pending = nil
+		w.mu.Unlock()
+		for _, entry := range batch {
+			if err := w.repo.AppendAlarmHistory(entry); err != nil {
+				log.Printf("Failed to record alarm history: %v", err)
+			}
+		}
+		w.mu.Lock()
+	}
+	w.writing = false
+	w.idle.Broadcast()
+	w.mu.Unlock()
+}
+
+// flush waits until every queued entry has been written.
+func (w *historyWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.writing {
+		w.idle.Wait()
+	}
+}
+
+// FlushHistory waits until every published transition is in the alarm
+// history. Reports call it before reading the history; the server calls it
+// on shutdown.
+func (s *AlarmService) FlushHistory() {
+	s.history.flush()
+}
@@ -0,0 +1,347 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidDefinition wraps definition validation failures so callers can
+// tell bad input apart from storage errors.
+var ErrInvalidDefinition = errors.New("invalid alarm definition")
+
+// PriorityMatrix derives a definition's priority from its rationalization:
+// how severe the consequence of not responding is, and how long the
+// operator has to respond. Shorter response times and worse consequences
+// give higher priorities.
+type PriorityMatrix struct {
+	// Severities from least to most severe, e.g. minor, major, severe.
+	Severities []string `json:"severities"`
+	// ResponseTimeSeconds are the ascending upper bounds of each response
+	// time band. A longer response time does not justify an alarm.
+	ResponseTimeSeconds []int `json:"response_time_seconds"`
+	// Priorities holds one row per response time band with one priority
+	// per severity.
+	Priorities [][]string `json:"priorities"`
+
+	// Targets is the recommended share of definitions at each priority.
+	Targets map[string]float64 `json:"targets"`
+	// Tolerance is how far, as a share, a priority may drift from its
+	// target before a warning is raised.
+	Tolerance float64 `json:"tolerance"`
+	// MinDefinitions is the smallest alarm database whose distribution is
+	// worth checking.
+	MinDefinitions int `json:"min_definitions"`
+
+	// Signed is the priority whose shelving or suppression has to be an
+	// electronically signed action once a SignatureVerifier is configured.
+	// It defaults to the matrix's highest priority: the shortest response
+	// time with the most severe consequence.
+	Signed string `json:"signed,omitempty"`
+	// Aliases maps priority names used before the matrix, e.g. Critical,
+	// onto the matrix's own. An aliased priority counts as its matrix
+	// priority for signatures, the matrix check and the distribution.
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// DefaultPriorityMatrix is a three priority matrix following the usual
+// ISA-18.2 guidance of roughly 5% high, 15% medium and 80% low.
+func DefaultPriorityMatrix() *PriorityMatrix {
+	return &PriorityMatrix{
+		Severities:          []string{"minor", "major", "severe"},
+		ResponseTimeSeconds: []int{180, 600, 1800},
+		Priorities: [][]string{
+			{"Medium", "High", "High"}, // up to 3 minutes
+			{"Low", "Medium", "High"},  // up to 10 minutes
+			{"Low", "Low", "Medium"},   // up to 30 minutes
+		},
+		Targets:        map[string]float64{"High": 0.05, "Medium": 0.15, "Low": 0.80},
+		Tolerance:      0.05,
+		MinDefinitions: 20,
+		Aliases:        map[string]string{"Critical": "High", "Warning": "Medium"},
+	}
+}
+
+// ParsePriorityMatrix reads a matrix as JSON and validates it. Tolerance
+// and MinDefinitions default to those of DefaultPriorityMatrix, and so do
+// Aliases onto priorities the matrix uses.
+func ParsePriorityMatrix(r io.Reader) (*PriorityMatrix, error) {
+	defaults := DefaultPriorityMatrix()
+	m := PriorityMatrix{Tolerance: defaults.Tolerance, MinDefinitions: defaults.MinDefinitions}
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid priority matrix JSON: %w", err)
+	}
+	if m.Aliases == nil {
+		m.Aliases = make(map[string]string)
+		for alias, priority := range defaults.Aliases {
+			if m.hasPriority(priority) && !m.hasPriority(alias) {
+				m.Aliases[alias] = priority
+			}
+		}
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (m *PriorityMatrix) Validate() error {
+	if len(m.Severities) == 0 || len(m.ResponseTimeSeconds) == 0 {
+		return fmt.Errorf("priority matrix needs severities and response time bands")
+	}
+	for i, bound := range m.ResponseTimeSeconds {
+		if bound <= 0 || (i > 0 && bound <= m.ResponseTimeSeconds[i-1]) {
+			return fmt.Errorf("priority matrix response times must be positive and ascending")
+		}
+	}
+	if len(m.Priorities) != len(m.ResponseTimeSeconds) {
+		return fmt.Errorf("priority matrix has %d rows for %d response time bands", len(m.Priorities), len(m.ResponseTimeSeconds))
+	}
+	for i, row := range m.Priorities {
+		if len(row) != len(m.Severities) {
+			return fmt.Errorf("priority matrix row %d has %d priorities for %d severities", i+1, len(row), len(m.Severities))
+		}
+	}
+	var total float64
+	for _, share := range m.Targets {
+		if share < 0 {
+			return fmt.Errorf("priority matrix targets must not be negative")
+		}
+		total += share
+	}
+	if len(m.Targets) > 0 && math.Abs(total-1) > 0.01 {
+		return fmt.Errorf("priority matrix targets add up to %.2f, not 1", total)
+	}
+	if m.Signed != "" && !m.hasPriority(m.Signed) {
+		return fmt.Errorf("priority matrix signed priority %s is not in the matrix", m.Signed)
+	}
+	for alias, priority := range m.Aliases {
+		if !m.hasPriority(priority) {
+			return fmt.Errorf("priority matrix alias %s maps to %s, which is not in the matrix", alias, priority)
+		}
+		if m.hasPriority(alias) {
+			return fmt.Errorf("priority matrix alias %s is already a priority in the matrix", alias)
+		}
+	}
+	return nil
+}
+
+func (m *PriorityMatrix) hasPriority(priority string) bool {
+	for _, row := range m.Priorities {
+		for _, p := range row {
+			if p == priority {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// levels returns the matrix priorities, each once, from the highest.
+func (m *PriorityMatrix) levels() []string {
+	var levels []string
+	seen := make(map[string]bool)
+	for _, row := range m.Priorities {
+		for i := len(row) - 1; i >= 0; i-- {
+			if !seen[row[i]] {
+				seen[row[i]] = true
+				levels = append(levels, row[i])
+			}
+		}
+	}
+	return levels
+}
+
+// Canonical returns the matrix priority that priority stands for.
+func (m *PriorityMatrix) Canonical(priority string) string {
+	if p, ok := m.Aliases[priority]; ok {
+		return p
+	}
+	return priority
+}
+
+// SignedPriority returns the matrix priority that requires signatures.
+func (m *PriorityMatrix) SignedPriority() string {
+	if m.Signed != "" {
+		return m.Signed
+	}
+	row := m.Priorities[0]
+	return row[len(row)-1]
+}
+
+// RequiresSignature reports whether shelving or suppressing an alarm of
+// the given priority has to be signed.
+func (m *PriorityMatrix) RequiresSignature(priority string) bool {
+	return priority != "" && m.Canonical(priority) == m.SignedPriority()
+}
+
+// Derive returns the priority for a severity and response time.
+func (m *PriorityMatrix) Derive(severity string, responseTimeSeconds int) (string, error) {
+	col := -1
+	for i, s := range m.Severities {
+		if strings.EqualFold(s, severity) {
+			col = i
+			break
+		}
+	}
+	if col < 0 {
+		return "", fmt.Errorf("unknown severity %q, expected one of %s", severity, strings.Join(m.Severities, ", "))
+	}
+	if responseTimeSeconds <= 0 {
+		return "", fmt.Errorf("response_time_seconds is required with a severity")
+	}
+	for row, bound := range m.ResponseTimeSeconds {
+		if responseTimeSeconds <= bound {
+			return m.Priorities[row][col], nil
+		}
+	}
+	return "", fmt.Errorf("a response time of %ds exceeds %ds and does not justify an alarm",
+		responseTimeSeconds, m.ResponseTimeSeconds[len(m.ResponseTimeSeconds)-1])
+}
+
+// Apply sets or checks the priority of a definition. For a rationalized
+// one an empty priority is filled in from the matrix; any other must match
+// it. Definitions without a severity have not been rationalized, but a
+// priority they carry must still be a matrix priority or an alias.
+func (m *PriorityMatrix) Apply(def *AlarmDefinition) error {
+	if def.Severity == "" {
+		if def.Priority != "" && !m.hasPriority(m.Canonical(def.Priority)) {
+			return fmt.Errorf("%s: unknown priority %q, expected one of %s",
+				def.Key(), def.Priority, strings.Join(m.levels(), ", "))
+		}
+		return nil
+	}
+	priority, err := m.Derive(def.Severity, def.ResponseTimeSeconds)
+	if err != nil {
+		return fmt.Errorf("%s: %w", def.Key(), err)
+	}
+	if def.Priority == "" {
+		def.Priority = priority
+		return nil
+	}
+	if m.Canonical(def.Priority) != priority {
+		return fmt.Errorf("%s: priority %s does not match %s from the priority matrix (severity %s, response time %ds)",
+			def.Key(), def.Priority, priority, def.Severity, def.ResponseTimeSeconds)
+	}
+	return nil
+}
+
+// PriorityShare is how one priority's share compares to its target.
+type PriorityShare struct {
+	Priority string  `json:"priority"`
+	Count    int     `json:"count"`
+	Share    float64 `json:"share"`
+	Target   float64 `json:"target"`
+	Drifted  bool    `json:"drifted"`
+}
+
+// PriorityDistribution summarises the priorities configured across the
+// alarm database against the matrix targets.
+type PriorityDistribution struct {
+	Total    int             `json:"total"`
+	Shares   []PriorityShare `json:"shares"`
+	Warnings []string        `json:"warnings"`
+}
+
+// Distribution counts defs by priority. Warnings are only raised once the
+// database holds MinDefinitions definitions.
+func (m *PriorityMatrix) Distribution(defs []*AlarmDefinition) *PriorityDistribution {
+	counts := make(map[string]int)
+	for _, def := range defs {
+		counts[m.Canonical(def.Priority)]++
+	}
+	for priority := range m.Targets {
+		if _, ok := counts[priority]; !ok {
+			counts[priority] = 0
+		}
+	}
+
+	dist := &PriorityDistribution{Total: len(defs), Shares: []PriorityShare{}, Warnings: []string{}}
+	for priority, n := range counts {
+		share := PriorityShare{Priority: priority, Count: n, Target: m.Targets[priority]}
+		if dist.Total > 0 {
+			share.Share = float64(n) / float64(dist.Total)
+		}
+		if len(m.Targets) > 0 && dist.Total >= m.MinDefinitions {
+			share.Drifted = math.Abs(share.Share-share.Target) > m.Tolerance+1e-9
+		}
+		dist.Shares = append(dist.Shares, share)
+	}
+	sort.Slice(dist.Shares, func(i, j int) bool {
+		a, b := dist.Shares[i], dist.Shares[j]
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Priority < b.Priority
+	})
+
+	for _, share := range dist.Shares {
+		if !share.Drifted {
+			continue
+		}
+		label := share.Priority
+		if label == "" {
+			label = "(none)"
+		}
+		dist.Warnings = append(dist.Warnings, fmt.Sprintf("%s priority is %.1f%% of %d definitions, target %.1f%%",
+			label, share.Share*100, dist.Total, share.Target*100))
+	}
+	return dist
+}
+
+// SetPriorityMatrix replaces the matrix used to derive and check
+// priorities.
+func (s *AlarmService) SetPriorityMatrix(m *PriorityMatrix) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.priorityMatrix = m
+}
+
+// GetPriorityDistribution reports the loaded definitions' priorities
+// against the matrix targets.
+func (s *AlarmService) GetPriorityDistribution() *PriorityDistribution {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.priorityDistributionLocked()
+}
+
+func (s *AlarmService) priorityDistributionLocked() *PriorityDistribution {
+	defs := make([]*AlarmDefinition, 0, len(s.definitionsByID))
+	for _, def := range s.definitionsByID {
+		defs = append(defs, def)
+	}
+	return s.priorityMatrix.Distribution(defs)
+}
+
+// warnPriorityDriftLocked logs a distribution that has drifted from its
+// targets. It is audited when the set of drifted priorities changes, not
+// on every reload while it stays the same. Callers hold s.mu.
+func (s *AlarmService) warnPriorityDriftLocked() {
+	dist := s.priorityDistributionLocked()
+	var drifted []string
+	for _, share := range dist.Shares {
+		if share.Drifted {
+			drifted = append(drifted, share.Priority)
+		}
+	}
+	for _, w := range dist.Warnings {
+		log.Printf("Alarm priority distribution: %s", w)
+	}
+	key := strings.Join(drifted, ",")
+	if key == s.priorityDrift {
+		return
+	}
+	s.priorityDrift = key
+	if len(drifted) == 0 {
+		return
+	}
+	s.publishAudit(ActorSystem, "alarm_priority_distribution_drift", map[string]interface{}{
+		"total":    dist.Total,
+		"warnings": dist.Warnings,
+	})
+}
@@ -0,0 +1,242 @@
+package core_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+)
+
+func TestPriorityMatrix_Derive(t *testing.T) {
+	m := core.DefaultPriorityMatrix()
+	tests := []struct {
+		severity     string
+		responseTime int
+		want         string
+	}{
+		{"severe", 60, "High"},
+		{"minor", 180, "Medium"},
+		{"Major", 181, "Medium"},
+		{"minor", 600, "Low"},
+		{"severe", 1800, "Medium"},
+	}
+	for _, tt := range tests {
+		got, err := m.Derive(tt.severity, tt.responseTime)
+		if err != nil || got != tt.want {
+			t.Errorf("Derive(%s, %d) = %q, %v; want %q", tt.severity, tt.responseTime, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []struct {
+		severity     string
+		responseTime int
+	}{{"catastrophic", 60}, {"major", 0}, {"major", 3600}} {
+		if _, err := m.Derive(bad.severity, bad.responseTime); err == nil {
+			t.Errorf("Derive(%s, %d): expected error", bad.severity, bad.responseTime)
+		}
+	}
+}
+
+func TestPriorityMatrix_Apply(t *testing.T) {
+	m := core.DefaultPriorityMatrix()
+
+	def := &core.AlarmDefinition{Tag: "t", Type: "High", Rationalization: core.Rationalization{Severity: "major", ResponseTimeSeconds: 120}}
+	if err := m.Apply(def); err != nil || def.Priority != "High" {
+		t.Errorf("Expected derived High priority, got %q (%v)", def.Priority, err)
+	}
+
+	def.Priority = "Low"
+	if err := m.Apply(def); err == nil {
+		t.Error("Expected priority that contradicts the matrix to be rejected")
+	}
+
+	legacy := &core.AlarmDefinition{Tag: "t", Type: "High", Priority: "Warning"}
+	if err := m.Apply(legacy); err != nil || legacy.Priority != "Warning" {
+		t.Errorf("Expected unrationalized definition to be left alone, got %q (%v)", legacy.Priority, err)
+	}
+	for _, priority := range []string{"Banana", "high"} {
+		unknown := &core.AlarmDefinition{Tag: "t", Type: "High", Priority: priority}
+		if err := m.Apply(unknown); err == nil {
+			t.Errorf("Expected unrationalized definition with priority %q to be rejected", priority)
+		}
+	}
+	if err := m.Apply(&core.AlarmDefinition{Tag: "t", Type: "High"}); err != nil {
+		t.Errorf("Expected definition without priority to be left alone, got %v", err)
+	}
+
+	// A legacy name for the derived priority is accepted as is
+	legacy.Rationalization = core.Rationalization{Severity: "severe", ResponseTimeSeconds: 60}
+	legacy.Priority = "Critical"
+	if err := m.Apply(legacy); err != nil || legacy.Priority != "Critical" {
+		t.Errorf("Expected Critical to stand for High, got %q (%v)", legacy.Priority, err)
+	}
+}
+
+func TestPriorityMatrix_SignedPriority(t *testing.T) {
+	m := core.DefaultPriorityMatrix()
+	if m.SignedPriority() != "High" {
+		t.Errorf("Expected the highest priority to be signed, got %s", m.SignedPriority())
+	}
+	for priority, want := range map[string]bool{"High": true, "Critical": true, "Medium": false, "Warning": false, "": false} {
+		if got := m.RequiresSignature(priority); got != want {
+			t.Errorf("RequiresSignature(%q) = %v, want %v", priority, got, want)
+		}
+	}
+}
+
+func TestParsePriorityMatrix(t *testing.T) {
+	valid := `{"severities":["low","high"],"response_time_seconds":[300,900],
+		"priorities":[["Medium","High"],["Low","Medium"]],"targets":{"High":0.1,"Medium":0.3,"Low":0.6}}`
+	m, err := core.ParsePriorityMatrix(strings.NewReader(valid))
+	if err != nil {
+		t.Fatalf("ParsePriorityMatrix failed: %v", err)
+	}
+	if p, _ := m.Derive("high", 200); p != "High" {
+		t.Errorf("Expected High, got %s", p)
+	}
+	if m.Canonical("Critical") != "High" || m.Canonical("Warning") != "Medium" {
+		t.Errorf("Expected the default aliases onto the matrix's priorities, got %v", m.Aliases)
+	}
+
+	custom := `{"severities":["a"],"response_time_seconds":[60,300],"priorities":[["Emergency"],["Low"]],"signed":"Emergency","aliases":{"Critical":"Emergency"}}`
+	m, err = core.ParsePriorityMatrix(strings.NewReader(custom))
+	if err != nil {
+		t.Fatalf("ParsePriorityMatrix failed: %v", err)
+	}
+	if !m.RequiresSignature("Critical") || m.RequiresSignature("Low") {
+		t.Errorf("Expected Critical to stand for the signed Emergency priority")
+	}
+
+	invalid := map[string]string{
+		"ragged rows":     `{"severities":["a","b"],"response_time_seconds":[60],"priorities":[["High"]]}`,
+		"missing row":     `{"severities":["a"],"response_time_seconds":[60,120],"priorities":[["High"]]}`,
+		"descending":      `{"severities":["a"],"response_time_seconds":[120,60],"priorities":[["High"],["Low"]]}`,
+		"targets over 1":  `{"severities":["a"],"response_time_seconds":[60],"priorities":[["High"]],"targets":{"High":0.5,"Low":0.7}}`,
+		"not even JSON":   `severities: a`,
+		"no severities":   `{"response_time_seconds":[60],"priorities":[[]]}`,
+		"negative target": `{"severities":["a"],"response_time_seconds":[60],"priorities":[["High"]],"targets":{"High":-0.5,"Low":1.5}}`,
+		"unknown signed":  `{"severities":["a"],"response_time_seconds":[60],"priorities":[["High"]],"signed":"Critical"}`,
+		"unknown alias":   `{"severities":["a"],"response_time_seconds":[60],"priorities":[["High"]],"aliases":{"Critical":"Urgent"}}`,
+		"alias shadowing": `{"severities":["a","b"],"response_time_seconds":[60],"priorities":[["High","Low"]],"aliases":{"Low":"High"}}`,
+	}
+	for name, input := range invalid {
+		if _, err := core.ParsePriorityMatrix(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func definitionsWithPriorities(counts map[string]int) []*core.AlarmDefinition {
+	var defs []*core.AlarmDefinition
+	for priority, n := range counts {
+		for i := 0; i < n; i++ {
+			defs = append(defs, &core.AlarmDefinition{Tag: fmt.Sprintf("%s%d", priority, i), Type: "High", Priority: priority})
+		}
+	}
+	return defs
+}
+
+func TestPriorityMatrix_Distribution(t *testing.T) {
+	m := core.DefaultPriorityMatrix()
+
+	healthy := m.Distribution(definitionsWithPriorities(map[string]int{"High": 1, "Medium": 3, "Low": 16}))
+	if healthy.Total != 20 || len(healthy.Warnings) != 0 {
+		t.Errorf("Expected no drift, got %+v", healthy)
+	}
+
+	topHeavy := m.Distribution(definitionsWithPriorities(map[string]int{"High": 8, "Medium": 4, "Low": 8}))
+	if len(topHeavy.Warnings) != 2 {
+		t.Fatalf("Expected High and Low to drift, got %v", topHeavy.Warnings)
+	}
+	if !strings.HasPrefix(topHeavy.Warnings[0], "High priority is 40.0% of 20 definitions") {
+		t.Errorf("Unexpected warning %q", topHeavy.Warnings[0])
+	}
+
+	// Legacy names count as the priorities they stand for
+	legacy := m.Distribution(definitionsWithPriorities(map[string]int{"Critical": 1, "Warning": 2, "Medium": 1, "Low": 16}))
+	if len(legacy.Warnings) != 0 || len(legacy.Shares) != 3 {
+		t.Errorf("Expected Critical and Warning to count as High and Medium, got %+v", legacy)
+	}
+
+	small := m.Distribution(definitionsWithPriorities(map[string]int{"High": 3}))
+	if len(small.Warnings) != 0 {
+		t.Errorf("Expected no warnings below MinDefinitions, got %v", small.Warnings)
+	}
+}
+
+func TestAlarmService_CreateDefinitionUsesPriorityMatrix(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repo, publisher)
+
+	def := &core.AlarmDefinition{Tag: "tank.level", Type: "High", Threshold: 90, Rationalization: core.Rationalization{Severity: "minor", ResponseTimeSeconds: 900}}
+	if err := svc.CreateDefinition(def); err != nil {
+		t.Fatalf("CreateDefinition failed: %v", err)
+	}
+	if def.Priority != "Low" {
+		t.Errorf("Expected derived Low priority, got %q", def.Priority)
+	}
+
+	wrong := &core.AlarmDefinition{Tag: "tank.level", Type: "Low", Threshold: 5, Priority: "High", Rationalization: core.Rationalization{Severity: "minor", ResponseTimeSeconds: 900}}
+	if err := svc.CreateDefinition(wrong); !errors.Is(err, core.ErrInvalidDefinition) {
+		t.Errorf("Expected ErrInvalidDefinition, got %v", err)
+	}
+	if len(storedDefinitions(t, repo)) != 1 {
+		t.Errorf("Expected rejected definition not to be stored")
+	}
+}
+
+func TestAlarmService_ImportReportsPriorityDrift(t *testing.T) {
+	svc := core.NewAlarmService(repository.NewMemoryRepository(), &MockPublisher{})
+
+	defs := definitionsWithPriorities(map[string]int{"High": 10, "Low": 10})
+	report, err := svc.ImportDefinitions(defs, "alice", true)
+	if err != nil {
+		t.Fatalf("ImportDefinitions failed: %v", err)
+	}
+	if report.PriorityDistribution == nil || len(report.PriorityDistribution.Warnings) == 0 {
+		t.Errorf("Expected drift warnings in the import report, got %+v", report.PriorityDistribution)
+	}
+
+	bad := []*core.AlarmDefinition{{Tag: "t", Type: "High", Rationalization: core.Rationalization{Severity: "major", ResponseTimeSeconds: 7200}}}
+	if _, err := svc.ImportDefinitions(bad, "alice", true); err == nil {
+		t.Error("Expected response time beyond the matrix to be rejected")
+	}
+}
+
+func TestAlarmService_PriorityDriftAuditedOnChange(t *testing.T) {
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repository.NewMemoryRepository(), publisher)
+	driftRecords := func() int {
+		n := 0
+		for _, r := range publisher.records {
+			if r.Action == "alarm_priority_distribution_drift" {
+				n++
+			}
+		}
+		return n
+	}
+
+	if _, err := svc.ImportDefinitions(definitionsWithPriorities(map[string]int{"High": 10, "Low": 10}), "alice", false); err != nil {
+		t.Fatalf("ImportDefinitions failed: %v", err)
+	}
+	svc.LoadDefinitions()
+	svc.CreateDefinition(&core.AlarmDefinition{Tag: "extra", Type: "High", Priority: "High"})
+	if n := driftRecords(); n != 1 {
+		t.Errorf("Expected the same drift to be audited once, got %d records", n)
+	}
+
+	// Back within targets, then drifted again: audited anew
+	if _, err := svc.ImportDefinitions(definitionsWithPriorities(map[string]int{"High": 1, "Medium": 3, "Low": 16}), "alice", false); err != nil {
+		t.Fatalf("ImportDefinitions failed: %v", err)
+	}
+	if _, err := svc.ImportDefinitions(definitionsWithPriorities(map[string]int{"High": 10, "Low": 10}), "alice", false); err != nil {
+		t.Fatalf("ImportDefinitions failed: %v", err)
+	}
+	if n := driftRecords(); n != 2 {
+		t.Errorf("Expected the returning drift to be audited, got %d records", n)
+	}
+}
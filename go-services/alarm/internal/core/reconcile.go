@@ -0,0 +1,61 @@
+package core
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+// LatestValueReader looks up the most recent stored value of a tag.
+// It returns nil without error when the tag has no recent data.
+type LatestValueReader interface {
+	LatestValue(ctx context.Context, tag string) (*pb.SensorData, error)
+}
+
+// ReconcileOnStartup evaluates every defined tag against its last stored
+// value, so alarms that cleared or fired while the service was down are
+// corrected and their transitions published. Tags that can't be read are
+// skipped and left for the next live value.
+func (s *AlarmService) ReconcileOnStartup(ctx context.Context, reader LatestValueReader, perTagTimeout time.Duration) {
+	s.mu.RLock()
+	tags := make([]string, 0, len(s.definitions))
+	for tag := range s.definitions {
+		tags = append(tags, tag)
+	}
+	s.mu.RUnlock()
+
+	reconciled := 0
+	for _, tag := range tags {
+		if ctx.Err() != nil {
+			return
+		}
+
+		tagCtx, cancel := context.WithTimeout(ctx, perTagTimeout)
+		data, err := reader.LatestValue(tagCtx, tag)
+		cancel()
+		if err != nil {
+			log.Printf("Reconciliation: failed to read last value of %s: %v", tag, err)
+			continue
+		}
+		if data == nil {
+			continue
+		}
+
+		s.mu.RLock()
+		defs := s.definitions[tag]
+		s.mu.RUnlock()
+
+		for _, def := range defs {
+			if def.Type == AnomalyType {
+				continue
+			}
+			if err := s.evaluateDefinition(def, data.Value, time.UnixMilli(data.TimestampMs), "startup reconciliation"); err != nil {
+				log.Printf("Reconciliation: failed to evaluate definition %d: %v", def.ID, err)
+			}
+		}
+		reconciled++
+	}
+	log.Printf("Reconciliation: evaluated %d of %d tags against historian values", reconciled, len(tags))
+}
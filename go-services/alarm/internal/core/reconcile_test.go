@@ -0,0 +1,77 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+type mockValueReader struct {
+	values map[string]float64
+	failed map[string]bool
+}
+
+func (m *mockValueReader) LatestValue(ctx context.Context, tag string) (*pb.SensorData, error) {
+	if m.failed[tag] {
+		return nil, errors.New("unavailable")
+	}
+	v, ok := m.values[tag]
+	if !ok {
+		return nil, nil
+	}
+	return &pb.SensorData{SensorId: tag, Value: v}, nil
+}
+
+func TestAlarmService_ReconcileOnStartup(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+
+	cleared := &core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High"}
+	raised := &core.AlarmDefinition{Tag: "sensor2", Threshold: 100, Type: "High"}
+	unknown := &core.AlarmDefinition{Tag: "sensor3", Threshold: 100, Type: "High"}
+	repo.CreateDefinition(cleared)
+	repo.CreateDefinition(raised)
+	repo.CreateDefinition(unknown)
+
+	// Alarms that were standing when the service went down
+	repo.CreateActiveAlarm(&core.ActiveAlarm{DefinitionID: cleared.ID, State: string(core.StateAckActive), Value: 120})
+	repo.CreateActiveAlarm(&core.ActiveAlarm{DefinitionID: unknown.ID, State: string(core.StateUnackActive), Value: 120})
+
+	svc := core.NewAlarmService(repo, publisher)
+	svc.LoadDefinitions()
+
+	reader := &mockValueReader{
+		values: map[string]float64{"sensor1": 90, "sensor2": 130},
+		failed: map[string]bool{"sensor3": true},
+	}
+	svc.ReconcileOnStartup(context.Background(), reader, time.Second)
+
+	states := make(map[int]string)
+	for _, a := range svc.GetActiveAlarms() {
+		states[a.DefinitionID] = a.State
+	}
+	if _, ok := states[cleared.ID]; ok {
+		t.Errorf("Expected the cleared alarm to return to Normal, got %s", states[cleared.ID])
+	}
+	if states[raised.ID] != string(core.StateUnackActive) {
+		t.Errorf("Expected the raised alarm to be UnackActive, got %q", states[raised.ID])
+	}
+	if states[unknown.ID] != string(core.StateUnackActive) {
+		t.Errorf("Expected the unreadable alarm to be left alone, got %q", states[unknown.ID])
+	}
+
+	if len(publisher.events) != 2 {
+		t.Fatalf("Expected 2 corrective transitions, got %d", len(publisher.events))
+	}
+	for _, e := range publisher.events {
+		if !strings.Contains(e.Message, "startup reconciliation") {
+			t.Errorf("Expected reconciliation to be noted in the message, got %q", e.Message)
+		}
+	}
+}
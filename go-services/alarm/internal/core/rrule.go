@@ -0,0 +1,177 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence is the subset of RFC 5545 RRULE used for suppression windows:
+// FREQ (HOURLY, DAILY, WEEKLY, MONTHLY), INTERVAL, COUNT, UNTIL and, for
+// weekly rules, BYDAY. Weeks are anchored on the weekday of DTSTART.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []time.Weekday
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func ParseRRule(rule string) (*Recurrence, error) {
+	r := &Recurrence{Interval: 1}
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	for _, part := range strings.Split(rule, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			switch value = strings.ToUpper(value); value {
+			case "HOURLY", "DAILY", "WEEKLY", "MONTHLY":
+				r.Freq = value
+			default:
+				return nil, fmt.Errorf("unsupported RRULE FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE COUNT %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE UNTIL %q", value)
+			}
+			r.Until = until
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[strings.ToUpper(day)]
+				if !ok {
+					return nil, fmt.Errorf("unsupported RRULE BYDAY %q", day)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %q", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("RRULE requires FREQ")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("RRULE cannot combine COUNT and UNTIL")
+	}
+	if len(r.ByDay) > 0 && r.Freq != "WEEKLY" {
+		return nil, fmt.Errorf("RRULE BYDAY is only supported with FREQ=WEEKLY")
+	}
+	return r, nil
+}
+
+func parseRRuleTime(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+// periodStart returns the start of the n-th recurrence period after dtstart.
+func (r *Recurrence) periodStart(dtstart time.Time, n int) time.Time {
+	switch r.Freq {
+	case "HOURLY":
+		return dtstart.Add(time.Duration(n*r.Interval) * time.Hour)
+	case "DAILY":
+		return dtstart.AddDate(0, 0, n*r.Interval)
+	case "WEEKLY":
+		return dtstart.AddDate(0, 0, 7*n*r.Interval)
+	default: // MONTHLY
+		return dtstart.AddDate(0, n*r.Interval, 0)
+	}
+}
+
+// periodIndex returns a lower bound for the index of the period containing t.
+func (r *Recurrence) periodIndex(dtstart, t time.Time) int {
+	elapsed := t.Sub(dtstart)
+	var n int
+	switch r.Freq {
+	case "HOURLY":
+		n = int(elapsed/time.Hour) / r.Interval
+	case "DAILY":
+		n = int(elapsed/(24*time.Hour)) / r.Interval
+	case "WEEKLY":
+		n = int(elapsed/(7*24*time.Hour)) / r.Interval
+	default:
+		months := (t.Year()-dtstart.Year())*12 + int(t.Month()-dtstart.Month())
+		n = months / r.Interval
+	}
+	// Step back one period to absorb DST and month-length drift
+	if n > 0 {
+		n--
+	}
+	return n
+}
+
+// occurrencesInPeriod lists the occurrence starts in the n-th period, sorted.
+func (r *Recurrence) occurrencesInPeriod(dtstart time.Time, n int) []time.Time {
+	start := r.periodStart(dtstart, n)
+	if len(r.ByDay) == 0 {
+		return []time.Time{start}
+	}
+	occs := make([]time.Time, 0, len(r.ByDay))
+	for _, wd := range r.ByDay {
+		offset := (int(wd) - int(start.Weekday()) + 7) % 7
+		occs = append(occs, start.AddDate(0, 0, offset))
+	}
+	sort.Slice(occs, func(i, j int) bool { return occs[i].Before(occs[j]) })
+	return occs
+}
+
+// LatestOccurrence returns the start of the most recent occurrence at or
+// before t. It also reports whether any occurrence follows it.
+func (r *Recurrence) LatestOccurrence(dtstart, t time.Time) (latest time.Time, found, more bool) {
+	first := 0
+	if r.Count == 0 {
+		from := t
+		if !r.Until.IsZero() && r.Until.Before(t) {
+			from = r.Until
+		}
+		first = r.periodIndex(dtstart, from)
+	}
+
+	emitted := 0
+	for n := first; ; n++ {
+		for _, occ := range r.occurrencesInPeriod(dtstart, n) {
+			if occ.Before(dtstart) {
+				continue
+			}
+			if (r.Count > 0 && emitted >= r.Count) || (!r.Until.IsZero() && occ.After(r.Until)) {
+				return latest, found, false
+			}
+			if occ.After(t) {
+				return latest, found, true
+			}
+			latest, found = occ, true
+			emitted++
+		}
+	}
+}
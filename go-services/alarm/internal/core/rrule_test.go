@@ -0,0 +1,57 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+	r, err := ParseRRule("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;UNTIL=20261231T000000Z")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if r.Freq != "WEEKLY" || r.Interval != 2 || len(r.ByDay) != 2 || r.Until.IsZero() {
+		t.Errorf("Unexpected recurrence: %+v", r)
+	}
+
+	for _, rule := range []string{"", "FREQ=YEARLY", "FREQ=DAILY;BYDAY=MO", "FREQ=DAILY;COUNT=2;UNTIL=20260101", "FREQ=DAILY;BYMONTH=1"} {
+		if _, err := ParseRRule(rule); err == nil {
+			t.Errorf("Expected error for %q", rule)
+		}
+	}
+}
+
+func TestRecurrence_LatestOccurrence(t *testing.T) {
+	// Monday 2026-01-05 02:00 UTC
+	dtstart := time.Date(2026, 1, 5, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		rule       string
+		at         time.Time
+		wantFound  bool
+		wantLatest time.Time
+		wantMore   bool
+	}{
+		{"before start", "FREQ=DAILY", dtstart.Add(-time.Hour), false, time.Time{}, true},
+		{"daily", "FREQ=DAILY", dtstart.AddDate(0, 0, 400).Add(time.Hour), true, dtstart.AddDate(0, 0, 400), true},
+		{"every other day", "FREQ=DAILY;INTERVAL=2", dtstart.AddDate(0, 0, 3), true, dtstart.AddDate(0, 0, 2), true},
+		{"weekly by day", "FREQ=WEEKLY;BYDAY=MO,WE", dtstart.AddDate(0, 0, 3), true, dtstart.AddDate(0, 0, 2), true},
+		{"count exhausted", "FREQ=DAILY;COUNT=3", dtstart.AddDate(0, 0, 10), true, dtstart.AddDate(0, 0, 2), false},
+		{"until reached", "FREQ=HOURLY;UNTIL=20260105T050000Z", dtstart.Add(10 * time.Hour), true, dtstart.Add(3 * time.Hour), false},
+		{"monthly", "FREQ=MONTHLY", time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC), true, time.Date(2026, 3, 5, 2, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			latest, found, more := r.LatestOccurrence(dtstart, tt.at)
+			if found != tt.wantFound || !latest.Equal(tt.wantLatest) || more != tt.wantMore {
+				t.Errorf("Expected (%v, %v, %v), got (%v, %v, %v)", tt.wantLatest, tt.wantFound, tt.wantMore, latest, found, more)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
@@ -11,31 +12,170 @@ import (
 
 type EventPublisher interface {
 	PublishAlarmEvent(event *pb.AlarmEvent) error
+	PublishAuditRecord(record *AuditRecord) error
 }
 
+// AuditRecord is an operational event that belongs in the audit trail but
+// is not a transition of a single alarm, e.g. a suppression window starting.
+type AuditRecord struct {
+	Actor   string
+	Action  string
+	Details map[string]interface{}
+}
+
+// ActorSystem is recorded as the actor of transitions driven by the evaluator
+// or by background tasks rather than by an operator.
+const ActorSystem = "system"
+
+// MetricsRecorder receives operational measurements from the service.
+type MetricsRecorder interface {
+	// ObserveSample records one processed value: how old the sample was on
+	// arrival (zero when unknown) and how long evaluating it took.
+	ObserveSample(lag, evaluation time.Duration)
+	// PublishFailed counts a failed publish of the given kind, "alarm_event"
+	// or "audit_record".
+	PublishFailed(kind string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveSample(lag, evaluation time.Duration) {}
+func (nopMetrics) PublishFailed(kind string)                   {}
+
 type AlarmService struct {
-	repo         AlarmRepository
-	publisher    EventPublisher
-	definitions  map[string][]*AlarmDefinition
-	activeAlarms map[int]*ActiveAlarm
-	mu           sync.RWMutex
+	repo            AlarmRepository
+	history         *historyWriter
+	publisher       EventPublisher
+	metrics         MetricsRecorder
+	clock           func() time.Time
+	signatures      SignatureVerifier
+	usedSignatures  map[string]time.Time
+	priorityMatrix  *PriorityMatrix
+	priorityDrift   string // drifted priorities last audited
+	definitions     map[string][]*AlarmDefinition
+	definitionsByID map[int]*AlarmDefinition
+	activeAlarms    map[int]*ActiveAlarm
+	windows         map[int]*SuppressionWindow
+	windowActive    map[int]bool
+	chatter         map[int]*chatterTracker
+	chatterCfg      ChatterConfig
+	anomalyLastSeen map[int]time.Time
+	anomalyCfg      AnomalyConfig
+	shiftCalendar   *ShiftCalendar
+	groups          map[int]*AlarmGroup
+	groupOf         map[int]*AlarmGroup // Definition ID -> group
+	episodes        map[int]*groupEpisode
+	sequence        atomic.Uint64
+	mu              sync.RWMutex
+	importMu        sync.Mutex // serializes definition imports
 }
 
 func NewAlarmService(repo AlarmRepository, publisher EventPublisher) *AlarmService {
 	return &AlarmService{
-		repo:         repo,
-		publisher:    publisher,
-		definitions:  make(map[string][]*AlarmDefinition),
-		activeAlarms: make(map[int]*ActiveAlarm),
+		repo:            repo,
+		history:         newHistoryWriter(repo),
+		publisher:       publisher,
+		metrics:         nopMetrics{},
+		clock:           time.Now,
+		usedSignatures:  make(map[string]time.Time),
+		priorityMatrix:  DefaultPriorityMatrix(),
+		definitions:     make(map[string][]*AlarmDefinition),
+		definitionsByID: make(map[int]*AlarmDefinition),
+		activeAlarms:    make(map[int]*ActiveAlarm),
+		windows:         make(map[int]*SuppressionWindow),
+		windowActive:    make(map[int]bool),
+		chatter:         make(map[int]*chatterTracker),
+		chatterCfg:      DefaultChatterConfig(),
+		anomalyLastSeen: make(map[int]time.Time),
+		anomalyCfg:      DefaultAnomalyConfig(),
+		shiftCalendar:   DefaultShiftCalendar(),
+		groups:          make(map[int]*AlarmGroup),
+		groupOf:         make(map[int]*AlarmGroup),
+		episodes:        make(map[int]*groupEpisode),
 	}
 }
 
+// newEvent builds an AlarmEvent carrying the definition context, so consumers
+// don't have to look the definition up to know which tag or priority fired.
+func (s *AlarmService) newEvent(defID int, alarm *ActiveAlarm, prevState, newState AlarmState, actor, comment, message string) *pb.AlarmEvent {
+	event := &pb.AlarmEvent{
+		AlarmId:       int32(alarm.ID),
+		DefinitionId:  int32(defID),
+		State:         string(newState),
+		PreviousState: string(prevState),
+		Value:         alarm.Value,
+		TimestampMs:   s.clock().UnixMilli(),
+		Message:       message,
+		Actor:         actor,
+		Comment:       comment,
+	}
+	if def, ok := s.definitionsByID[defID]; ok {
+		event.Tag = def.Tag
+		event.Priority = s.groupPriorityLocked(def)
+		event.AlarmType = def.Type
+		event.Threshold = def.Threshold
+		event.Cause = def.Cause
+		event.Consequence = def.Consequence
+		event.CorrectiveAction = def.CorrectiveAction
+		event.ResponseTimeSeconds = int32(def.ResponseTimeSeconds)
+		event.AlarmClass = def.Class
+		groupID, firstOut, consequential := s.annotateGroupLocked(def)
+		event.GroupId = int32(groupID)
+		event.FirstOut = firstOut
+		event.Consequential = consequential
+	}
+	return event
+}
+
+// publish stamps the event with the next sequence number, queues it for
+// the alarm history and sends it. Failures are logged; they never roll
+// back a state change.
+func (s *AlarmService) publish(event *pb.AlarmEvent) {
+	event.Sequence = s.sequence.Add(1)
+	s.history.enqueue(historyFromEvent(event))
+
+	if s.publisher == nil {
+		return
+	}
+	if err := s.publisher.PublishAlarmEvent(event); err != nil {
+		s.metrics.PublishFailed("alarm_event")
+		log.Printf("Failed to publish alarm event: %v", err)
+	}
+}
+
+func (s *AlarmService) publishAudit(actor, action string, details map[string]interface{}) {
+	if s.publisher == nil {
+		return
+	}
+	record := &AuditRecord{Actor: actor, Action: action, Details: details}
+	if err := s.publisher.PublishAuditRecord(record); err != nil {
+		s.metrics.PublishFailed("audit_record")
+		log.Printf("Failed to publish audit record %s: %v", action, err)
+	}
+}
+
+// SetClock replaces the wall clock used when evaluating values and stamping
+// events. Offline replays set it to the sample time so on-delays and chatter
+// windows play out as they would have live.
+func (s *AlarmService) SetClock(clock func() time.Time) {
+	s.clock = clock
+}
+
+// SetMetrics must be called before the service starts processing.
+func (s *AlarmService) SetMetrics(m MetricsRecorder) {
+	s.metrics = m
+}
+
 func (s *AlarmService) StartBackgroundTasks() {
 	go func() {
 		ticker := time.NewTicker(1 * time.Second)
 		defer ticker.Stop()
 		for range ticker.C {
 			s.checkShelvedAlarms()
+			s.checkSuppressionWindows()
+			s.checkChattering()
+			s.checkAnomalies()
+			s.checkAlarmGroups()
 		}
 	}()
 }
@@ -63,6 +203,7 @@ func (s *AlarmService) checkShelvedAlarms() {
 			// and will be re-evaluated on next sensor update.
 
 			newState := StateNormal
+			prevState := AlarmState(active.State)
 			active.State = string(newState)
 			active.ShelvedUntil = nil
 			active.UpdatedAt = now
@@ -84,17 +225,7 @@ func (s *AlarmService) checkShelvedAlarms() {
 			delete(s.activeAlarms, active.DefinitionID)
 
 			// Publish Event
-			if s.publisher != nil {
-				eventPayload := &pb.AlarmEvent{
-					AlarmId:      int32(active.ID),
-					DefinitionId: int32(active.DefinitionID),
-					State:        string(newState),
-					Value:        active.Value,
-					TimestampMs:  now.UnixMilli(),
-					Message:      "Alarm unshelved (expired)",
-				}
-				s.publisher.PublishAlarmEvent(eventPayload)
-			}
+			s.publish(s.newEvent(active.DefinitionID, active, prevState, newState, ActorSystem, "", "Alarm unshelved (expired)"))
 		}
 	}
 }
@@ -111,12 +242,29 @@ func (s *AlarmService) LoadDefinitions() error {
 		return err
 	}
 
+	windows, err := s.repo.ListSuppressionWindows()
+	if err != nil {
+		return err
+	}
+
+	groups, err := s.repo.ListAlarmGroups()
+	if err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.definitions = make(map[string][]*AlarmDefinition)
+	s.definitionsByID = make(map[int]*AlarmDefinition)
 	for _, def := range defs {
 		s.definitions[def.Tag] = append(s.definitions[def.Tag], def)
+		s.definitionsByID[def.ID] = def
+	}
+	for defID := range s.chatter {
+		if _, ok := s.definitionsByID[defID]; !ok {
+			delete(s.chatter, defID)
+		}
 	}
 
 	s.activeAlarms = make(map[int]*ActiveAlarm)
@@ -124,10 +272,53 @@ func (s *AlarmService) LoadDefinitions() error {
 		s.activeAlarms[a.DefinitionID] = a
 	}
 
+	// Window state is taken as-is on load so a restart doesn't re-announce
+	// windows that were already running
+	now := time.Now()
+	s.windows = make(map[int]*SuppressionWindow)
+	s.windowActive = make(map[int]bool)
+	for _, w := range windows {
+		if err := w.Validate(); err != nil {
+			log.Printf("Ignoring invalid suppression window %d: %v", w.ID, err)
+			continue
+		}
+		s.windows[w.ID] = w
+		s.windowActive[w.ID], _ = w.ActiveAt(now)
+	}
+
+	s.groups = make(map[int]*AlarmGroup)
+	s.groupOf = make(map[int]*AlarmGroup)
+	s.episodes = make(map[int]*groupEpisode)
+	for _, g := range groups {
+		s.addGroupLocked(g)
+	}
+
+	s.warnPriorityDriftLocked()
 	return nil
 }
 
+// ProcessValue evaluates a value sampled now.
 func (s *AlarmService) ProcessValue(sensorId string, value float64) error {
+	start := time.Now()
+	err := s.processSample(sensorId, value, start)
+	s.metrics.ObserveSample(0, time.Since(start))
+	return err
+}
+
+// ProcessSensorData evaluates a sample at its source timestamp, which
+// orders alarm onsets within groups.
+func (s *AlarmService) ProcessSensorData(data *pb.SensorData) error {
+	start := time.Now()
+	sampledAt := start
+	if data.TimestampMs > 0 {
+		sampledAt = time.UnixMilli(data.TimestampMs)
+	}
+	err := s.processSample(data.SensorId, data.Value, sampledAt)
+	s.metrics.ObserveSample(start.Sub(sampledAt), time.Since(start))
+	return err
+}
+
+func (s *AlarmService) processSample(sensorId string, value float64, sampledAt time.Time) error {
 	s.mu.RLock()
 	defs, ok := s.definitions[sensorId]
 	s.mu.RUnlock()
@@ -138,7 +329,10 @@ func (s *AlarmService) ProcessValue(sensorId string, value float64) error {
 
 	var errs []error
 	for _, def := range defs {
-		if err := s.evaluateDefinition(def, value); err != nil {
+		if def.Type == AnomalyType {
+			continue // Raised by ProcessAnomaly, not by sensor values
+		}
+		if err := s.evaluateDefinition(def, value, sampledAt, ""); err != nil {
 			log.Printf("Error evaluating definition %d: %v", def.ID, err)
 			errs = append(errs, err)
 		}
@@ -150,7 +344,10 @@ func (s *AlarmService) ProcessValue(sensorId string, value float64) error {
 	return nil
 }
 
-func (s *AlarmService) evaluateDefinition(def *AlarmDefinition, value float64) error {
+// evaluateDefinition runs one value sampled at sampledAt through a
+// definition's FSM. A non-empty origin is appended to the event message,
+// e.g. for startup reconciliation.
+func (s *AlarmService) evaluateDefinition(def *AlarmDefinition, value float64, sampledAt time.Time, origin string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -161,14 +358,27 @@ func (s *AlarmService) evaluateDefinition(def *AlarmDefinition, value float64) e
 		currentState = AlarmState(active.State)
 	}
 
+	// Covered by a suppression window: no new alarms, and suppressed ones
+	// stay put until the window ends
+	if s.isSuppressedLocked(def) {
+		return nil
+	}
+
 	fsm := NewAlarmFSM(currentState)
 
-	shouldFire := Evaluate(def, value)
+	now := s.clock()
+	shouldFire := s.mitigateLocked(def, currentState, value, Evaluate(def, value), now)
+	onset := s.recordOnsetLocked(def, shouldFire, sampledAt)
+	defer s.reconcileGroupOfLocked(def, now)
 
 	var event AlarmEvent
-	if shouldFire {
+	switch {
+	case shouldFire && s.groupSuppressesLocked(def, onset):
+		// Consequential child of an alarming parent
+		event = EventSuppress
+	case shouldFire:
 		event = EventTrigger
-	} else {
+	default:
 		event = EventClear
 	}
 
@@ -179,29 +389,30 @@ func (s *AlarmService) evaluateDefinition(def *AlarmDefinition, value float64) e
 
 	if newState != currentState {
 		// State changed!
-		var alarmID int
+		var alarm *ActiveAlarm
 		if !exists {
 			// Create new active alarm
 			newAlarm := &ActiveAlarm{
 				DefinitionID:   def.ID,
 				State:          string(newState),
-				ActivationTime: time.Now(),
+				ActivationTime: now,
+				OnsetTime:      &onset,
 				Value:          value,
 			}
 			if err := s.repo.CreateActiveAlarm(newAlarm); err != nil {
 				return err
 			}
 			s.activeAlarms[def.ID] = newAlarm
-			alarmID = newAlarm.ID
+			alarm = newAlarm
 		} else {
 			// Update existing
 			active.State = string(newState)
 			active.Value = value
-			active.UpdatedAt = time.Now()
+			active.UpdatedAt = now
 			if err := s.repo.UpdateActiveAlarmState(active.ID, string(newState)); err != nil {
 				return err
 			}
-			alarmID = active.ID
+			alarm = active
 
 			if newState == StateNormal {
 				delete(s.activeAlarms, def.ID)
@@ -209,25 +420,26 @@ func (s *AlarmService) evaluateDefinition(def *AlarmDefinition, value float64) e
 		}
 
 		// Publish Event
-		if s.publisher != nil {
-			eventPayload := &pb.AlarmEvent{
-				AlarmId:      int32(alarmID),
-				DefinitionId: int32(def.ID),
-				State:        string(newState),
-				Value:        value,
-				TimestampMs:  time.Now().UnixMilli(),
-				Message:      fmt.Sprintf("Alarm %s transitioned to %s", def.Tag, newState),
-			}
-			if err := s.publisher.PublishAlarmEvent(eventPayload); err != nil {
-				log.Printf("Failed to publish alarm event: %v", err)
-			}
+		message := fmt.Sprintf("Alarm %s transitioned to %s", def.Tag, newState)
+		if event == EventSuppress {
+			message = fmt.Sprintf("Alarm %s suppressed as a consequence of %s", def.Tag, s.parentTagLocked(s.groupOf[def.ID]))
+		}
+		if origin != "" {
+			message = fmt.Sprintf("%s (%s)", message, origin)
+		}
+		alarmEvent := s.newEvent(def.ID, alarm, currentState, newState, ActorSystem, "", message)
+		alarmEvent.SourceTimestampMs = sampledAt.UnixMilli()
+		s.publish(alarmEvent)
+
+		if newState == StateUnackActive {
+			s.recordActivationLocked(def, now)
 		}
 	}
 
 	return nil
 }
 
-func (s *AlarmService) Acknowledge(alarmID int) error {
+func (s *AlarmService) Acknowledge(alarmID int, actor, comment string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -280,23 +492,19 @@ func (s *AlarmService) Acknowledge(alarmID int) error {
 		}
 
 		// Publish Event
-		if s.publisher != nil {
-			eventPayload := &pb.AlarmEvent{
-				AlarmId:      int32(alarmID),
-				DefinitionId: int32(defID),
-				State:        string(newState),
-				Value:        active.Value,
-				TimestampMs:  time.Now().UnixMilli(),
-				Message:      "Alarm acknowledged",
-			}
-			s.publisher.PublishAlarmEvent(eventPayload)
-		}
+		s.publish(s.newEvent(defID, active, currentState, newState, actor, comment, "Alarm acknowledged"))
 	}
 
 	return nil
 }
 
-func (s *AlarmService) Shelve(alarmID int, duration time.Duration) error {
+func (s *AlarmService) Shelve(alarmID int, duration time.Duration, actor, comment string) error {
+	return s.ShelveSigned(alarmID, duration, actor, comment, nil)
+}
+
+// ShelveSigned shelves an alarm, checking sig when the alarm's priority
+// requires a signed action. An empty actor is taken from the signature.
+func (s *AlarmService) ShelveSigned(alarmID int, duration time.Duration, actor, comment string, sig *Signature) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -324,6 +532,18 @@ func (s *AlarmService) Shelve(alarmID int, duration time.Duration) error {
 		return fmt.Errorf("cannot shelve alarm in state %s: %w", currentState, err)
 	}
 
+	var priority string
+	if def, ok := s.definitionsByID[defID]; ok {
+		priority = def.Priority
+	}
+	signed, err := s.checkSignatureLocked(priority, actor, sig)
+	if err != nil {
+		return fmt.Errorf("cannot shelve alarm %d: %w", alarmID, err)
+	}
+	if signed != nil {
+		actor = signed.Signer
+	}
+
 	shelvedUntil := time.Now().Add(duration)
 
 	active.State = string(newState)
@@ -335,22 +555,28 @@ func (s *AlarmService) Shelve(alarmID int, duration time.Duration) error {
 	}
 
 	// Publish Event
-	if s.publisher != nil {
-		eventPayload := &pb.AlarmEvent{
-			AlarmId:      int32(alarmID),
-			DefinitionId: int32(defID),
-			State:        string(newState),
-			Value:        active.Value,
-			TimestampMs:  time.Now().UnixMilli(),
-			Message:      fmt.Sprintf("Alarm shelved until %s", shelvedUntil),
-		}
-		s.publisher.PublishAlarmEvent(eventPayload)
+	event := s.newEvent(defID, active, currentState, newState, actor, comment,
+		fmt.Sprintf("Alarm shelved until %s", shelvedUntil))
+	if signed != nil {
+		event.SignedBy = signed.Signer
+		event.SignatureMeaning = signed.Meaning
 	}
+	s.publish(event)
 
 	return nil
 }
 
 func (s *AlarmService) CreateDefinition(def *AlarmDefinition) error {
+	if err := def.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+	}
+	s.mu.RLock()
+	err := s.priorityMatrix.Apply(def)
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDefinition, err)
+	}
+
 	if err := s.repo.CreateDefinition(def); err != nil {
 		return err
 	}
@@ -359,6 +585,8 @@ func (s *AlarmService) CreateDefinition(def *AlarmDefinition) error {
 	defer s.mu.Unlock()
 
 	s.definitions[def.Tag] = append(s.definitions[def.Tag], def)
+	s.definitionsByID[def.ID] = def
+	s.warnPriorityDriftLocked()
 	return nil
 }
 
@@ -372,3 +600,27 @@ func (s *AlarmService) GetActiveAlarms() []*ActiveAlarm {
 	}
 	return alarms
 }
+
+// GetActiveAlarmViews returns the active alarms with their definition's
+// tag, priority and rationalization guidance attached.
+func (s *AlarmService) GetActiveAlarmViews() []*ActiveAlarmView {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	views := make([]*ActiveAlarmView, 0, len(s.activeAlarms))
+	for _, a := range s.activeAlarms {
+		view := &ActiveAlarmView{ActiveAlarm: a}
+		if def, ok := s.definitionsByID[a.DefinitionID]; ok {
+			view.Tag = def.Tag
+			view.Type = def.Type
+			view.Priority = s.groupPriorityLocked(def)
+			view.GroupID, view.FirstOut, view.Consequential = s.annotateGroupLocked(def)
+			if def.Rationalization != (Rationalization{}) {
+				guidance := def.Rationalization
+				view.Guidance = &guidance
+			}
+		}
+		views = append(views, view)
+	}
+	return views
+}
@@ -1,115 +1,46 @@
-package core
+package core_test
 
 import (
 	"testing"
 	"time"
 
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
 	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
 )
 
-// MockRepo implements AlarmRepository for testing
-type MockRepo struct {
-	definitions  map[int]*AlarmDefinition
-	activeAlarms map[int]*ActiveAlarm
-	nextDefID    int
-	nextAlarmID  int
-}
-
-func NewMockRepo() *MockRepo {
-	return &MockRepo{
-		definitions:  make(map[int]*AlarmDefinition),
-		activeAlarms: make(map[int]*ActiveAlarm),
-		nextDefID:    1,
-		nextAlarmID:  1,
-	}
-}
-
-func (m *MockRepo) CreateDefinition(def *AlarmDefinition) error {
-	def.ID = m.nextDefID
-	m.nextDefID++
-	m.definitions[def.ID] = def
-	return nil
-}
-
-func (m *MockRepo) GetDefinition(id int) (*AlarmDefinition, error) {
-	return m.definitions[id], nil
-}
-
-func (m *MockRepo) ListDefinitions() ([]*AlarmDefinition, error) {
-	var defs []*AlarmDefinition
-	for _, d := range m.definitions {
-		defs = append(defs, d)
-	}
-	return defs, nil
-}
-
-func (m *MockRepo) GetDefinitionsByTag(tag string) ([]*AlarmDefinition, error) {
-	var defs []*AlarmDefinition
-	for _, d := range m.definitions {
-		if d.Tag == tag {
-			defs = append(defs, d)
-		}
-	}
-	return defs, nil
-}
-
-func (m *MockRepo) CreateActiveAlarm(alarm *ActiveAlarm) error {
-	alarm.ID = m.nextAlarmID
-	m.nextAlarmID++
-	m.activeAlarms[alarm.ID] = alarm
-	return nil
-}
-
-func (m *MockRepo) UpdateActiveAlarmState(id int, state string) error {
-	if a, ok := m.activeAlarms[id]; ok {
-		a.State = state
-	}
-	return nil
+type MockPublisher struct {
+	events  []*pb.AlarmEvent
+	records []*core.AuditRecord
 }
 
-func (m *MockRepo) AckActiveAlarm(id int, ackTime time.Time) error {
-	if a, ok := m.activeAlarms[id]; ok {
-		a.State = "AckActive"
-		a.AckTime = &ackTime
-	}
+func (m *MockPublisher) PublishAlarmEvent(event *pb.AlarmEvent) error {
+	m.events = append(m.events, event)
 	return nil
 }
 
-func (m *MockRepo) ShelveActiveAlarm(id int, shelvedUntil time.Time) error {
-	if a, ok := m.activeAlarms[id]; ok {
-		a.State = "Shelved"
-		a.ShelvedUntil = &shelvedUntil
-	}
+func (m *MockPublisher) PublishAuditRecord(record *core.AuditRecord) error {
+	m.records = append(m.records, record)
 	return nil
 }
 
-func (m *MockRepo) GetActiveAlarms() ([]*ActiveAlarm, error) {
-	var alarms []*ActiveAlarm
-	for _, a := range m.activeAlarms {
-		if a.State != "Normal" {
-			alarms = append(alarms, a)
-		}
+// storedDefinitions returns the definitions the repository holds.
+func storedDefinitions(t *testing.T, repo core.AlarmRepository) []*core.AlarmDefinition {
+	t.Helper()
+	defs, err := repo.ListDefinitions()
+	if err != nil {
+		t.Fatalf("ListDefinitions failed: %v", err)
 	}
-	return alarms, nil
-}
-
-// MockPublisher implements EventPublisher for testing
-type MockPublisher struct {
-	events []*pb.AlarmEvent
-}
-
-func (m *MockPublisher) PublishAlarmEvent(event *pb.AlarmEvent) error {
-	m.events = append(m.events, event)
-	return nil
+	return defs
 }
 
 func TestAlarmService_ProcessValue(t *testing.T) {
-	repo := NewMockRepo()
+	repo := repository.NewMemoryRepository()
 	publisher := &MockPublisher{}
-	svc := NewAlarmService(repo, publisher)
+	svc := core.NewAlarmService(repo, publisher)
 
 	// Create Definition
-	def := &AlarmDefinition{
+	def := &core.AlarmDefinition{
 		Tag:       "sensor1",
 		Threshold: 100,
 		Type:      "High",
@@ -134,7 +65,7 @@ func TestAlarmService_ProcessValue(t *testing.T) {
 
 	// 2. UnackActive -> Ack -> AckActive
 	alarmID := alarms[0].ID
-	err := svc.Acknowledge(alarmID)
+	err := svc.Acknowledge(alarmID, "operator1", "checked locally")
 	if err != nil {
 		t.Fatalf("Failed to acknowledge: %v", err)
 	}
@@ -160,11 +91,11 @@ func TestAlarmService_ProcessValue(t *testing.T) {
 }
 
 func TestAlarmService_Shelve(t *testing.T) {
-	repo := NewMockRepo()
+	repo := repository.NewMemoryRepository()
 	publisher := &MockPublisher{}
-	svc := NewAlarmService(repo, publisher)
+	svc := core.NewAlarmService(repo, publisher)
 
-	def := &AlarmDefinition{
+	def := &core.AlarmDefinition{
 		Tag:       "sensor1",
 		Threshold: 100,
 		Type:      "High",
@@ -178,7 +109,7 @@ func TestAlarmService_Shelve(t *testing.T) {
 	alarmID := alarms[0].ID
 
 	// Shelve
-	err := svc.Shelve(alarmID, 1*time.Hour)
+	err := svc.Shelve(alarmID, 1*time.Hour, "operator1", "")
 	if err != nil {
 		t.Fatalf("Failed to shelve: %v", err)
 	}
@@ -191,3 +122,123 @@ func TestAlarmService_Shelve(t *testing.T) {
 		t.Error("Expected ShelvedUntil to be set")
 	}
 }
+
+func TestAlarmService_EventContext(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repo, publisher)
+
+	def := &core.AlarmDefinition{
+		Tag:       "sensor1",
+		Threshold: 100,
+		Type:      "High",
+		Priority:  "Critical",
+	}
+	repo.CreateDefinition(def)
+	svc.LoadDefinitions()
+
+	svc.ProcessValue("sensor1", 101)
+	alarmID := svc.GetActiveAlarms()[0].ID
+	if err := svc.Acknowledge(alarmID, "operator1", "checked locally"); err != nil {
+		t.Fatalf("Failed to acknowledge: %v", err)
+	}
+
+	if len(publisher.events) != 2 {
+		t.Fatalf("Expected 2 events published, got %d", len(publisher.events))
+	}
+
+	trigger := publisher.events[0]
+	if trigger.Tag != "sensor1" || trigger.Priority != "Critical" || trigger.AlarmType != "High" || trigger.Threshold != 100 {
+		t.Errorf("Expected definition context on event, got %+v", trigger)
+	}
+	if trigger.PreviousState != "Normal" || trigger.Actor != core.ActorSystem {
+		t.Errorf("Expected system transition from Normal, got previous=%s actor=%s", trigger.PreviousState, trigger.Actor)
+	}
+
+	ack := publisher.events[1]
+	if ack.PreviousState != "UnackActive" || ack.State != "AckActive" {
+		t.Errorf("Expected UnackActive -> AckActive, got %s -> %s", ack.PreviousState, ack.State)
+	}
+	if ack.Actor != "operator1" || ack.Comment != "checked locally" {
+		t.Errorf("Expected operator actor and comment, got actor=%s comment=%s", ack.Actor, ack.Comment)
+	}
+	if ack.Sequence <= trigger.Sequence {
+		t.Errorf("Expected increasing sequence, got %d then %d", trigger.Sequence, ack.Sequence)
+	}
+}
+
+func TestAlarmService_Guidance(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repo, publisher)
+
+	def := &core.AlarmDefinition{
+		Tag:       "sensor1",
+		Threshold: 100,
+		Type:      "High",
+		Priority:  "Critical",
+		Rationalization: core.Rationalization{
+			Cause:               "Cooling water valve closed",
+			CorrectiveAction:    "Open CW valve",
+			ResponseTimeSeconds: 300,
+		},
+	}
+	repo.CreateDefinition(def)
+	svc.LoadDefinitions()
+
+	svc.ProcessValue("sensor1", 101)
+
+	views := svc.GetActiveAlarmViews()
+	if len(views) != 1 {
+		t.Fatalf("Expected 1 active alarm, got %d", len(views))
+	}
+	if views[0].Tag != "sensor1" || views[0].Priority != "Critical" {
+		t.Errorf("Expected definition context on view, got tag=%s priority=%s", views[0].Tag, views[0].Priority)
+	}
+	if views[0].Guidance == nil || views[0].Guidance.CorrectiveAction != "Open CW valve" {
+		t.Errorf("Expected guidance on view, got %+v", views[0].Guidance)
+	}
+
+	event := publisher.events[0]
+	if event.Cause != "Cooling water valve closed" || event.ResponseTimeSeconds != 300 {
+		t.Errorf("Expected guidance on event, got cause=%q response=%d", event.Cause, event.ResponseTimeSeconds)
+	}
+}
+
+// stalledHistoryRepo holds every history write until released.
+type stalledHistoryRepo struct {
+	*repository.MemoryRepository
+	release chan struct{}
+}
+
+func (r *stalledHistoryRepo) AppendAlarmHistory(entry *core.AlarmHistoryEntry) error {
+	<-r.release
+	return r.MemoryRepository.AppendAlarmHistory(entry)
+}
+
+func TestAlarmService_HistoryWrittenOutsideLock(t *testing.T) {
+	repo := &stalledHistoryRepo{MemoryRepository: repository.NewMemoryRepository(), release: make(chan struct{})}
+	svc := core.NewAlarmService(repo, &MockPublisher{})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High", Priority: "High"})
+	svc.LoadDefinitions()
+
+	done := make(chan struct{})
+	go func() {
+		svc.ProcessValue("sensor1", 101)
+		svc.ProcessValue("sensor1", 99)
+		svc.GetActiveAlarmViews()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected processing to continue while the history write is stalled")
+	}
+
+	close(repo.release)
+	svc.FlushHistory()
+	history, _ := repo.ListAlarmHistory(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if len(history) != 2 || history[0].State != string(core.StateUnackActive) || history[1].PreviousState != string(core.StateUnackActive) {
+		t.Errorf("Expected both transitions in order, got %+v", history)
+	}
+}
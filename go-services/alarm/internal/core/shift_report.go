@@ -0,0 +1,344 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// topOffenderLimit caps the number of definitions listed as top offenders.
+const topOffenderLimit = 10
+
+// ackLookback is how far before a shift starts the report looks for the
+// activations of alarms acknowledged during the shift.
+const ackLookback = 24 * time.Hour
+
+// ShiftCalendar splits each day into shifts starting at fixed local times.
+// Start times are offsets from midnight; a shift ends where the next begins.
+type ShiftCalendar struct {
+	Starts   []time.Duration
+	Location *time.Location
+}
+
+// DefaultShiftCalendar is the classic three-shift pattern in UTC.
+func DefaultShiftCalendar() *ShiftCalendar {
+	return &ShiftCalendar{
+		Starts:   []time.Duration{6 * time.Hour, 14 * time.Hour, 22 * time.Hour},
+		Location: time.UTC,
+	}
+}
+
+// ParseShiftCalendar parses shift start times of the form "06:00,14:00,22:00".
+func ParseShiftCalendar(starts string, loc *time.Location) (*ShiftCalendar, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	c := &ShiftCalendar{Location: loc}
+	seen := make(map[time.Duration]bool)
+	for _, part := range strings.Split(starts, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		t, err := time.Parse("15:04", part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shift start %q, expected HH:MM", part)
+		}
+		offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		if seen[offset] {
+			return nil, fmt.Errorf("duplicate shift start %q", part)
+		}
+		seen[offset] = true
+		c.Starts = append(c.Starts, offset)
+	}
+	if len(c.Starts) == 0 {
+		return nil, fmt.Errorf("shift calendar needs at least one start time")
+	}
+	sort.Slice(c.Starts, func(i, j int) bool { return c.Starts[i] < c.Starts[j] })
+	return c, nil
+}
+
+// boundary returns the shift start on the given local day. Wall-clock
+// construction keeps shifts aligned across DST changes.
+func (c *ShiftCalendar) boundary(year int, month time.Month, day int, offset time.Duration) time.Time {
+	return time.Date(year, month, day, int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, c.Location)
+}
+
+// ShiftAt returns the bounds of the shift containing t.
+func (c *ShiftCalendar) ShiftAt(t time.Time) (start, end time.Time) {
+	local := t.In(c.Location)
+	y, m, d := local.Date()
+	for day := d - 1; day <= d+1; day++ {
+		for _, offset := range c.Starts {
+			b := c.boundary(y, m, day, offset)
+			if !b.After(t) {
+				start = b
+			} else if end.IsZero() {
+				end = b
+			}
+		}
+	}
+	return start, end
+}
+
+// PreviousShift returns the bounds of the last shift completed before t.
+func (c *ShiftCalendar) PreviousShift(t time.Time) (start, end time.Time) {
+	current, _ := c.ShiftAt(t)
+	return c.ShiftAt(current.Add(-time.Nanosecond))
+}
+
+// AlarmOffender is a definition ranked by activations during a shift.
+type AlarmOffender struct {
+	DefinitionID int    `json:"definition_id"`
+	Tag          string `json:"tag"`
+	Priority     string `json:"priority"`
+	Activations  int    `json:"activations"`
+}
+
+// AckLatency summarizes how quickly alarms were acknowledged.
+type AckLatency struct {
+	Operator    string  `json:"operator,omitempty"`
+	Acks        int     `json:"acks"`
+	MeanSeconds float64 `json:"mean_seconds"`
+	MaxSeconds  float64 `json:"max_seconds"`
+}
+
+func (l *AckLatency) add(latency time.Duration) {
+	seconds := latency.Seconds()
+	l.MeanSeconds = (l.MeanSeconds*float64(l.Acks) + seconds) / float64(l.Acks+1)
+	l.Acks++
+	if seconds > l.MaxSeconds {
+		l.MaxSeconds = seconds
+	}
+}
+
+// ShiftReport is the handover summary for one shift. Alarm counts and
+// acknowledgement latency come from the alarm history; standing, shelved
+// and out-of-service alarms reflect the state when the report was generated.
+type ShiftReport struct {
+	ShiftStart       time.Time          `json:"shift_start"`
+	ShiftEnd         time.Time          `json:"shift_end"`
+	GeneratedAt      time.Time          `json:"generated_at"`
+	AlarmsRaised     int                `json:"alarms_raised"`
+	RaisedByPriority map[string]int     `json:"raised_by_priority"`
+	Standing         []*ActiveAlarmView `json:"standing"`
+	Shelved          []*ActiveAlarmView `json:"shelved"`
+	OutOfService     []*ActiveAlarmView `json:"out_of_service"`
+	TopOffenders     []*AlarmOffender   `json:"top_offenders"`
+	AckLatency       AckLatency         `json:"ack_latency"`
+	AckByOperator    []*AckLatency      `json:"ack_latency_by_operator"`
+}
+
+// GenerateShiftReport builds the report for the shift [start, end).
+func (s *AlarmService) GenerateShiftReport(start, end time.Time) (*ShiftReport, error) {
+	s.FlushHistory()
+	history, err := s.repo.ListAlarmHistory(start.Add(-ackLookback), end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ShiftReport{
+		ShiftStart:       start,
+		ShiftEnd:         end,
+		GeneratedAt:      time.Now(),
+		RaisedByPriority: make(map[string]int),
+		Standing:         make([]*ActiveAlarmView, 0),
+		Shelved:          make([]*ActiveAlarmView, 0),
+		OutOfService:     make([]*ActiveAlarmView, 0),
+		TopOffenders:     make([]*AlarmOffender, 0),
+		AckByOperator:    make([]*AckLatency, 0),
+	}
+
+	offenders := make(map[int]*AlarmOffender)
+	activatedAt := make(map[int]time.Time)
+	byOperator := make(map[string]*AckLatency)
+	for _, e := range history {
+		inShift := !e.Timestamp.Before(start)
+		switch {
+		case e.IsActivation():
+			activatedAt[e.AlarmID] = e.Timestamp
+			if !inShift {
+				continue
+			}
+			report.AlarmsRaised++
+			report.RaisedByPriority[e.Priority]++
+			o, ok := offenders[e.DefinitionID]
+			if !ok {
+				o = &AlarmOffender{DefinitionID: e.DefinitionID, Tag: e.Tag, Priority: e.Priority}
+				offenders[e.DefinitionID] = o
+			}
+			o.Activations++
+		case e.IsAck():
+			raised, ok := activatedAt[e.AlarmID]
+			if !inShift || !ok {
+				continue
+			}
+			latency := e.Timestamp.Sub(raised)
+			report.AckLatency.add(latency)
+			op, ok := byOperator[e.Actor]
+			if !ok {
+				op = &AckLatency{Operator: e.Actor}
+				byOperator[e.Actor] = op
+			}
+			op.add(latency)
+		}
+	}
+
+	for _, o := range offenders {
+		report.TopOffenders = append(report.TopOffenders, o)
+	}
+	sort.Slice(report.TopOffenders, func(i, j int) bool {
+		a, b := report.TopOffenders[i], report.TopOffenders[j]
+		if a.Activations != b.Activations {
+			return a.Activations > b.Activations
+		}
+		return a.DefinitionID < b.DefinitionID
+	})
+	if len(report.TopOffenders) > topOffenderLimit {
+		report.TopOffenders = report.TopOffenders[:topOffenderLimit]
+	}
+
+	for _, op := range byOperator {
+		report.AckByOperator = append(report.AckByOperator, op)
+	}
+	sort.Slice(report.AckByOperator, func(i, j int) bool {
+		return report.AckByOperator[i].Operator < report.AckByOperator[j].Operator
+	})
+
+	for _, view := range s.GetActiveAlarmViews() {
+		switch AlarmState(view.State) {
+		case StateUnackActive, StateAckActive, StateUnackRTN:
+			report.Standing = append(report.Standing, view)
+		case StateShelved:
+			report.Shelved = append(report.Shelved, view)
+		case StateSuppressed:
+			report.OutOfService = append(report.OutOfService, view)
+		}
+	}
+	for _, views := range [][]*ActiveAlarmView{report.Standing, report.Shelved, report.OutOfService} {
+		sort.Slice(views, func(i, j int) bool { return views[i].ActivationTime.Before(views[j].ActivationTime) })
+	}
+	return report, nil
+}
+
+// EncodeShiftReport writes the report as indented JSON, or as CSV rows of
+// section, item, detail and value so it opens cleanly in a spreadsheet.
+func EncodeShiftReport(w io.Writer, format string, report *ShiftReport) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		rows := [][]string{
+			{"section", "item", "detail", "value"},
+			{"shift", "start", "", report.ShiftStart.Format(time.RFC3339)},
+			{"shift", "end", "", report.ShiftEnd.Format(time.RFC3339)},
+			{"shift", "generated_at", "", report.GeneratedAt.Format(time.RFC3339)},
+			{"raised", "total", "", strconv.Itoa(report.AlarmsRaised)},
+		}
+
+		priorities := make([]string, 0, len(report.RaisedByPriority))
+		for p := range report.RaisedByPriority {
+			priorities = append(priorities, p)
+		}
+		sort.Strings(priorities)
+		for _, p := range priorities {
+			rows = append(rows, []string{"raised", "priority", p, strconv.Itoa(report.RaisedByPriority[p])})
+		}
+
+		for _, section := range []struct {
+			name  string
+			views []*ActiveAlarmView
+		}{
+			{"standing", report.Standing},
+			{"shelved", report.Shelved},
+			{"out_of_service", report.OutOfService},
+		} {
+			for _, v := range section.views {
+				rows = append(rows, []string{section.name, v.Tag, v.State, v.ActivationTime.Format(time.RFC3339)})
+			}
+		}
+
+		for _, o := range report.TopOffenders {
+			rows = append(rows, []string{"top_offender", o.Tag, o.Priority, strconv.Itoa(o.Activations)})
+		}
+
+		latencyRows := func(l *AckLatency, name string) {
+			rows = append(rows,
+				[]string{"ack_latency", name, "acks", strconv.Itoa(l.Acks)},
+				[]string{"ack_latency", name, "mean_seconds", strconv.FormatFloat(l.MeanSeconds, 'f', 1, 64)},
+				[]string{"ack_latency", name, "max_seconds", strconv.FormatFloat(l.MaxSeconds, 'f', 1, 64)},
+			)
+		}
+		latencyRows(&report.AckLatency, "all")
+		for _, op := range report.AckByOperator {
+			latencyRows(op, op.Operator)
+		}
+
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// ShiftReportSink stores reports produced by the scheduled job.
+type ShiftReportSink interface {
+	SaveShiftReport(report *ShiftReport) error
+}
+
+func (s *AlarmService) SetShiftCalendar(c *ShiftCalendar) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shiftCalendar = c
+}
+
+func (s *AlarmService) ShiftCalendar() *ShiftCalendar {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.shiftCalendar
+}
+
+// StartShiftReports generates a report at every shift boundary and hands
+// it to sink, which may be nil when reports are only audited.
+func (s *AlarmService) StartShiftReports(sink ShiftReportSink) {
+	go func() {
+		for {
+			_, end := s.ShiftCalendar().ShiftAt(time.Now())
+			time.Sleep(time.Until(end))
+			s.runShiftReport(sink, time.Now())
+		}
+	}()
+}
+
+func (s *AlarmService) runShiftReport(sink ShiftReportSink, now time.Time) {
+	start, end := s.ShiftCalendar().PreviousShift(now)
+	report, err := s.GenerateShiftReport(start, end)
+	if err != nil {
+		log.Printf("Failed to generate shift report: %v", err)
+		return
+	}
+	if sink != nil {
+		if err := sink.SaveShiftReport(report); err != nil {
+			log.Printf("Failed to save shift report: %v", err)
+		}
+	}
+	s.publishAudit(ActorSystem, "alarm_shift_report_generated", map[string]interface{}{
+		"shift_start":    start,
+		"shift_end":      end,
+		"alarms_raised":  report.AlarmsRaised,
+		"standing":       len(report.Standing),
+		"shelved":        len(report.Shelved),
+		"out_of_service": len(report.OutOfService),
+	})
+}
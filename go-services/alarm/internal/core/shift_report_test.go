@@ -0,0 +1,130 @@
+package core_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+)
+
+func TestShiftCalendar(t *testing.T) {
+	cal, err := core.ParseShiftCalendar("22:00, 06:00,14:00", time.UTC)
+	if err != nil {
+		t.Fatalf("ParseShiftCalendar failed: %v", err)
+	}
+
+	tests := []struct {
+		at         string
+		start, end string
+	}{
+		{"2026-03-10T07:30:00Z", "2026-03-10T06:00:00Z", "2026-03-10T14:00:00Z"},
+		{"2026-03-10T14:00:00Z", "2026-03-10T14:00:00Z", "2026-03-10T22:00:00Z"},
+		{"2026-03-10T02:00:00Z", "2026-03-09T22:00:00Z", "2026-03-10T06:00:00Z"},
+		{"2026-03-10T23:00:00Z", "2026-03-10T22:00:00Z", "2026-03-11T06:00:00Z"},
+	}
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		start, end := cal.ShiftAt(at)
+		if start.Format(time.RFC3339) != tt.start || end.Format(time.RFC3339) != tt.end {
+			t.Errorf("ShiftAt(%s) = %s - %s, want %s - %s", tt.at, start.Format(time.RFC3339), end.Format(time.RFC3339), tt.start, tt.end)
+		}
+	}
+
+	at, _ := time.Parse(time.RFC3339, "2026-03-10T07:30:00Z")
+	start, end := cal.PreviousShift(at)
+	if start.Format(time.RFC3339) != "2026-03-09T22:00:00Z" || end.Format(time.RFC3339) != "2026-03-10T06:00:00Z" {
+		t.Errorf("PreviousShift = %s - %s", start, end)
+	}
+
+	for _, bad := range []string{"", "25:00", "06:00,06:00"} {
+		if _, err := core.ParseShiftCalendar(bad, time.UTC); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestAlarmService_ShiftReport(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := core.NewAlarmService(repo, &MockPublisher{})
+
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High", Priority: "High"})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor2", Threshold: 10, Type: "Low", Priority: "Low"})
+	svc.LoadDefinitions()
+
+	start := time.Now().Add(-time.Hour)
+	end := time.Now().Add(time.Hour)
+
+	// sensor1 raises twice, is acknowledged once, and is left standing
+	svc.ProcessValue("sensor1", 101)
+	svc.ProcessValue("sensor1", 99)
+	svc.ProcessValue("sensor1", 101)
+	// sensor2 raises once and is shelved
+	svc.ProcessValue("sensor2", 5)
+
+	views := svc.GetActiveAlarmViews()
+	for _, v := range views {
+		if v.Tag == "sensor1" {
+			if err := svc.Acknowledge(v.ID, "alice", ""); err != nil {
+				t.Fatalf("Acknowledge failed: %v", err)
+			}
+		} else if err := svc.Shelve(v.ID, time.Hour, "bob", "maintenance"); err != nil {
+			t.Fatalf("Shelve failed: %v", err)
+		}
+	}
+
+	report, err := svc.GenerateShiftReport(start, end)
+	if err != nil {
+		t.Fatalf("GenerateShiftReport failed: %v", err)
+	}
+
+	if report.AlarmsRaised != 3 || report.RaisedByPriority["High"] != 2 || report.RaisedByPriority["Low"] != 1 {
+		t.Errorf("Unexpected raised counts: %d %v", report.AlarmsRaised, report.RaisedByPriority)
+	}
+	if len(report.TopOffenders) != 2 || report.TopOffenders[0].Tag != "sensor1" || report.TopOffenders[0].Activations != 2 {
+		t.Errorf("Unexpected top offenders: %+v", report.TopOffenders)
+	}
+	if len(report.Standing) != 1 || report.Standing[0].Tag != "sensor1" {
+		t.Errorf("Expected sensor1 standing, got %+v", report.Standing)
+	}
+	if len(report.Shelved) != 1 || report.Shelved[0].Tag != "sensor2" {
+		t.Errorf("Expected sensor2 shelved, got %+v", report.Shelved)
+	}
+	if report.AckLatency.Acks != 1 || len(report.AckByOperator) != 1 || report.AckByOperator[0].Operator != "alice" {
+		t.Errorf("Unexpected ack latency: %+v %+v", report.AckLatency, report.AckByOperator)
+	}
+
+	// Transitions outside the shift are not counted
+	report, _ = svc.GenerateShiftReport(end, end.Add(time.Hour))
+	if report.AlarmsRaised != 0 || report.AckLatency.Acks != 0 {
+		t.Errorf("Expected an empty shift, got %d raised, %d acks", report.AlarmsRaised, report.AckLatency.Acks)
+	}
+}
+
+func TestEncodeShiftReport_CSV(t *testing.T) {
+	report := &core.ShiftReport{
+		AlarmsRaised:     2,
+		RaisedByPriority: map[string]int{"High": 2},
+		TopOffenders:     []*core.AlarmOffender{{DefinitionID: 1, Tag: "sensor1", Priority: "High", Activations: 2}},
+		AckByOperator:    []*core.AckLatency{{Operator: "alice", Acks: 1, MeanSeconds: 12, MaxSeconds: 12}},
+	}
+
+	var buf bytes.Buffer
+	if err := core.EncodeShiftReport(&buf, core.FormatCSV, report); err != nil {
+		t.Fatalf("EncodeShiftReport failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"section,item,detail,value",
+		"raised,total,,2",
+		"raised,priority,High,2",
+		"top_offender,sensor1,High,2",
+		"ack_latency,alice,mean_seconds,12.0",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected CSV to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+)
+
+// fakeVerifier accepts tokens of the form "token-<username>".
+type fakeVerifier struct{}
+
+func (fakeVerifier) VerifySigningToken(token string) (*core.Signer, error) {
+	if len(token) < 7 || token[:6] != "token-" {
+		return nil, errors.New("bad token")
+	}
+	return &core.Signer{Username: token[6:], ExpiresAt: time.Now().Add(time.Minute)}, nil
+}
+
+func newSignedService(t *testing.T) (*core.AlarmService, *MockPublisher) {
+	t.Helper()
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repo, publisher)
+	svc.SetSignatureVerifier(fakeVerifier{})
+
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "reactor.temp", Threshold: 100, Type: "High", Priority: "Critical"})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "room.humidity", Threshold: 60, Type: "High", Priority: "Low"})
+	svc.LoadDefinitions()
+
+	svc.ProcessValue("reactor.temp", 120)
+	svc.ProcessValue("room.humidity", 70)
+	return svc, publisher
+}
+
+func alarmIDFor(t *testing.T, svc *core.AlarmService, tag string) int {
+	t.Helper()
+	for _, view := range svc.GetActiveAlarmViews() {
+		if view.Tag == tag {
+			return view.ID
+		}
+	}
+	t.Fatalf("No active alarm for %s", tag)
+	return 0
+}
+
+func TestAlarmService_ShelveCriticalRequiresSignature(t *testing.T) {
+	svc, publisher := newSignedService(t)
+	id := alarmIDFor(t, svc, "reactor.temp")
+
+	err := svc.Shelve(id, time.Hour, "alice", "")
+	if !errors.Is(err, core.ErrSignatureRequired) {
+		t.Fatalf("Expected ErrSignatureRequired, got %v", err)
+	}
+
+	rejected := []*core.Signature{
+		{Token: "token-alice"},
+		{Token: "forged", Meaning: "approval"},
+		{Token: "token-bob", Meaning: "approval"},
+	}
+	for _, sig := range rejected {
+		if err := svc.ShelveSigned(id, time.Hour, "alice", "", sig); !errors.Is(err, core.ErrInvalidSignature) {
+			t.Errorf("Expected %+v to be rejected, got %v", sig, err)
+		}
+	}
+
+	sig := &core.Signature{Token: "token-alice", Meaning: "approval"}
+	if err := svc.ShelveSigned(id, time.Hour, "", "maintenance", sig); err != nil {
+		t.Fatalf("Signed shelve failed: %v", err)
+	}
+	event := publisher.events[len(publisher.events)-1]
+	if event.State != string(core.StateShelved) || event.Actor != "alice" || event.SignedBy != "alice" || event.SignatureMeaning != "approval" {
+		t.Errorf("Expected signed shelve event, got %+v", event)
+	}
+}
+
+func TestAlarmService_ShelveTopMatrixPriorityRequiresSignature(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := core.NewAlarmService(repo, &MockPublisher{})
+	svc.SetSignatureVerifier(fakeVerifier{})
+
+	// Severe with a minute to respond: the matrix's highest priority
+	def := &core.AlarmDefinition{Tag: "reactor.pressure", Threshold: 10, Type: "High",
+		Rationalization: core.Rationalization{Severity: "severe", ResponseTimeSeconds: 60}}
+	if err := svc.CreateDefinition(def); err != nil {
+		t.Fatalf("CreateDefinition failed: %v", err)
+	}
+	if def.Priority != svc.SignedPriority() {
+		t.Fatalf("Expected the derived priority %s to be the signed one, got %s", def.Priority, svc.SignedPriority())
+	}
+	svc.ProcessValue("reactor.pressure", 12)
+
+	id := alarmIDFor(t, svc, "reactor.pressure")
+	if err := svc.Shelve(id, time.Hour, "alice", ""); !errors.Is(err, core.ErrSignatureRequired) {
+		t.Fatalf("Expected ErrSignatureRequired, got %v", err)
+	}
+}
+
+func TestAlarmService_SigningTokenIsSingleUse(t *testing.T) {
+	svc, _ := newSignedService(t)
+	id := alarmIDFor(t, svc, "reactor.temp")
+
+	sig := &core.Signature{Token: "token-alice", Meaning: "approval"}
+	if err := svc.ShelveSigned(id, time.Hour, "alice", "", sig); err != nil {
+		t.Fatalf("Signed shelve failed: %v", err)
+	}
+
+	w := &core.SuppressionWindow{Name: "Reactor PM", TagPrefix: "reactor.", Start: time.Now(), End: time.Now().Add(time.Hour), CreatedBy: "alice"}
+	if err := svc.CreateSuppressionWindowSigned(w, sig); !errors.Is(err, core.ErrInvalidSignature) {
+		t.Errorf("Expected reused token to be rejected, got %v", err)
+	}
+}
+
+func TestAlarmService_ShelveLowPriorityUnsigned(t *testing.T) {
+	svc, publisher := newSignedService(t)
+	id := alarmIDFor(t, svc, "room.humidity")
+
+	if err := svc.Shelve(id, time.Hour, "alice", ""); err != nil {
+		t.Fatalf("Expected unsigned shelve of a Low alarm, got %v", err)
+	}
+	if event := publisher.events[len(publisher.events)-1]; event.SignedBy != "" {
+		t.Errorf("Expected unsigned event, got signer %q", event.SignedBy)
+	}
+}
+
+func TestAlarmService_SuppressCriticalRequiresSignature(t *testing.T) {
+	svc, publisher := newSignedService(t)
+
+	window := func() *core.SuppressionWindow {
+		return &core.SuppressionWindow{Name: "Reactor PM", TagPrefix: "reactor.", Start: time.Now(), End: time.Now().Add(time.Hour), CreatedBy: "alice"}
+	}
+	if err := svc.CreateSuppressionWindow(window()); !errors.Is(err, core.ErrSignatureRequired) {
+		t.Fatalf("Expected ErrSignatureRequired, got %v", err)
+	}
+	if len(svc.GetSuppressionWindows()) != 0 {
+		t.Fatal("Expected rejected window not to be stored")
+	}
+
+	if err := svc.CreateSuppressionWindowSigned(window(), &core.Signature{Token: "token-alice", Meaning: "responsibility"}); err != nil {
+		t.Fatalf("Signed suppression failed: %v", err)
+	}
+	var created *core.AuditRecord
+	for _, r := range publisher.records {
+		if r.Action == "alarm_suppression_window_created" {
+			created = r
+		}
+	}
+	if created == nil || created.Details["signed_by"] != "alice" || created.Details["signature_meaning"] != "responsibility" {
+		t.Errorf("Expected signer in audit record, got %+v", created)
+	}
+
+	other := &core.SuppressionWindow{Name: "HVAC", TagPrefix: "room.", Start: time.Now(), End: time.Now().Add(time.Hour), CreatedBy: "alice"}
+	if err := svc.CreateSuppressionWindow(other); err != nil {
+		t.Errorf("Expected unsigned suppression of Low alarms, got %v", err)
+	}
+}
+
+func TestAlarmService_NoVerifierLeavesActionsUnsigned(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := core.NewAlarmService(repo, &MockPublisher{})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "reactor.temp", Threshold: 100, Type: "High", Priority: "Critical"})
+	svc.LoadDefinitions()
+	svc.ProcessValue("reactor.temp", 120)
+
+	if err := svc.Shelve(alarmIDFor(t, svc, "reactor.temp"), time.Hour, "alice", ""); err != nil {
+		t.Errorf("Expected shelve without signature gating, got %v", err)
+	}
+}
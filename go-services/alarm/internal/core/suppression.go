@@ -0,0 +1,256 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// SuppressionWindow suppresses an area (tag prefix) or an explicit set of
+// definitions between Start and End. With RRule set the window repeats;
+// each occurrence lasts End - Start.
+type SuppressionWindow struct {
+	ID            int        `json:"id"`
+	Name          string     `json:"name"`
+	TagPrefix     string     `json:"tag_prefix,omitempty"`
+	DefinitionIDs []int      `json:"definition_ids,omitempty"`
+	Start         time.Time  `json:"start"`
+	End           time.Time  `json:"end"`
+	RRule         string     `json:"rrule,omitempty"`
+	Reason        string     `json:"reason"`
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiredAt     *time.Time `json:"expired_at,omitempty"`
+
+	recurrence *Recurrence
+}
+
+func (w *SuppressionWindow) Validate() error {
+	if w.TagPrefix == "" && len(w.DefinitionIDs) == 0 {
+		return fmt.Errorf("suppression window needs a tag_prefix or definition_ids")
+	}
+	if !w.End.After(w.Start) {
+		return fmt.Errorf("suppression window end must be after start")
+	}
+	if w.RRule != "" {
+		r, err := ParseRRule(w.RRule)
+		if err != nil {
+			return err
+		}
+		w.recurrence = r
+	}
+	return nil
+}
+
+// Covers reports whether the window applies to the definition.
+func (w *SuppressionWindow) Covers(def *AlarmDefinition) bool {
+	if w.TagPrefix != "" && strings.HasPrefix(def.Tag, w.TagPrefix) {
+		return true
+	}
+	for _, id := range w.DefinitionIDs {
+		if id == def.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveAt reports whether an occurrence of the window covers t, and
+// whether the window can still become active after t.
+func (w *SuppressionWindow) ActiveAt(t time.Time) (active, pending bool) {
+	if w.recurrence == nil {
+		return !t.Before(w.Start) && t.Before(w.End), t.Before(w.End)
+	}
+	occ, found, more := w.recurrence.LatestOccurrence(w.Start, t)
+	if !found {
+		return false, more
+	}
+	active = t.Before(occ.Add(w.End.Sub(w.Start)))
+	return active, active || more
+}
+
+func (w *SuppressionWindow) auditDetails() map[string]interface{} {
+	details := map[string]interface{}{
+		"window_id": w.ID,
+		"name":      w.Name,
+		"start":     w.Start,
+		"end":       w.End,
+		"reason":    w.Reason,
+	}
+	if w.TagPrefix != "" {
+		details["tag_prefix"] = w.TagPrefix
+	}
+	if len(w.DefinitionIDs) > 0 {
+		details["definition_ids"] = w.DefinitionIDs
+	}
+	if w.RRule != "" {
+		details["rrule"] = w.RRule
+	}
+	return details
+}
+
+func (s *AlarmService) CreateSuppressionWindow(w *SuppressionWindow) error {
+	return s.CreateSuppressionWindowSigned(w, nil)
+}
+
+// CreateSuppressionWindowSigned creates a window, checking sig when the
+// window takes an alarm of the signed priority out of service. An empty CreatedBy is
+// taken from the signature.
+func (s *AlarmService) CreateSuppressionWindowSigned(w *SuppressionWindow, sig *Signature) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	// The window is as sensitive as the most critical alarm it covers
+	s.mu.Lock()
+	var priority, covered string
+	for _, def := range s.definitionsByID {
+		if s.priorityMatrix.RequiresSignature(def.Priority) && w.Covers(def) {
+			priority, covered = def.Priority, def.Key()
+			break
+		}
+	}
+	signed, err := s.checkSignatureLocked(priority, w.CreatedBy, sig)
+	s.mu.Unlock()
+	if err != nil {
+		if covered != "" {
+			return fmt.Errorf("cannot suppress %s: %w", covered, err)
+		}
+		return err
+	}
+	if signed != nil {
+		w.CreatedBy = signed.Signer
+	}
+
+	if err := s.repo.CreateSuppressionWindow(w); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.windows[w.ID] = w
+	s.mu.Unlock()
+
+	details := w.auditDetails()
+	signed.auditDetails(details)
+	s.publishAudit(w.CreatedBy, "alarm_suppression_window_created", details)
+
+	// Apply immediately rather than waiting for the next tick
+	s.checkSuppressionWindows()
+	return nil
+}
+
+// CancelSuppressionWindow ends a window early on behalf of an operator.
+func (s *AlarmService) CancelSuppressionWindow(id int, actor string) error {
+	s.mu.Lock()
+	w, ok := s.windows[id]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("suppression window %d not found", id)
+	}
+	err := s.expireWindowLocked(w, time.Now(), actor, "alarm_suppression_window_cancelled")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.checkSuppressionWindows()
+	return nil
+}
+
+func (s *AlarmService) GetSuppressionWindows() []*SuppressionWindow {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	windows := make([]*SuppressionWindow, 0, len(s.windows))
+	for _, w := range s.windows {
+		windows = append(windows, w)
+	}
+	return windows
+}
+
+// expireWindowLocked removes a window for good. Callers hold s.mu.
+func (s *AlarmService) expireWindowLocked(w *SuppressionWindow, now time.Time, actor, action string) error {
+	if err := s.repo.ExpireSuppressionWindow(w.ID, now); err != nil {
+		return err
+	}
+	w.ExpiredAt = &now
+	delete(s.windows, w.ID)
+	delete(s.windowActive, w.ID)
+	s.publishAudit(actor, action, w.auditDetails())
+	return nil
+}
+
+// isSuppressedLocked reports whether an active window covers def. Callers hold s.mu.
+func (s *AlarmService) isSuppressedLocked(def *AlarmDefinition) bool {
+	for id, w := range s.windows {
+		if s.windowActive[id] && w.Covers(def) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSuppressionWindows runs on the background ticker. It records window
+// start, end and expiry, then reconciles alarm states: covered alarms are
+// suppressed, and suppressed alarms no longer covered return to Normal so
+// the next value re-evaluates them.
+func (s *AlarmService) checkSuppressionWindows() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, w := range s.windows {
+		active, pending := w.ActiveAt(now)
+		if active != s.windowActive[id] {
+			s.windowActive[id] = active
+			action := "alarm_suppression_window_started"
+			if !active {
+				action = "alarm_suppression_window_ended"
+			}
+			s.publishAudit(ActorSystem, action, w.auditDetails())
+		}
+		if !pending {
+			if err := s.expireWindowLocked(w, now, ActorSystem, "alarm_suppression_window_expired"); err != nil {
+				log.Printf("Failed to expire suppression window %d: %v", id, err)
+			}
+		}
+	}
+
+	for defID, active := range s.activeAlarms {
+		def, ok := s.definitionsByID[defID]
+		if !ok {
+			continue
+		}
+		currentState := AlarmState(active.State)
+		suppressed := s.isSuppressedLocked(def)
+
+		var event AlarmEvent
+		var message string
+		switch {
+		case suppressed && currentState != StateSuppressed && currentState != StateShelved:
+			event, message = EventSuppress, "Alarm suppressed by maintenance window"
+		case !suppressed && currentState == StateSuppressed && !s.groupSuppressesLocked(def, time.Time{}):
+			event, message = EventUnsuppress, "Alarm suppression ended"
+		default:
+			continue
+		}
+
+		fsm := NewAlarmFSM(currentState)
+		newState, err := fsm.Transition(event)
+		if err != nil {
+			continue
+		}
+		if err := s.repo.UpdateActiveAlarmState(active.ID, string(newState)); err != nil {
+			log.Printf("Failed to update suppressed alarm %d: %v", active.ID, err)
+			continue
+		}
+		active.State = string(newState)
+		active.UpdatedAt = now
+		if newState == StateNormal {
+			delete(s.activeAlarms, defID)
+		}
+
+		s.publish(s.newEvent(defID, active, currentState, newState, ActorSystem, "", message))
+	}
+}
@@ -0,0 +1,97 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+)
+
+func TestAlarmService_SuppressionWindow(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	publisher := &MockPublisher{}
+	svc := core.NewAlarmService(repo, publisher)
+
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "enterprise.site.area1.temp", Threshold: 100, Type: "High"})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "enterprise.site.area2.temp", Threshold: 100, Type: "High"})
+	svc.LoadDefinitions()
+
+	// An alarm that is already active when the window opens gets suppressed
+	svc.ProcessValue("enterprise.site.area1.temp", 101)
+
+	window := &core.SuppressionWindow{
+		Name:      "Area 1 turnaround",
+		TagPrefix: "enterprise.site.area1.",
+		Start:     time.Now().Add(-time.Minute),
+		End:       time.Now().Add(time.Hour),
+		CreatedBy: "planner",
+	}
+	if err := svc.CreateSuppressionWindow(window); err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+
+	alarms := svc.GetActiveAlarms()
+	if len(alarms) != 1 || alarms[0].State != string(core.StateSuppressed) {
+		t.Fatalf("Expected the area1 alarm to be suppressed, got %+v", alarms)
+	}
+
+	// No transitions for covered tags while the window is active
+	svc.ProcessValue("enterprise.site.area1.temp", 50)
+	svc.ProcessValue("enterprise.site.area2.temp", 101)
+	if len(svc.GetActiveAlarms()) != 2 {
+		t.Errorf("Expected the suppressed alarm and the new area2 alarm, got %d", len(svc.GetActiveAlarms()))
+	}
+
+	if len(publisher.records) != 2 ||
+		publisher.records[0].Action != "alarm_suppression_window_created" ||
+		publisher.records[1].Action != "alarm_suppression_window_started" {
+		t.Fatalf("Expected created and started audit records, got %+v", publisher.records)
+	}
+
+	// Ending the window releases the alarm and normal evaluation resumes
+	if err := svc.CancelSuppressionWindow(window.ID, "planner"); err != nil {
+		t.Fatalf("Failed to cancel window: %v", err)
+	}
+	if len(svc.GetActiveAlarms()) != 1 {
+		t.Errorf("Expected only the area2 alarm after suppression ended, got %d", len(svc.GetActiveAlarms()))
+	}
+	svc.ProcessValue("enterprise.site.area1.temp", 101)
+	if len(svc.GetActiveAlarms()) != 2 {
+		t.Errorf("Expected area1 to alarm again after the window, got %d", len(svc.GetActiveAlarms()))
+	}
+	if windows, _ := repo.ListSuppressionWindows(); len(windows) != 0 {
+		t.Error("Expected the cancelled window to be expired in the repository")
+	}
+}
+
+func TestSuppressionWindow_ActiveAt(t *testing.T) {
+	start := time.Date(2026, 1, 5, 2, 0, 0, 0, time.UTC)
+	w := &core.SuppressionWindow{
+		DefinitionIDs: []int{1},
+		Start:         start,
+		End:           start.Add(2 * time.Hour),
+		RRule:         "FREQ=DAILY;COUNT=2",
+	}
+	if err := w.Validate(); err != nil {
+		t.Fatalf("Failed to validate: %v", err)
+	}
+
+	tests := []struct {
+		at          time.Time
+		active      bool
+		pending     bool
+		description string
+	}{
+		{start.Add(time.Hour), true, true, "inside first occurrence"},
+		{start.Add(3 * time.Hour), false, true, "between occurrences"},
+		{start.AddDate(0, 0, 1).Add(time.Hour), true, true, "inside last occurrence"},
+		{start.AddDate(0, 0, 1).Add(3 * time.Hour), false, false, "after last occurrence"},
+	}
+	for _, tt := range tests {
+		active, pending := w.ActiveAt(tt.at)
+		if active != tt.active || pending != tt.pending {
+			t.Errorf("%s: expected active=%v pending=%v, got %v %v", tt.description, tt.active, tt.pending, active, pending)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package metrics exposes the alarm service's operational metrics in the
+// Prometheus text format.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "alarm"
+
+// Metrics implements core.MetricsRecorder. It keeps its own registry so
+// tests can build as many as they like without clashing.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	valuesProcessed   prometheus.Counter
+	evaluationLatency prometheus.Histogram
+	sampleLag         prometheus.Gauge
+	publishFailures   *prometheus.CounterVec
+	repositoryErrors  *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		valuesProcessed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "values_processed_total",
+			Help:      "Tag values evaluated against alarm definitions.",
+		}),
+		evaluationLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "evaluation_duration_seconds",
+			Help:      "Time taken to evaluate one tag value.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}),
+		sampleLag: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sample_lag_seconds",
+			Help:      "Age of the most recent sensor sample when it was evaluated.",
+		}),
+		publishFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "publish_failures_total",
+			Help:      "Alarm events and audit records that could not be published.",
+		}, []string{"kind"}),
+		repositoryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repository_errors_total",
+			Help:      "Repository calls that returned an error, by operation.",
+		}, []string{"operation"}),
+	}
+	m.registry.MustRegister(
+		m.valuesProcessed,
+		m.evaluationLatency,
+		m.sampleLag,
+		m.publishFailures,
+		m.repositoryErrors,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+func (m *Metrics) ObserveSample(lag, evaluation time.Duration) {
+	m.valuesProcessed.Inc()
+	m.evaluationLatency.Observe(evaluation.Seconds())
+	if lag > 0 {
+		m.sampleLag.Set(lag.Seconds())
+	}
+}
+
+func (m *Metrics) PublishFailed(kind string) {
+	m.publishFailures.WithLabelValues(kind).Inc()
+}
+
+// RegisterActiveAlarms reports the service's active alarms by state and
+// priority, counted at scrape time.
+func (m *Metrics) RegisterActiveAlarms(svc *core.AlarmService) {
+	m.registry.MustRegister(&activeAlarmsCollector{svc: svc})
+}
+
+// RegisterConsumerBacklog reports how many received NATS messages are still
+// waiting to be handled, read from pending at scrape time.
+func (m *Metrics) RegisterConsumerBacklog(pending func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_pending_messages",
+		Help:      "Messages received from NATS but not yet processed.",
+	}, func() float64 { return float64(pending()) }))
+}
+
+// Handler serves the registry for scraping.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+var activeAlarmsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "active_alarms"),
+	"Alarms not in the Normal state, by state and priority.",
+	[]string{"state", "priority"}, nil,
+)
+
+type activeAlarmsCollector struct {
+	svc *core.AlarmService
+}
+
+func (c *activeAlarmsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeAlarmsDesc
+}
+
+func (c *activeAlarmsCollector) Collect(ch chan<- prometheus.Metric) {
+	type key struct{ state, priority string }
+	counts := make(map[key]int)
+	for _, view := range c.svc.GetActiveAlarmViews() {
+		counts[key{view.State, view.Priority}]++
+	}
+	for k, n := range counts {
+		ch <- prometheus.MustNewConstMetric(activeAlarmsDesc, prometheus.GaugeValue, float64(n), k.state, k.priority)
+	}
+}
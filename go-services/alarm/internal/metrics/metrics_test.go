@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+type failingPublisher struct{}
+
+func (failingPublisher) PublishAlarmEvent(*pb.AlarmEvent) error {
+	return errors.New("nats down")
+}
+
+func (failingPublisher) PublishAuditRecord(*core.AuditRecord) error { return nil }
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestMetrics_ServiceInstrumentation(t *testing.T) {
+	m := New()
+	repo := repository.NewMemoryRepository()
+	svc := core.NewAlarmService(m.InstrumentRepository(repo), failingPublisher{})
+	svc.SetMetrics(m)
+	m.RegisterActiveAlarms(svc)
+	m.RegisterConsumerBacklog(func() int { return 7 })
+
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "tank.level", Threshold: 90, Type: "High", Priority: "Critical"})
+	svc.LoadDefinitions()
+
+	svc.ProcessValue("tank.level", 95)
+	svc.ProcessValue("tank.level", 96)
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		"alarm_values_processed_total 2",
+		"alarm_evaluation_duration_seconds_count 2",
+		`alarm_active_alarms{priority="Critical",state="UnackActive"} 1`,
+		`alarm_publish_failures_total{kind="alarm_event"} 1`,
+		"alarm_consumer_pending_messages 7",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in scrape output", want)
+		}
+	}
+}
+
+type brokenRepository struct {
+	core.AlarmRepository
+}
+
+func (brokenRepository) ListDefinitions() ([]*core.AlarmDefinition, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestMetrics_RepositoryErrors(t *testing.T) {
+	m := New()
+	repo := m.InstrumentRepository(brokenRepository{})
+
+	if _, err := repo.ListDefinitions(); err == nil {
+		t.Fatal("Expected the underlying error to pass through")
+	}
+
+	if out := scrape(t, m); !strings.Contains(out, `alarm_repository_errors_total{operation="list_definitions"} 1`) {
+		t.Errorf("Expected repository error to be counted, got:\n%s", out)
+	}
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+)
+
+// InstrumentRepository wraps repo so that every call returning an error is
+// counted under its operation name.
+func (m *Metrics) InstrumentRepository(repo core.AlarmRepository) core.AlarmRepository {
+	return &instrumentedRepository{repo: repo, metrics: m}
+}
+
+type instrumentedRepository struct {
+	repo    core.AlarmRepository
+	metrics *Metrics
+}
+
+func (r *instrumentedRepository) observe(operation string, err error) error {
+	if err != nil {
+		r.metrics.repositoryErrors.WithLabelValues(operation).Inc()
+	}
+	return err
+}
+
+func (r *instrumentedRepository) CreateDefinition(def *core.AlarmDefinition) error {
+	return r.observe("create_definition", r.repo.CreateDefinition(def))
+}
+
+func (r *instrumentedRepository) GetDefinition(id int) (*core.AlarmDefinition, error) {
+	def, err := r.repo.GetDefinition(id)
+	return def, r.observe("get_definition", err)
+}
+
+func (r *instrumentedRepository) ListDefinitions() ([]*core.AlarmDefinition, error) {
+	defs, err := r.repo.ListDefinitions()
+	return defs, r.observe("list_definitions", err)
+}
+
+func (r *instrumentedRepository) GetDefinitionsByTag(tag string) ([]*core.AlarmDefinition, error) {
+	defs, err := r.repo.GetDefinitionsByTag(tag)
+	return defs, r.observe("get_definitions_by_tag", err)
+}
+
+func (r *instrumentedRepository) ApplyDefinitionImport(adds, updates []*core.AlarmDefinition, deleteIDs []int) error {
+	return r.observe("apply_definition_import", r.repo.ApplyDefinitionImport(adds, updates, deleteIDs))
+}
+
+func (r *instrumentedRepository) UpdateDefinitionPriority(id int, priority string) error {
+	return r.observe("update_definition_priority", r.repo.UpdateDefinitionPriority(id, priority))
+}
+
+func (r *instrumentedRepository) CreateActiveAlarm(alarm *core.ActiveAlarm) error {
+	return r.observe("create_active_alarm", r.repo.CreateActiveAlarm(alarm))
+}
+
+func (r *instrumentedRepository) UpdateActiveAlarmState(id int, state string) error {
+	return r.observe("update_active_alarm_state", r.repo.UpdateActiveAlarmState(id, state))
+}
+
+func (r *instrumentedRepository) AckActiveAlarm(id int, ackTime time.Time) error {
+	return r.observe("ack_active_alarm", r.repo.AckActiveAlarm(id, ackTime))
+}
+
+func (r *instrumentedRepository) ShelveActiveAlarm(id int, shelvedUntil time.Time) error {
+	return r.observe("shelve_active_alarm", r.repo.ShelveActiveAlarm(id, shelvedUntil))
+}
+
+func (r *instrumentedRepository) GetActiveAlarms() ([]*core.ActiveAlarm, error) {
+	alarms, err := r.repo.GetActiveAlarms()
+	return alarms, r.observe("get_active_alarms", err)
+}
+
+func (r *instrumentedRepository) CreateSuppressionWindow(w *core.SuppressionWindow) error {
+	return r.observe("create_suppression_window", r.repo.CreateSuppressionWindow(w))
+}
+
+func (r *instrumentedRepository) ListSuppressionWindows() ([]*core.SuppressionWindow, error) {
+	windows, err := r.repo.ListSuppressionWindows()
+	return windows, r.observe("list_suppression_windows", err)
+}
+
+func (r *instrumentedRepository) ExpireSuppressionWindow(id int, expiredAt time.Time) error {
+	return r.observe("expire_suppression_window", r.repo.ExpireSuppressionWindow(id, expiredAt))
+}
+
+func (r *instrumentedRepository) AppendAlarmHistory(entry *core.AlarmHistoryEntry) error {
+	return r.observe("append_alarm_history", r.repo.AppendAlarmHistory(entry))
+}
+
+func (r *instrumentedRepository) ListAlarmHistory(from, to time.Time) ([]*core.AlarmHistoryEntry, error) {
+	entries, err := r.repo.ListAlarmHistory(from, to)
+	return entries, r.observe("list_alarm_history", err)
+}
+
+func (r *instrumentedRepository) CreateAlarmGroup(g *core.AlarmGroup) error {
+	return r.observe("create_alarm_group", r.repo.CreateAlarmGroup(g))
+}
+
+func (r *instrumentedRepository) ListAlarmGroups() ([]*core.AlarmGroup, error) {
+	groups, err := r.repo.ListAlarmGroups()
+	return groups, r.observe("list_alarm_groups", err)
+}
+
+func (r *instrumentedRepository) DeleteAlarmGroup(id int) error {
+	return r.observe("delete_alarm_group", r.repo.DeleteAlarmGroup(id))
+}
@@ -0,0 +1,66 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"time"
+
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+// Player paces samples by their recorded timestamps.
+type Player struct {
+	// Speed is the replay rate relative to the recording: 1 is real time,
+	// 10 is ten times faster. Zero or less publishes as fast as possible.
+	Speed float64
+	// Restamp moves timestamps onto the replay timeline so the recording
+	// looks live to consumers. Otherwise the original timestamps are kept.
+	Restamp bool
+}
+
+// Play publishes every sample from src and returns how many were sent.
+// Samples are sent in recording order; one stamped earlier than its
+// predecessor is sent without waiting.
+func (p *Player) Play(ctx context.Context, src Source, publish func(*pb.SensorData) error) (int, error) {
+	var first int64
+	var started time.Time
+	sent := 0
+	for {
+		data, err := src.Next()
+		if err == io.EOF {
+			return sent, nil
+		}
+		if err != nil {
+			return sent, err
+		}
+
+		if sent == 0 {
+			first, started = data.TimestampMs, time.Now()
+		}
+		due := started
+		if p.Speed > 0 {
+			offset := time.Duration(float64(data.TimestampMs-first) * float64(time.Millisecond) / p.Speed)
+			due = started.Add(offset)
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return sent, ctx.Err()
+				}
+			}
+		} else if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		if p.Restamp {
+			data.TimestampMs = due.UnixMilli()
+			if p.Speed <= 0 {
+				data.TimestampMs = time.Now().UnixMilli()
+			}
+		}
+
+		if err := publish(data); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+}
@@ -0,0 +1,161 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+func readAll(t *testing.T, input, format string) []*pb.SensorData {
+	t.Helper()
+	src, err := NewFileSource(strings.NewReader(input), format)
+	if err != nil {
+		t.Fatalf("NewFileSource failed: %v", err)
+	}
+	var samples []*pb.SensorData
+	_, err = (&Player{}).Play(context.Background(), src, func(d *pb.SensorData) error {
+		samples = append(samples, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Play failed: %v", err)
+	}
+	return samples
+}
+
+func TestFileSource_Formats(t *testing.T) {
+	csvInput := "Value,Tag,Timestamp,Quality\n" +
+		"91.5,tank.level,2026-03-01T08:00:00Z,1\n" +
+		"12,pump.flow,1772352001000,0\n"
+	jsonlInput := `{"timestamp":"2026-03-01T08:00:00Z","tag":"tank.level","value":91.5}` + "\n\n" +
+		`{"timestamp":1772352001000,"tag":"pump.flow","value":12,"quality":0}` + "\n"
+
+	for format, input := range map[string]string{FormatCSV: csvInput, FormatJSONL: jsonlInput} {
+		samples := readAll(t, input, format)
+		if len(samples) != 2 {
+			t.Fatalf("%s: expected 2 samples, got %d", format, len(samples))
+		}
+		first, second := samples[0], samples[1]
+		if first.SensorId != "tank.level" || first.Value != 91.5 || first.TimestampMs != 1772352000000 || first.Quality != 1 {
+			t.Errorf("%s: unexpected first sample %v", format, first)
+		}
+		if second.SensorId != "pump.flow" || second.TimestampMs != 1772352001000 || second.Quality != 0 {
+			t.Errorf("%s: unexpected second sample %v", format, second)
+		}
+	}
+}
+
+func TestFileSource_Errors(t *testing.T) {
+	if _, err := NewFileSource(strings.NewReader("tag,value\n"), FormatCSV); err == nil {
+		t.Error("Expected missing timestamp column to be rejected")
+	}
+
+	src, _ := NewFileSource(strings.NewReader("timestamp,tag,value\nyesterday,t,1\n"), FormatCSV)
+	if _, err := src.Next(); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected line number in error, got %v", err)
+	}
+}
+
+func TestPlayer_PacesAndRestamps(t *testing.T) {
+	input := "timestamp,tag,value\n1000,a,1\n3000,a,2\n5000,a,3\n"
+	src, _ := NewFileSource(strings.NewReader(input), FormatCSV)
+
+	// Four recorded seconds at 100x should take about 40ms
+	player := &Player{Speed: 100, Restamp: true}
+	var stamps []int64
+	start := time.Now()
+	sent, err := player.Play(context.Background(), src, func(d *pb.SensorData) error {
+		stamps = append(stamps, d.TimestampMs)
+		return nil
+	})
+	elapsed := time.Since(start)
+
+	if err != nil || sent != 3 {
+		t.Fatalf("Expected 3 samples sent, got %d (%v)", sent, err)
+	}
+	if elapsed < 35*time.Millisecond {
+		t.Errorf("Replay finished too quickly: %v", elapsed)
+	}
+	if gap := stamps[2] - stamps[0]; gap < 39 || gap > 41 {
+		t.Errorf("Expected restamped samples 40ms apart, got %dms", gap)
+	}
+	if stamps[0] < start.UnixMilli() {
+		t.Errorf("Expected restamped timestamps on the replay timeline, got %d", stamps[0])
+	}
+}
+
+func TestDryRun_ReportsTransitions(t *testing.T) {
+	input := "timestamp,tag,value\n" +
+		"2026-03-01T08:00:00Z,tank.level,80\n" +
+		"2026-03-01T08:00:05Z,tank.level,95\n" +
+		"2026-03-01T08:00:10Z,tank.level,96\n" +
+		"2026-03-01T08:00:15Z,tank.level,70\n" +
+		"2026-03-01T08:00:20Z,other.tag,1000\n"
+	src, _ := NewFileSource(strings.NewReader(input), FormatCSV)
+	defs := []*core.AlarmDefinition{{Tag: "tank.level", Type: "High", Threshold: 90, Priority: "High"}}
+
+	result, err := DryRun(src, defs, core.DefaultChatterConfig())
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if result.Samples != 5 {
+		t.Errorf("Expected 5 samples evaluated, got %d", result.Samples)
+	}
+	if len(result.Transitions) != 2 {
+		t.Fatalf("Expected activation and return to normal, got %+v", result.Transitions)
+	}
+
+	raised, cleared := result.Transitions[0], result.Transitions[1]
+	if raised.To != string(core.StateUnackActive) || raised.Value != 95 || !raised.Time.Equal(time.Date(2026, 3, 1, 8, 0, 5, 0, time.UTC)) {
+		t.Errorf("Unexpected activation %+v", raised)
+	}
+	if cleared.From != string(core.StateUnackActive) || cleared.To != string(core.StateUnackRTN) {
+		t.Errorf("Unexpected clear %+v", cleared)
+	}
+
+	var out bytes.Buffer
+	WriteTransitions(&out, false, result.Transitions)
+	if lines := strings.Count(out.String(), "\n"); lines != 2 || !strings.Contains(out.String(), "tank.level") {
+		t.Errorf("Unexpected text output:\n%s", out.String())
+	}
+}
+
+func TestDryRun_ChatterFollowsSampleClock(t *testing.T) {
+	// Three activations within a recorded minute, replayed instantly; with
+	// a 30s on-delay the fourth excursion must persist before re-alarming
+	var rows strings.Builder
+	rows.WriteString("timestamp,tag,value\n")
+	base := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	values := []float64{95, 70, 95, 70, 95, 70, 95, 95, 95}
+	offsets := []int{0, 5, 10, 15, 20, 25, 30, 40, 70}
+	for i, v := range values {
+		rows.WriteString(base.Add(time.Duration(offsets[i])*time.Second).Format(time.RFC3339) + ",tank.level," + strconv.FormatFloat(v, 'g', -1, 64) + "\n")
+	}
+	src, _ := NewFileSource(strings.NewReader(rows.String()), FormatCSV)
+	defs := []*core.AlarmDefinition{{Tag: "tank.level", Type: "High", Threshold: 90, Priority: "High"}}
+	chatter := core.ChatterConfig{Threshold: 3, Window: time.Minute, Action: "delay", Delay: 30 * time.Second}
+
+	result, err := DryRun(src, defs, chatter)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+
+	var activations []time.Time
+	for _, tr := range result.Transitions {
+		if tr.To == string(core.StateUnackActive) {
+			activations = append(activations, tr.Time)
+		}
+	}
+	if len(activations) != 4 {
+		t.Fatalf("Expected 4 activations, got %d: %+v", len(activations), result.Transitions)
+	}
+	if want := base.Add(70 * time.Second); !activations[3].Equal(want) {
+		t.Errorf("Expected delayed re-activation at %v, got %v", want, activations[3])
+	}
+}
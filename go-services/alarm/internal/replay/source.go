@@ -0,0 +1,176 @@
+// Package replay feeds recorded tag values back through the alarm pipeline,
+// either by republishing them to NATS or by evaluating them offline.
+package replay
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Source yields samples in the order they were recorded. Next returns
+// io.EOF once the recording is exhausted.
+type Source interface {
+	Next() (*pb.SensorData, error)
+}
+
+// FormatFromPath guesses the file format from its extension.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	default:
+		return FormatJSONL
+	}
+}
+
+// NewFileSource reads samples in the given format from r.
+func NewFileSource(r io.Reader, format string) (Source, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVSource(r)
+	case FormatJSONL:
+		return &jsonlSource{scanner: bufio.NewScanner(r)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// parseTimestamp accepts RFC 3339 or Unix milliseconds.
+func parseTimestamp(v string) (int64, error) {
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", v)
+	}
+	return t.UnixMilli(), nil
+}
+
+// csvSource reads "timestamp,tag,value[,quality]" rows. Columns are matched
+// by header name; quality defaults to good.
+type csvSource struct {
+	reader  *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+func newCSVSource(r io.Reader) (*csvSource, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"timestamp", "tag", "value"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing required CSV column %q", name)
+		}
+	}
+	return &csvSource{reader: cr, columns: columns, line: 1}, nil
+}
+
+func (s *csvSource) field(record []string, name string) string {
+	i, ok := s.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func (s *csvSource) Next() (*pb.SensorData, error) {
+	record, err := s.reader.Read()
+	s.line++
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", s.line, err)
+	}
+
+	ts, err := parseTimestamp(s.field(record, "timestamp"))
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", s.line, err)
+	}
+	value, err := strconv.ParseFloat(s.field(record, "value"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: invalid value %q", s.line, s.field(record, "value"))
+	}
+	data := &pb.SensorData{SensorId: s.field(record, "tag"), Value: value, TimestampMs: ts, Quality: 1}
+	if q := s.field(record, "quality"); q != "" {
+		quality, err := strconv.ParseInt(q, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid quality %q", s.line, q)
+		}
+		data.Quality = int32(quality)
+	}
+	if data.SensorId == "" {
+		return nil, fmt.Errorf("line %d: missing tag", s.line)
+	}
+	return data, nil
+}
+
+// jsonlRecord is one line of a JSONL recording. The timestamp may be an
+// RFC 3339 string or Unix milliseconds.
+type jsonlRecord struct {
+	Timestamp json.RawMessage `json:"timestamp"`
+	Tag       string          `json:"tag"`
+	Value     float64         `json:"value"`
+	Quality   *int32          `json:"quality"`
+}
+
+type jsonlSource struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (s *jsonlSource) Next() (*pb.SensorData, error) {
+	for s.scanner.Scan() {
+		s.line++
+		text := strings.TrimSpace(s.scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var rec jsonlRecord
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", s.line, err)
+		}
+		if rec.Tag == "" {
+			return nil, fmt.Errorf("line %d: missing tag", s.line)
+		}
+		ts, err := parseTimestamp(strings.Trim(string(rec.Timestamp), `"`))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", s.line, err)
+		}
+		data := &pb.SensorData{SensorId: rec.Tag, Value: rec.Value, TimestampMs: ts, Quality: 1}
+		if rec.Quality != nil {
+			data.Quality = *rec.Quality
+		}
+		return data, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
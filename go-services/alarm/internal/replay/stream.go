@@ -0,0 +1,77 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/proto"
+)
+
+// streamSource reads SensorData recorded in a JetStream stream from the
+// first message to the last one present when it was opened.
+type streamSource struct {
+	ctx      context.Context
+	consumer jetstream.Consumer
+	done     bool
+}
+
+// NewStreamSource replays the messages of stream matching filter, or all
+// of them when filter is empty.
+func NewStreamSource(ctx context.Context, nc *nats.Conn, stream, filter string) (Source, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+	s, err := js.Stream(ctx, stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stream %s: %w", stream, err)
+	}
+
+	cfg := jetstream.OrderedConsumerConfig{DeliverPolicy: jetstream.DeliverAllPolicy}
+	if filter != "" {
+		cfg.FilterSubjects = []string{filter}
+	}
+	consumer, err := s.OrderedConsumer(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer on %s: %w", stream, err)
+	}
+
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consumer info: %w", err)
+	}
+	return &streamSource{ctx: ctx, consumer: consumer, done: info.NumPending == 0}, nil
+}
+
+func (s *streamSource) Next() (*pb.SensorData, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	msg, err := s.consumer.Next(jetstream.FetchMaxWait(5 * time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from stream: %w", err)
+	}
+	meta, err := msg.Metadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message metadata: %w", err)
+	}
+	s.done = meta.NumPending == 0
+
+	var data pb.SensorData
+	if err := proto.Unmarshal(msg.Data(), &data); err != nil {
+		return nil, fmt.Errorf("stream sequence %d: %w", meta.Sequence.Stream, err)
+	}
+	if data.TimestampMs == 0 {
+		data.TimestampMs = meta.Timestamp.UnixMilli()
+	}
+	return &data, nil
+}
@@ -0,0 +1,341 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+)
+
+// testRepository is the repository under test plus its cleanup.
+type testRepository interface {
+	core.AlarmRepository
+	Close()
+}
+
+// runConformance checks the behaviour AlarmService relies on. Every
+// AlarmRepository implementation must pass it; newRepo returns an empty
+// repository for each subtest.
+func runConformance(t *testing.T, newRepo func(t *testing.T) testRepository) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo core.AlarmRepository)
+	}{
+		{"Definitions", testDefinitions},
+		{"DuplicateDefinition", testDuplicateDefinition},
+		{"DefinitionImport", testDefinitionImport},
+		{"DefinitionPriority", testDefinitionPriority},
+		{"ActiveAlarms", testActiveAlarms},
+		{"SuppressionWindows", testSuppressionWindows},
+		{"AlarmHistory", testAlarmHistory},
+		{"AlarmGroups", testAlarmGroups},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			defer repo.Close()
+			tt.fn(t, repo)
+		})
+	}
+}
+
+func testDefinitions(t *testing.T, repo core.AlarmRepository) {
+	def := &core.AlarmDefinition{
+		Tag: "sensor1", Threshold: 100, Type: "High", Priority: "High", Rationale: "Protect the pump",
+		Rationalization: core.Rationalization{Cause: "Blocked outlet", Severity: "major", ResponseTimeSeconds: 300, Class: "Safety"},
+	}
+	if err := repo.CreateDefinition(def); err != nil {
+		t.Fatalf("CreateDefinition failed: %v", err)
+	}
+	if def.ID == 0 || def.CreatedAt.IsZero() {
+		t.Fatalf("Expected ID and CreatedAt to be assigned, got %+v", def)
+	}
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor1", Threshold: 10, Type: "Low", Priority: "Low"})
+	repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor2", Threshold: 50, Type: "High", Priority: "Low"})
+
+	got, err := repo.GetDefinition(def.ID)
+	if err != nil {
+		t.Fatalf("GetDefinition failed: %v", err)
+	}
+	if got == nil || got.Tag != "sensor1" || got.Threshold != 100 || got.Rationale != def.Rationale || got.Rationalization != def.Rationalization {
+		t.Errorf("GetDefinition = %+v, want %+v", got, def)
+	}
+
+	missing, err := repo.GetDefinition(9999)
+	if err != nil || missing != nil {
+		t.Errorf("Expected nil, nil for a missing definition, got %+v, %v", missing, err)
+	}
+
+	all, err := repo.ListDefinitions()
+	if err != nil {
+		t.Fatalf("ListDefinitions failed: %v", err)
+	}
+	if len(all) != 3 || all[0].ID != def.ID || all[0].ID > all[1].ID || all[1].ID > all[2].ID {
+		t.Errorf("Expected 3 definitions ordered by ID, got %+v", all)
+	}
+
+	byTag, err := repo.GetDefinitionsByTag("sensor1")
+	if err != nil {
+		t.Fatalf("GetDefinitionsByTag failed: %v", err)
+	}
+	if len(byTag) != 2 {
+		t.Errorf("Expected 2 definitions for sensor1, got %d", len(byTag))
+	}
+}
+
+func testDuplicateDefinition(t *testing.T, repo core.AlarmRepository) {
+	if err := repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High"}); err != nil {
+		t.Fatalf("CreateDefinition failed: %v", err)
+	}
+	if err := repo.CreateDefinition(&core.AlarmDefinition{Tag: "sensor1", Threshold: 90, Type: "High"}); err == nil {
+		t.Error("Expected an error for a duplicate tag and type")
+	}
+}
+
+func testDefinitionPriority(t *testing.T, repo core.AlarmRepository) {
+	def := &core.AlarmDefinition{Tag: "pump.vibration", Type: "Anomaly", Priority: "Medium", Rationale: "Raised by analytics"}
+	other := &core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High", Priority: "Medium"}
+	repo.CreateDefinition(def)
+	repo.CreateDefinition(other)
+
+	if err := repo.UpdateDefinitionPriority(def.ID, "High"); err != nil {
+		t.Fatalf("UpdateDefinitionPriority failed: %v", err)
+	}
+	got, _ := repo.GetDefinition(def.ID)
+	if got.Priority != "High" || got.Rationale != def.Rationale || got.UpdatedAt.Before(def.UpdatedAt) {
+		t.Errorf("Expected only the priority to change, got %+v", got)
+	}
+	if untouched, _ := repo.GetDefinition(other.ID); untouched.Priority != "Medium" {
+		t.Errorf("Expected other definitions to keep their priority, got %s", untouched.Priority)
+	}
+	if err := repo.UpdateDefinitionPriority(9999, "High"); err == nil {
+		t.Error("Expected updating a missing definition to fail")
+	}
+}
+
+func testDefinitionImport(t *testing.T, repo core.AlarmRepository) {
+	keep := &core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High", Priority: "High"}
+	drop := &core.AlarmDefinition{Tag: "sensor2", Threshold: 10, Type: "Low", Priority: "Low"}
+	repo.CreateDefinition(keep)
+	repo.CreateDefinition(drop)
+	alarm := &core.ActiveAlarm{DefinitionID: drop.ID, State: "UnackActive", ActivationTime: time.Now(), Value: 5}
+	if err := repo.CreateActiveAlarm(alarm); err != nil {
+		t.Fatalf("CreateActiveAlarm failed: %v", err)
+	}
+
+	updated := *keep
+	updated.Threshold = 120
+	updated.Rationalization.Consequence = "Seal damage"
+	updated.Rationalization.Severity = "severe"
+	added := &core.AlarmDefinition{Tag: "sensor3", Threshold: 1, Type: "High", Priority: "Medium"}
+
+	// A definition with a standing alarm is not deleted
+	err := repo.ApplyDefinitionImport([]*core.AlarmDefinition{added}, []*core.AlarmDefinition{&updated}, []int{drop.ID})
+	if !errors.Is(err, core.ErrDefinitionInUse) {
+		t.Fatalf("Expected ErrDefinitionInUse, got %v", err)
+	}
+	if defs, _ := repo.ListDefinitions(); len(defs) != 2 {
+		t.Fatalf("Expected refused import to be rolled back, got %d definitions", len(defs))
+	}
+	added.ID = 0
+
+	// Once cleared it is
+	if err := repo.UpdateActiveAlarmState(alarm.ID, string(core.StateNormal)); err != nil {
+		t.Fatalf("UpdateActiveAlarmState failed: %v", err)
+	}
+	if err := repo.ApplyDefinitionImport([]*core.AlarmDefinition{added}, []*core.AlarmDefinition{&updated}, []int{drop.ID}); err != nil {
+		t.Fatalf("ApplyDefinitionImport failed: %v", err)
+	}
+	if added.ID == 0 {
+		t.Error("Expected added definition to get an ID")
+	}
+
+	defs, _ := repo.ListDefinitions()
+	if len(defs) != 2 {
+		t.Fatalf("Expected 2 definitions after import, got %d", len(defs))
+	}
+	got, _ := repo.GetDefinition(keep.ID)
+	if got.Threshold != 120 || got.Consequence != "Seal damage" || got.Severity != "severe" {
+		t.Errorf("Expected update to be applied, got %+v", got)
+	}
+	if gone, _ := repo.GetDefinition(drop.ID); gone != nil {
+		t.Error("Expected deleted definition to be gone")
+	}
+	alarms, _ := repo.GetActiveAlarms()
+	if len(alarms) != 0 {
+		t.Errorf("Expected cleared alarms of deleted definitions to be removed, got %d", len(alarms))
+	}
+
+	// A failing import leaves nothing behind
+	bad := &core.AlarmDefinition{ID: 9999, Tag: "ghost", Type: "High"}
+	extra := &core.AlarmDefinition{Tag: "sensor4", Threshold: 1, Type: "High"}
+	if err := repo.ApplyDefinitionImport([]*core.AlarmDefinition{extra}, []*core.AlarmDefinition{bad}, []int{keep.ID}); err == nil {
+		t.Fatal("Expected an error when updating a missing definition")
+	}
+	if defs, _ := repo.ListDefinitions(); len(defs) != 2 {
+		t.Errorf("Expected failed import to be rolled back, got %d definitions", len(defs))
+	}
+}
+
+func testActiveAlarms(t *testing.T, repo core.AlarmRepository) {
+	def := &core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High"}
+	repo.CreateDefinition(def)
+
+	activation := time.Now().Truncate(time.Millisecond)
+	onset := activation.Add(-2 * time.Second)
+	alarm := &core.ActiveAlarm{DefinitionID: def.ID, State: "UnackActive", ActivationTime: activation, OnsetTime: &onset, Value: 101}
+	if err := repo.CreateActiveAlarm(alarm); err != nil {
+		t.Fatalf("CreateActiveAlarm failed: %v", err)
+	}
+	if alarm.ID == 0 {
+		t.Fatal("Expected alarm ID to be assigned")
+	}
+
+	ackTime := activation.Add(30 * time.Second)
+	if err := repo.AckActiveAlarm(alarm.ID, ackTime); err != nil {
+		t.Fatalf("AckActiveAlarm failed: %v", err)
+	}
+	alarms, _ := repo.GetActiveAlarms()
+	if len(alarms) != 1 {
+		t.Fatalf("Expected 1 active alarm, got %d", len(alarms))
+	}
+	got := alarms[0]
+	if got.State != "AckActive" || got.AckTime == nil || !got.AckTime.Equal(ackTime) ||
+		!got.ActivationTime.Equal(activation) || !got.Onset().Equal(onset) || got.Value != 101 || got.ShelvedUntil != nil {
+		t.Errorf("Unexpected acknowledged alarm: %+v", got)
+	}
+
+	shelvedUntil := activation.Add(time.Hour)
+	if err := repo.ShelveActiveAlarm(alarm.ID, shelvedUntil); err != nil {
+		t.Fatalf("ShelveActiveAlarm failed: %v", err)
+	}
+	alarms, _ = repo.GetActiveAlarms()
+	if alarms[0].State != "Shelved" || alarms[0].ShelvedUntil == nil || !alarms[0].ShelvedUntil.Equal(shelvedUntil) {
+		t.Errorf("Unexpected shelved alarm: %+v", alarms[0])
+	}
+
+	// Normal alarms are no longer active
+	if err := repo.UpdateActiveAlarmState(alarm.ID, "Normal"); err != nil {
+		t.Fatalf("UpdateActiveAlarmState failed: %v", err)
+	}
+	if alarms, _ := repo.GetActiveAlarms(); len(alarms) != 0 {
+		t.Errorf("Expected no active alarms, got %d", len(alarms))
+	}
+
+	if err := repo.CreateActiveAlarm(&core.ActiveAlarm{DefinitionID: 9999, State: "UnackActive", ActivationTime: activation}); err == nil {
+		t.Error("Expected an error for an alarm without a definition")
+	}
+}
+
+func testSuppressionWindows(t *testing.T, repo core.AlarmRepository) {
+	start := time.Now().Truncate(time.Millisecond)
+	area := &core.SuppressionWindow{
+		Name: "Turnaround", TagPrefix: "enterprise.site.area1.", Start: start, End: start.Add(time.Hour),
+		RRule: "FREQ=DAILY", Reason: "Maintenance", CreatedBy: "alice",
+	}
+	explicit := &core.SuppressionWindow{
+		Name: "Calibration", DefinitionIDs: []int{1, 2}, Start: start, End: start.Add(time.Hour), CreatedBy: "bob",
+	}
+	for _, w := range []*core.SuppressionWindow{area, explicit} {
+		if err := repo.CreateSuppressionWindow(w); err != nil {
+			t.Fatalf("CreateSuppressionWindow failed: %v", err)
+		}
+		if w.ID == 0 || w.CreatedAt.IsZero() {
+			t.Fatalf("Expected ID and CreatedAt to be assigned, got %+v", w)
+		}
+	}
+
+	windows, err := repo.ListSuppressionWindows()
+	if err != nil {
+		t.Fatalf("ListSuppressionWindows failed: %v", err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("Expected 2 windows, got %d", len(windows))
+	}
+	got := windows[0]
+	if got.TagPrefix != area.TagPrefix || got.RRule != area.RRule || !got.Start.Equal(start) || !got.End.Equal(area.End) || got.CreatedBy != "alice" {
+		t.Errorf("Unexpected window: %+v", got)
+	}
+	if ids := windows[1].DefinitionIDs; len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("Expected definition IDs [1 2], got %v", ids)
+	}
+
+	if err := repo.ExpireSuppressionWindow(area.ID, time.Now()); err != nil {
+		t.Fatalf("ExpireSuppressionWindow failed: %v", err)
+	}
+	windows, _ = repo.ListSuppressionWindows()
+	if len(windows) != 1 || windows[0].ID != explicit.ID {
+		t.Errorf("Expected only the unexpired window, got %+v", windows)
+	}
+}
+
+func testAlarmHistory(t *testing.T, repo core.AlarmRepository) {
+	base := time.Now().Truncate(time.Millisecond)
+	entries := []*core.AlarmHistoryEntry{
+		{AlarmID: 1, DefinitionID: 1, Tag: "sensor1", Priority: "High", PreviousState: "Normal", State: "UnackActive", Actor: "system", Value: 101, Timestamp: base.Add(2 * time.Minute)},
+		{AlarmID: 1, DefinitionID: 1, Tag: "sensor1", Priority: "High", PreviousState: "UnackActive", State: "AckActive", Actor: "alice", Comment: "on it", Value: 101, Timestamp: base.Add(3 * time.Minute)},
+		{AlarmID: 2, DefinitionID: 2, Tag: "sensor2", PreviousState: "Normal", State: "UnackActive", Actor: "system", Value: 5, Timestamp: base.Add(time.Minute)},
+		{AlarmID: 3, DefinitionID: 3, Tag: "sensor3", PreviousState: "Normal", State: "UnackActive", Actor: "system", Value: 7, Timestamp: base.Add(10 * time.Minute)},
+	}
+	for _, e := range entries {
+		if err := repo.AppendAlarmHistory(e); err != nil {
+			t.Fatalf("AppendAlarmHistory failed: %v", err)
+		}
+		if e.ID == 0 {
+			t.Fatal("Expected history ID to be assigned")
+		}
+	}
+
+	got, err := repo.ListAlarmHistory(base, base.Add(10*time.Minute))
+	if err != nil {
+		t.Fatalf("ListAlarmHistory failed: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 entries in range, got %d", len(got))
+	}
+	if got[0].AlarmID != 2 || got[1].State != "UnackActive" || got[2].State != "AckActive" {
+		t.Errorf("Expected entries ordered by timestamp, got %+v %+v %+v", got[0], got[1], got[2])
+	}
+	if got[2].Actor != "alice" || got[2].Comment != "on it" || !got[2].Timestamp.Equal(entries[1].Timestamp) {
+		t.Errorf("Unexpected entry: %+v", got[2])
+	}
+}
+
+func testAlarmGroups(t *testing.T, repo core.AlarmRepository) {
+	trip := &core.AlarmGroup{
+		Name: "Pump trip", Mode: core.GroupModeParentChild, ParentID: 1, MemberIDs: []int{2, 3},
+		ChildAction: core.ChildActionDeprioritize, ChildPriority: "Low", CreatedBy: "alice",
+	}
+	firstOut := &core.AlarmGroup{Name: "Compressor", Mode: core.GroupModeFirstOut, MemberIDs: []int{4, 5}, CreatedBy: "bob"}
+	for _, g := range []*core.AlarmGroup{trip, firstOut} {
+		if err := repo.CreateAlarmGroup(g); err != nil {
+			t.Fatalf("CreateAlarmGroup failed: %v", err)
+		}
+		if g.ID == 0 || g.CreatedAt.IsZero() {
+			t.Fatalf("Expected ID and CreatedAt to be assigned, got %+v", g)
+		}
+	}
+
+	groups, err := repo.ListAlarmGroups()
+	if err != nil {
+		t.Fatalf("ListAlarmGroups failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+	got := groups[0]
+	if got.Name != "Pump trip" || got.Mode != core.GroupModeParentChild || got.ParentID != 1 ||
+		len(got.MemberIDs) != 2 || got.MemberIDs[1] != 3 || got.ChildAction != core.ChildActionDeprioritize ||
+		got.ChildPriority != "Low" || got.CreatedBy != "alice" {
+		t.Errorf("Unexpected group: %+v", got)
+	}
+
+	if err := repo.DeleteAlarmGroup(trip.ID); err != nil {
+		t.Fatalf("DeleteAlarmGroup failed: %v", err)
+	}
+	groups, _ = repo.ListAlarmGroups()
+	if len(groups) != 1 || groups[0].ID != firstOut.ID {
+		t.Errorf("Expected only the first-out group, got %+v", groups)
+	}
+}
@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+)
+
+// MemoryRepository keeps everything in process memory. It suits tests and
+// demos; nothing survives a restart. Records are copied on the way in and
+// out so callers cannot mutate stored state behind the repository's back.
+type MemoryRepository struct {
+	mu           sync.Mutex
+	definitions  map[int]*core.AlarmDefinition
+	activeAlarms map[int]*core.ActiveAlarm
+	windows      map[int]*core.SuppressionWindow
+	groups       map[int]*core.AlarmGroup
+	history      []*core.AlarmHistoryEntry
+	nextDefID    int
+	nextAlarmID  int
+	nextWindowID int
+	nextGroupID  int
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		definitions:  make(map[int]*core.AlarmDefinition),
+		activeAlarms: make(map[int]*core.ActiveAlarm),
+		windows:      make(map[int]*core.SuppressionWindow),
+		groups:       make(map[int]*core.AlarmGroup),
+		nextDefID:    1,
+		nextAlarmID:  1,
+		nextWindowID: 1,
+		nextGroupID:  1,
+	}
+}
+
+func (r *MemoryRepository) Close() {}
+
+// Ping always succeeds; there is nothing to lose contact with.
+func (r *MemoryRepository) Ping(ctx context.Context) error { return nil }
+
+func copyDefinition(def *core.AlarmDefinition) *core.AlarmDefinition {
+	c := *def
+	return &c
+}
+
+func copyActiveAlarm(alarm *core.ActiveAlarm) *core.ActiveAlarm {
+	c := *alarm
+	return &c
+}
+
+func copyWindow(w *core.SuppressionWindow) *core.SuppressionWindow {
+	c := *w
+	c.DefinitionIDs = append([]int(nil), w.DefinitionIDs...)
+	return &c
+}
+
+func (r *MemoryRepository) createDefinitionLocked(def *core.AlarmDefinition) error {
+	for _, existing := range r.definitions {
+		if existing.Tag == def.Tag && existing.Type == def.Type {
+			return fmt.Errorf("definition %s already exists", def.Key())
+		}
+	}
+	now := time.Now()
+	def.ID = r.nextDefID
+	def.CreatedAt = now
+	def.UpdatedAt = now
+	r.nextDefID++
+	r.definitions[def.ID] = copyDefinition(def)
+	return nil
+}
+
+func (r *MemoryRepository) CreateDefinition(def *core.AlarmDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.createDefinitionLocked(def); err != nil {
+		return fmt.Errorf("failed to create definition: %w", err)
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetDefinition(id int) (*core.AlarmDefinition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	def, ok := r.definitions[id]
+	if !ok {
+		return nil, nil
+	}
+	return copyDefinition(def), nil
+}
+
+func (r *MemoryRepository) ListDefinitions() ([]*core.AlarmDefinition, error) {
+	return r.filterDefinitions(func(*core.AlarmDefinition) bool { return true }), nil
+}
+
+func (r *MemoryRepository) GetDefinitionsByTag(tag string) ([]*core.AlarmDefinition, error) {
+	return r.filterDefinitions(func(def *core.AlarmDefinition) bool { return def.Tag == tag }), nil
+}
+
+func (r *MemoryRepository) filterDefinitions(keep func(*core.AlarmDefinition) bool) []*core.AlarmDefinition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var defs []*core.AlarmDefinition
+	for _, def := range r.definitions {
+		if keep(def) {
+			defs = append(defs, copyDefinition(def))
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].ID < defs[j].ID })
+	return defs
+}
+
+func (r *MemoryRepository) UpdateDefinitionPriority(id int, priority string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	def, ok := r.definitions[id]
+	if !ok {
+		return fmt.Errorf("failed to update definition %d priority: not found", id)
+	}
+	def.Priority = priority
+	def.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *MemoryRepository) ApplyDefinitionImport(adds, updates []*core.AlarmDefinition, deleteIDs []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Work on copies and swap them in at the end so a failed import
+	// leaves no partial changes, like the SQL transactions do
+	definitions := make(map[int]*core.AlarmDefinition, len(r.definitions))
+	for id, def := range r.definitions {
+		definitions[id] = copyDefinition(def)
+	}
+	activeAlarms := make(map[int]*core.ActiveAlarm, len(r.activeAlarms))
+	for id, alarm := range r.activeAlarms {
+		activeAlarms[id] = alarm
+	}
+	saved, savedNextID := r.definitions, r.nextDefID
+	r.definitions = definitions
+
+	for _, id := range deleteIDs {
+		for alarmID, alarm := range activeAlarms {
+			if alarm.DefinitionID != id {
+				continue
+			}
+			// A definition with an active alarm is kept; the alarm must
+			// not vanish without being cleared
+			if alarm.State != string(core.StateNormal) {
+				r.definitions = saved
+				return fmt.Errorf("failed to delete definition %d: %w", id, core.ErrDefinitionInUse)
+			}
+			delete(activeAlarms, alarmID)
+		}
+		delete(definitions, id)
+	}
+
+	now := time.Now()
+	for _, def := range updates {
+		stored, ok := definitions[def.ID]
+		if !ok {
+			r.definitions, r.nextDefID = saved, savedNextID
+			return fmt.Errorf("failed to update definition %s: not found", def.Key())
+		}
+		stored.Threshold = def.Threshold
+		stored.Priority = def.Priority
+		stored.Rationale = def.Rationale
+		stored.Rationalization = def.Rationalization
+		stored.UpdatedAt = now
+		def.UpdatedAt = now
+	}
+
+	for _, def := range adds {
+		if err := r.createDefinitionLocked(def); err != nil {
+			r.definitions, r.nextDefID = saved, savedNextID
+			return fmt.Errorf("failed to insert definition %s: %w", def.Key(), err)
+		}
+	}
+
+	r.activeAlarms = activeAlarms
+	return nil
+}
+
+func (r *MemoryRepository) CreateActiveAlarm(alarm *core.ActiveAlarm) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.definitions[alarm.DefinitionID]; !ok {
+		return fmt.Errorf("failed to create active alarm: definition %d not found", alarm.DefinitionID)
+	}
+	now := time.Now()
+	alarm.ID = r.nextAlarmID
+	alarm.CreatedAt = now
+	alarm.UpdatedAt = now
+	r.nextAlarmID++
+	r.activeAlarms[alarm.ID] = copyActiveAlarm(alarm)
+	return nil
+}
+
+func (r *MemoryRepository) updateAlarm(id int, update func(a *core.ActiveAlarm)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if a, ok := r.activeAlarms[id]; ok {
+		update(a)
+		a.UpdatedAt = time.Now()
+	}
+}
+
+func (r *MemoryRepository) UpdateActiveAlarmState(id int, state string) error {
+	r.updateAlarm(id, func(a *core.ActiveAlarm) { a.State = state })
+	return nil
+}
+
+func (r *MemoryRepository) AckActiveAlarm(id int, ackTime time.Time) error {
+	r.updateAlarm(id, func(a *core.ActiveAlarm) {
+		a.State = string(core.StateAckActive)
+		a.AckTime = &ackTime
+	})
+	return nil
+}
+
+func (r *MemoryRepository) ShelveActiveAlarm(id int, shelvedUntil time.Time) error {
+	r.updateAlarm(id, func(a *core.ActiveAlarm) {
+		a.State = string(core.StateShelved)
+		a.ShelvedUntil = &shelvedUntil
+	})
+	return nil
+}
+
+func (r *MemoryRepository) GetActiveAlarms() ([]*core.ActiveAlarm, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var alarms []*core.ActiveAlarm
+	for _, a := range r.activeAlarms {
+		if a.State != string(core.StateNormal) {
+			alarms = append(alarms, copyActiveAlarm(a))
+		}
+	}
+	sort.Slice(alarms, func(i, j int) bool { return alarms[i].ID < alarms[j].ID })
+	return alarms, nil
+}
+
+func (r *MemoryRepository) CreateSuppressionWindow(w *core.SuppressionWindow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w.ID = r.nextWindowID
+	w.CreatedAt = time.Now()
+	r.nextWindowID++
+	r.windows[w.ID] = copyWindow(w)
+	return nil
+}
+
+func (r *MemoryRepository) ListSuppressionWindows() ([]*core.SuppressionWindow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var windows []*core.SuppressionWindow
+	for _, w := range r.windows {
+		if w.ExpiredAt == nil {
+			windows = append(windows, copyWindow(w))
+		}
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].ID < windows[j].ID })
+	return windows, nil
+}
+
+func (r *MemoryRepository) ExpireSuppressionWindow(id int, expiredAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if w, ok := r.windows[id]; ok {
+		w.ExpiredAt = &expiredAt
+	}
+	return nil
+}
+
+func (r *MemoryRepository) AppendAlarmHistory(entry *core.AlarmHistoryEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.ID = len(r.history) + 1
+	c := *entry
+	r.history = append(r.history, &c)
+	return nil
+}
+
+func (r *MemoryRepository) ListAlarmHistory(from, to time.Time) ([]*core.AlarmHistoryEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var entries []*core.AlarmHistoryEntry
+	for _, e := range r.history {
+		if !e.Timestamp.Before(from) && e.Timestamp.Before(to) {
+			c := *e
+			entries = append(entries, &c)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.Before(entries[j].Timestamp) })
+	return entries, nil
+}
+
+func copyGroup(g *core.AlarmGroup) *core.AlarmGroup {
+	c := *g
+	c.MemberIDs = append([]int(nil), g.MemberIDs...)
+	return &c
+}
+
+func (r *MemoryRepository) CreateAlarmGroup(g *core.AlarmGroup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g.ID = r.nextGroupID
+	g.CreatedAt = time.Now()
+	r.nextGroupID++
+	r.groups[g.ID] = copyGroup(g)
+	return nil
+}
+
+func (r *MemoryRepository) ListAlarmGroups() ([]*core.AlarmGroup, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var groups []*core.AlarmGroup
+	for _, g := range r.groups {
+		groups = append(groups, copyGroup(g))
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups, nil
+}
+
+func (r *MemoryRepository) DeleteAlarmGroup(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.groups, id)
+	return nil
+}
@@ -0,0 +1,9 @@
+package repository
+
+import "testing"
+
+func TestMemoryRepository_Conformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) testRepository {
+		return NewMemoryRepository()
+	})
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -26,42 +27,70 @@ func (r *PostgresRepository) Close() {
 	r.pool.Close()
 }
 
+// Ping checks that the pool can reach the database.
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
+
+const definitionColumns = `id, tag, threshold, alarm_type, priority, rationale,
+	cause, consequence, severity, corrective_action, response_time_seconds, alarm_class, created_at, updated_at`
+
+func scanDefinition(row pgx.Row) (*core.AlarmDefinition, error) {
+	var def core.AlarmDefinition
+	err := row.Scan(&def.ID, &def.Tag, &def.Threshold, &def.Type, &def.Priority, &def.Rationale,
+		&def.Cause, &def.Consequence, &def.Severity, &def.CorrectiveAction, &def.ResponseTimeSeconds, &def.Class,
+		&def.CreatedAt, &def.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
 func (r *PostgresRepository) CreateDefinition(def *core.AlarmDefinition) error {
+	if err := insertDefinition(context.Background(), r.pool, def); err != nil {
+		return fmt.Errorf("failed to create definition: %w", err)
+	}
+	return nil
+}
+
+// queryRower is satisfied by both the pool and a transaction.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func insertDefinition(ctx context.Context, q queryRower, def *core.AlarmDefinition) error {
 	query := `
-		INSERT INTO alarm_definitions (tag, threshold, alarm_type, priority, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		INSERT INTO alarm_definitions (tag, threshold, alarm_type, priority, rationale,
+			cause, consequence, severity, corrective_action, response_time_seconds, alarm_class, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
 		RETURNING id, created_at, updated_at
 	`
-	err := r.pool.QueryRow(context.Background(), query, def.Tag, def.Threshold, def.Type, def.Priority).
+	return q.QueryRow(ctx, query, def.Tag, def.Threshold, def.Type, def.Priority, def.Rationale,
+		def.Cause, def.Consequence, def.Severity, def.CorrectiveAction, def.ResponseTimeSeconds, def.Class).
 		Scan(&def.ID, &def.CreatedAt, &def.UpdatedAt)
-	if err != nil {
-		return fmt.Errorf("failed to create definition: %w", err)
-	}
-	return nil
 }
 
 func (r *PostgresRepository) GetDefinition(id int) (*core.AlarmDefinition, error) {
 	query := `
-		SELECT id, tag, threshold, alarm_type, priority, created_at, updated_at
+		SELECT ` + definitionColumns + `
 		FROM alarm_definitions
 		WHERE id = $1
 	`
-	var def core.AlarmDefinition
-	err := r.pool.QueryRow(context.Background(), query, id).
-		Scan(&def.ID, &def.Tag, &def.Threshold, &def.Type, &def.Priority, &def.CreatedAt, &def.UpdatedAt)
+	def, err := scanDefinition(r.pool.QueryRow(context.Background(), query, id))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil // Or specific error
 		}
 		return nil, fmt.Errorf("failed to get definition: %w", err)
 	}
-	return &def, nil
+	return def, nil
 }
 
 func (r *PostgresRepository) ListDefinitions() ([]*core.AlarmDefinition, error) {
 	query := `
-		SELECT id, tag, threshold, alarm_type, priority, created_at, updated_at
+		SELECT ` + definitionColumns + `
 		FROM alarm_definitions
+		ORDER BY id
 	`
 	rows, err := r.pool.Query(context.Background(), query)
 	if err != nil {
@@ -71,20 +100,21 @@ func (r *PostgresRepository) ListDefinitions() ([]*core.AlarmDefinition, error)
 
 	var defs []*core.AlarmDefinition
 	for rows.Next() {
-		var def core.AlarmDefinition
-		if err := rows.Scan(&def.ID, &def.Tag, &def.Threshold, &def.Type, &def.Priority, &def.CreatedAt, &def.UpdatedAt); err != nil {
+		def, err := scanDefinition(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan definition: %w", err)
 		}
-		defs = append(defs, &def)
+		defs = append(defs, def)
 	}
 	return defs, nil
 }
 
 func (r *PostgresRepository) GetDefinitionsByTag(tag string) ([]*core.AlarmDefinition, error) {
 	query := `
-		SELECT id, tag, threshold, alarm_type, priority, created_at, updated_at
+		SELECT ` + definitionColumns + `
 		FROM alarm_definitions
 		WHERE tag = $1
+		ORDER BY id
 	`
 	rows, err := r.pool.Query(context.Background(), query, tag)
 	if err != nil {
@@ -94,22 +124,94 @@ func (r *PostgresRepository) GetDefinitionsByTag(tag string) ([]*core.AlarmDefin
 
 	var defs []*core.AlarmDefinition
 	for rows.Next() {
-		var def core.AlarmDefinition
-		if err := rows.Scan(&def.ID, &def.Tag, &def.Threshold, &def.Type, &def.Priority, &def.CreatedAt, &def.UpdatedAt); err != nil {
+		def, err := scanDefinition(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan definition: %w", err)
 		}
-		defs = append(defs, &def)
+		defs = append(defs, def)
 	}
 	return defs, nil
 }
 
+func (r *PostgresRepository) UpdateDefinitionPriority(id int, priority string) error {
+	query := `
+		UPDATE alarm_definitions
+		SET priority = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+	tag, err := r.pool.Exec(context.Background(), query, priority, id)
+	if err != nil {
+		return fmt.Errorf("failed to update definition priority: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to update definition %d priority: not found", id)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ApplyDefinitionImport(adds, updates []*core.AlarmDefinition, deleteIDs []int) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if len(deleteIDs) > 0 {
+		// A definition with an active alarm is kept; the alarm must not
+		// vanish without being cleared
+		var inUse int
+		err := tx.QueryRow(ctx, `SELECT definition_id FROM active_alarms WHERE definition_id = ANY($1) AND state != 'Normal' LIMIT 1`, deleteIDs).Scan(&inUse)
+		if err == nil {
+			return fmt.Errorf("failed to delete definition %d: %w", inUse, core.ErrDefinitionInUse)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to check active alarms of removed definitions: %w", err)
+		}
+		// Cleared alarms still reference their definition, so they go first
+		if _, err := tx.Exec(ctx, `DELETE FROM active_alarms WHERE definition_id = ANY($1)`, deleteIDs); err != nil {
+			return fmt.Errorf("failed to delete cleared alarms of removed definitions: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM alarm_definitions WHERE id = ANY($1)`, deleteIDs); err != nil {
+			return fmt.Errorf("failed to delete definitions: %w", err)
+		}
+	}
+
+	for _, def := range updates {
+		query := `
+			UPDATE alarm_definitions
+			SET threshold = $1, priority = $2, rationale = $3,
+				cause = $4, consequence = $5, severity = $6, corrective_action = $7, response_time_seconds = $8, alarm_class = $9,
+				updated_at = NOW()
+			WHERE id = $10
+			RETURNING updated_at
+		`
+		err := tx.QueryRow(ctx, query, def.Threshold, def.Priority, def.Rationale,
+			def.Cause, def.Consequence, def.Severity, def.CorrectiveAction, def.ResponseTimeSeconds, def.Class, def.ID).Scan(&def.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update definition %s: %w", def.Key(), err)
+		}
+	}
+
+	for _, def := range adds {
+		if err := insertDefinition(ctx, tx, def); err != nil {
+			return fmt.Errorf("failed to insert definition %s: %w", def.Key(), err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
 func (r *PostgresRepository) CreateActiveAlarm(alarm *core.ActiveAlarm) error {
 	query := `
-		INSERT INTO active_alarms (definition_id, state, activation_time, ack_time, shelved_until, value, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		INSERT INTO active_alarms (definition_id, state, activation_time, onset_time, ack_time, shelved_until, value, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
 		RETURNING id, created_at, updated_at
 	`
-	err := r.pool.QueryRow(context.Background(), query, alarm.DefinitionID, alarm.State, alarm.ActivationTime, alarm.AckTime, alarm.ShelvedUntil, alarm.Value).
+	err := r.pool.QueryRow(context.Background(), query, alarm.DefinitionID, alarm.State, alarm.ActivationTime, alarm.OnsetTime, alarm.AckTime, alarm.ShelvedUntil, alarm.Value).
 		Scan(&alarm.ID, &alarm.CreatedAt, &alarm.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create active alarm: %w", err)
@@ -158,9 +260,10 @@ func (r *PostgresRepository) ShelveActiveAlarm(id int, shelvedUntil time.Time) e
 
 func (r *PostgresRepository) GetActiveAlarms() ([]*core.ActiveAlarm, error) {
 	query := `
-		SELECT id, definition_id, state, activation_time, ack_time, shelved_until, value, created_at, updated_at
+		SELECT id, definition_id, state, activation_time, onset_time, ack_time, shelved_until, value, created_at, updated_at
 		FROM active_alarms
 		WHERE state != 'Normal'
+		ORDER BY id
 	`
 	rows, err := r.pool.Query(context.Background(), query)
 	if err != nil {
@@ -171,10 +274,153 @@ func (r *PostgresRepository) GetActiveAlarms() ([]*core.ActiveAlarm, error) {
 	var alarms []*core.ActiveAlarm
 	for rows.Next() {
 		var alarm core.ActiveAlarm
-		if err := rows.Scan(&alarm.ID, &alarm.DefinitionID, &alarm.State, &alarm.ActivationTime, &alarm.AckTime, &alarm.ShelvedUntil, &alarm.Value, &alarm.CreatedAt, &alarm.UpdatedAt); err != nil {
+		if err := rows.Scan(&alarm.ID, &alarm.DefinitionID, &alarm.State, &alarm.ActivationTime, &alarm.OnsetTime, &alarm.AckTime, &alarm.ShelvedUntil, &alarm.Value, &alarm.CreatedAt, &alarm.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan active alarm: %w", err)
 		}
 		alarms = append(alarms, &alarm)
 	}
 	return alarms, nil
 }
+
+func (r *PostgresRepository) CreateSuppressionWindow(w *core.SuppressionWindow) error {
+	query := `
+		INSERT INTO suppression_windows (name, tag_prefix, definition_ids, start_time, end_time, rrule, reason, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		RETURNING id, created_at
+	`
+	definitionIDs := w.DefinitionIDs
+	if definitionIDs == nil {
+		definitionIDs = []int{}
+	}
+	err := r.pool.QueryRow(context.Background(), query, w.Name, w.TagPrefix, definitionIDs, w.Start, w.End, w.RRule, w.Reason, w.CreatedBy).
+		Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create suppression window: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListSuppressionWindows() ([]*core.SuppressionWindow, error) {
+	query := `
+		SELECT id, name, tag_prefix, definition_ids, start_time, end_time, rrule, reason, created_by, created_at
+		FROM suppression_windows
+		WHERE expired_at IS NULL
+		ORDER BY id
+	`
+	rows, err := r.pool.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suppression windows: %w", err)
+	}
+	defer rows.Close()
+
+	var windows []*core.SuppressionWindow
+	for rows.Next() {
+		var w core.SuppressionWindow
+		if err := rows.Scan(&w.ID, &w.Name, &w.TagPrefix, &w.DefinitionIDs, &w.Start, &w.End, &w.RRule, &w.Reason, &w.CreatedBy, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan suppression window: %w", err)
+		}
+		windows = append(windows, &w)
+	}
+	return windows, nil
+}
+
+func (r *PostgresRepository) ExpireSuppressionWindow(id int, expiredAt time.Time) error {
+	query := `
+		UPDATE suppression_windows
+		SET expired_at = $1
+		WHERE id = $2
+	`
+	_, err := r.pool.Exec(context.Background(), query, expiredAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to expire suppression window: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) AppendAlarmHistory(entry *core.AlarmHistoryEntry) error {
+	query := `
+		INSERT INTO alarm_history (alarm_id, definition_id, tag, priority, previous_state, state, actor, comment, message, value, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`
+	err := r.pool.QueryRow(context.Background(), query, entry.AlarmID, entry.DefinitionID, entry.Tag, entry.Priority,
+		entry.PreviousState, entry.State, entry.Actor, entry.Comment, entry.Message, entry.Value, entry.Timestamp).
+		Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to append alarm history: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListAlarmHistory(from, to time.Time) ([]*core.AlarmHistoryEntry, error) {
+	query := `
+		SELECT id, alarm_id, definition_id, tag, priority, previous_state, state, actor, comment, message, value, timestamp
+		FROM alarm_history
+		WHERE timestamp >= $1 AND timestamp < $2
+		ORDER BY timestamp, id
+	`
+	rows, err := r.pool.Query(context.Background(), query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alarm history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*core.AlarmHistoryEntry
+	for rows.Next() {
+		var e core.AlarmHistoryEntry
+		if err := rows.Scan(&e.ID, &e.AlarmID, &e.DefinitionID, &e.Tag, &e.Priority, &e.PreviousState, &e.State,
+			&e.Actor, &e.Comment, &e.Message, &e.Value, &e.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan alarm history: %w", err)
+		}
+		entries = append(entries, &e)
+	}
+	return entries, nil
+}
+
+func (r *PostgresRepository) CreateAlarmGroup(g *core.AlarmGroup) error {
+	query := `
+		INSERT INTO alarm_groups (name, mode, parent_id, member_ids, child_action, child_priority, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING id, created_at
+	`
+	memberIDs := g.MemberIDs
+	if memberIDs == nil {
+		memberIDs = []int{}
+	}
+	err := r.pool.QueryRow(context.Background(), query, g.Name, g.Mode, g.ParentID, memberIDs, g.ChildAction, g.ChildPriority, g.CreatedBy).
+		Scan(&g.ID, &g.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create alarm group: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListAlarmGroups() ([]*core.AlarmGroup, error) {
+	query := `
+		SELECT id, name, mode, parent_id, member_ids, child_action, child_priority, created_by, created_at
+		FROM alarm_groups
+		ORDER BY id
+	`
+	rows, err := r.pool.Query(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alarm groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*core.AlarmGroup
+	for rows.Next() {
+		var g core.AlarmGroup
+		if err := rows.Scan(&g.ID, &g.Name, &g.Mode, &g.ParentID, &g.MemberIDs, &g.ChildAction, &g.ChildPriority, &g.CreatedBy, &g.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alarm group: %w", err)
+		}
+		groups = append(groups, &g)
+	}
+	return groups, nil
+}
+
+func (r *PostgresRepository) DeleteAlarmGroup(id int) error {
+	if _, err := r.pool.Exec(context.Background(), `DELETE FROM alarm_groups WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete alarm group: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TestPostgresRepository_Conformance runs against the database in
+// ALARM_TEST_DB_URL. Each subtest gets a fresh schema with the migrations
+// applied, so the database itself is left untouched.
+func TestPostgresRepository_Conformance(t *testing.T) {
+	dbUrl := os.Getenv("ALARM_TEST_DB_URL")
+	if dbUrl == "" {
+		t.Skip("ALARM_TEST_DB_URL not set")
+	}
+
+	migrations, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("Failed to find migrations: %v", err)
+	}
+	sort.Strings(migrations)
+
+	runConformance(t, func(t *testing.T) testRepository {
+		ctx := context.Background()
+		conn, err := pgx.Connect(ctx, dbUrl)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close(ctx)
+
+		schema := fmt.Sprintf("alarm_conformance_%d", time.Now().UnixNano())
+		if _, err := conn.Exec(ctx, "CREATE SCHEMA "+schema+"; SET search_path TO "+schema); err != nil {
+			t.Fatalf("Failed to create schema: %v", err)
+		}
+		t.Cleanup(func() {
+			if conn, err := pgx.Connect(context.Background(), dbUrl); err == nil {
+				conn.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+				conn.Close(context.Background())
+			}
+		})
+		for _, path := range migrations {
+			sql, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", path, err)
+			}
+			if _, err := conn.Exec(ctx, string(sql)); err != nil {
+				t.Fatalf("Failed to apply %s: %v", filepath.Base(path), err)
+			}
+		}
+
+		sep := "?"
+		if strings.Contains(dbUrl, "?") {
+			sep = "&"
+		}
+		repo, err := NewPostgresRepository(dbUrl + sep + "search_path=" + schema)
+		if err != nil {
+			t.Fatalf("NewPostgresRepository failed: %v", err)
+		}
+		return repo
+	})
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+)
+
+// ShiftReportDir stores each scheduled shift report as a JSON and a CSV
+// file named after the shift start, e.g. shift_20260101T060000Z.json.
+type ShiftReportDir struct {
+	dir string
+}
+
+func NewShiftReportDir(dir string) (*ShiftReportDir, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create shift report directory: %w", err)
+	}
+	return &ShiftReportDir{dir: dir}, nil
+}
+
+func (d *ShiftReportDir) SaveShiftReport(report *core.ShiftReport) error {
+	name := "shift_" + report.ShiftStart.UTC().Format("20060102T150405Z")
+	for _, format := range []string{core.FormatJSON, core.FormatCSV} {
+		if err := d.write(filepath.Join(d.dir, name+"."+format), format, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *ShiftReportDir) write(path, format string, report *core.ShiftReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create shift report: %w", err)
+	}
+	if err := core.EncodeShiftReport(f, format, report); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write shift report: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,625 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema mirrors the Postgres migrations. It is applied on open since
+// embedded deployments have no migration tooling. Timestamps are stored as
+// Unix microseconds, the precision Postgres keeps.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS alarm_definitions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tag TEXT NOT NULL,
+    threshold REAL NOT NULL,
+    alarm_type TEXT NOT NULL,
+    priority TEXT NOT NULL,
+    rationale TEXT NOT NULL DEFAULT '',
+    cause TEXT NOT NULL DEFAULT '',
+    consequence TEXT NOT NULL DEFAULT '',
+    severity TEXT NOT NULL DEFAULT '',
+    corrective_action TEXT NOT NULL DEFAULT '',
+    response_time_seconds INTEGER NOT NULL DEFAULT 0,
+    alarm_class TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alarm_definitions_tag_type ON alarm_definitions(tag, alarm_type);
+
+CREATE TABLE IF NOT EXISTS active_alarms (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    definition_id INTEGER NOT NULL REFERENCES alarm_definitions(id),
+    state TEXT NOT NULL,
+    activation_time INTEGER NOT NULL,
+    onset_time INTEGER,
+    ack_time INTEGER,
+    shelved_until INTEGER,
+    value REAL NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_active_alarms_state ON active_alarms(state);
+
+CREATE TABLE IF NOT EXISTS suppression_windows (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    tag_prefix TEXT NOT NULL DEFAULT '',
+    definition_ids TEXT NOT NULL DEFAULT '[]',
+    start_time INTEGER NOT NULL,
+    end_time INTEGER NOT NULL,
+    rrule TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    expired_at INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS alarm_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    alarm_id INTEGER NOT NULL,
+    definition_id INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    priority TEXT NOT NULL DEFAULT '',
+    previous_state TEXT NOT NULL,
+    state TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    value REAL NOT NULL,
+    timestamp INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_alarm_history_timestamp ON alarm_history(timestamp);
+
+CREATE TABLE IF NOT EXISTS alarm_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    mode TEXT NOT NULL,
+    parent_id INTEGER NOT NULL DEFAULT 0,
+    member_ids TEXT NOT NULL DEFAULT '[]',
+    child_action TEXT NOT NULL DEFAULT '',
+    child_priority TEXT NOT NULL DEFAULT '',
+    created_by TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+`
+
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository opens (or creates) the database file at path and
+// applies the schema. Use ":memory:" for a throwaway database.
+func NewSQLiteRepository(path string) (*SQLiteRepository, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	if path != ":memory:" {
+		dsn += "&_pragma=journal_mode(WAL)"
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database: %w", err)
+	}
+	// SQLite allows one writer at a time; a single connection also keeps
+	// an in-memory database alive for the life of the repository.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to apply schema: %w", err)
+	}
+	for _, c := range sqliteAddedColumns {
+		if err := addSQLiteColumn(db, c.table, c.column, c.definition); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to upgrade schema: %w", err)
+		}
+	}
+	return &SQLiteRepository{db: db}, nil
+}
+
+// sqliteAddedColumns are columns added to existing tables after the schema
+// was first released. CREATE TABLE IF NOT EXISTS leaves older database
+// files without them.
+var sqliteAddedColumns = []struct{ table, column, definition string }{
+	{"alarm_definitions", "severity", "TEXT NOT NULL DEFAULT ''"},
+	{"active_alarms", "onset_time", "INTEGER"},
+}
+
+func addSQLiteColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+func (r *SQLiteRepository) Close() {
+	r.db.Close()
+}
+
+// Ping checks that the database file is still usable.
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func toMicros(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+func toNullMicros(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixMicro(), Valid: true}
+}
+
+func fromNullMicros(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.UnixMicro(v.Int64)
+	return &t
+}
+
+// sqliteQueryRower is satisfied by both the database and a transaction.
+type sqliteQueryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func scanSQLiteDefinition(row interface{ Scan(dest ...any) error }) (*core.AlarmDefinition, error) {
+	var def core.AlarmDefinition
+	var createdAt, updatedAt int64
+	err := row.Scan(&def.ID, &def.Tag, &def.Threshold, &def.Type, &def.Priority, &def.Rationale,
+		&def.Cause, &def.Consequence, &def.Severity, &def.CorrectiveAction, &def.ResponseTimeSeconds, &def.Class,
+		&createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	def.CreatedAt = time.UnixMicro(createdAt)
+	def.UpdatedAt = time.UnixMicro(updatedAt)
+	return &def, nil
+}
+
+func insertSQLiteDefinition(ctx context.Context, q sqliteQueryRower, def *core.AlarmDefinition) error {
+	query := `
+		INSERT INTO alarm_definitions (tag, threshold, alarm_type, priority, rationale,
+			cause, consequence, severity, corrective_action, response_time_seconds, alarm_class, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+	now := time.UnixMicro(toMicros(time.Now()))
+	err := q.QueryRowContext(ctx, query, def.Tag, def.Threshold, def.Type, def.Priority, def.Rationale,
+		def.Cause, def.Consequence, def.Severity, def.CorrectiveAction, def.ResponseTimeSeconds, def.Class,
+		toMicros(now), toMicros(now)).Scan(&def.ID)
+	if err != nil {
+		return err
+	}
+	def.CreatedAt = now
+	def.UpdatedAt = now
+	return nil
+}
+
+func (r *SQLiteRepository) CreateDefinition(def *core.AlarmDefinition) error {
+	if err := insertSQLiteDefinition(context.Background(), r.db, def); err != nil {
+		return fmt.Errorf("failed to create definition: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) GetDefinition(id int) (*core.AlarmDefinition, error) {
+	query := `
+		SELECT ` + definitionColumns + `
+		FROM alarm_definitions
+		WHERE id = ?
+	`
+	def, err := scanSQLiteDefinition(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get definition: %w", err)
+	}
+	return def, nil
+}
+
+func (r *SQLiteRepository) ListDefinitions() ([]*core.AlarmDefinition, error) {
+	query := `
+		SELECT ` + definitionColumns + `
+		FROM alarm_definitions
+		ORDER BY id
+	`
+	defs, err := r.queryDefinitions(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list definitions: %w", err)
+	}
+	return defs, nil
+}
+
+func (r *SQLiteRepository) GetDefinitionsByTag(tag string) ([]*core.AlarmDefinition, error) {
+	query := `
+		SELECT ` + definitionColumns + `
+		FROM alarm_definitions
+		WHERE tag = ?
+		ORDER BY id
+	`
+	defs, err := r.queryDefinitions(query, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get definitions by tag: %w", err)
+	}
+	return defs, nil
+}
+
+func (r *SQLiteRepository) queryDefinitions(query string, args ...any) ([]*core.AlarmDefinition, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var defs []*core.AlarmDefinition
+	for rows.Next() {
+		def, err := scanSQLiteDefinition(rows)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	return defs, rows.Err()
+}
+
+func (r *SQLiteRepository) UpdateDefinitionPriority(id int, priority string) error {
+	query := `
+		UPDATE alarm_definitions
+		SET priority = ?, updated_at = ?
+		WHERE id = ?
+	`
+	res, err := r.db.Exec(query, priority, toMicros(time.Now()), id)
+	if err != nil {
+		return fmt.Errorf("failed to update definition priority: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to update definition %d priority: not found", id)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) ApplyDefinitionImport(adds, updates []*core.AlarmDefinition, deleteIDs []int) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range deleteIDs {
+		// A definition with an active alarm is kept; the alarm must not
+		// vanish without being cleared
+		var inUse int
+		err := tx.QueryRowContext(ctx, `SELECT definition_id FROM active_alarms WHERE definition_id = ? AND state != 'Normal' LIMIT 1`, id).Scan(&inUse)
+		if err == nil {
+			return fmt.Errorf("failed to delete definition %d: %w", id, core.ErrDefinitionInUse)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to check active alarms of removed definitions: %w", err)
+		}
+		// Cleared alarms still reference their definition, so they go first
+		if _, err := tx.ExecContext(ctx, `DELETE FROM active_alarms WHERE definition_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete cleared alarms of removed definitions: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM alarm_definitions WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete definitions: %w", err)
+		}
+	}
+
+	now := time.Now()
+	for _, def := range updates {
+		query := `
+			UPDATE alarm_definitions
+			SET threshold = ?, priority = ?, rationale = ?,
+				cause = ?, consequence = ?, severity = ?, corrective_action = ?, response_time_seconds = ?, alarm_class = ?,
+				updated_at = ?
+			WHERE id = ?
+			RETURNING updated_at
+		`
+		var updatedAt int64
+		err := tx.QueryRowContext(ctx, query, def.Threshold, def.Priority, def.Rationale,
+			def.Cause, def.Consequence, def.Severity, def.CorrectiveAction, def.ResponseTimeSeconds, def.Class,
+			toMicros(now), def.ID).Scan(&updatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to update definition %s: %w", def.Key(), err)
+		}
+		def.UpdatedAt = time.UnixMicro(updatedAt)
+	}
+
+	for _, def := range adds {
+		if err := insertSQLiteDefinition(ctx, tx, def); err != nil {
+			return fmt.Errorf("failed to insert definition %s: %w", def.Key(), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) CreateActiveAlarm(alarm *core.ActiveAlarm) error {
+	query := `
+		INSERT INTO active_alarms (definition_id, state, activation_time, onset_time, ack_time, shelved_until, value, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+	now := time.UnixMicro(toMicros(time.Now()))
+	err := r.db.QueryRow(query, alarm.DefinitionID, alarm.State, toMicros(alarm.ActivationTime), toNullMicros(alarm.OnsetTime),
+		toNullMicros(alarm.AckTime), toNullMicros(alarm.ShelvedUntil), alarm.Value, toMicros(now), toMicros(now)).
+		Scan(&alarm.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create active alarm: %w", err)
+	}
+	alarm.CreatedAt = now
+	alarm.UpdatedAt = now
+	return nil
+}
+
+func (r *SQLiteRepository) UpdateActiveAlarmState(id int, state string) error {
+	query := `
+		UPDATE active_alarms
+		SET state = ?, updated_at = ?
+		WHERE id = ?
+	`
+	if _, err := r.db.Exec(query, state, toMicros(time.Now()), id); err != nil {
+		return fmt.Errorf("failed to update active alarm state: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) AckActiveAlarm(id int, ackTime time.Time) error {
+	query := `
+		UPDATE active_alarms
+		SET state = 'AckActive', ack_time = ?, updated_at = ?
+		WHERE id = ?
+	`
+	if _, err := r.db.Exec(query, toMicros(ackTime), toMicros(time.Now()), id); err != nil {
+		return fmt.Errorf("failed to ack active alarm: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) ShelveActiveAlarm(id int, shelvedUntil time.Time) error {
+	query := `
+		UPDATE active_alarms
+		SET state = 'Shelved', shelved_until = ?, updated_at = ?
+		WHERE id = ?
+	`
+	if _, err := r.db.Exec(query, toMicros(shelvedUntil), toMicros(time.Now()), id); err != nil {
+		return fmt.Errorf("failed to shelve active alarm: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) GetActiveAlarms() ([]*core.ActiveAlarm, error) {
+	query := `
+		SELECT id, definition_id, state, activation_time, onset_time, ack_time, shelved_until, value, created_at, updated_at
+		FROM active_alarms
+		WHERE state != 'Normal'
+		ORDER BY id
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active alarms: %w", err)
+	}
+	defer rows.Close()
+
+	var alarms []*core.ActiveAlarm
+	for rows.Next() {
+		var alarm core.ActiveAlarm
+		var activation, createdAt, updatedAt int64
+		var onset, ackTime, shelvedUntil sql.NullInt64
+		if err := rows.Scan(&alarm.ID, &alarm.DefinitionID, &alarm.State, &activation, &onset, &ackTime, &shelvedUntil,
+			&alarm.Value, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan active alarm: %w", err)
+		}
+		alarm.ActivationTime = time.UnixMicro(activation)
+		alarm.OnsetTime = fromNullMicros(onset)
+		alarm.AckTime = fromNullMicros(ackTime)
+		alarm.ShelvedUntil = fromNullMicros(shelvedUntil)
+		alarm.CreatedAt = time.UnixMicro(createdAt)
+		alarm.UpdatedAt = time.UnixMicro(updatedAt)
+		alarms = append(alarms, &alarm)
+	}
+	return alarms, rows.Err()
+}
+
+func (r *SQLiteRepository) CreateSuppressionWindow(w *core.SuppressionWindow) error {
+	query := `
+		INSERT INTO suppression_windows (name, tag_prefix, definition_ids, start_time, end_time, rrule, reason, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+	definitionIDs := w.DefinitionIDs
+	if definitionIDs == nil {
+		definitionIDs = []int{}
+	}
+	ids, err := json.Marshal(definitionIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode definition ids: %w", err)
+	}
+	now := time.UnixMicro(toMicros(time.Now()))
+	err = r.db.QueryRow(query, w.Name, w.TagPrefix, string(ids), toMicros(w.Start), toMicros(w.End),
+		w.RRule, w.Reason, w.CreatedBy, toMicros(now)).Scan(&w.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create suppression window: %w", err)
+	}
+	w.CreatedAt = now
+	return nil
+}
+
+func (r *SQLiteRepository) ListSuppressionWindows() ([]*core.SuppressionWindow, error) {
+	query := `
+		SELECT id, name, tag_prefix, definition_ids, start_time, end_time, rrule, reason, created_by, created_at
+		FROM suppression_windows
+		WHERE expired_at IS NULL
+		ORDER BY id
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suppression windows: %w", err)
+	}
+	defer rows.Close()
+
+	var windows []*core.SuppressionWindow
+	for rows.Next() {
+		var w core.SuppressionWindow
+		var ids string
+		var start, end, createdAt int64
+		if err := rows.Scan(&w.ID, &w.Name, &w.TagPrefix, &ids, &start, &end, &w.RRule, &w.Reason, &w.CreatedBy, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan suppression window: %w", err)
+		}
+		if err := json.Unmarshal([]byte(ids), &w.DefinitionIDs); err != nil {
+			return nil, fmt.Errorf("failed to decode definition ids of window %d: %w", w.ID, err)
+		}
+		w.Start = time.UnixMicro(start)
+		w.End = time.UnixMicro(end)
+		w.CreatedAt = time.UnixMicro(createdAt)
+		windows = append(windows, &w)
+	}
+	return windows, rows.Err()
+}
+
+func (r *SQLiteRepository) ExpireSuppressionWindow(id int, expiredAt time.Time) error {
+	query := `
+		UPDATE suppression_windows
+		SET expired_at = ?
+		WHERE id = ?
+	`
+	if _, err := r.db.Exec(query, toMicros(expiredAt), id); err != nil {
+		return fmt.Errorf("failed to expire suppression window: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) AppendAlarmHistory(entry *core.AlarmHistoryEntry) error {
+	query := `
+		INSERT INTO alarm_history (alarm_id, definition_id, tag, priority, previous_state, state, actor, comment, message, value, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+	err := r.db.QueryRow(query, entry.AlarmID, entry.DefinitionID, entry.Tag, entry.Priority,
+		entry.PreviousState, entry.State, entry.Actor, entry.Comment, entry.Message, entry.Value, toMicros(entry.Timestamp)).
+		Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to append alarm history: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) ListAlarmHistory(from, to time.Time) ([]*core.AlarmHistoryEntry, error) {
+	query := `
+		SELECT id, alarm_id, definition_id, tag, priority, previous_state, state, actor, comment, message, value, timestamp
+		FROM alarm_history
+		WHERE timestamp >= ? AND timestamp < ?
+		ORDER BY timestamp, id
+	`
+	rows, err := r.db.Query(query, toMicros(from), toMicros(to))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alarm history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*core.AlarmHistoryEntry
+	for rows.Next() {
+		var e core.AlarmHistoryEntry
+		var ts int64
+		if err := rows.Scan(&e.ID, &e.AlarmID, &e.DefinitionID, &e.Tag, &e.Priority, &e.PreviousState, &e.State,
+			&e.Actor, &e.Comment, &e.Message, &e.Value, &ts); err != nil {
+			return nil, fmt.Errorf("failed to scan alarm history: %w", err)
+		}
+		e.Timestamp = time.UnixMicro(ts)
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *SQLiteRepository) CreateAlarmGroup(g *core.AlarmGroup) error {
+	query := `
+		INSERT INTO alarm_groups (name, mode, parent_id, member_ids, child_action, child_priority, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+	memberIDs := g.MemberIDs
+	if memberIDs == nil {
+		memberIDs = []int{}
+	}
+	ids, err := json.Marshal(memberIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode member ids: %w", err)
+	}
+	now := time.UnixMicro(toMicros(time.Now()))
+	err = r.db.QueryRow(query, g.Name, g.Mode, g.ParentID, string(ids), g.ChildAction, g.ChildPriority, g.CreatedBy, toMicros(now)).
+		Scan(&g.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create alarm group: %w", err)
+	}
+	g.CreatedAt = now
+	return nil
+}
+
+func (r *SQLiteRepository) ListAlarmGroups() ([]*core.AlarmGroup, error) {
+	query := `
+		SELECT id, name, mode, parent_id, member_ids, child_action, child_priority, created_by, created_at
+		FROM alarm_groups
+		ORDER BY id
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alarm groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*core.AlarmGroup
+	for rows.Next() {
+		var g core.AlarmGroup
+		var ids string
+		var createdAt int64
+		if err := rows.Scan(&g.ID, &g.Name, &g.Mode, &g.ParentID, &ids, &g.ChildAction, &g.ChildPriority, &g.CreatedBy, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alarm group: %w", err)
+		}
+		if err := json.Unmarshal([]byte(ids), &g.MemberIDs); err != nil {
+			return nil, fmt.Errorf("failed to decode member ids of group %d: %w", g.ID, err)
+		}
+		g.CreatedAt = time.UnixMicro(createdAt)
+		groups = append(groups, &g)
+	}
+	return groups, rows.Err()
+}
+
+func (r *SQLiteRepository) DeleteAlarmGroup(id int) error {
+	if _, err := r.db.Exec(`DELETE FROM alarm_groups WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete alarm group: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+)
+
+func TestSQLiteRepository_Conformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) testRepository {
+		repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "alarms.db"))
+		if err != nil {
+			t.Fatalf("NewSQLiteRepository failed: %v", err)
+		}
+		return repo
+	})
+}
+
+func TestSQLiteRepository_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alarms.db")
+	repo, err := NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository failed: %v", err)
+	}
+	def := &core.AlarmDefinition{Tag: "sensor1", Threshold: 100, Type: "High"}
+	repo.CreateDefinition(def)
+	repo.CreateActiveAlarm(&core.ActiveAlarm{DefinitionID: def.ID, State: "UnackActive", ActivationTime: time.Now(), Value: 101})
+	repo.Close()
+
+	repo, err = NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("Reopening failed: %v", err)
+	}
+	defer repo.Close()
+	defs, _ := repo.ListDefinitions()
+	alarms, _ := repo.GetActiveAlarms()
+	if len(defs) != 1 || len(alarms) != 1 {
+		t.Errorf("Expected state to survive a restart, got %d definitions and %d alarms", len(defs), len(alarms))
+	}
+}
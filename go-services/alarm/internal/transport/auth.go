@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type contextKey string
+
+const actorKey contextKey = "actor"
+
+// actorFromContext returns the username Authenticator.Authenticate let
+// through.
+func actorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey).(string)
+	return actor, ok && actor != ""
+}
+
+// Authenticator accepts access tokens issued by the auth service's
+// /api/v1/login: RS256 JWTs with type "access". Operator actions are
+// recorded under the token's username.
+type Authenticator struct {
+	publicKey *rsa.PublicKey
+}
+
+// NewAuthenticator loads the auth service's RSA public key from a PEM
+// file, in PKIX ("PUBLIC KEY") or PKCS#1 ("RSA PUBLIC KEY") form.
+func NewAuthenticator(publicKeyPath string) (*Authenticator, error) {
+	data, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth public key: %w", err)
+	}
+	key, err := parseRSAPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse auth public key: %w", err)
+	}
+	return &Authenticator{publicKey: key}, nil
+}
+
+// verifyAccessToken returns the username an access token was issued to.
+func (a *Authenticator) verifyAccessToken(tokenString string) (string, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return a.publicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", err
+	}
+	if claims["type"] != "access" {
+		return "", fmt.Errorf("not an access token")
+	}
+	username, _ := claims["username"].(string)
+	if username == "" {
+		return "", fmt.Errorf("access token has no username")
+	}
+	return username, nil
+}
+
+// Authenticate lets through requests with a valid access token, with its
+// username in the request context.
+func (a *Authenticator) Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || tokenString == "" {
+			http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+			return
+		}
+		username, err := a.verifyAccessToken(tokenString)
+		if err != nil {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), actorKey, username)))
+	}
+}
@@ -0,0 +1,140 @@
+package transport
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/repository"
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type nopPublisher struct{}
+
+func (nopPublisher) PublishAlarmEvent(*pb.AlarmEvent) error     { return nil }
+func (nopPublisher) PublishAuditRecord(*core.AuditRecord) error { return nil }
+
+func newTestAuthenticator(t *testing.T) (*Authenticator, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+	path := filepath.Join(t.TempDir(), "auth_public.pem")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: der}), 0o600)
+
+	auth, err := NewAuthenticator(path)
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+	return auth, key
+}
+
+// accessClaims mirrors what the auth service's login endpoint issues.
+func accessClaims(username string, now time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":      7,
+		"username": username,
+		"role":     "OPERATOR",
+		"type":     "access",
+		"exp":      now.Add(time.Minute).Unix(),
+		"iat":      now.Unix(),
+	}
+}
+
+func newTestMux(auth *Authenticator) *http.ServeMux {
+	h := NewHttpHandler(core.NewAlarmService(repository.NewMemoryRepository(), nopPublisher{}))
+	if auth != nil {
+		h.SetAuthenticator(auth)
+	}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	return mux
+}
+
+func createSuppression(mux *http.ServeMux, token string) *httptest.ResponseRecorder {
+	body := `{"name":"maintenance","tag_prefix":"tank.","start":"2030-01-01T00:00:00Z","end":"2030-01-01T04:00:00Z","actor":"mallory"}`
+	req := httptest.NewRequest("POST", "/api/v1/alarms/suppressions", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHttpHandler_OperatorActorFromToken(t *testing.T) {
+	auth, key := newTestAuthenticator(t)
+	mux := newTestMux(auth)
+
+	rec := createSuppression(mux, sign(t, key, accessClaims("alice", time.Now())))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var window core.SuppressionWindow
+	json.NewDecoder(rec.Body).Decode(&window)
+	if window.CreatedBy != "alice" {
+		t.Errorf("Expected the actor from the token, got %q", window.CreatedBy)
+	}
+}
+
+func TestHttpHandler_OperatorRequiresToken(t *testing.T) {
+	auth, key := newTestAuthenticator(t)
+	mux := newTestMux(auth)
+	now := time.Now()
+
+	expired := accessClaims("alice", now.Add(-time.Hour))
+	signing := accessClaims("alice", now)
+	signing["type"] = "signing"
+	noUsername := accessClaims("", now)
+	_, otherKey := newTestAuthenticator(t)
+
+	for name, token := range map[string]string{
+		"missing":     "",
+		"expired":     sign(t, key, expired),
+		"signing":     sign(t, key, signing),
+		"no username": sign(t, key, noUsername),
+		"other key":   sign(t, otherKey, accessClaims("alice", now)),
+	} {
+		if rec := createSuppression(mux, token); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s token: expected 401, got %d", name, rec.Code)
+		}
+	}
+
+	// Imports rewrite every definition, so they need one too.
+	req := httptest.NewRequest("POST", "/api/v1/alarms/definitions/import", strings.NewReader(`[]`))
+	req.Header.Set("Content-Type", "application/json")
+	importRec := httptest.NewRecorder()
+	mux.ServeHTTP(importRec, req)
+	if importRec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected import without a token to be refused, got %d", importRec.Code)
+	}
+
+	// Reads don't need a token.
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/alarms/suppressions", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected listing to be open, got %d", rec.Code)
+	}
+}
+
+func TestHttpHandler_OperatorWithoutAuthenticator(t *testing.T) {
+	_, key := newTestAuthenticator(t)
+	mux := newTestMux(nil)
+
+	if rec := createSuppression(mux, sign(t, key, accessClaims("alice", time.Now()))); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without an authenticator, got %d", rec.Code)
+	}
+}
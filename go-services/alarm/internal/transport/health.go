@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// ReadinessCheck returns nil when the dependency it probes is usable.
+type ReadinessCheck func(ctx context.Context) error
+
+// HealthHandler serves liveness and readiness probes. Liveness only says
+// the process is serving HTTP; readiness runs every registered check.
+type HealthHandler struct {
+	names   []string
+	checks  map[string]ReadinessCheck
+	timeout time.Duration
+}
+
+func NewHealthHandler() *HealthHandler {
+	return &HealthHandler{checks: make(map[string]ReadinessCheck), timeout: 2 * time.Second}
+}
+
+// AddCheck registers a readiness check reported under name.
+func (h *HealthHandler) AddCheck(name string, check ReadinessCheck) {
+	if _, ok := h.checks[name]; !ok {
+		h.names = append(h.names, name)
+	}
+	h.checks[name] = check
+}
+
+func (h *HealthHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", h.handleHealthz)
+	mux.HandleFunc("GET /readyz", h.handleReadyz)
+}
+
+func (h *HealthHandler) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+func (h *HealthHandler) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	status := http.StatusOK
+	results := make(map[string]string, len(h.names))
+	for _, name := range h.names {
+		if err := h.checks[name](ctx); err != nil {
+			results[name] = err.Error()
+			status = http.StatusServiceUnavailable
+		} else {
+			results[name] = "ok"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ready":  status == http.StatusOK,
+		"checks": results,
+	})
+}
@@ -0,0 +1,71 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"time"
+
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// HistorianClient reads stored values from the engine's HistorianQuery gRPC service.
+type HistorianClient struct {
+	conn     *grpc.ClientConn
+	client   pb.HistorianQueryClient
+	lookback time.Duration
+}
+
+// NewHistorianClient connects to the engine. Only values newer than
+// lookback are considered when looking up a tag's last value.
+func NewHistorianClient(addr string, lookback time.Duration) (*HistorianClient, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return &HistorianClient{
+		conn:     conn,
+		client:   pb.NewHistorianQueryClient(conn),
+		lookback: lookback,
+	}, nil
+}
+
+// LatestValue implements core.LatestValueReader. Only the newest point in
+// the lookback window is requested.
+func (c *HistorianClient) LatestValue(ctx context.Context, tag string) (*pb.SensorData, error) {
+	now := time.Now()
+	stream, err := c.client.Query(ctx, &pb.QueryRequest{
+		SensorId:   tag,
+		StartTs:    now.Add(-c.lookback).UnixMilli(),
+		EndTs:      now.UnixMilli(),
+		LatestOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the last point, in case the engine predates latest_only and
+	// streams the whole window
+	var latest *pb.SensorData
+	for {
+		point, err := stream.Recv()
+		if err == io.EOF {
+			return latest, nil
+		}
+		if err != nil {
+			// The engine reports an empty range as NOT_FOUND
+			if status.Code(err) == codes.NotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+		latest = point
+	}
+}
+
+func (c *HistorianClient) Close() {
+	c.conn.Close()
+}
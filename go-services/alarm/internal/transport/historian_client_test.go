@@ -0,0 +1,83 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeHistorian answers queries from a fixed series, honouring latest_only
+// like the engine does.
+type fakeHistorian struct {
+	pb.UnimplementedHistorianQueryServer
+	points   []*pb.SensorData
+	requests []*pb.QueryRequest
+}
+
+func (f *fakeHistorian) Query(req *pb.QueryRequest, stream pb.HistorianQuery_QueryServer) error {
+	f.requests = append(f.requests, req)
+	if len(f.points) == 0 {
+		return status.Error(codes.NotFound, "no data in range")
+	}
+	points := f.points
+	if req.LatestOnly {
+		points = points[len(points)-1:]
+	}
+	for _, p := range points {
+		if err := stream.Send(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestHistorianClient(t *testing.T, historian *fakeHistorian) *HistorianClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterHistorianQueryServer(server, historian)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	c := &HistorianClient{conn: conn, client: pb.NewHistorianQueryClient(conn), lookback: time.Hour}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestHistorianClient_LatestValue(t *testing.T) {
+	historian := &fakeHistorian{points: []*pb.SensorData{
+		{SensorId: "tank.level", Value: 80, TimestampMs: 1000},
+		{SensorId: "tank.level", Value: 95, TimestampMs: 2000},
+	}}
+	c := newTestHistorianClient(t, historian)
+
+	latest, err := c.LatestValue(context.Background(), "tank.level")
+	if err != nil {
+		t.Fatalf("LatestValue failed: %v", err)
+	}
+	if latest == nil || latest.Value != 95 {
+		t.Errorf("Expected the newest point, got %+v", latest)
+	}
+	if len(historian.requests) != 1 || !historian.requests[0].LatestOnly || historian.requests[0].SensorId != "tank.level" {
+		t.Errorf("Expected a single latest_only request, got %+v", historian.requests)
+	}
+
+	historian.points = nil
+	if latest, err := c.LatestValue(context.Background(), "tank.level"); err != nil || latest != nil {
+		t.Errorf("Expected no value for an empty window, got %+v %v", latest, err)
+	}
+}
@@ -2,6 +2,9 @@ package transport
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,17 +14,65 @@ import (
 
 type HttpHandler struct {
 	service *core.AlarmService
+	auth    *Authenticator
 }
 
 func NewHttpHandler(service *core.AlarmService) *HttpHandler {
 	return &HttpHandler{service: service}
 }
 
+// SetAuthenticator enables operator actions. They are recorded under the
+// username of the caller's access token; without an authenticator they are
+// refused, since there is no one to attribute them to.
+func (h *HttpHandler) SetAuthenticator(auth *Authenticator) {
+	h.auth = auth
+}
+
+// operator wraps a handler for an operator action, which needs a verified
+// actor.
+func (h *HttpHandler) operator(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.auth == nil {
+			http.Error(w, "Operator actions require authentication, which is not configured", http.StatusServiceUnavailable)
+			return
+		}
+		h.auth.Authenticate(next)(w, r)
+	}
+}
+
+// requestActor returns the actor operator wrapped the request with.
+func requestActor(w http.ResponseWriter, r *http.Request) (string, bool) {
+	actor, ok := actorFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}
+	return actor, ok
+}
+
+// actionErrorStatus maps a rejected operator action to a status code.
+func actionErrorStatus(err error, fallback int) int {
+	if errors.Is(err, core.ErrSignatureRequired) || errors.Is(err, core.ErrInvalidSignature) {
+		return http.StatusForbidden
+	}
+	return fallback
+}
+
 func (h *HttpHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("POST /api/v1/alarms/{id}/ack", h.handleAck)
-	mux.HandleFunc("POST /api/v1/alarms/{id}/shelve", h.handleShelve)
+	mux.HandleFunc("POST /api/v1/alarms/{id}/ack", h.operator(h.handleAck))
+	mux.HandleFunc("POST /api/v1/alarms/{id}/shelve", h.operator(h.handleShelve))
 	mux.HandleFunc("GET /api/v1/alarms/active", h.handleListActive)
+	mux.HandleFunc("GET /api/v1/alarms/chattering", h.handleListChattering)
 	mux.HandleFunc("POST /api/v1/alarms/definitions", h.handleCreateDefinition)
+	mux.HandleFunc("GET /api/v1/alarms/definitions/export", h.handleExportDefinitions)
+	mux.HandleFunc("POST /api/v1/alarms/definitions/import", h.operator(h.handleImportDefinitions))
+	mux.HandleFunc("GET /api/v1/alarms/definitions/priority-distribution", h.handlePriorityDistribution)
+	mux.HandleFunc("GET /api/v1/alarms/groups", h.handleListGroups)
+	mux.HandleFunc("POST /api/v1/alarms/groups", h.operator(h.handleCreateGroup))
+	mux.HandleFunc("DELETE /api/v1/alarms/groups/{id}", h.operator(h.handleDeleteGroup))
+	mux.HandleFunc("GET /api/v1/alarms/reports/shift", h.handleShiftReport)
+	mux.HandleFunc("GET /api/v1/alarms/suppressions", h.handleListSuppressions)
+	mux.HandleFunc("POST /api/v1/alarms/suppressions", h.operator(h.handleCreateSuppression))
+	mux.HandleFunc("DELETE /api/v1/alarms/suppressions/{id}", h.operator(h.handleCancelSuppression))
 }
 
 func (h *HttpHandler) handleAck(w http.ResponseWriter, r *http.Request) {
@@ -32,7 +83,21 @@ func (h *HttpHandler) handleAck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.Acknowledge(id); err != nil {
+	actor, ok := requestActor(w, r)
+	if !ok {
+		return
+	}
+
+	// The body is optional for acknowledgements
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Acknowledge(id, actor, req.Comment); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -49,8 +114,15 @@ func (h *HttpHandler) handleShelve(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor, ok := requestActor(w, r)
+	if !ok {
+		return
+	}
+
 	var req struct {
-		DurationSeconds int `json:"duration_seconds"`
+		DurationSeconds int    `json:"duration_seconds"`
+		Comment         string `json:"comment"`
+		core.Signature
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -58,8 +130,8 @@ func (h *HttpHandler) handleShelve(w http.ResponseWriter, r *http.Request) {
 	}
 
 	duration := time.Duration(req.DurationSeconds) * time.Second
-	if err := h.service.Shelve(id, duration); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.ShelveSigned(id, duration, actor, req.Comment, &req.Signature); err != nil {
+		http.Error(w, err.Error(), actionErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -68,7 +140,13 @@ func (h *HttpHandler) handleShelve(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *HttpHandler) handleListActive(w http.ResponseWriter, r *http.Request) {
-	alarms := h.service.GetActiveAlarms()
+	alarms := h.service.GetActiveAlarmViews()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alarms)
+}
+
+func (h *HttpHandler) handleListChattering(w http.ResponseWriter, r *http.Request) {
+	alarms := h.service.GetChatteringAlarms()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(alarms)
 }
@@ -81,10 +159,219 @@ func (h *HttpHandler) handleCreateDefinition(w http.ResponseWriter, r *http.Requ
 	}
 
 	if err := h.service.CreateDefinition(&def); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, core.ErrInvalidDefinition) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(def)
 }
+
+// definitionFormat picks csv or json from the format query parameter,
+// falling back to the Content-Type of the request body.
+func definitionFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "text/csv" {
+		return core.FormatCSV
+	}
+	return core.FormatJSON
+}
+
+func (h *HttpHandler) handleExportDefinitions(w http.ResponseWriter, r *http.Request) {
+	format := definitionFormat(r)
+	if format != core.FormatCSV && format != core.FormatJSON {
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+		return
+	}
+
+	defs, err := h.service.ExportDefinitions()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if format == core.FormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="alarm_definitions.csv"`)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	core.EncodeDefinitions(w, format, defs)
+}
+
+func (h *HttpHandler) handleImportDefinitions(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requestActor(w, r)
+	if !ok {
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	defs, err := core.DecodeDefinitions(r.Body, definitionFormat(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.ImportDefinitions(defs, actor, dryRun)
+	if err != nil {
+		status := http.StatusUnprocessableEntity
+		if errors.Is(err, core.ErrDefinitionInUse) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *HttpHandler) handlePriorityDistribution(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.GetPriorityDistribution())
+}
+
+// handleShiftReport reports on the shift containing ?at (RFC 3339), or on
+// the last completed shift when at is omitted.
+func (h *HttpHandler) handleShiftReport(w http.ResponseWriter, r *http.Request) {
+	format := definitionFormat(r)
+	if format != core.FormatCSV && format != core.FormatJSON {
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+		return
+	}
+
+	calendar := h.service.ShiftCalendar()
+	start, end := calendar.PreviousShift(time.Now())
+	if at := r.URL.Query().Get("at"); at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			http.Error(w, "Invalid at timestamp", http.StatusBadRequest)
+			return
+		}
+		start, end = calendar.ShiftAt(t)
+	}
+
+	report, err := h.service.GenerateShiftReport(start, end)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if format == core.FormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="shift_report.csv"`)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	core.EncodeShiftReport(w, format, report)
+}
+
+func (h *HttpHandler) handleListSuppressions(w http.ResponseWriter, r *http.Request) {
+	windows := h.service.GetSuppressionWindows()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(windows)
+}
+
+func (h *HttpHandler) handleCreateSuppression(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requestActor(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		core.SuppressionWindow
+		core.Signature
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	window := req.SuppressionWindow
+	window.CreatedBy = actor
+
+	if err := h.service.CreateSuppressionWindowSigned(&window, &req.Signature); err != nil {
+		http.Error(w, err.Error(), actionErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&window)
+}
+
+func (h *HttpHandler) handleCancelSuppression(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid suppression window ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requestActor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelSuppressionWindow(id, actor); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"cancelled"}`))
+}
+
+func (h *HttpHandler) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	groups := h.service.GetAlarmGroups()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+func (h *HttpHandler) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requestActor(w, r)
+	if !ok {
+		return
+	}
+
+	var group core.AlarmGroup
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	group.CreatedBy = actor
+
+	if err := h.service.CreateAlarmGroup(&group); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&group)
+}
+
+func (h *HttpHandler) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid alarm group ID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := requestActor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAlarmGroup(id, actor); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"deleted"}`))
+}
@@ -1,7 +1,13 @@
 package transport
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
 
 	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
 	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
@@ -12,6 +18,9 @@ import (
 type NatsTransport struct {
 	conn    *nats.Conn
 	service *core.AlarmService
+
+	mu   sync.Mutex
+	subs []*nats.Subscription
 }
 
 func NewNatsTransport(url string) (*NatsTransport, error) {
@@ -27,19 +36,79 @@ func (t *NatsTransport) SetService(s *core.AlarmService) {
 }
 
 func (t *NatsTransport) Start() error {
-	_, err := t.conn.Subscribe("enterprise.>", func(msg *nats.Msg) {
+	sub, err := t.conn.Subscribe("sys.analytics.anomaly", func(msg *nats.Msg) {
+		var anomaly pb.AnomalyEvent
+		if err := proto.Unmarshal(msg.Data, &anomaly); err != nil {
+			log.Printf("Failed to unmarshal anomaly event: %v", err)
+			return
+		}
+		if t.service != nil {
+			if err := t.service.ProcessAnomaly(&anomaly); err != nil {
+				log.Printf("Failed to process anomaly for %s: %v", anomaly.SourceTag, err)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	t.track(sub)
+
+	sub, err = t.conn.Subscribe("enterprise.>", func(msg *nats.Msg) {
 		var sensorData pb.SensorData
 		if err := proto.Unmarshal(msg.Data, &sensorData); err != nil {
 			log.Printf("Failed to unmarshal sensor data: %v", err)
 			return
 		}
 		if t.service != nil {
-			if err := t.service.ProcessValue(sensorData.SensorId, sensorData.Value); err != nil {
+			if err := t.service.ProcessSensorData(&sensorData); err != nil {
 				log.Printf("Failed to process value for %s: %v", sensorData.SensorId, err)
 			}
 		}
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	t.track(sub)
+	return nil
+}
+
+func (t *NatsTransport) track(sub *nats.Subscription) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.subs = append(t.subs, sub)
+}
+
+// Ready reports whether the connection is up and every subscription made
+// by Start is still valid. It fails until Start has subscribed.
+func (t *NatsTransport) Ready() error {
+	if !t.conn.IsConnected() {
+		return fmt.Errorf("nats connection %s", t.conn.Status())
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.subs) == 0 {
+		return errors.New("nats consumer not subscribed")
+	}
+	for _, sub := range t.subs {
+		if !sub.IsValid() {
+			return fmt.Errorf("nats subscription %s is closed", sub.Subject)
+		}
+	}
+	return nil
+}
+
+// Pending returns how many received messages are waiting to be handled
+// across all subscriptions, i.e. how far behind the consumer is.
+func (t *NatsTransport) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	total := 0
+	for _, sub := range t.subs {
+		if n, _, err := sub.Pending(); err == nil {
+			total += n
+		}
+	}
+	return total
 }
 
 func (t *NatsTransport) Close() {
@@ -52,5 +121,33 @@ func (t *NatsTransport) PublishAlarmEvent(event *pb.AlarmEvent) error {
 	if err != nil {
 		return err
 	}
-	return t.conn.Publish("sys.alarm.events", data)
+	return t.conn.PublishMsg(newMsg("sys.alarm.events", data))
+}
+
+// PublishAuditRecord sends a JSON record to the audit trail. The audit
+// service stores actor and action as columns and the rest as details.
+func (t *NatsTransport) PublishAuditRecord(record *core.AuditRecord) error {
+	payload := make(map[string]interface{}, len(record.Details)+2)
+	for k, v := range record.Details {
+		payload[k] = v
+	}
+	payload["actor"] = record.Actor
+	payload["action"] = record.Action
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return t.conn.PublishMsg(newMsg("sys.audit.alarm", data))
+}
+
+// newMsg builds a message with a unique Nats-Msg-Id, which the audit
+// service records so a redelivered record is stored once.
+func newMsg(subject string, data []byte) *nats.Msg {
+	id := make([]byte, 16)
+	rand.Read(id)
+	msg := nats.NewMsg(subject)
+	msg.Header.Set(nats.MsgIdHdr, hex.EncodeToString(id))
+	msg.Data = data
+	return msg
 }
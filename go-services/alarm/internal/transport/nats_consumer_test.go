@@ -0,0 +1,20 @@
+package transport
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestNewMsg_UniqueMsgID(t *testing.T) {
+	first := newMsg("sys.audit.alarm", []byte(`{"action":"alarm_shelved"}`))
+	second := newMsg("sys.audit.alarm", []byte(`{"action":"alarm_shelved"}`))
+
+	id := first.Header.Get(nats.MsgIdHdr)
+	if id == "" || id == second.Header.Get(nats.MsgIdHdr) {
+		t.Errorf("Expected a unique Nats-Msg-Id per message, got %q and %q", id, second.Header.Get(nats.MsgIdHdr))
+	}
+	if first.Subject != "sys.audit.alarm" || string(first.Data) != `{"action":"alarm_shelved"}` {
+		t.Errorf("Unexpected message %s %s", first.Subject, first.Data)
+	}
+}
@@ -0,0 +1,91 @@
+package transport
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/alarm/internal/core"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTSignatureVerifier accepts signing tokens from the auth service's
+// /api/v1/re-auth endpoint: RS256 JWTs with type "signing" and scope
+// "signature" that expire a minute after issue.
+type JWTSignatureVerifier struct {
+	publicKey *rsa.PublicKey
+}
+
+// NewJWTSignatureVerifier loads the auth service's RSA public key from a
+// PEM file, in PKIX ("PUBLIC KEY") or PKCS#1 ("RSA PUBLIC KEY") form.
+func NewJWTSignatureVerifier(publicKeyPath string) (*JWTSignatureVerifier, error) {
+	data, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing public key: %w", err)
+	}
+	key, err := parseRSAPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing public key: %w", err)
+	}
+	return &JWTSignatureVerifier{publicKey: key}, nil
+}
+
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not RSA")
+		}
+		return rsaKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+}
+
+func (v *JWTSignatureVerifier) VerifySigningToken(tokenString string) (*core.Signer, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return v.publicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	if claims["type"] != "signing" || claims["scope"] != "signature" {
+		return nil, errors.New("not a signing token")
+	}
+	username, _ := claims["username"].(string)
+	if username == "" {
+		return nil, errors.New("signing token has no username")
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return nil, err
+	}
+	// The auth service issues these for one minute; refuse anything that
+	// claims to live longer
+	if iat, err := claims.GetIssuedAt(); err != nil || iat == nil || exp.Sub(iat.Time) > time.Minute {
+		return nil, errors.New("signing token lifetime exceeds one minute")
+	}
+
+	signer := &core.Signer{Username: username, ExpiresAt: exp.Time}
+	if sub, ok := claims["sub"]; ok {
+		signer.UserID = fmt.Sprint(sub)
+	}
+	return signer, nil
+}
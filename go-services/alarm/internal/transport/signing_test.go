@@ -0,0 +1,96 @@
+package transport
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestVerifier(t *testing.T) (*JWTSignatureVerifier, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	path := filepath.Join(t.TempDir(), "auth_public.pem")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+
+	v, err := NewJWTSignatureVerifier(path)
+	if err != nil {
+		t.Fatalf("NewJWTSignatureVerifier failed: %v", err)
+	}
+	return v, key
+}
+
+// signingClaims mirrors what the auth service's re-auth endpoint issues.
+func signingClaims(now time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":      7,
+		"username": "alice",
+		"role":     "OPERATOR",
+		"type":     "signing",
+		"scope":    "signature",
+		"exp":      now.Add(time.Minute).Unix(),
+		"iat":      now.Unix(),
+	}
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestJWTSignatureVerifier_AcceptsSigningToken(t *testing.T) {
+	v, key := newTestVerifier(t)
+
+	signer, err := v.VerifySigningToken(sign(t, key, signingClaims(time.Now())))
+	if err != nil {
+		t.Fatalf("Expected signing token to verify, got %v", err)
+	}
+	if signer.Username != "alice" || signer.UserID != "7" || signer.ExpiresAt.IsZero() {
+		t.Errorf("Unexpected signer %+v", signer)
+	}
+}
+
+func TestJWTSignatureVerifier_Rejects(t *testing.T) {
+	v, key := newTestVerifier(t)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	now := time.Now()
+
+	access := signingClaims(now)
+	access["type"] = "access"
+	delete(access, "scope")
+
+	expired := signingClaims(now.Add(-2 * time.Minute))
+
+	longLived := signingClaims(now)
+	longLived["exp"] = now.Add(time.Hour).Unix()
+
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, signingClaims(now)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	tests := map[string]string{
+		"access token":    sign(t, key, access),
+		"expired":         sign(t, key, expired),
+		"long lived":      sign(t, key, longLived),
+		"wrong key":       sign(t, otherKey, signingClaims(now)),
+		"not a JWT":       "signed",
+		"unsigned (none)": unsigned,
+	}
+	for name, token := range tests {
+		if _, err := v.VerifySigningToken(token); err == nil {
+			t.Errorf("%s: expected token to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE alarm_definitions DROP COLUMN IF EXISTS rationale;
@@ -0,0 +1 @@
+ALTER TABLE alarm_definitions ADD COLUMN rationale TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+ALTER TABLE alarm_definitions
+    DROP COLUMN IF EXISTS cause,
+    DROP COLUMN IF EXISTS consequence,
+    DROP COLUMN IF EXISTS corrective_action,
+    DROP COLUMN IF EXISTS response_time_seconds,
+    DROP COLUMN IF EXISTS alarm_class;
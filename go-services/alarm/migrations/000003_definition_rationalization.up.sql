@@ -0,0 +1,6 @@
+ALTER TABLE alarm_definitions
+    ADD COLUMN cause TEXT NOT NULL DEFAULT '',
+    ADD COLUMN consequence TEXT NOT NULL DEFAULT '',
+    ADD COLUMN corrective_action TEXT NOT NULL DEFAULT '',
+    ADD COLUMN response_time_seconds INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN alarm_class VARCHAR(50) NOT NULL DEFAULT '';
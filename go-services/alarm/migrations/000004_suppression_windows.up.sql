@@ -0,0 +1,15 @@
+CREATE TABLE suppression_windows (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    tag_prefix VARCHAR(255) NOT NULL DEFAULT '',
+    definition_ids INTEGER[] NOT NULL DEFAULT '{}',
+    start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    rrule TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expired_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_suppression_windows_expired_at ON suppression_windows(expired_at);
@@ -0,0 +1,17 @@
+CREATE TABLE alarm_history (
+    id BIGSERIAL PRIMARY KEY,
+    alarm_id INTEGER NOT NULL,
+    definition_id INTEGER NOT NULL,
+    tag VARCHAR(255) NOT NULL,
+    priority VARCHAR(50) NOT NULL DEFAULT '',
+    previous_state VARCHAR(50) NOT NULL,
+    state VARCHAR(50) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    value DOUBLE PRECISION NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_alarm_history_timestamp ON alarm_history(timestamp);
+CREATE INDEX idx_alarm_history_alarm_id ON alarm_history(alarm_id);
@@ -0,0 +1,11 @@
+CREATE TABLE alarm_groups (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    mode VARCHAR(50) NOT NULL,
+    parent_id INTEGER NOT NULL DEFAULT 0,
+    member_ids INTEGER[] NOT NULL DEFAULT '{}',
+    child_action VARCHAR(50) NOT NULL DEFAULT '',
+    child_priority VARCHAR(50) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
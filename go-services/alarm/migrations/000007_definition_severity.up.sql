@@ -0,0 +1 @@
+ALTER TABLE alarm_definitions ADD COLUMN severity VARCHAR(50) NOT NULL DEFAULT '';
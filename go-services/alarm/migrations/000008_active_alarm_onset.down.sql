@@ -0,0 +1 @@
+ALTER TABLE active_alarms DROP COLUMN IF EXISTS onset_time;
@@ -0,0 +1 @@
+ALTER TABLE active_alarms ADD COLUMN onset_time TIMESTAMP WITH TIME ZONE;
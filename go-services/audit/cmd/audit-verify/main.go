@@ -0,0 +1,126 @@
+// audit-verify checks an export bundle offline: file digests, the hash
+// chain recomputed with the service's hashers, checkpoint signatures and,
+// given TSA roots, timestamp tokens. It exits 0 when the bundle is intact,
+// 1 when it is not and 2 when it can't be checked.
+//
+//	audit-verify -key checkpoint.pub audit-2025-q1.zip
+//	audit-verify -key checkpoint.pub -tsa-roots tsa.pem -json all.zip
+package main
+
+import (
+	"crypto"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/checkpoint"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/export"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/tsa"
+)
+
+// keyFiles collects repeated -key flags.
+type keyFiles []string
+
+func (k *keyFiles) String() string     { return strings.Join(*k, ",") }
+func (k *keyFiles) Set(v string) error { *k = append(*k, v); return nil }
+
+func main() {
+	var keys keyFiles
+	flag.Var(&keys, "key", "Trusted checkpoint public key (PEM), repeatable")
+	tsaRoots := flag.String("tsa-roots", "", "PEM root certificates to verify timestamp tokens against")
+	jsonOut := flag.Bool("json", false, "Print the report as JSON")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] bundle.zip\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	report, err := verify(flag.Arg(0), keys, *tsaRoots)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit-verify: %v\n", err)
+		os.Exit(2)
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printReport(report)
+	}
+	if !report.Valid {
+		os.Exit(1)
+	}
+}
+
+func verify(path string, keyPaths []string, rootsPath string) (*export.Report, error) {
+	opts := export.VerifyOptions{Hashers: core.NewHashers()}
+	if len(keyPaths) > 0 {
+		var keys []crypto.PublicKey
+		for _, p := range keyPaths {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read key: %w", err)
+			}
+			key, err := checkpoint.ParsePublicKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key %s: %w", p, err)
+			}
+			keys = append(keys, key)
+		}
+		verifier, err := checkpoint.NewVerifier(keys...)
+		if err != nil {
+			return nil, err
+		}
+		opts.Checkpoints = verifier
+	}
+	if rootsPath != "" {
+		roots, err := tsa.LoadRoots(rootsPath)
+		if err != nil {
+			return nil, err
+		}
+		opts.Timestamps = tsa.NewVerifier(roots)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return export.Verify(f, info.Size(), opts)
+}
+
+func printReport(r *export.Report) {
+	m := r.Manifest
+	fmt.Printf("Bundle:      %s, created %s\n", m.Format, m.CreatedAt.Format("2006-01-02T15:04:05Z07:00"))
+	fmt.Printf("Entries:     %d (seq %d-%d), hash versions %v\n", m.Count, m.FirstSeq, m.LastSeq, m.HashVersions)
+	fmt.Printf("Anchor:      %s\n", m.Anchor)
+	fmt.Printf("Head:        %s\n", m.Head)
+	if r.Chain != nil {
+		fmt.Printf("Checked:     %d entries, %d checkpoints, %d timestamps\n", r.Chain.Checked, r.Chain.Checkpoints, r.Chain.Timestamps)
+		for _, b := range r.Chain.Broken {
+			fmt.Printf("BROKEN       seq %d (%s): %s\n", b.Seq, b.ID, b.Reason)
+		}
+	}
+	for _, p := range r.Problems {
+		fmt.Printf("PROBLEM      %s\n", p)
+	}
+	for _, w := range r.Warnings {
+		fmt.Printf("WARNING      %s\n", w)
+	}
+	if r.Valid {
+		fmt.Println("Result:      VALID")
+	} else {
+		fmt.Println("Result:      INVALID")
+	}
+}
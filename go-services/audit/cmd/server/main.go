@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,10 +13,14 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/checkpoint"
 	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/config"
 	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/deadletter"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/proof"
 	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
 	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/transport"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/tsa"
 )
 
 func main() {
@@ -40,9 +47,13 @@ func run() error {
 	}
 	defer repo.Close()
 
-	hasher := core.NewSHA256Hasher()
+	hashers := core.NewHashers()
+	hasher, err := hashers.ForVersion(cfg.HashVersion)
+	if err != nil {
+		return fmt.Errorf("invalid HASH_VERSION: %w", err)
+	}
 
-	consumer, err := transport.NewAuditConsumer(cfg.NatsUrl, repo, hasher)
+	consumer, err := transport.NewAuditConsumer(cfg.NatsUrl, repo, hasher, cfg.AppendBatchSize, cfg.AppendBatchWait)
 	if err != nil {
 		return fmt.Errorf("failed to initialize consumer: %w", err)
 	}
@@ -53,9 +64,89 @@ func run() error {
 	}
 
 	// HTTP Server
-	httpHandler := transport.NewHttpHandler(repo, hasher)
+	httpHandler := transport.NewHttpHandler(repo, hashers)
+	httpHandler.SetLogWriter(consumer)
 	mux := http.NewServeMux()
+
+	if cfg.CheckpointKeyFile != "" {
+		signer, verifier, err := loadCheckpointKeys(cfg)
+		if err != nil {
+			return err
+		}
+		publicKey, err := signer.PublicKey()
+		if err != nil {
+			return err
+		}
+		httpHandler.SetCheckpoints(verifier, publicKey)
+		publicKeys, err := verifier.PublicKeys()
+		if err != nil {
+			return err
+		}
+		httpHandler.SetExport(signer, publicKeys)
+
+		publish := func(cp *core.Checkpoint) error {
+			data, err := json.Marshal(cp)
+			if err != nil {
+				return err
+			}
+			return consumer.Publish(checkpoint.Subject, data)
+		}
+		checkpointer := checkpoint.NewCheckpointer(repo, signer, publish, cfg.CheckpointInterval, cfg.CheckpointEvery)
+		go checkpointer.Run(ctx)
+		log.Printf("Signing audit checkpoints with %s key %s", publicKey.Algorithm, publicKey.KeyID)
+
+		if cfg.TSAUrl != "" {
+			authority, tsaVerifier, local, err := loadTSA(cfg)
+			if err != nil {
+				return err
+			}
+			httpHandler.SetTimestamps(tsaVerifier)
+			if local != nil {
+				mux.Handle("POST /api/v1/audit/tsa", local)
+				mux.HandleFunc("GET /api/v1/audit/tsa/certificate", func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/x-pem-file")
+					w.Write(local.CertificatePEM())
+				})
+			}
+			go tsa.NewStamper(repo, authority, tsaVerifier).Run(ctx)
+			log.Printf("Timestamping audit checkpoints with %s", authority.Name())
+		}
+	} else {
+		log.Println("CHECKPOINT_KEY_FILE not set, audit checkpoints are disabled")
+	}
+
+	prover := proof.NewProver(repo, cfg.MerkleBatchSize)
+	httpHandler.SetProver(prover)
+	go proof.NewSealer(repo, cfg.MerkleBatchSize).Run(ctx)
+
+	if cfg.AuthPublicKey != "" {
+		auth, err := transport.NewAuthenticator(cfg.AuthPublicKey)
+		if err != nil {
+			return err
+		}
+		mux.HandleFunc("GET /api/v1/audit/logs", auth.RequireRole(httpHandler.SearchLogs, transport.RoleAuditor, transport.RoleAdmin))
+		mux.HandleFunc("GET /api/v1/audit/export", auth.RequireRole(httpHandler.Export, transport.RoleAuditor, transport.RoleAdmin))
+
+		deadLetters, err := deadletter.NewQueue(ctx, consumer.JetStream(), consumer)
+		if err != nil {
+			return err
+		}
+		httpHandler.SetDeadLetters(deadLetters)
+		mux.HandleFunc("GET /api/v1/audit/dead-letters", auth.RequireRole(httpHandler.ListDeadLetters, transport.RoleAdmin))
+		mux.HandleFunc("GET /api/v1/audit/dead-letters/{seq}", auth.RequireRole(httpHandler.GetDeadLetter, transport.RoleAdmin))
+		mux.HandleFunc("POST /api/v1/audit/dead-letters/{seq}/replay", auth.RequireRole(httpHandler.ReplayDeadLetter, transport.RoleAdmin))
+		mux.HandleFunc("POST /api/v1/audit/dead-letters/{seq}/discard", auth.RequireRole(httpHandler.DiscardDeadLetter, transport.RoleAdmin))
+	} else {
+		log.Println("AUTH_PUBLIC_KEY not set, the audit log, export and dead-letter APIs are disabled")
+	}
+
 	mux.HandleFunc("/api/v1/audit/verify", httpHandler.Verify)
+	mux.HandleFunc("GET /api/v1/audit/checkpoints", httpHandler.ListCheckpoints)
+	mux.HandleFunc("GET /api/v1/audit/checkpoints/public-key", httpHandler.CheckpointPublicKey)
+	mux.HandleFunc("GET /api/v1/audit/timestamps", httpHandler.ListTimestamps)
+	mux.HandleFunc("GET /api/v1/audit/proofs/inclusion", httpHandler.InclusionProof)
+	mux.HandleFunc("GET /api/v1/audit/proofs/consistency", httpHandler.ConsistencyProof)
+	mux.HandleFunc("GET /api/v1/audit/merkle/batches", httpHandler.MerkleBatches)
 
 	server := &http.Server{
 		Addr:    ":" + cfg.Port,
@@ -88,3 +179,49 @@ func run() error {
 	log.Println("Shutdown complete")
 	return nil
 }
+
+// loadCheckpointKeys loads the checkpoint signing key and a verifier that
+// trusts it along with any retired keys.
+func loadCheckpointKeys(cfg *config.Config) (*checkpoint.Signer, *checkpoint.Verifier, error) {
+	signer, err := checkpoint.LoadSigner(cfg.CheckpointKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := []crypto.PublicKey{signer.Public()}
+	for _, path := range cfg.CheckpointTrustedKeys {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read trusted checkpoint key: %w", err)
+		}
+		key, err := checkpoint.ParsePublicKey(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid trusted checkpoint key %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	verifier, err := checkpoint.NewVerifier(keys...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signer, verifier, nil
+}
+
+// loadTSA sets up the timestamping authority and the verifier for its
+// tokens. For the built-in TSA it also returns the TSA to serve.
+func loadTSA(cfg *config.Config) (tsa.Authority, *tsa.Verifier, *tsa.LocalTSA, error) {
+	if cfg.TSAUrl != config.TSALocal {
+		roots, err := tsa.LoadRoots(cfg.TSARootsFile)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return tsa.NewClient(cfg.TSAUrl), tsa.NewVerifier(roots), nil, nil
+	}
+
+	local, err := tsa.LoadLocalTSA(cfg.TSALocalCertFile, cfg.TSALocalKeyFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(local.Certificate())
+	return local, tsa.NewVerifier(roots), local, nil
+}
@@ -6,6 +6,9 @@ toolchain go1.24.11
 
 require (
 	github.com/ahmetsah/industrial-historian/go-services/pkg/proto v0.0.0-00010101000000-000000000000
+	github.com/digitorus/pkcs7 v0.0.0-20230818184609-3a137a874352
+	github.com/digitorus/timestamp v0.0.0-20231217203849-220c5c2851b7
+	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/jackc/pgx/v5 v5.7.6
 	github.com/nats-io/nats.go v1.47.0
 	google.golang.org/protobuf v1.36.10
@@ -0,0 +1,153 @@
+package checkpoint
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+)
+
+func writeKey(t *testing.T, block *pem.Block) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "checkpoint.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return path
+}
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	keys := map[string]*pem.Block{
+		AlgorithmEd25519:   {Type: "PRIVATE KEY", Bytes: edDER},
+		AlgorithmRSASHA256: {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+	}
+	for algorithm, block := range keys {
+		signer, err := LoadSigner(writeKey(t, block))
+		if err != nil {
+			t.Fatalf("%s: LoadSigner failed: %v", algorithm, err)
+		}
+		published, _ := signer.PublicKey()
+		pub, err := ParsePublicKey([]byte(published.PEM))
+		if err != nil {
+			t.Fatalf("%s: published key does not parse: %v", algorithm, err)
+		}
+		verifier, _ := NewVerifier(pub)
+
+		cp := &core.Checkpoint{Seq: 42, HeadHash: "ab12", Count: 42, CreatedAt: time.Now().UTC()}
+		if err := signer.Sign(cp); err != nil {
+			t.Fatalf("%s: Sign failed: %v", algorithm, err)
+		}
+		if cp.Algorithm != algorithm || cp.KeyID != published.KeyID {
+			t.Errorf("%s: unexpected checkpoint %+v", algorithm, cp)
+		}
+		if err := verifier.VerifyCheckpoint(cp); err != nil {
+			t.Errorf("%s: expected checkpoint to verify, got %v", algorithm, err)
+		}
+
+		tampered := *cp
+		tampered.HeadHash = "cd34"
+		if err := verifier.VerifyCheckpoint(&tampered); err == nil {
+			t.Errorf("%s: expected tampered checkpoint to be rejected", algorithm)
+		}
+	}
+}
+
+func TestVerifier_UnknownKey(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := NewSigner(key)
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	verifier, _ := NewVerifier(otherPub)
+
+	cp := &core.Checkpoint{Seq: 1, HeadHash: "ab12", Count: 1, CreatedAt: time.Now()}
+	signer.Sign(cp)
+	if err := verifier.VerifyCheckpoint(cp); err == nil {
+		t.Error("Expected checkpoint signed by an untrusted key to be rejected")
+	}
+}
+
+// fakeRepo keeps just enough state for the checkpointer.
+type fakeRepo struct {
+	repository.Repository
+	head        *core.LogEntry
+	checkpoints []*core.Checkpoint
+}
+
+func (r *fakeRepo) LastLog(ctx context.Context) (*core.LogEntry, error) { return r.head, nil }
+
+func (r *fakeRepo) CountLogs(ctx context.Context, rng repository.LogRange) (int64, error) {
+	return rng.ToSeq, nil
+}
+
+func (r *fakeRepo) LatestCheckpoint(ctx context.Context) (*core.Checkpoint, error) {
+	if len(r.checkpoints) == 0 {
+		return nil, nil
+	}
+	return r.checkpoints[len(r.checkpoints)-1], nil
+}
+
+func (r *fakeRepo) SaveCheckpoint(ctx context.Context, cp *core.Checkpoint) error {
+	r.checkpoints = append(r.checkpoints, cp)
+	return nil
+}
+
+func TestCheckpointer_Triggers(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := NewSigner(crypto.Signer(key))
+	repo := &fakeRepo{}
+	var published []*core.Checkpoint
+	c := NewCheckpointer(repo, signer, func(cp *core.Checkpoint) error {
+		published = append(published, cp)
+		return nil
+	}, time.Hour, 100)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if cp, _ := c.Checkpoint(ctx); cp != nil {
+		t.Fatal("Expected no checkpoint of an empty chain")
+	}
+
+	repo.head = &core.LogEntry{Seq: 10, CurrHash: "h10"}
+	if cp, err := c.Checkpoint(ctx); err != nil || cp == nil || cp.Seq != 10 || cp.Count != 10 {
+		t.Fatalf("Expected first checkpoint at seq 10, got %+v (%v)", cp, err)
+	}
+
+	repo.head = &core.LogEntry{Seq: 50, CurrHash: "h50"}
+	if cp, _ := c.Checkpoint(ctx); cp != nil {
+		t.Errorf("Expected no checkpoint before 100 entries or an hour, got %+v", cp)
+	}
+
+	repo.head = &core.LogEntry{Seq: 110, CurrHash: "h110"}
+	if cp, _ := c.Checkpoint(ctx); cp == nil || cp.Seq != 110 {
+		t.Errorf("Expected checkpoint after 100 entries, got %+v", cp)
+	}
+
+	repo.head = &core.LogEntry{Seq: 111, CurrHash: "h111"}
+	now = now.Add(time.Hour)
+	if cp, _ := c.Checkpoint(ctx); cp == nil || cp.Seq != 111 {
+		t.Errorf("Expected checkpoint after an hour, got %+v", cp)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if cp, _ := c.Checkpoint(ctx); cp != nil {
+		t.Errorf("Expected no checkpoint when the head hasn't moved, got %+v", cp)
+	}
+
+	if len(published) != 3 {
+		t.Errorf("Expected 3 published checkpoints, got %d", len(published))
+	}
+}
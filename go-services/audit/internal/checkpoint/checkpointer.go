@@ -0,0 +1,124 @@
+package checkpoint
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+)
+
+// Subject is where checkpoints are published. It is outside the subjects
+// the audit stream ingests so checkpoints don't become audit entries.
+const Subject = "audit.checkpoints"
+
+// pollInterval is how often the head is checked when checkpointing every N
+// entries.
+const pollInterval = time.Second
+
+// Checkpointer signs the chain head once Interval has passed or Every
+// entries have been appended since the last checkpoint, whichever comes
+// first. A zero Interval or Every turns that trigger off.
+type Checkpointer struct {
+	repo     repository.Repository
+	signer   *Signer
+	publish  func(*core.Checkpoint) error
+	interval time.Duration
+	every    int64
+	now      func() time.Time
+}
+
+func NewCheckpointer(repo repository.Repository, signer *Signer, publish func(*core.Checkpoint) error, interval time.Duration, every int64) *Checkpointer {
+	return &Checkpointer{
+		repo:     repo,
+		signer:   signer,
+		publish:  publish,
+		interval: interval,
+		every:    every,
+		now:      time.Now,
+	}
+}
+
+// Run checkpoints until ctx is done.
+func (c *Checkpointer) Run(ctx context.Context) {
+	period := c.interval
+	if c.every > 0 || period <= 0 {
+		period = pollInterval
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Checkpoint(ctx); err != nil {
+				log.Printf("Failed to checkpoint audit chain: %v", err)
+			}
+		}
+	}
+}
+
+// Checkpoint signs, stores and publishes a checkpoint of the current head
+// if one is due. It returns nil when none was.
+func (c *Checkpointer) Checkpoint(ctx context.Context) (*core.Checkpoint, error) {
+	head, err := c.repo.LastLog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := c.repo.LatestCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := c.now()
+	if !c.due(head, latest, now) {
+		return nil, nil
+	}
+
+	count, err := c.repo.CountLogs(ctx, repository.LogRange{ToSeq: head.Seq})
+	if err != nil {
+		return nil, err
+	}
+	cp := &core.Checkpoint{
+		Seq:      head.Seq,
+		HeadHash: head.CurrHash,
+		Count:    count,
+		// Postgres keeps microseconds; the signature has to survive the
+		// round trip.
+		CreatedAt: now.Truncate(time.Microsecond).UTC(),
+	}
+	if err := c.signer.Sign(cp); err != nil {
+		return nil, err
+	}
+	if err := c.repo.SaveCheckpoint(ctx, cp); err != nil {
+		return nil, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	log.Printf("Audit checkpoint at seq %d (%d entries)", cp.Seq, cp.Count)
+
+	if c.publish != nil {
+		if err := c.publish(cp); err != nil {
+			// The checkpoint is stored; the next one will be published.
+			log.Printf("Failed to publish audit checkpoint %d: %v", cp.Seq, err)
+		}
+	}
+	return cp, nil
+}
+
+func (c *Checkpointer) due(head *core.LogEntry, latest *core.Checkpoint, now time.Time) bool {
+	if head == nil {
+		return false
+	}
+	if latest == nil {
+		return true
+	}
+	if head.Seq <= latest.Seq {
+		return false
+	}
+	if c.every > 0 && head.Seq-latest.Seq >= c.every {
+		return true
+	}
+	return c.interval > 0 && now.Sub(latest.CreatedAt) >= c.interval
+}
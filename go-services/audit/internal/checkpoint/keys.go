@@ -0,0 +1,222 @@
+package checkpoint
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+)
+
+// Signature algorithms.
+const (
+	AlgorithmEd25519   = "Ed25519"
+	AlgorithmRSASHA256 = "RSA-SHA256"
+)
+
+var errUnsupportedKey = errors.New("checkpoint key must be Ed25519 or RSA")
+
+// Signer signs checkpoints with the service's private key.
+type Signer struct {
+	key       crypto.Signer
+	algorithm string
+	keyID     string
+}
+
+// LoadSigner reads a PEM private key: PKCS#8 (Ed25519 or RSA) or PKCS#1
+// (RSA).
+func LoadSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("checkpoint key %s is not PEM", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errUnsupportedKey
+	}
+	return NewSigner(signer)
+}
+
+// NewSigner signs with an ed25519.PrivateKey or *rsa.PrivateKey.
+func NewSigner(key crypto.Signer) (*Signer, error) {
+	var algorithm string
+	switch key.(type) {
+	case ed25519.PrivateKey:
+		algorithm = AlgorithmEd25519
+	case *rsa.PrivateKey:
+		algorithm = AlgorithmRSASHA256
+	default:
+		return nil, errUnsupportedKey
+	}
+	keyID, err := KeyID(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{key: key, algorithm: algorithm, keyID: keyID}, nil
+}
+
+// KeyID identifies a public key by the start of its SHA-256 fingerprint.
+func KeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// Sign fills in the checkpoint's key, algorithm and signature.
+func (s *Signer) Sign(cp *core.Checkpoint) error {
+	cp.KeyID = s.keyID
+	cp.Algorithm = s.algorithm
+
+	payload := cp.SigningPayload()
+	var sig []byte
+	var err error
+	switch s.algorithm {
+	case AlgorithmEd25519:
+		sig, err = s.key.Sign(rand.Reader, payload, crypto.Hash(0))
+	default:
+		digest := sha256.Sum256(payload)
+		sig, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sign checkpoint: %w", err)
+	}
+	cp.Signature = base64.StdEncoding.EncodeToString(sig)
+	return nil
+}
+
+// Public returns the signer's public key.
+func (s *Signer) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// PublicKey describes the key checkpoints are verified with.
+type PublicKey struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	PEM       string `json:"public_key"`
+}
+
+// PublicKey returns the signer's public key in PEM form.
+func (s *Signer) PublicKey() (*PublicKey, error) {
+	return describeKey(s.key.Public())
+}
+
+func describeKey(key crypto.PublicKey) (*PublicKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	keyID, err := KeyID(key)
+	if err != nil {
+		return nil, err
+	}
+	algorithm := AlgorithmRSASHA256
+	if _, ok := key.(ed25519.PublicKey); ok {
+		algorithm = AlgorithmEd25519
+	}
+	return &PublicKey{
+		KeyID:     keyID,
+		Algorithm: algorithm,
+		PEM:       string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}, nil
+}
+
+// Verifier checks checkpoint signatures against a set of public keys, so
+// checkpoints signed before a key rotation still verify.
+type Verifier struct {
+	keys map[string]crypto.PublicKey
+}
+
+// NewVerifier trusts keys, which are ed25519.PublicKey or *rsa.PublicKey.
+func NewVerifier(keys ...crypto.PublicKey) (*Verifier, error) {
+	v := &Verifier{keys: make(map[string]crypto.PublicKey)}
+	for _, key := range keys {
+		switch key.(type) {
+		case ed25519.PublicKey, *rsa.PublicKey:
+		default:
+			return nil, errUnsupportedKey
+		}
+		id, err := KeyID(key)
+		if err != nil {
+			return nil, err
+		}
+		v.keys[id] = key
+	}
+	return v, nil
+}
+
+// PublicKeys returns every trusted key in PEM form, ordered by key ID.
+func (v *Verifier) PublicKeys() ([]*PublicKey, error) {
+	keys := make([]*PublicKey, 0, len(v.keys))
+	for _, key := range v.keys {
+		pub, err := describeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, pub)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].KeyID < keys[j].KeyID })
+	return keys, nil
+}
+
+// ParsePublicKey reads a PEM PKIX public key.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("public key is not PEM")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func (v *Verifier) VerifyCheckpoint(cp *core.Checkpoint) error {
+	key, ok := v.keys[cp.KeyID]
+	if !ok {
+		return fmt.Errorf("unknown checkpoint key %s", cp.KeyID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(cp.Signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+
+	payload := cp.SigningPayload()
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		if cp.Algorithm != AlgorithmEd25519 || !ed25519.Verify(key, payload, sig) {
+			return errors.New("signature does not verify")
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(payload)
+		if cp.Algorithm != AlgorithmRSASHA256 || rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) != nil {
+			return errors.New("signature does not verify")
+		}
+	}
+	return nil
+}
@@ -3,14 +3,66 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/proof"
 )
 
+// TSALocal as TSA_URL selects the built-in timestamping authority.
+const TSALocal = "local"
+
 type Config struct {
 	DbUrl   string
 	NatsUrl string
 	Port    string
+	// HashVersion is the hash format new entries are written with.
+	HashVersion int
+	// AuthPublicKey is a PEM file holding the auth service's RSA public
+	// key, which access tokens for the log API are checked against. The
+	// log API is off without it.
+	AuthPublicKey string
+
+	// CheckpointKeyFile is a PEM Ed25519 or RSA private key. Checkpoints
+	// are off without one.
+	CheckpointKeyFile string
+	// CheckpointInterval and CheckpointEvery trigger a checkpoint after a
+	// time or a number of entries; zero turns a trigger off.
+	CheckpointInterval time.Duration
+	CheckpointEvery    int64
+	// CheckpointTrustedKeys are PEM public key files of retired checkpoint
+	// keys whose checkpoints should still verify.
+	CheckpointTrustedKeys []string
+
+	// TSAUrl is the RFC 3161 timestamping authority checkpoints are
+	// timestamped by, TSALocal for the built-in one, or empty for none.
+	TSAUrl string
+	// TSARootsFile holds the PEM root certificates a remote TSA's tokens
+	// must chain to.
+	TSARootsFile string
+	// TSALocalCertFile and TSALocalKeyFile are the built-in TSA's
+	// certificate and key, required with TSALocal so its tokens still
+	// verify after a restart.
+	TSALocalCertFile string
+	TSALocalKeyFile  string
+
+	// MerkleBatchSize is the number of entries per sealed Merkle batch, a
+	// power of two.
+	MerkleBatchSize int64
+
+	// AppendBatchSize is the most messages written to the chain in one
+	// transaction, and AppendBatchWait how long to wait for a batch to
+	// fill before writing what has arrived.
+	AppendBatchSize int
+	AppendBatchWait time.Duration
 }
 
+// maxAppendBatchSize keeps a batch within JetStream's default limit of
+// unacknowledged messages per consumer.
+const maxAppendBatchSize = 1000
+
 func LoadConfig() (*Config, error) {
 	dbUrl := os.Getenv("DB_URL")
 	if dbUrl == "" {
@@ -27,9 +79,100 @@ func LoadConfig() (*Config, error) {
 		port = "8082"
 	}
 
+	hashVersion := core.CurrentHashVersion
+	if v := os.Getenv("HASH_VERSION"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("HASH_VERSION must be an integer: %w", err)
+		}
+		hashVersion = parsed
+	}
+
+	checkpointInterval := time.Hour
+	if v := os.Getenv("CHECKPOINT_INTERVAL"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("CHECKPOINT_INTERVAL must be a duration: %w", err)
+		}
+		checkpointInterval = parsed
+	}
+
+	var checkpointEvery int64
+	if v := os.Getenv("CHECKPOINT_EVERY"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("CHECKPOINT_EVERY must be a non-negative integer")
+		}
+		checkpointEvery = parsed
+	}
+
+	var trustedKeys []string
+	for _, path := range strings.Split(os.Getenv("CHECKPOINT_TRUSTED_KEYS"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			trustedKeys = append(trustedKeys, path)
+		}
+	}
+
+	merkleBatchSize := int64(proof.DefaultBatchSize)
+	if v := os.Getenv("MERKLE_BATCH_SIZE"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || !proof.ValidBatchSize(parsed) {
+			return nil, fmt.Errorf("MERKLE_BATCH_SIZE must be a power of two")
+		}
+		merkleBatchSize = parsed
+	}
+
+	appendBatchSize := 500
+	if v := os.Getenv("APPEND_BATCH_SIZE"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > maxAppendBatchSize {
+			return nil, fmt.Errorf("APPEND_BATCH_SIZE must be between 1 and %d", maxAppendBatchSize)
+		}
+		appendBatchSize = parsed
+	}
+
+	appendBatchWait := 100 * time.Millisecond
+	if v := os.Getenv("APPEND_BATCH_WAIT"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("APPEND_BATCH_WAIT must be a positive duration")
+		}
+		appendBatchWait = parsed
+	}
+
+	tsaUrl := os.Getenv("TSA_URL")
+	tsaRootsFile := os.Getenv("TSA_ROOTS_FILE")
+	tsaLocalCertFile := os.Getenv("TSA_LOCAL_CERT_FILE")
+	tsaLocalKeyFile := os.Getenv("TSA_LOCAL_KEY_FILE")
+	if tsaUrl != "" && os.Getenv("CHECKPOINT_KEY_FILE") == "" {
+		return nil, fmt.Errorf("TSA_URL requires CHECKPOINT_KEY_FILE, only checkpoints are timestamped")
+	}
+	if tsaUrl != "" && tsaUrl != TSALocal && tsaRootsFile == "" {
+		return nil, fmt.Errorf("TSA_ROOTS_FILE is required to verify tokens from %s", tsaUrl)
+	}
+	if tsaUrl == TSALocal && (tsaLocalCertFile == "" || tsaLocalKeyFile == "") {
+		return nil, fmt.Errorf("TSA_URL=local requires TSA_LOCAL_CERT_FILE and TSA_LOCAL_KEY_FILE")
+	}
+	if (tsaLocalCertFile == "") != (tsaLocalKeyFile == "") {
+		return nil, fmt.Errorf("TSA_LOCAL_CERT_FILE and TSA_LOCAL_KEY_FILE must be set together")
+	}
+
 	return &Config{
-		DbUrl:   dbUrl,
-		NatsUrl: natsUrl,
-		Port:    port,
+		DbUrl:                 dbUrl,
+		NatsUrl:               natsUrl,
+		Port:                  port,
+		HashVersion:           hashVersion,
+		AuthPublicKey:         os.Getenv("AUTH_PUBLIC_KEY"),
+		CheckpointKeyFile:     os.Getenv("CHECKPOINT_KEY_FILE"),
+		CheckpointInterval:    checkpointInterval,
+		CheckpointEvery:       checkpointEvery,
+		CheckpointTrustedKeys: trustedKeys,
+		TSAUrl:                tsaUrl,
+		TSARootsFile:          tsaRootsFile,
+		TSALocalCertFile:      tsaLocalCertFile,
+		TSALocalKeyFile:       tsaLocalKeyFile,
+		MerkleBatchSize:       merkleBatchSize,
+		AppendBatchSize:       appendBatchSize,
+		AppendBatchWait:       appendBatchWait,
 	}, nil
 }
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"ip": "127.0.0.1"}`:                  `{"ip":"127.0.0.1"}`,
+		`{ "b" : 1, "a" : [true, null, "x"] }`: `{"a":[true,null,"x"],"b":1}`,
+		`{"\u20ac":1,"\r":2,"\ufb33":3,"1":4,"\ud83d\ude00":5,"\u0080":6,"\u00f6":7}`:    "{\"\\r\":2,\"1\":4,\"\u0080\":6,\"\u00f6\":7,\"\u20ac\":1,\"\U0001f600\":5,\"\ufb33\":3}",
+		`"tab\there \u001f é \"q\" </>"`:                                                 `"tab\there \u001f é \"q\" </>"`,
+		`[1.0, -0, 1e21, 1e20, 0.000001, 1e-7, 333333333.33333329, 4.50, 2e-3, -1.5E+2]`: `[1,0,1e+21,100000000000000000000,0.000001,1e-7,333333333.3333333,4.5,0.002,-150]`,
+	}
+	for input, want := range tests {
+		got, err := CanonicalJSON([]byte(input))
+		if err != nil {
+			t.Errorf("CanonicalJSON(%s) failed: %v", input, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("CanonicalJSON(%s) = %s, want %s", input, got, want)
+		}
+	}
+
+	for _, bad := range []string{`{"a":}`, `{"a":1} {}`, `1e400`} {
+		if _, err := CanonicalJSON([]byte(bad)); err == nil {
+			t.Errorf("CanonicalJSON(%s): expected error", bad)
+		}
+	}
+}
+
+func TestFormatES6Number(t *testing.T) {
+	// Examples from RFC 8785 appendix B.
+	tests := map[float64]string{
+		math.Float64frombits(0x0000000000000001): "5e-324",
+		math.Float64frombits(0x7fefffffffffffff): "1.7976931348623157e+308",
+		math.Float64frombits(0x4340000000000000): "9007199254740992",
+		math.Float64frombits(0xc470000000000000): "-4.722366482869645e+21",
+		math.Float64frombits(0x444b1ae4d6e2ef50): "1e+21",
+		math.Float64frombits(0x3eb0c6f7a0b5ed8d): "0.000001",
+		math.Float64frombits(0x3eb0c6f7a0b5ed8c): "9.999999999999997e-7",
+	}
+	for f, want := range tests {
+		if got := formatES6Number(f); got != want {
+			t.Errorf("formatES6Number(%v) = %s, want %s", f, got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+)
+
+// Checkpoint is a signed statement of the chain head at a point in time.
+// Someone able to write to the database can rewrite the whole chain so
+// that it links up again, but can't sign a checkpoint for the new head
+// without the checkpoint key.
+type Checkpoint struct {
+	Seq       int64     `json:"seq"`
+	HeadHash  string    `json:"head_hash"`
+	Count     int64     `json:"count"`
+	CreatedAt time.Time `json:"created_at"`
+	KeyID     string    `json:"key_id"`
+	Algorithm string    `json:"algorithm"`
+	// Signature is the base64 signature of SigningPayload.
+	Signature string `json:"signature"`
+}
+
+// SigningPayload is the exact bytes a checkpoint signature covers.
+func (c *Checkpoint) SigningPayload() []byte {
+	return []byte(fmt.Sprintf("audit-checkpoint-v1\nseq=%d\nhead_hash=%s\ncount=%d\ncreated_at=%s\nkey_id=%s\nalgorithm=%s\n",
+		c.Seq, c.HeadHash, c.Count, c.CreatedAt.UTC().Format(time.RFC3339Nano), c.KeyID, c.Algorithm))
+}
+
+// Digest is the SHA-256 of the signing payload, which is what a trusted
+// timestamp of the checkpoint covers.
+func (c *Checkpoint) Digest() []byte {
+	sum := sha256.Sum256(c.SigningPayload())
+	return sum[:]
+}
+
+// CheckpointTimestamp is an RFC 3161 timestamp token from a timestamping
+// authority (TSA) over a checkpoint's Digest, proving to a third party that
+// the checkpoint, and so the chain up to it, existed by GenTime.
+type CheckpointTimestamp struct {
+	Seq       int64     `json:"seq"`
+	TSA       string    `json:"tsa"`
+	Token     []byte    `json:"token"`
+	GenTime   time.Time `json:"gen_time"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TimestampVerifier checks a timestamp token over digest and returns the
+// time the TSA vouches for.
+type TimestampVerifier interface {
+	VerifyTimestamp(token, digest []byte) (time.Time, error)
+}
+
+// CheckpointVerifier checks a checkpoint's signature.
+type CheckpointVerifier interface {
+	VerifyCheckpoint(c *Checkpoint) error
+}
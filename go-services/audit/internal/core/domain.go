@@ -6,6 +6,8 @@ import (
 )
 
 type LogEntry struct {
+	// Seq is the entry's position in the hash chain, starting at 1.
+	Seq       int64           `json:"seq"`
 	ID        string          `json:"id"`
 	Timestamp time.Time       `json:"timestamp"`
 	Actor     string          `json:"actor"`
@@ -13,4 +15,22 @@ type LogEntry struct {
 	Details   json.RawMessage `json:"details"`
 	PrevHash  string          `json:"prev_hash"`
 	CurrHash  string          `json:"curr_hash"`
+	// HashVersion is the format CurrHash was computed with.
+	HashVersion int `json:"hash_version"`
+	// MsgID identifies the message the entry was ingested from: its
+	// Nats-Msg-Id, or its stream and sequence when it has none. It is not
+	// covered by the hash.
+	MsgID string `json:"msg_id,omitempty"`
+}
+
+// MerkleBatch is the stored Merkle root of a full, fixed-size batch of
+// entries. Batch sizes are powers of two, so each batch is a complete
+// subtree of the Merkle tree over the whole log.
+type MerkleBatch struct {
+	BatchSize  int64     `json:"batch_size"`
+	BatchIndex int64     `json:"batch_index"`
+	FirstSeq   int64     `json:"first_seq"`
+	LastSeq    int64     `json:"last_seq"`
+	RootHash   string    `json:"root_hash"`
+	CreatedAt  time.Time `json:"created_at"`
 }
@@ -1,5 +1,43 @@
 package core
 
+import "fmt"
+
+// Hash format versions. Each entry records the version it was hashed with
+// so that older entries keep verifying after the format changes.
+const (
+	// HashVersion1 concatenates the fields and raw details.
+	HashVersion1 = 1
+	// HashVersion2 length-prefixes the fields and canonicalizes details.
+	HashVersion2 = 2
+
+	// CurrentHashVersion is the version new entries are hashed with.
+	CurrentHashVersion = HashVersion2
+)
+
 type Hasher interface {
+	// Version is the hash version recorded with entries this hasher hashes.
+	Version() int
 	Hash(prevHash string, log *LogEntry) string
 }
+
+// Hashers selects the hasher for an entry's hash version.
+type Hashers struct {
+	byVersion map[int]Hasher
+}
+
+// NewHashers returns the hashers for every supported version.
+func NewHashers() *Hashers {
+	return &Hashers{byVersion: map[int]Hasher{
+		HashVersion1: NewSHA256Hasher(),
+		HashVersion2: NewSHA256HasherV2(),
+	}}
+}
+
+// ForVersion returns the hasher for version.
+func (h *Hashers) ForVersion(version int) (Hasher, error) {
+	hasher, ok := h.byVersion[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash version %d", version)
+	}
+	return hasher, nil
+}
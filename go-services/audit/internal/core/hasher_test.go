@@ -37,3 +37,58 @@ func TestSHA256Hasher_Hash(t *testing.T) {
 		t.Errorf("Hash should change when content changes")
 	}
 }
+
+func TestSHA256HasherV2_Hash(t *testing.T) {
+	hasher := NewSHA256HasherV2()
+	timestamp, _ := time.Parse(time.RFC3339, "2023-01-01T00:00:00Z")
+	entry := func(actor, action, details string) *LogEntry {
+		return &LogEntry{Seq: 1, Timestamp: timestamp, Actor: actor, Action: action, Details: json.RawMessage(details)}
+	}
+
+	hash := hasher.Hash(GenesisHash, entry("ab", "c", `{"ip":"127.0.0.1","port":22}`))
+	if len(hash) != 64 {
+		t.Errorf("Expected hash length 64, got %d", len(hash))
+	}
+
+	// Field boundaries are part of the hash.
+	if hash == hasher.Hash(GenesisHash, entry("a", "bc", `{"ip":"127.0.0.1","port":22}`)) {
+		t.Error("Expected shifting a field boundary to change the hash")
+	}
+
+	// JSONB may re-space and reorder details.
+	if hash != hasher.Hash(GenesisHash, entry("ab", "c", `{"port": 22, "ip": "127.0.0.1"}`)) {
+		t.Error("Expected equivalent details to hash the same")
+	}
+
+	// The sequence number is bound into the hash.
+	moved := entry("ab", "c", `{"ip":"127.0.0.1","port":22}`)
+	moved.Seq = 2
+	if hash == hasher.Hash(GenesisHash, moved) {
+		t.Error("Expected the sequence number to change the hash")
+	}
+
+	// The time zone a timestamp is read back in doesn't matter.
+	local := entry("ab", "c", `{"ip":"127.0.0.1","port":22}`)
+	local.Timestamp = timestamp.In(time.FixedZone("UTC+3", 3*3600))
+	if hash != hasher.Hash(GenesisHash, local) {
+		t.Error("Expected the same instant in another zone to hash the same")
+	}
+
+	v1 := NewSHA256Hasher()
+	if v1.Hash(GenesisHash, entry("ab", "c", `{}`)) != v1.Hash(GenesisHash, entry("a", "bc", `{}`)) {
+		t.Error("Expected version 1 to be ambiguous, which is why version 2 exists")
+	}
+}
+
+func TestHashers_ForVersion(t *testing.T) {
+	hashers := NewHashers()
+	for _, version := range []int{HashVersion1, HashVersion2} {
+		h, err := hashers.ForVersion(version)
+		if err != nil || h.Version() != version {
+			t.Errorf("ForVersion(%d) = %v, %v", version, h, err)
+		}
+	}
+	if _, err := hashers.ForVersion(3); err == nil {
+		t.Error("Expected unsupported version to be rejected")
+	}
+}
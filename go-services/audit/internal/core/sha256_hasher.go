@@ -7,12 +7,19 @@ import (
 	"time"
 )
 
+// SHA256Hasher is hash version 1. Its unseparated fields are ambiguous and
+// it hashes details as stored, so it is kept only to verify entries written
+// with it; new entries use SHA256HasherV2.
 type SHA256Hasher struct{}
 
 func NewSHA256Hasher() *SHA256Hasher {
 	return &SHA256Hasher{}
 }
 
+func (h *SHA256Hasher) Version() int {
+	return HashVersion1
+}
+
 func (h *SHA256Hasher) Hash(prevHash string, log *LogEntry) string {
 	// Truncate to Microsecond to match Postgres storage precision
 	ts := log.Timestamp.Truncate(time.Microsecond)
@@ -0,0 +1,245 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GenesisHash is the prev_hash of the first entry in the chain.
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Reasons a link in the chain can be broken.
+const (
+	// BrokenPrevHash means an entry does not point at the entry before it,
+	// i.e. entries were removed, inserted or reordered.
+	BrokenPrevHash = "prev_hash_mismatch"
+	// BrokenCurrHash means an entry's content no longer hashes to its
+	// stored hash, i.e. it was modified.
+	BrokenCurrHash = "curr_hash_mismatch"
+	// BrokenSeq means the sequence skips or repeats a number.
+	BrokenSeq = "seq_gap"
+	// BrokenHashVersion means an entry's hash version is unknown, or older
+	// than the entry before it; versions never go backwards.
+	BrokenHashVersion = "hash_version_invalid"
+	// BrokenCheckpoint means the chain disagrees with a signed checkpoint:
+	// the entry at its sequence has a different hash, entries before it
+	// were removed, or the chain no longer reaches it.
+	BrokenCheckpoint = "checkpoint_mismatch"
+	// BrokenCheckpointSignature means a checkpoint's signature does not
+	// verify, so it can't be relied on.
+	BrokenCheckpointSignature = "checkpoint_signature_invalid"
+	// BrokenTimestamp means a checkpoint's RFC 3161 timestamp token does not
+	// verify or does not cover the checkpoint.
+	BrokenTimestamp = "timestamp_invalid"
+)
+
+// BrokenLink is one place where the chain does not verify.
+type BrokenLink struct {
+	Seq       int64     `json:"seq"`
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason"`
+	Expected  string    `json:"expected"`
+	Actual    string    `json:"actual"`
+}
+
+// VerifyResult summarises a verification run.
+type VerifyResult struct {
+	Valid   bool  `json:"valid"`
+	Checked int64 `json:"checked"`
+	// Checkpoints is how many signed checkpoints the chain was checked
+	// against.
+	Checkpoints int `json:"checkpoints"`
+	// Timestamps is how many RFC 3161 timestamp tokens over checkpoints
+	// verified.
+	Timestamps int          `json:"timestamps"`
+	FirstID    string       `json:"first_id,omitempty"`
+	LastID     string       `json:"last_id,omitempty"`
+	Broken     []BrokenLink `json:"broken"`
+}
+
+// ChainVerifier checks entries one at a time, in chain order, so a chain of
+// any length can be verified while it is streamed from storage. After a
+// broken link it carries on from the stored hash, so each tampered entry is
+// reported once rather than breaking everything after it.
+type ChainVerifier struct {
+	hashers     *Hashers
+	partial     bool
+	prevHash    string
+	firstSeq    int64
+	prevSeq     int64
+	prevVersion int
+	checkpoints map[int64]*Checkpoint
+	signed      map[int64]*Checkpoint
+	result      VerifyResult
+}
+
+// NewChainVerifier verifies a chain from its first entry, which must link to
+// GenesisHash.
+func NewChainVerifier(hashers *Hashers) *ChainVerifier {
+	return &ChainVerifier{hashers: hashers, prevHash: GenesisHash, result: VerifyResult{Valid: true, Broken: []BrokenLink{}}}
+}
+
+// NewRangeVerifier verifies part of a chain. The first entry's prev_hash is
+// taken as given since the entry before it is outside the range; its content
+// and every later link are still checked.
+func NewRangeVerifier(hashers *Hashers) *ChainVerifier {
+	v := NewChainVerifier(hashers)
+	v.prevHash = ""
+	v.partial = true
+	return v
+}
+
+// UseCheckpoints checks the chain against signed checkpoints. Checkpoints
+// whose signature doesn't verify are reported and not used. Call it before
+// adding entries.
+func (v *ChainVerifier) UseCheckpoints(checkpoints []*Checkpoint, verifier CheckpointVerifier) {
+	if v.checkpoints == nil {
+		v.checkpoints = make(map[int64]*Checkpoint)
+		v.signed = make(map[int64]*Checkpoint)
+	}
+	for _, cp := range checkpoints {
+		if err := verifier.VerifyCheckpoint(cp); err != nil {
+			v.result.Valid = false
+			v.result.Broken = append(v.result.Broken, BrokenLink{
+				Seq:       cp.Seq,
+				Timestamp: cp.CreatedAt,
+				Reason:    BrokenCheckpointSignature,
+				Expected:  cp.KeyID,
+				Actual:    err.Error(),
+			})
+			continue
+		}
+		v.checkpoints[cp.Seq] = cp
+		v.signed[cp.Seq] = cp
+	}
+}
+
+// UseTimestamps checks trusted timestamp tokens over checkpoints. Call it
+// after UseCheckpoints; a token for a checkpoint that is missing or whose
+// signature failed is reported.
+func (v *ChainVerifier) UseTimestamps(timestamps []*CheckpointTimestamp, verifier TimestampVerifier) {
+	for _, ts := range timestamps {
+		cp, ok := v.signed[ts.Seq]
+		if !ok {
+			v.brokenTimestamp(ts, "no valid checkpoint at this seq")
+			continue
+		}
+		genTime, err := verifier.VerifyTimestamp(ts.Token, cp.Digest())
+		if err != nil {
+			v.brokenTimestamp(ts, err.Error())
+			continue
+		}
+		// The database keeps microseconds, tokens may carry more
+		if !genTime.Truncate(time.Microsecond).Equal(ts.GenTime.Truncate(time.Microsecond)) {
+			v.brokenTimestamp(ts, fmt.Sprintf("token time %s differs from stored %s",
+				genTime.Format(time.RFC3339Nano), ts.GenTime.Format(time.RFC3339Nano)))
+			continue
+		}
+		v.result.Timestamps++
+	}
+}
+
+func (v *ChainVerifier) brokenTimestamp(ts *CheckpointTimestamp, actual string) {
+	v.result.Valid = false
+	v.result.Broken = append(v.result.Broken, BrokenLink{
+		Seq:       ts.Seq,
+		Timestamp: ts.GenTime,
+		Reason:    BrokenTimestamp,
+		Expected:  ts.TSA,
+		Actual:    actual,
+	})
+}
+
+// Add checks the next entry in the chain.
+func (v *ChainVerifier) Add(log *LogEntry) {
+	if v.result.Checked == 0 {
+		v.result.FirstID = log.ID
+		v.firstSeq = log.Seq
+		if v.prevHash == "" {
+			v.prevHash = log.PrevHash
+		}
+	}
+	v.result.Checked++
+	v.result.LastID = log.ID
+
+	if v.result.Checked > 1 && log.Seq != v.prevSeq+1 {
+		v.broken(log, BrokenSeq, strconv.FormatInt(v.prevSeq+1, 10), strconv.FormatInt(log.Seq, 10))
+	}
+	if log.PrevHash != v.prevHash {
+		v.broken(log, BrokenPrevHash, v.prevHash, log.PrevHash)
+	}
+	hasher, err := v.hashers.ForVersion(log.HashVersion)
+	if err != nil || log.HashVersion < v.prevVersion {
+		v.broken(log, BrokenHashVersion, strconv.Itoa(max(v.prevVersion, HashVersion1)), strconv.Itoa(log.HashVersion))
+	}
+	if err == nil {
+		if calculated := hasher.Hash(log.PrevHash, log); calculated != log.CurrHash {
+			v.broken(log, BrokenCurrHash, calculated, log.CurrHash)
+		}
+	}
+	if cp, ok := v.checkpoints[log.Seq]; ok {
+		if log.CurrHash != cp.HeadHash {
+			v.broken(log, BrokenCheckpoint, cp.HeadHash, log.CurrHash)
+		} else if !v.partial && v.result.Checked != cp.Count {
+			v.broken(log, BrokenCheckpoint, fmt.Sprintf("%d entries", cp.Count), fmt.Sprintf("%d entries", v.result.Checked))
+		}
+		v.result.Checkpoints++
+		delete(v.checkpoints, log.Seq)
+	}
+	v.prevHash = log.CurrHash
+	v.prevSeq = log.Seq
+	v.prevVersion = max(v.prevVersion, log.HashVersion)
+}
+
+func (v *ChainVerifier) broken(log *LogEntry, reason, expected, actual string) {
+	v.result.Valid = false
+	v.result.Broken = append(v.result.Broken, BrokenLink{
+		Seq:       log.Seq,
+		ID:        log.ID,
+		Timestamp: log.Timestamp,
+		Reason:    reason,
+		Expected:  expected,
+		Actual:    actual,
+	})
+}
+
+// Finish is called once every entry has been added. A checkpoint the
+// chain never reached means the entry it signed was removed; when verifying
+// the whole chain that includes checkpoints past its end.
+func (v *ChainVerifier) Finish() *VerifyResult {
+	var unreached []int64
+	for seq := range v.checkpoints {
+		inRange := v.result.Checked > 0 && seq >= v.firstSeq && seq <= v.prevSeq
+		if !v.partial || inRange {
+			unreached = append(unreached, seq)
+		}
+	}
+	sort.Slice(unreached, func(i, j int) bool { return unreached[i] < unreached[j] })
+	for _, seq := range unreached {
+		cp := v.checkpoints[seq]
+		v.result.Valid = false
+		v.result.Broken = append(v.result.Broken, BrokenLink{
+			Seq:       cp.Seq,
+			Timestamp: cp.CreatedAt,
+			Reason:    BrokenCheckpoint,
+			Expected:  cp.HeadHash,
+			Actual:    fmt.Sprintf("no entry with seq %d", cp.Seq),
+		})
+		delete(v.checkpoints, seq)
+	}
+	return v.Result()
+}
+
+// Checked is the number of entries verified so far.
+func (v *ChainVerifier) Checked() int64 {
+	return v.result.Checked
+}
+
+// Result returns the outcome of the entries added so far.
+func (v *ChainVerifier) Result() *VerifyResult {
+	result := v.result
+	return &result
+}
@@ -0,0 +1,242 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func buildChain(hasher Hasher, n int) []*LogEntry {
+	var logs []*LogEntry
+	prevHash := GenesisHash
+	start, _ := time.Parse(time.RFC3339, "2023-01-01T00:00:00Z")
+	for i := 0; i < n; i++ {
+		log := &LogEntry{
+			Seq:         int64(i + 1),
+			ID:          fmt.Sprintf("log-%d", i),
+			Timestamp:   start.Add(time.Duration(i) * time.Second),
+			Actor:       "admin",
+			Action:      "changed_setpoint",
+			Details:     json.RawMessage(fmt.Sprintf(`{"value":%d}`, i)),
+			PrevHash:    prevHash,
+			HashVersion: hasher.Version(),
+		}
+		log.CurrHash = hasher.Hash(prevHash, log)
+		prevHash = log.CurrHash
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+func verify(v *ChainVerifier, logs []*LogEntry) *VerifyResult {
+	for _, log := range logs {
+		v.Add(log)
+	}
+	return v.Result()
+}
+
+func TestChainVerifier_ValidChain(t *testing.T) {
+	result := verify(NewChainVerifier(NewHashers()), buildChain(NewSHA256HasherV2(), 2500))
+	if !result.Valid || result.Checked != 2500 || len(result.Broken) != 0 {
+		t.Errorf("Expected valid chain of 2500, got %+v", result)
+	}
+	if result.FirstID != "log-0" || result.LastID != "log-2499" {
+		t.Errorf("Unexpected bounds %s..%s", result.FirstID, result.LastID)
+	}
+}
+
+func TestChainVerifier_ReportsEveryBrokenLink(t *testing.T) {
+	logs := buildChain(NewSHA256HasherV2(), 10)
+
+	logs[2].Details = json.RawMessage(`{"value":999}`) // modified
+	logs[6].Actor = "mallory"                          // modified
+	logs = append(logs[:8], logs[9:]...)               // log-8 removed
+
+	result := verify(NewChainVerifier(NewHashers()), logs)
+	if result.Valid {
+		t.Fatal("Expected tampered chain to be invalid")
+	}
+	want := []struct{ id, reason string }{
+		{"log-2", BrokenCurrHash},
+		{"log-6", BrokenCurrHash},
+		{"log-9", BrokenSeq},
+		{"log-9", BrokenPrevHash},
+	}
+	if len(result.Broken) != len(want) {
+		t.Fatalf("Expected %d broken links, got %+v", len(want), result.Broken)
+	}
+	for i, w := range want {
+		if result.Broken[i].ID != w.id || result.Broken[i].Reason != w.reason {
+			t.Errorf("Broken link %d: got %s %s, want %s %s", i, result.Broken[i].ID, result.Broken[i].Reason, w.id, w.reason)
+		}
+	}
+}
+
+func TestChainVerifier_Range(t *testing.T) {
+	logs := buildChain(NewSHA256HasherV2(), 10)
+
+	if result := verify(NewChainVerifier(NewHashers()), logs[4:]); result.Valid {
+		t.Error("Expected a chain not starting at genesis to be invalid")
+	}
+	if result := verify(NewRangeVerifier(NewHashers()), logs[4:8]); !result.Valid || result.Checked != 4 {
+		t.Errorf("Expected valid sub-range, got %+v", result)
+	}
+
+	logs[4].Action = "deleted_user"
+	if result := verify(NewRangeVerifier(NewHashers()), logs[4:8]); result.Valid {
+		t.Error("Expected tampered first entry of a range to be reported")
+	}
+}
+
+func TestChainVerifier_MixedHashVersions(t *testing.T) {
+	v1 := buildChain(NewSHA256Hasher(), 3)
+	last := v1[len(v1)-1]
+	next := &LogEntry{
+		Seq:         last.Seq + 1,
+		ID:          "log-3",
+		Timestamp:   last.Timestamp.Add(time.Second),
+		Actor:       "admin",
+		Action:      "login",
+		Details:     json.RawMessage(`{}`),
+		PrevHash:    last.CurrHash,
+		HashVersion: HashVersion2,
+	}
+	next.CurrHash = NewSHA256HasherV2().Hash(next.PrevHash, next)
+	if result := verify(NewChainVerifier(NewHashers()), []*LogEntry{v1[0], v1[1], v1[2], next}); !result.Valid {
+		t.Errorf("Expected v1 entries followed by v2 to verify, got %+v", result.Broken)
+	}
+
+	// A version 1 entry after version 2 entries is a downgrade.
+	after := &LogEntry{Seq: next.Seq + 1, ID: "log-4", Timestamp: next.Timestamp, PrevHash: next.CurrHash, HashVersion: HashVersion1}
+	after.CurrHash = NewSHA256Hasher().Hash(after.PrevHash, after)
+	result := verify(NewChainVerifier(NewHashers()), []*LogEntry{v1[0], v1[1], v1[2], next, after})
+	if result.Valid || len(result.Broken) != 1 || result.Broken[0].Reason != BrokenHashVersion {
+		t.Errorf("Expected hash version downgrade to be reported, got %+v", result.Broken)
+	}
+
+	unknown := *next
+	unknown.HashVersion = 9
+	result = verify(NewChainVerifier(NewHashers()), []*LogEntry{v1[0], v1[1], v1[2], &unknown})
+	if result.Valid || result.Broken[0].Reason != BrokenHashVersion {
+		t.Errorf("Expected unknown hash version to be reported, got %+v", result.Broken)
+	}
+}
+
+// acceptSigned accepts checkpoints whose signature is "ok".
+type acceptSigned struct{}
+
+func (acceptSigned) VerifyCheckpoint(cp *Checkpoint) error {
+	if cp.Signature != "ok" {
+		return fmt.Errorf("bad signature")
+	}
+	return nil
+}
+
+func checkpointAt(log *LogEntry) *Checkpoint {
+	return &Checkpoint{Seq: log.Seq, HeadHash: log.CurrHash, Count: log.Seq, Signature: "ok"}
+}
+
+func TestChainVerifier_Checkpoints(t *testing.T) {
+	hasher := NewSHA256HasherV2()
+	logs := buildChain(hasher, 10)
+	checkpoints := []*Checkpoint{checkpointAt(logs[3]), checkpointAt(logs[9])}
+
+	v := NewChainVerifier(NewHashers())
+	v.UseCheckpoints(checkpoints, acceptSigned{})
+	if result := verify(v, logs); !result.Valid {
+		t.Fatalf("Expected chain to match checkpoints, got %+v", result.Broken)
+	}
+	if result := v.Finish(); !result.Valid || result.Checkpoints != 2 {
+		t.Errorf("Expected 2 checkpoints to be checked, got %+v", result)
+	}
+
+	// A rewritten chain links up again but no longer matches the signed
+	// head hashes.
+	rewritten := buildChain(hasher, 10)
+	rewritten[1].Actor = "mallory"
+	prevHash := rewritten[0].CurrHash
+	for _, log := range rewritten[1:] {
+		log.PrevHash = prevHash
+		log.CurrHash = hasher.Hash(prevHash, log)
+		prevHash = log.CurrHash
+	}
+	v = NewChainVerifier(NewHashers())
+	v.UseCheckpoints(checkpoints, acceptSigned{})
+	verify(v, rewritten)
+	if result := v.Finish(); result.Valid || len(result.Broken) != 2 || result.Broken[0].Reason != BrokenCheckpoint {
+		t.Errorf("Expected rewritten chain to contradict both checkpoints, got %+v", result.Broken)
+	}
+
+	// Dropping the end of the chain leaves the last checkpoint unreached.
+	v = NewChainVerifier(NewHashers())
+	v.UseCheckpoints(checkpoints, acceptSigned{})
+	verify(v, logs[:6])
+	if result := v.Finish(); result.Valid || len(result.Broken) != 1 || result.Broken[0].Seq != 10 {
+		t.Errorf("Expected truncated chain to miss checkpoint 10, got %+v", result.Broken)
+	}
+
+	// A range stops short of later checkpoints without that being a break.
+	v = NewRangeVerifier(NewHashers())
+	v.UseCheckpoints(checkpoints, acceptSigned{})
+	verify(v, logs[2:6])
+	if result := v.Finish(); !result.Valid || result.Checkpoints != 1 {
+		t.Errorf("Expected range to check one checkpoint, got %+v", result)
+	}
+
+	forged := checkpointAt(logs[5])
+	forged.Signature = "forged"
+	v = NewChainVerifier(NewHashers())
+	v.UseCheckpoints([]*Checkpoint{forged}, acceptSigned{})
+	verify(v, logs)
+	if result := v.Finish(); result.Valid || result.Broken[0].Reason != BrokenCheckpointSignature {
+		t.Errorf("Expected forged checkpoint to be reported, got %+v", result.Broken)
+	}
+}
+
+// digestTokens accepts a token that is the hex digest it covers, stamped
+// at a fixed time finer than the database keeps.
+type digestTokens struct{}
+
+var tokenTime = time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.UTC)
+
+func (digestTokens) VerifyTimestamp(token, digest []byte) (time.Time, error) {
+	if string(token) != fmt.Sprintf("%x", digest) {
+		return time.Time{}, fmt.Errorf("token does not cover digest")
+	}
+	return tokenTime, nil
+}
+
+func TestChainVerifier_Timestamps(t *testing.T) {
+	logs := buildChain(&SHA256Hasher{}, 10)
+	good, forged := checkpointAt(logs[3]), checkpointAt(logs[9])
+	forged.Signature = "forged"
+	stamp := func(cp *Checkpoint) *CheckpointTimestamp {
+		// Stored as Postgres returns it, to the microsecond
+		return &CheckpointTimestamp{Seq: cp.Seq, TSA: "test", Token: []byte(fmt.Sprintf("%x", cp.Digest())), GenTime: tokenTime.Truncate(time.Microsecond)}
+	}
+
+	v := NewChainVerifier(NewHashers())
+	v.UseCheckpoints([]*Checkpoint{good}, acceptSigned{})
+	v.UseTimestamps([]*CheckpointTimestamp{stamp(good)}, digestTokens{})
+	verify(v, logs)
+	if result := v.Finish(); !result.Valid || result.Timestamps != 1 {
+		t.Fatalf("Expected timestamp to verify, got %+v", result)
+	}
+
+	// A token over another checkpoint, a token whose stored time was
+	// changed, and a token for a checkpoint whose signature failed.
+	swapped := stamp(good)
+	swapped.Token = stamp(checkpointAt(logs[5])).Token
+	backdated := stamp(good)
+	backdated.GenTime = backdated.GenTime.Add(-time.Hour)
+	for name, ts := range map[string]*CheckpointTimestamp{"swapped": swapped, "backdated": backdated, "forged": stamp(forged)} {
+		v := NewChainVerifier(NewHashers())
+		v.UseCheckpoints([]*Checkpoint{good}, acceptSigned{})
+		v.UseTimestamps([]*CheckpointTimestamp{ts}, digestTokens{})
+		verify(v, logs)
+		if result := v.Finish(); result.Valid || result.Timestamps != 0 || result.Broken[0].Reason != BrokenTimestamp {
+			t.Errorf("%s: expected timestamp to be reported, got %+v", name, result.Broken)
+		}
+	}
+}
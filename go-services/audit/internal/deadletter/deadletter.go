@@ -0,0 +1,252 @@
+package deadletter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Stream and Subject are where audit messages that could not be processed
+// are kept. The subject is outside the AUDIT_EVENTS stream's subjects.
+const (
+	Stream  = "AUDIT_DLQ"
+	Subject = "audit.dlq"
+)
+
+// Headers a dead letter carries along with the original message's data.
+// The original Nats-Msg-Id is moved to HeaderMsgID so the dead-letter
+// stream doesn't drop a message dead-lettered again as a duplicate.
+const (
+	HeaderSubject    = "Audit-Subject"
+	HeaderMsgID      = "Audit-Msg-Id"
+	HeaderStreamSeq  = "Audit-Stream-Seq"
+	HeaderDeliveries = "Audit-Deliveries"
+	HeaderError      = "Audit-Error"
+	HeaderFailedAt   = "Audit-Failed-At"
+)
+
+// Actions recorded in the audit chain for decisions on dead letters.
+const (
+	ActionReplayed  = "dead_letter_replayed"
+	ActionDiscarded = "dead_letter_discarded"
+)
+
+// ErrNotFound is returned for a dead letter that doesn't exist, or was
+// already replayed or discarded.
+var ErrNotFound = errors.New("dead letter not found")
+
+// StreamConfig is the dead-letter stream.
+var StreamConfig = jetstream.StreamConfig{
+	Name:     Stream,
+	Subjects: []string{Subject},
+	Storage:  jetstream.FileStorage,
+}
+
+// NewMessage builds the dead letter for msg, which failed with cause on
+// its last delivery.
+func NewMessage(msg jetstream.Msg, meta *jetstream.MsgMetadata, cause error) *nats.Msg {
+	dl := nats.NewMsg(Subject)
+	for key, values := range msg.Headers() {
+		if key != jetstream.MsgIDHeader {
+			dl.Header[key] = values
+		}
+	}
+	if id := msg.Headers().Get(jetstream.MsgIDHeader); id != "" {
+		dl.Header.Set(HeaderMsgID, id)
+	}
+	dl.Header.Set(HeaderSubject, msg.Subject())
+	dl.Header.Set(HeaderStreamSeq, strconv.FormatUint(meta.Sequence.Stream, 10))
+	dl.Header.Set(HeaderDeliveries, strconv.FormatUint(meta.NumDelivered, 10))
+	dl.Header.Set(HeaderError, cause.Error())
+	dl.Header.Set(HeaderFailedAt, time.Now().UTC().Format(time.RFC3339Nano))
+	dl.Data = msg.Data()
+	return dl
+}
+
+// Letter is a dead letter as the admin API shows it.
+type Letter struct {
+	// Seq is the letter's sequence in the dead-letter stream.
+	Seq        uint64    `json:"seq"`
+	Subject    string    `json:"subject"`
+	MsgID      string    `json:"msg_id,omitempty"`
+	StreamSeq  uint64    `json:"stream_seq"`
+	Deliveries uint64    `json:"deliveries"`
+	Error      string    `json:"error"`
+	FailedAt   time.Time `json:"failed_at"`
+	Size       int       `json:"size"`
+	// Data is the original message, only returned by Get.
+	Data []byte `json:"data,omitempty"`
+
+	header nats.Header
+}
+
+func newLetter(raw *jetstream.RawStreamMsg) *Letter {
+	l := &Letter{
+		Seq:     raw.Sequence,
+		Subject: raw.Header.Get(HeaderSubject),
+		MsgID:   raw.Header.Get(HeaderMsgID),
+		Error:   raw.Header.Get(HeaderError),
+		Size:    len(raw.Data),
+		Data:    raw.Data,
+		header:  raw.Header,
+	}
+	l.StreamSeq, _ = strconv.ParseUint(raw.Header.Get(HeaderStreamSeq), 10, 64)
+	l.Deliveries, _ = strconv.ParseUint(raw.Header.Get(HeaderDeliveries), 10, 64)
+	l.FailedAt, _ = time.Parse(time.RFC3339Nano, raw.Header.Get(HeaderFailedAt))
+	return l
+}
+
+// Writer appends entries to the audit chain. The audit consumer is one,
+// so decisions are written by the chain's single writer like events are.
+type Writer interface {
+	Append(ctx context.Context, log *core.LogEntry) error
+}
+
+// Queue lists dead letters and replays or discards them. Every replay and
+// discard is written to the audit chain before the letter is removed.
+type Queue struct {
+	js     jetstream.JetStream
+	stream jetstream.Stream
+	writer Writer
+}
+
+// NewQueue creates the dead-letter stream if needed.
+func NewQueue(ctx context.Context, js jetstream.JetStream, writer Writer) (*Queue, error) {
+	stream, err := js.CreateOrUpdateStream(ctx, StreamConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter stream: %w", err)
+	}
+	return &Queue{js: js, stream: stream, writer: writer}, nil
+}
+
+// List returns up to limit letters after seq after, oldest first, without
+// their data.
+func (q *Queue) List(ctx context.Context, after uint64, limit int) ([]*Letter, error) {
+	info, err := q.stream.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter stream: %w", err)
+	}
+	letters := []*Letter{}
+	for seq := max(after+1, info.State.FirstSeq); seq <= info.State.LastSeq && len(letters) < limit; seq++ {
+		raw, err := q.stream.GetMsg(ctx, seq)
+		if errors.Is(err, jetstream.ErrMsgNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dead letter %d: %w", seq, err)
+		}
+		l := newLetter(raw)
+		l.Data = nil
+		letters = append(letters, l)
+	}
+	return letters, nil
+}
+
+// Get returns a letter with its data.
+func (q *Queue) Get(ctx context.Context, seq uint64) (*Letter, error) {
+	raw, err := q.stream.GetMsg(ctx, seq)
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrNotFound, seq)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter %d: %w", seq, err)
+	}
+	return newLetter(raw), nil
+}
+
+// Replay publishes a letter back to its original subject for another
+// round of deliveries, on behalf of actor.
+func (q *Queue) Replay(ctx context.Context, seq uint64, actor, reason string) (*Letter, error) {
+	l, err := q.Get(ctx, seq)
+	if err != nil {
+		return nil, err
+	}
+	if l.Subject == "" {
+		return nil, fmt.Errorf("dead letter %d has no original subject", seq)
+	}
+	if err := q.record(ctx, l, ActionReplayed, actor, reason); err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(l.Subject)
+	for key, values := range l.header {
+		if !isLetterHeader(key) {
+			msg.Header[key] = values
+		}
+	}
+	if l.MsgID != "" {
+		msg.Header.Set(jetstream.MsgIDHeader, l.MsgID)
+	}
+	msg.Data = l.Data
+	if _, err := q.js.PublishMsg(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to replay dead letter %d: %w", seq, err)
+	}
+	return l, q.remove(ctx, seq)
+}
+
+// Discard drops a letter for good on behalf of actor. The audit entry
+// keeps the data, so discarding never loses what was received.
+func (q *Queue) Discard(ctx context.Context, seq uint64, actor, reason string) (*Letter, error) {
+	l, err := q.Get(ctx, seq)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.record(ctx, l, ActionDiscarded, actor, reason); err != nil {
+		return nil, err
+	}
+	return l, q.remove(ctx, seq)
+}
+
+// record appends the decision on l to the audit chain.
+func (q *Queue) record(ctx context.Context, l *Letter, action, actor, reason string) error {
+	sum := sha256.Sum256(l.Data)
+	details, err := json.Marshal(map[string]interface{}{
+		"dead_letter_seq": l.Seq,
+		"subject":         l.Subject,
+		"msg_id":          l.MsgID,
+		"stream_seq":      l.StreamSeq,
+		"deliveries":      l.Deliveries,
+		"error":           l.Error,
+		"failed_at":       l.FailedAt,
+		"reason":          reason,
+		"data":            l.Data,
+		"data_sha256":     hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		return err
+	}
+	entry := &core.LogEntry{
+		Timestamp: time.Now(),
+		Actor:     actor,
+		Action:    action,
+		Details:   details,
+	}
+	if err := q.writer.Append(ctx, entry); err != nil {
+		return fmt.Errorf("failed to audit %s of dead letter %d: %w", action, l.Seq, err)
+	}
+	return nil
+}
+
+func (q *Queue) remove(ctx context.Context, seq uint64) error {
+	if err := q.stream.DeleteMsg(ctx, seq); err != nil {
+		return fmt.Errorf("failed to remove dead letter %d: %w", seq, err)
+	}
+	return nil
+}
+
+func isLetterHeader(key string) bool {
+	switch key {
+	case HeaderSubject, HeaderMsgID, HeaderStreamSeq, HeaderDeliveries, HeaderError, HeaderFailedAt:
+		return true
+	}
+	return false
+}
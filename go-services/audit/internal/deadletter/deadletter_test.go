@@ -0,0 +1,189 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// memStream is a stream kept in memory; it backs both the dead-letter
+// stream and, through memJS, what is published.
+type memStream struct {
+	jetstream.Stream
+	msgs    map[uint64]*jetstream.RawStreamMsg
+	lastSeq uint64
+}
+
+func (s *memStream) add(msg *nats.Msg) uint64 {
+	s.lastSeq++
+	s.msgs[s.lastSeq] = &jetstream.RawStreamMsg{Subject: msg.Subject, Sequence: s.lastSeq, Header: msg.Header, Data: msg.Data}
+	return s.lastSeq
+}
+
+func (s *memStream) Info(ctx context.Context, opts ...jetstream.StreamInfoOpt) (*jetstream.StreamInfo, error) {
+	return &jetstream.StreamInfo{State: jetstream.StreamState{FirstSeq: 1, LastSeq: s.lastSeq}}, nil
+}
+
+func (s *memStream) GetMsg(ctx context.Context, seq uint64, opts ...jetstream.GetMsgOpt) (*jetstream.RawStreamMsg, error) {
+	if msg, ok := s.msgs[seq]; ok {
+		return msg, nil
+	}
+	return nil, jetstream.ErrMsgNotFound
+}
+
+func (s *memStream) DeleteMsg(ctx context.Context, seq uint64) error {
+	delete(s.msgs, seq)
+	return nil
+}
+
+type memJS struct {
+	jetstream.JetStream
+	dlq       *memStream
+	published []*nats.Msg
+}
+
+func (js *memJS) CreateOrUpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	return js.dlq, nil
+}
+
+func (js *memJS) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	js.published = append(js.published, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+type auditWriter struct {
+	logs []*core.LogEntry
+	err  error
+}
+
+func (w *auditWriter) Append(ctx context.Context, log *core.LogEntry) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.logs = append(w.logs, log)
+	return nil
+}
+
+type failedMsg struct {
+	jetstream.Msg
+	subject string
+	headers nats.Header
+	data    []byte
+}
+
+func (m *failedMsg) Subject() string      { return m.subject }
+func (m *failedMsg) Headers() nats.Header { return m.headers }
+func (m *failedMsg) Data() []byte         { return m.data }
+
+func newQueue(t *testing.T) (*Queue, *memJS, *auditWriter) {
+	t.Helper()
+	js := &memJS{dlq: &memStream{msgs: make(map[uint64]*jetstream.RawStreamMsg)}}
+	writer := &auditWriter{}
+	q, err := NewQueue(context.Background(), js, writer)
+	if err != nil {
+		t.Fatalf("NewQueue failed: %v", err)
+	}
+	return q, js, writer
+}
+
+// deadLetter adds a letter for a message on subject that failed with cause.
+func deadLetter(js *memJS, subject, msgID string, data string) uint64 {
+	msg := &failedMsg{subject: subject, headers: nats.Header{}, data: []byte(data)}
+	if msgID != "" {
+		msg.headers.Set(jetstream.MsgIDHeader, msgID)
+	}
+	meta := &jetstream.MsgMetadata{NumDelivered: 5, Sequence: jetstream.SequencePair{Stream: 42}}
+	return js.dlq.add(NewMessage(msg, meta, errors.New("invalid JSON: unexpected end of input")))
+}
+
+func TestQueue_ListAndGet(t *testing.T) {
+	q, js, _ := newQueue(t)
+	ctx := context.Background()
+	first := deadLetter(js, "sys.audit.setpoint", "setpoint-1", `{"actor":`)
+	deadLetter(js, "sys.alarm.events", "", "\x01\x02")
+	deadLetter(js, "sys.audit.login", "", `{`)
+	js.dlq.DeleteMsg(ctx, 2)
+
+	letters, err := q.List(ctx, 0, 10)
+	if err != nil || len(letters) != 2 || letters[0].Seq != first || letters[1].Seq != 3 {
+		t.Fatalf("Expected the 2 remaining letters, got %+v %v", letters, err)
+	}
+	l := letters[0]
+	if l.Subject != "sys.audit.setpoint" || l.MsgID != "setpoint-1" || l.StreamSeq != 42 || l.Deliveries != 5 ||
+		l.Error == "" || l.FailedAt.IsZero() || l.Size != 9 || l.Data != nil {
+		t.Errorf("Unexpected letter in list %+v", l)
+	}
+	if page, _ := q.List(ctx, first, 1); len(page) != 1 || page[0].Seq != 3 {
+		t.Errorf("Expected paging after seq %d, got %+v", first, page)
+	}
+
+	l, err = q.Get(ctx, first)
+	if err != nil || string(l.Data) != `{"actor":` {
+		t.Errorf("Expected letter with data, got %+v %v", l, err)
+	}
+	if _, err := q.Get(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestQueue_Replay(t *testing.T) {
+	q, js, writer := newQueue(t)
+	ctx := context.Background()
+	seq := deadLetter(js, "sys.audit.setpoint", "setpoint-1", `{"actor":"admin"}`)
+
+	if _, err := q.Replay(ctx, seq, "alice", "parser fixed"); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if len(js.published) != 1 {
+		t.Fatalf("Expected the letter to be republished, got %d", len(js.published))
+	}
+	msg := js.published[0]
+	if msg.Subject != "sys.audit.setpoint" || msg.Header.Get(jetstream.MsgIDHeader) != "setpoint-1" || msg.Header.Get(HeaderError) != "" {
+		t.Errorf("Expected the original message back, got %s %v", msg.Subject, msg.Header)
+	}
+	if len(writer.logs) != 1 || writer.logs[0].Action != ActionReplayed || writer.logs[0].Actor != "alice" {
+		t.Errorf("Expected the replay to be audited, got %+v", writer.logs)
+	}
+	if _, err := q.Get(ctx, seq); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected replayed letter to be removed, got %v", err)
+	}
+}
+
+func TestQueue_Discard(t *testing.T) {
+	q, js, writer := newQueue(t)
+	ctx := context.Background()
+	seq := deadLetter(js, "sys.alarm.events", "", "\x01\x02")
+
+	// Without an audit entry the letter stays.
+	writer.err = errors.New("database down")
+	if _, err := q.Discard(ctx, seq, "alice", "garbage"); err == nil {
+		t.Fatal("Expected discard to fail when it can't be audited")
+	}
+	if _, err := q.Get(ctx, seq); err != nil {
+		t.Fatalf("Expected letter to be kept, got %v", err)
+	}
+
+	writer.err = nil
+	if _, err := q.Discard(ctx, seq, "alice", "garbage from a test PLC"); err != nil {
+		t.Fatalf("Discard failed: %v", err)
+	}
+	if len(js.published) != 0 {
+		t.Error("Expected nothing to be republished")
+	}
+	if len(writer.logs) != 1 || writer.logs[0].Action != ActionDiscarded || writer.logs[0].Actor != "alice" {
+		t.Fatalf("Expected the discard to be audited, got %+v", writer.logs)
+	}
+	var details map[string]interface{}
+	json.Unmarshal(writer.logs[0].Details, &details)
+	if details["reason"] != "garbage from a test PLC" || details["subject"] != "sys.alarm.events" || details["data"] != "AQI=" {
+		t.Errorf("Expected the audit entry to keep the decision and the data, got %v", details)
+	}
+	if _, err := q.Get(ctx, seq); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected discarded letter to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/checkpoint"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+)
+
+// Format identifies the bundle layout.
+const Format = "audit-export-v1"
+
+// Files in a bundle.
+const (
+	ManifestFile    = "manifest.json"
+	LogsFile        = "logs.jsonl"
+	CheckpointsFile = "checkpoints.json"
+	TimestampsFile  = "timestamps.json"
+	PublicKeysFile  = "public_keys.json"
+)
+
+// Action is recorded in the audit chain for each export served.
+const Action = "audit_exported"
+
+// ErrEmptyRange is returned when there is nothing to export.
+var ErrEmptyRange = errors.New("no audit log entries in range")
+
+// Manifest describes a bundle. The chain in it starts from Anchor, the
+// prev_hash of its first entry, and ends at Head; HeadCheckpoint, signed
+// when the bundle was made, vouches for Head so entries after the last
+// stored checkpoint are covered too.
+type Manifest struct {
+	Format         string                `json:"format"`
+	CreatedAt      time.Time             `json:"created_at"`
+	FirstSeq       int64                 `json:"first_seq"`
+	LastSeq        int64                 `json:"last_seq"`
+	Count          int64                 `json:"count"`
+	Anchor         string                `json:"anchor"`
+	Head           string                `json:"head"`
+	HashVersions   []int                 `json:"hash_versions"`
+	HeadCheckpoint *core.Checkpoint      `json:"head_checkpoint,omitempty"`
+	Files          map[string]FileDigest `json:"files"`
+}
+
+// FileDigest is the size and SHA-256 of a file in the bundle.
+type FileDigest struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Options control what goes into a bundle besides the entries.
+type Options struct {
+	Range repository.LogRange
+	// Signer, if set, signs a checkpoint of the bundle's last entry.
+	Signer *checkpoint.Signer
+	// PublicKeys are included so the bundle can be checked without the
+	// service; an inspector should still compare them with keys obtained
+	// separately.
+	PublicKeys []*checkpoint.PublicKey
+	// Timestamps includes checkpoint timestamp tokens.
+	Timestamps bool
+}
+
+// Write exports the entries in opts.Range, with the stored checkpoints and
+// timestamps that fall in it, as a zip bundle.
+func Write(ctx context.Context, repo repository.Repository, opts Options, w io.Writer) (*Manifest, error) {
+	zw := zip.NewWriter(w)
+	manifest := &Manifest{
+		Format:    Format,
+		CreatedAt: time.Now().Truncate(time.Microsecond).UTC(),
+		Files:     make(map[string]FileDigest),
+	}
+
+	versions := make(map[int]bool)
+	var last *core.LogEntry
+	err := writeFile(zw, manifest, LogsFile, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return repo.IterateLogs(ctx, opts.Range, func(log *core.LogEntry) error {
+			if manifest.Count == 0 {
+				manifest.FirstSeq = log.Seq
+				manifest.Anchor = log.PrevHash
+			}
+			manifest.Count++
+			versions[log.HashVersion] = true
+			last = log
+			return enc.Encode(newEntryLine(log))
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, ErrEmptyRange
+	}
+	manifest.LastSeq, manifest.Head = last.Seq, last.CurrHash
+	for v := range versions {
+		manifest.HashVersions = append(manifest.HashVersions, v)
+	}
+	sort.Ints(manifest.HashVersions)
+
+	checkpoints, err := repo.ListCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var inRange []*core.Checkpoint
+	seqs := make(map[int64]bool)
+	for _, cp := range checkpoints {
+		if cp.Seq >= manifest.FirstSeq && cp.Seq <= manifest.LastSeq {
+			inRange = append(inRange, cp)
+			seqs[cp.Seq] = true
+		}
+	}
+	if err := writeJSON(zw, manifest, CheckpointsFile, emptyIfNil(inRange)); err != nil {
+		return nil, err
+	}
+
+	if opts.Timestamps {
+		timestamps, err := repo.ListTimestamps(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var stamped []*core.CheckpointTimestamp
+		for _, ts := range timestamps {
+			if seqs[ts.Seq] {
+				stamped = append(stamped, ts)
+			}
+		}
+		if err := writeJSON(zw, manifest, TimestampsFile, emptyIfNil(stamped)); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.PublicKeys != nil {
+		if err := writeJSON(zw, manifest, PublicKeysFile, opts.PublicKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Signer != nil {
+		count, err := repo.CountLogs(ctx, repository.LogRange{ToSeq: manifest.LastSeq})
+		if err != nil {
+			return nil, err
+		}
+		cp := &core.Checkpoint{Seq: manifest.LastSeq, HeadHash: manifest.Head, Count: count, CreatedAt: manifest.CreatedAt}
+		if err := opts.Signer.Sign(cp); err != nil {
+			return nil, err
+		}
+		manifest.HeadCheckpoint = cp
+	}
+
+	mw, err := zw.Create(ManifestFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", ManifestFile, err)
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", ManifestFile, err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish bundle: %w", err)
+	}
+	return manifest, nil
+}
+
+// entryLine is an entry as written to logs.jsonl. Hash version 1 covers
+// details byte for byte as stored, which JSON encoding would compact, so
+// for those entries the stored text is kept in DetailsRaw when encoding
+// changes it; an empty DetailsRaw means details are NULL.
+type entryLine struct {
+	*core.LogEntry
+	DetailsRaw *string `json:"details_raw,omitempty"`
+}
+
+func newEntryLine(log *core.LogEntry) entryLine {
+	line := entryLine{LogEntry: log}
+	var compact bytes.Buffer
+	if log.HashVersion != core.HashVersion1 {
+		return line
+	}
+	if log.Details == nil || json.Compact(&compact, log.Details) != nil || !bytes.Equal(compact.Bytes(), log.Details) {
+		raw := string(log.Details)
+		line.DetailsRaw = &raw
+	}
+	return line
+}
+
+// entry returns the entry as it was stored.
+func (l entryLine) entry() *core.LogEntry {
+	if l.DetailsRaw != nil {
+		l.LogEntry.Details = nil
+		if *l.DetailsRaw != "" {
+			l.LogEntry.Details = json.RawMessage(*l.DetailsRaw)
+		}
+	}
+	return l.LogEntry
+}
+
+func emptyIfNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// writeFile adds a file to the bundle and records its digest.
+func writeFile(zw *zip.Writer, manifest *Manifest, name string, write func(io.Writer) error) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	d := newDigester(fw)
+	if err := write(d); err != nil {
+		return err
+	}
+	manifest.Files[name] = d.digest()
+	return nil
+}
+
+func writeJSON(zw *zip.Writer, manifest *Manifest, name string, v interface{}) error {
+	return writeFile(zw, manifest, name, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	})
+}
+
+// digester hashes and counts what passes through it.
+type digester struct {
+	w    io.Writer
+	h    hash.Hash
+	size int64
+}
+
+func newDigester(w io.Writer) *digester {
+	return &digester{w: w, h: sha256.New()}
+}
+
+func (d *digester) Write(p []byte) (int, error) {
+	d.h.Write(p)
+	d.size += int64(len(p))
+	return d.w.Write(p)
+}
+
+func (d *digester) digest() FileDigest {
+	return FileDigest{Size: d.size, SHA256: hex.EncodeToString(d.h.Sum(nil))}
+}
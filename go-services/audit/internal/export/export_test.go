@@ -0,0 +1,226 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/checkpoint"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+)
+
+// memRepo keeps a chain and its checkpoints in memory.
+type memRepo struct {
+	repository.Repository
+	logs        []*core.LogEntry
+	checkpoints []*core.Checkpoint
+}
+
+// newMemRepo builds a chain of n entries, the first half hashed with
+// version 1 and details as Postgres prints them, and a checkpoint every
+// 4 entries.
+func newMemRepo(t *testing.T, n int, signer *checkpoint.Signer) *memRepo {
+	t.Helper()
+	hashers := core.NewHashers()
+	r := &memRepo{}
+	prevHash := core.GenesisHash
+	base := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		log := &core.LogEntry{
+			Seq:       int64(i),
+			ID:        fmt.Sprintf("id-%d", i),
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+			Actor:     "operator",
+			Action:    "SETPOINT_CHANGED",
+			Details:   json.RawMessage(fmt.Sprintf(`{"tag": "TI-%d", "value": %d}`, i, i*10)),
+			PrevHash:  prevHash,
+		}
+		version := core.HashVersion2
+		if i <= n/2 {
+			version = core.HashVersion1
+		}
+		if i == 2 {
+			log.Details = nil
+		}
+		hasher, _ := hashers.ForVersion(version)
+		log.HashVersion = version
+		log.CurrHash = hasher.Hash(prevHash, log)
+		prevHash = log.CurrHash
+		r.logs = append(r.logs, log)
+
+		if i%4 == 0 {
+			cp := &core.Checkpoint{Seq: log.Seq, HeadHash: log.CurrHash, Count: log.Seq, CreatedAt: log.Timestamp}
+			if err := signer.Sign(cp); err != nil {
+				t.Fatalf("Sign failed: %v", err)
+			}
+			r.checkpoints = append(r.checkpoints, cp)
+		}
+	}
+	return r
+}
+
+func (r *memRepo) IterateLogs(ctx context.Context, rng repository.LogRange, callback func(*core.LogEntry) error) error {
+	for _, log := range r.logs {
+		if log.Seq >= rng.FromSeq && (rng.ToSeq == 0 || log.Seq <= rng.ToSeq) {
+			if err := callback(log); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) CountLogs(ctx context.Context, rng repository.LogRange) (int64, error) {
+	var count int64
+	r.IterateLogs(ctx, rng, func(*core.LogEntry) error { count++; return nil })
+	return count, nil
+}
+
+func (r *memRepo) ListCheckpoints(ctx context.Context) ([]*core.Checkpoint, error) {
+	return r.checkpoints, nil
+}
+
+func newSigner(t *testing.T) (*checkpoint.Signer, *checkpoint.Verifier) {
+	t.Helper()
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := checkpoint.NewSigner(key)
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+	verifier, _ := checkpoint.NewVerifier(signer.Public())
+	return signer, verifier
+}
+
+func exportBundle(t *testing.T, repo *memRepo, opts Options) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := Write(context.Background(), repo, opts, &buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func verifyBundle(t *testing.T, bundle []byte, opts VerifyOptions) *Report {
+	t.Helper()
+	opts.Hashers = core.NewHashers()
+	report, err := Verify(bytes.NewReader(bundle), int64(len(bundle)), opts)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	return report
+}
+
+// rewrite returns bundle with a file replaced, updating the manifest
+// digest as well when fixManifest is set.
+func rewrite(t *testing.T, bundle []byte, name string, edit func([]byte) []byte, fixManifest bool) []byte {
+	t.Helper()
+	zr, _ := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	contents := make(map[string][]byte)
+	var names []string
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = data
+		names = append(names, f.Name)
+	}
+	contents[name] = edit(contents[name])
+	if fixManifest {
+		var manifest Manifest
+		json.Unmarshal(contents[ManifestFile], &manifest)
+		d := newDigester(io.Discard)
+		d.Write(contents[name])
+		manifest.Files[name] = d.digest()
+		contents[ManifestFile], _ = json.Marshal(manifest)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, n := range names {
+		w, _ := zw.Create(n)
+		w.Write(contents[n])
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestBundle_RoundTrip(t *testing.T) {
+	signer, verifier := newSigner(t)
+	repo := newMemRepo(t, 10, signer)
+	publicKeys, _ := verifier.PublicKeys()
+	bundle := exportBundle(t, repo, Options{Signer: signer, PublicKeys: publicKeys})
+
+	report := verifyBundle(t, bundle, VerifyOptions{Checkpoints: verifier})
+	if !report.Valid || report.Chain.Checked != 10 || report.Chain.Checkpoints != 3 {
+		t.Fatalf("Expected bundle to verify against 2 checkpoints and the head, got %+v", report)
+	}
+	m := report.Manifest
+	if m.FirstSeq != 1 || m.LastSeq != 10 || m.Anchor != core.GenesisHash || len(m.HashVersions) != 2 {
+		t.Errorf("Unexpected manifest %+v", m)
+	}
+
+	// Without keys given, the bundle's own keys are used and that is
+	// called out.
+	report = verifyBundle(t, bundle, VerifyOptions{})
+	if !report.Valid || len(report.Warnings) == 0 {
+		t.Errorf("Expected bundle keys to be used with a warning, got %+v", report)
+	}
+}
+
+func TestBundle_Range(t *testing.T) {
+	signer, verifier := newSigner(t)
+	repo := newMemRepo(t, 10, signer)
+	bundle := exportBundle(t, repo, Options{Range: repository.LogRange{FromSeq: 3, ToSeq: 7}, Signer: signer})
+
+	report := verifyBundle(t, bundle, VerifyOptions{Checkpoints: verifier})
+	if !report.Valid || report.Chain.Checked != 5 || report.Manifest.Anchor != repo.logs[1].CurrHash {
+		t.Fatalf("Expected range bundle to verify from its anchor, got %+v", report)
+	}
+}
+
+func TestBundle_Tampering(t *testing.T) {
+	signer, verifier := newSigner(t)
+	repo := newMemRepo(t, 10, signer)
+	bundle := exportBundle(t, repo, Options{Signer: signer})
+
+	changeActor := func(data []byte) []byte {
+		return bytes.Replace(data, []byte(`"actor":"operator"`), []byte(`"actor":"mallory"`), 1)
+	}
+	// Edited without fixing the manifest: the digest gives it away.
+	report := verifyBundle(t, rewrite(t, bundle, LogsFile, changeActor, false), VerifyOptions{Checkpoints: verifier})
+	if report.Valid || len(report.Problems) == 0 {
+		t.Errorf("Expected edited logs to fail the digest, got %+v", report)
+	}
+	// With the manifest fixed up, the hash chain still breaks.
+	report = verifyBundle(t, rewrite(t, bundle, LogsFile, changeActor, true), VerifyOptions{Checkpoints: verifier})
+	if report.Valid || report.Chain.Broken[0].Reason != core.BrokenCurrHash {
+		t.Errorf("Expected edited entry to break the chain, got %+v", report.Chain)
+	}
+
+	// Dropping the last entry leaves the signed head unreached.
+	dropLast := func(data []byte) []byte {
+		lines := strings.SplitAfter(string(data), "\n")
+		return []byte(strings.Join(lines[:len(lines)-2], ""))
+	}
+	report = verifyBundle(t, rewrite(t, bundle, LogsFile, dropLast, true), VerifyOptions{Checkpoints: verifier})
+	if report.Valid {
+		t.Errorf("Expected truncated bundle to be rejected, got %+v", report)
+	}
+
+	// A bundle signed with another key doesn't verify against the real one.
+	otherSigner, _ := newSigner(t)
+	forged := exportBundle(t, newMemRepo(t, 10, otherSigner), Options{Signer: otherSigner})
+	report = verifyBundle(t, forged, VerifyOptions{Checkpoints: verifier})
+	if report.Valid || report.Chain.Broken[0].Reason != core.BrokenCheckpointSignature {
+		t.Errorf("Expected checkpoints signed by another key to be rejected, got %+v", report.Chain)
+	}
+}
@@ -0,0 +1,254 @@
+package export
+
+import (
+	"archive/zip"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/checkpoint"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+)
+
+// VerifyOptions say what a bundle is verified against.
+type VerifyOptions struct {
+	Hashers *core.Hashers
+	// Checkpoints verifies checkpoint signatures. When nil the keys in the
+	// bundle are used, which only shows the bundle is consistent with
+	// itself.
+	Checkpoints core.CheckpointVerifier
+	// Timestamps verifies timestamp tokens; they are skipped when nil.
+	Timestamps core.TimestampVerifier
+}
+
+// Report is the outcome of verifying a bundle.
+type Report struct {
+	Valid    bool               `json:"valid"`
+	Manifest *Manifest          `json:"manifest"`
+	Chain    *core.VerifyResult `json:"chain"`
+	// Problems make the bundle invalid: files that don't match the
+	// manifest, or a manifest that doesn't match the entries.
+	Problems []string `json:"problems"`
+	// Warnings are limits on what could be verified.
+	Warnings []string `json:"warnings"`
+}
+
+func (r *Report) problem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+func (r *Report) warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Verify checks a bundle without the service or its database: the files
+// against the manifest, and the entries' hash chain from the manifest's
+// anchor against the checkpoints and their timestamps. It returns an error
+// only when the bundle can't be read at all.
+func Verify(r io.ReaderAt, size int64, opts VerifyOptions) (*Report, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not an export bundle: %w", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	report := &Report{Problems: []string{}, Warnings: []string{}}
+	manifest := &Manifest{}
+	if err := readJSON(files, ManifestFile, manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Format != Format {
+		return nil, fmt.Errorf("unsupported bundle format %q", manifest.Format)
+	}
+	report.Manifest = manifest
+
+	checkFiles(files, manifest, report)
+
+	var checkpoints []*core.Checkpoint
+	if err := readJSON(files, CheckpointsFile, &checkpoints); err != nil {
+		report.problem("%v", err)
+	}
+	if manifest.HeadCheckpoint != nil {
+		checkpoints = append(checkpoints, manifest.HeadCheckpoint)
+	} else {
+		report.warn("the bundle has no signed head checkpoint, entries after seq %d are only covered by the hash chain", lastSeq(checkpoints))
+	}
+
+	cpVerifier := opts.Checkpoints
+	if cpVerifier == nil {
+		cpVerifier, err = bundleKeys(files)
+		if err != nil {
+			report.problem("%v", err)
+		}
+		if cpVerifier != nil {
+			report.warn("checkpoints were verified with the public keys in the bundle; compare them with keys obtained separately")
+		}
+	}
+
+	var chain *core.ChainVerifier
+	if manifest.FirstSeq == 1 {
+		chain = core.NewChainVerifier(opts.Hashers)
+	} else {
+		chain = core.NewRangeVerifier(opts.Hashers)
+	}
+	if cpVerifier != nil {
+		chain.UseCheckpoints(checkpoints, cpVerifier)
+		if _, ok := files[TimestampsFile]; ok {
+			var timestamps []*core.CheckpointTimestamp
+			if err := readJSON(files, TimestampsFile, &timestamps); err != nil {
+				report.problem("%v", err)
+			} else if opts.Timestamps != nil {
+				chain.UseTimestamps(timestamps, opts.Timestamps)
+			} else if len(timestamps) > 0 {
+				report.warn("%d timestamp tokens were not verified, no TSA roots were given", len(timestamps))
+			}
+		}
+	} else if len(checkpoints) > 0 {
+		report.warn("%d checkpoints were not verified, no public keys were given", len(checkpoints))
+	}
+
+	if err := verifyLogs(files, manifest, chain, report); err != nil {
+		report.problem("%v", err)
+	}
+	report.Chain = chain.Finish()
+	report.Valid = report.Chain.Valid && len(report.Problems) == 0
+	return report, nil
+}
+
+// checkFiles compares every file with its digest in the manifest.
+func checkFiles(files map[string]*zip.File, manifest *Manifest, report *Report) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := manifest.Files[name]; !ok && name != ManifestFile {
+			report.problem("%s is not listed in the manifest", name)
+		}
+	}
+
+	names = names[:0]
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want := manifest.Files[name]
+		f, ok := files[name]
+		if !ok {
+			report.problem("%s is missing", name)
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			report.problem("failed to read %s: %v", name, err)
+			continue
+		}
+		d := newDigester(io.Discard)
+		_, err = io.Copy(d, rc)
+		rc.Close()
+		if err != nil {
+			report.problem("failed to read %s: %v", name, err)
+		} else if got := d.digest(); got != want {
+			report.problem("%s does not match the manifest: sha256 %s, expected %s", name, got.SHA256, want.SHA256)
+		}
+	}
+}
+
+// verifyLogs streams the entries through chain and checks they are the
+// ones the manifest describes.
+func verifyLogs(files map[string]*zip.File, manifest *Manifest, chain *core.ChainVerifier, report *Report) error {
+	f, ok := files[LogsFile]
+	if !ok {
+		return fmt.Errorf("%s is missing", LogsFile)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", LogsFile, err)
+	}
+	defer rc.Close()
+
+	dec := json.NewDecoder(rc)
+	var first, last *core.LogEntry
+	for dec.More() {
+		line := entryLine{LogEntry: &core.LogEntry{}}
+		if err := dec.Decode(&line); err != nil {
+			return fmt.Errorf("%s is malformed after %d entries: %w", LogsFile, chain.Checked(), err)
+		}
+		log := line.entry()
+		if first == nil {
+			first = log
+		}
+		last = log
+		chain.Add(log)
+	}
+
+	if first == nil {
+		return fmt.Errorf("%s has no entries", LogsFile)
+	}
+	if first.Seq != manifest.FirstSeq || first.PrevHash != manifest.Anchor {
+		report.problem("the first entry (seq %d, prev_hash %s) is not the manifest's anchor (seq %d, prev_hash %s)",
+			first.Seq, first.PrevHash, manifest.FirstSeq, manifest.Anchor)
+	}
+	if last.Seq != manifest.LastSeq || last.CurrHash != manifest.Head {
+		report.problem("the last entry (seq %d, hash %s) is not the manifest's head (seq %d, hash %s)",
+			last.Seq, last.CurrHash, manifest.LastSeq, manifest.Head)
+	}
+	if chain.Checked() != manifest.Count {
+		report.problem("the bundle has %d entries, the manifest says %d", chain.Checked(), manifest.Count)
+	}
+	return nil
+}
+
+func bundleKeys(files map[string]*zip.File) (core.CheckpointVerifier, error) {
+	if _, ok := files[PublicKeysFile]; !ok {
+		return nil, nil
+	}
+	var published []*checkpoint.PublicKey
+	if err := readJSON(files, PublicKeysFile, &published); err != nil {
+		return nil, err
+	}
+	var keys []crypto.PublicKey
+	for _, pub := range published {
+		key, err := checkpoint.ParsePublicKey([]byte(pub.PEM))
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %s in bundle: %w", pub.KeyID, err)
+		}
+		keys = append(keys, key)
+	}
+	verifier, err := checkpoint.NewVerifier(keys...)
+	if err != nil {
+		return nil, err
+	}
+	return verifier, nil
+}
+
+func readJSON(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("%s is missing", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%s is malformed: %w", name, err)
+	}
+	return nil
+}
+
+func lastSeq(checkpoints []*core.Checkpoint) int64 {
+	var seq int64
+	for _, cp := range checkpoints {
+		seq = max(seq, cp.Seq)
+	}
+	return seq
+}
@@ -0,0 +1,163 @@
+package proof
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/pkg/merkle"
+)
+
+// memRepo keeps entries and batches in memory.
+type memRepo struct {
+	repository.Repository
+	logs    []*core.LogEntry
+	batches []*core.MerkleBatch
+}
+
+func newMemRepo(n int) *memRepo {
+	r := &memRepo{}
+	for i := 1; i <= n; i++ {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("entry-%d", i)))
+		r.logs = append(r.logs, &core.LogEntry{Seq: int64(i), ID: fmt.Sprintf("id-%d", i), CurrHash: hex.EncodeToString(sum[:])})
+	}
+	return r
+}
+
+func (r *memRepo) LastLog(ctx context.Context) (*core.LogEntry, error) {
+	if len(r.logs) == 0 {
+		return nil, nil
+	}
+	return r.logs[len(r.logs)-1], nil
+}
+
+func (r *memRepo) GetLog(ctx context.Context, id string) (*core.LogEntry, error) {
+	for _, log := range r.logs {
+		if log.ID == id {
+			return log, nil
+		}
+	}
+	return nil, repository.ErrLogNotFound
+}
+
+func (r *memRepo) IterateLogs(ctx context.Context, rng repository.LogRange, callback func(*core.LogEntry) error) error {
+	for _, log := range r.logs {
+		if log.Seq >= rng.FromSeq && (rng.ToSeq == 0 || log.Seq <= rng.ToSeq) {
+			if err := callback(log); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) ListMerkleBatches(ctx context.Context, batchSize int64) ([]*core.MerkleBatch, error) {
+	return r.batches, nil
+}
+
+func (r *memRepo) SaveMerkleBatch(ctx context.Context, b *core.MerkleBatch) error {
+	r.batches = append(r.batches, b)
+	return nil
+}
+
+func TestSealer_SealsFullBatches(t *testing.T) {
+	repo := newMemRepo(21)
+	sealer := NewSealer(repo, 8)
+
+	if n, err := sealer.Seal(context.Background()); err != nil || n != 2 {
+		t.Fatalf("Expected 2 batches sealed, got %d (%v)", n, err)
+	}
+	if n, _ := sealer.Seal(context.Background()); n != 0 {
+		t.Errorf("Expected nothing left to seal, got %d", n)
+	}
+	if b := repo.batches[1]; b.FirstSeq != 9 || b.LastSeq != 16 {
+		t.Errorf("Unexpected second batch %+v", b)
+	}
+}
+
+func TestProver_ProofsVerifyOffline(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemRepo(37)
+	NewSealer(repo, 8).Seal(ctx)
+	prover := NewProver(repo, 8)
+
+	for _, size := range []uint64{1, 8, 13, 37} {
+		for seq := 1; seq <= int(size); seq++ {
+			p, err := prover.Inclusion(ctx, fmt.Sprintf("id-%d", seq), size)
+			if err != nil {
+				t.Fatalf("Inclusion(%d, %d): %v", seq, size, err)
+			}
+			if err := p.Verify(p.Entry.CurrHash); err != nil {
+				t.Fatalf("Inclusion proof for seq %d in %d does not verify: %v", seq, size, err)
+			}
+		}
+	}
+
+	full, _ := prover.Inclusion(ctx, "id-3", 0)
+	if full.TreeSize != 37 || full.Batch == nil || full.Batch.BatchIndex != 0 {
+		t.Errorf("Expected proof against the whole log within batch 0, got %+v", full)
+	}
+
+	for first := uint64(1); first <= 37; first++ {
+		p, err := prover.Consistency(ctx, first, 0)
+		if err != nil {
+			t.Fatalf("Consistency(%d): %v", first, err)
+		}
+		if err := p.Verify(); err != nil {
+			t.Fatalf("Consistency proof %d -> 37 does not verify: %v", first, err)
+		}
+	}
+
+	if _, err := prover.Inclusion(ctx, "id-30", 20); !errors.Is(err, ErrInvalidTreeSize) {
+		t.Errorf("Expected entry after the tree size to be rejected, got %v", err)
+	}
+	if _, err := prover.Consistency(ctx, 5, 40); !errors.Is(err, ErrInvalidTreeSize) {
+		t.Errorf("Expected tree size past the head to be rejected, got %v", err)
+	}
+}
+
+func TestProver_MatchesTreeFromLeaves(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemRepo(37)
+	NewSealer(repo, 8).Seal(ctx)
+
+	leaves := make([][]byte, len(repo.logs))
+	for i, log := range repo.logs {
+		data, _ := hex.DecodeString(log.CurrHash)
+		leaves[i] = merkle.LeafHash(data)
+	}
+	want, _ := merkle.FromLeafHashes(leaves)(0, 37)
+
+	p, _ := NewProver(repo, 8).Consistency(ctx, 37, 0)
+	if p.SecondRoot != hex.EncodeToString(want) {
+		t.Errorf("Root from sealed batches %s differs from root over leaves %x", p.SecondRoot, want)
+	}
+}
+
+func TestProver_DetectsRewrittenEntry(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemRepo(16)
+	NewSealer(repo, 8).Seal(ctx)
+
+	repo.logs[2].CurrHash = hex.EncodeToString(make([]byte, 32))
+	p, err := NewProver(repo, 8).Inclusion(ctx, "id-3", 0)
+	if err != nil {
+		t.Fatalf("Inclusion failed: %v", err)
+	}
+	if err := p.Verify(p.Entry.CurrHash); err == nil {
+		t.Error("Expected an entry rewritten after sealing to fail its inclusion proof")
+	}
+}
+
+func TestValidBatchSize(t *testing.T) {
+	for n, want := range map[int64]bool{1: true, 1024: true, 0: false, 1000: false, -8: false} {
+		if ValidBatchSize(n) != want {
+			t.Errorf("ValidBatchSize(%d) = %v", n, !want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package proof builds Merkle trees over the audit log and serves RFC 6962
+// style inclusion and consistency proofs, which can be checked offline
+// with pkg/merkle.
+package proof
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/pkg/merkle"
+)
+
+// DefaultBatchSize is the number of entries per sealed batch.
+const DefaultBatchSize = 1024
+
+// ErrInvalidTreeSize is returned for tree sizes the log can't prove.
+var ErrInvalidTreeSize = errors.New("invalid tree size")
+
+// ValidBatchSize reports whether n can be used as a batch size. Batches
+// must be powers of two to be complete subtrees of the log tree.
+func ValidBatchSize(n int64) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// Prover builds proofs over the Merkle tree whose leaves are the entries'
+// curr_hash values in sequence order. Stored batch roots stand in for the
+// subtrees they cover, so only the leaves of the batch being proved and of
+// a trailing partial batch are read.
+type Prover struct {
+	repo      repository.Repository
+	batchSize int64
+}
+
+func NewProver(repo repository.Repository, batchSize int64) *Prover {
+	return &Prover{repo: repo, batchSize: batchSize}
+}
+
+// InclusionProof proves that an entry is in the log.
+type InclusionProof struct {
+	merkle.InclusionProof
+	Entry *core.LogEntry `json:"entry"`
+	// Batch is the sealed batch holding the entry, if it is sealed yet.
+	Batch *core.MerkleBatch `json:"batch,omitempty"`
+}
+
+// Inclusion proves that entry id is in the tree of size treeSize, or of the
+// whole log when treeSize is 0.
+func (p *Prover) Inclusion(ctx context.Context, id string, treeSize uint64) (*InclusionProof, error) {
+	entry, err := p.repo.GetLog(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	t, err := p.tree(ctx, treeSize)
+	if err != nil {
+		return nil, err
+	}
+	index := uint64(entry.Seq - 1)
+	if index >= t.size {
+		return nil, fmt.Errorf("%w: entry %s has seq %d, after tree size %d", ErrInvalidTreeSize, id, entry.Seq, t.size)
+	}
+
+	path, err := merkle.ProveInclusion(index, t.size, t.mth)
+	if err != nil {
+		return nil, err
+	}
+	root, err := t.mth(0, t.size)
+	if err != nil {
+		return nil, err
+	}
+	return &InclusionProof{
+		InclusionProof: merkle.InclusionProof{
+			LeafIndex: index,
+			TreeSize:  t.size,
+			RootHash:  hex.EncodeToString(root),
+			AuditPath: merkle.EncodeHashes(path),
+		},
+		Entry: entry,
+		Batch: t.batches[int64(index)/p.batchSize],
+	}, nil
+}
+
+// Consistency proves that the tree of size first is a prefix of the tree
+// of size second, or of the whole log when second is 0.
+func (p *Prover) Consistency(ctx context.Context, first, second uint64) (*merkle.ConsistencyProof, error) {
+	t, err := p.tree(ctx, second)
+	if err != nil {
+		return nil, err
+	}
+	if first == 0 || first > t.size {
+		return nil, fmt.Errorf("%w: first size must be between 1 and %d", ErrInvalidTreeSize, t.size)
+	}
+
+	proof, err := merkle.ProveConsistency(first, t.size, t.mth)
+	if err != nil {
+		return nil, err
+	}
+	firstRoot, err := t.mth(0, first)
+	if err != nil {
+		return nil, err
+	}
+	secondRoot, err := t.mth(0, t.size)
+	if err != nil {
+		return nil, err
+	}
+	return &merkle.ConsistencyProof{
+		First:      first,
+		Second:     t.size,
+		FirstRoot:  hex.EncodeToString(firstRoot),
+		SecondRoot: hex.EncodeToString(secondRoot),
+		Proof:      merkle.EncodeHashes(proof),
+	}, nil
+}
+
+// Batches returns the sealed batch roots.
+func (p *Prover) Batches(ctx context.Context) ([]*core.MerkleBatch, error) {
+	return p.repo.ListMerkleBatches(ctx, p.batchSize)
+}
+
+// tree is a view of the log tree at a fixed size for one request.
+type tree struct {
+	ctx       context.Context
+	repo      repository.Repository
+	size      uint64
+	batchSize uint64
+	batches   map[int64]*core.MerkleBatch
+	leaves    map[uint64][][]byte
+}
+
+func (p *Prover) tree(ctx context.Context, size uint64) (*tree, error) {
+	head, err := p.repo.LastLog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var headSize uint64
+	if head != nil {
+		headSize = uint64(head.Seq)
+	}
+	if size == 0 {
+		size = headSize
+	}
+	if size == 0 || size > headSize {
+		return nil, fmt.Errorf("%w: tree size must be between 1 and %d", ErrInvalidTreeSize, headSize)
+	}
+
+	batches, err := p.repo.ListMerkleBatches(ctx, p.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	t := &tree{
+		ctx:       ctx,
+		repo:      p.repo,
+		size:      size,
+		batchSize: uint64(p.batchSize),
+		batches:   make(map[int64]*core.MerkleBatch),
+		leaves:    make(map[uint64][][]byte),
+	}
+	for _, b := range batches {
+		t.batches[b.BatchIndex] = b
+	}
+	return t, nil
+}
+
+// mth is the merkle.SubtreeHasher of the tree.
+func (t *tree) mth(lo, hi uint64) ([]byte, error) {
+	if hi > t.size || lo >= hi {
+		return nil, fmt.Errorf("subtree [%d, %d) outside a tree of size %d", lo, hi, t.size)
+	}
+	batch := lo / t.batchSize
+	if lo%t.batchSize == 0 && hi-lo == t.batchSize {
+		if b, ok := t.batches[int64(batch)]; ok {
+			return hex.DecodeString(b.RootHash)
+		}
+	}
+	if batch == (hi-1)/t.batchSize {
+		leaves, err := t.batchLeaves(batch)
+		if err != nil {
+			return nil, err
+		}
+		start := batch * t.batchSize
+		return merkle.FromLeafHashes(leaves)(lo-start, hi-start)
+	}
+	k := merkle.Split(hi - lo)
+	left, err := t.mth(lo, lo+k)
+	if err != nil {
+		return nil, err
+	}
+	right, err := t.mth(lo+k, hi)
+	if err != nil {
+		return nil, err
+	}
+	return merkle.NodeHash(left, right), nil
+}
+
+// batchLeaves reads the leaf hashes of a batch, up to the tree size.
+func (t *tree) batchLeaves(batch uint64) ([][]byte, error) {
+	if leaves, ok := t.leaves[batch]; ok {
+		return leaves, nil
+	}
+	from := batch*t.batchSize + 1
+	to := min((batch+1)*t.batchSize, t.size)
+	leaves, err := readLeaves(t.ctx, t.repo, int64(from), int64(to))
+	if err != nil {
+		return nil, err
+	}
+	t.leaves[batch] = leaves
+	return leaves, nil
+}
+
+// readLeaves returns the leaf hashes of entries fromSeq to toSeq.
+func readLeaves(ctx context.Context, repo repository.Repository, fromSeq, toSeq int64) ([][]byte, error) {
+	leaves := make([][]byte, 0, toSeq-fromSeq+1)
+	err := repo.IterateLogs(ctx, repository.LogRange{FromSeq: fromSeq, ToSeq: toSeq}, func(log *core.LogEntry) error {
+		if want := fromSeq + int64(len(leaves)); log.Seq != want {
+			return fmt.Errorf("audit log seq %d is missing", want)
+		}
+		data, err := hex.DecodeString(log.CurrHash)
+		if err != nil {
+			return fmt.Errorf("entry %d has an invalid curr_hash: %w", log.Seq, err)
+		}
+		leaves = append(leaves, merkle.LeafHash(data))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(leaves)) != toSeq-fromSeq+1 {
+		return nil, fmt.Errorf("audit log seq %d is missing", fromSeq+int64(len(leaves)))
+	}
+	return leaves, nil
+}
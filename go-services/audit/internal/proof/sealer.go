@@ -0,0 +1,89 @@
+package proof
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/pkg/merkle"
+)
+
+// sealInterval is how often the sealer looks for full batches.
+const sealInterval = 5 * time.Second
+
+// Sealer stores the Merkle root of each batch once it is full.
+type Sealer struct {
+	repo      repository.Repository
+	batchSize int64
+	now       func() time.Time
+}
+
+func NewSealer(repo repository.Repository, batchSize int64) *Sealer {
+	return &Sealer{repo: repo, batchSize: batchSize, now: time.Now}
+}
+
+// Run seals batches until ctx is done.
+func (s *Sealer) Run(ctx context.Context) {
+	ticker := time.NewTicker(sealInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Seal(ctx); err != nil {
+			log.Printf("Failed to seal audit merkle batch: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Seal stores the roots of every full batch not stored yet and returns how
+// many it sealed.
+func (s *Sealer) Seal(ctx context.Context) (int, error) {
+	head, err := s.repo.LastLog(ctx)
+	if err != nil || head == nil {
+		return 0, err
+	}
+	batches, err := s.repo.ListMerkleBatches(ctx, s.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	next := int64(0)
+	if len(batches) > 0 {
+		next = batches[len(batches)-1].BatchIndex + 1
+	}
+
+	sealed := 0
+	for ; (next+1)*s.batchSize <= head.Seq; next++ {
+		first, last := next*s.batchSize+1, (next+1)*s.batchSize
+		leaves, err := readLeaves(ctx, s.repo, first, last)
+		if err != nil {
+			return sealed, err
+		}
+		root, err := merkle.FromLeafHashes(leaves)(0, uint64(len(leaves)))
+		if err != nil {
+			return sealed, err
+		}
+		b := &core.MerkleBatch{
+			BatchSize:  s.batchSize,
+			BatchIndex: next,
+			FirstSeq:   first,
+			LastSeq:    last,
+			RootHash:   hex.EncodeToString(root),
+			CreatedAt:  s.now().UTC(),
+		}
+		if err := s.repo.SaveMerkleBatch(ctx, b); err != nil {
+			return sealed, fmt.Errorf("failed to save merkle batch %d: %w", next, err)
+		}
+		sealed++
+	}
+	if sealed > 0 {
+		log.Printf("Sealed %d audit merkle batches up to seq %d", sealed, next*s.batchSize)
+	}
+	return sealed, nil
+}
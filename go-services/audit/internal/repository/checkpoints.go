@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+const checkpointColumns = `seq, head_hash, count, created_at, key_id, algorithm, signature`
+
+// SaveCheckpoint stores a checkpoint. Saving one for a sequence that
+// already has a checkpoint, e.g. from another replica, is a no-op.
+func (r *PostgresRepository) SaveCheckpoint(ctx context.Context, cp *core.Checkpoint) error {
+	_, err := r.pool.Exec(ctx, `INSERT INTO audit_checkpoints (`+checkpointColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (seq) DO NOTHING`,
+		cp.Seq, cp.HeadHash, cp.Count, cp.CreatedAt, cp.KeyID, cp.Algorithm, cp.Signature)
+	return err
+}
+
+// LatestCheckpoint returns the checkpoint with the highest sequence, or nil
+// when there is none.
+func (r *PostgresRepository) LatestCheckpoint(ctx context.Context) (*core.Checkpoint, error) {
+	checkpoints, err := r.queryCheckpoints(ctx, `SELECT `+checkpointColumns+` FROM audit_checkpoints ORDER BY seq DESC LIMIT 1`)
+	if err != nil || len(checkpoints) == 0 {
+		return nil, err
+	}
+	return checkpoints[0], nil
+}
+
+// ListCheckpoints returns every checkpoint in sequence order.
+func (r *PostgresRepository) ListCheckpoints(ctx context.Context) ([]*core.Checkpoint, error) {
+	return r.queryCheckpoints(ctx, `SELECT `+checkpointColumns+` FROM audit_checkpoints ORDER BY seq ASC`)
+}
+
+func (r *PostgresRepository) queryCheckpoints(ctx context.Context, query string) ([]*core.Checkpoint, error) {
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checkpoints: %w", err)
+	}
+	checkpoints, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*core.Checkpoint, error) {
+		var cp core.Checkpoint
+		err := row.Scan(&cp.Seq, &cp.HeadHash, &cp.Count, &cp.CreatedAt, &cp.KeyID, &cp.Algorithm, &cp.Signature)
+		cp.CreatedAt = cp.CreatedAt.UTC()
+		return &cp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
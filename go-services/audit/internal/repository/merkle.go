@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveMerkleBatch stores a batch root. Saving a batch that is already
+// stored is a no-op.
+func (r *PostgresRepository) SaveMerkleBatch(ctx context.Context, b *core.MerkleBatch) error {
+	_, err := r.pool.Exec(ctx, `INSERT INTO audit_merkle_batches
+		(batch_size, batch_index, first_seq, last_seq, root_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (batch_size, batch_index) DO NOTHING`,
+		b.BatchSize, b.BatchIndex, b.FirstSeq, b.LastSeq, b.RootHash, b.CreatedAt)
+	return err
+}
+
+// ListMerkleBatches returns the stored roots of batches of batchSize
+// entries in order.
+func (r *PostgresRepository) ListMerkleBatches(ctx context.Context, batchSize int64) ([]*core.MerkleBatch, error) {
+	rows, err := r.pool.Query(ctx, `SELECT batch_size, batch_index, first_seq, last_seq, root_hash, created_at
+		FROM audit_merkle_batches WHERE batch_size = $1 ORDER BY batch_index ASC`, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merkle batches: %w", err)
+	}
+	batches, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*core.MerkleBatch, error) {
+		var b core.MerkleBatch
+		err := row.Scan(&b.BatchSize, &b.BatchIndex, &b.FirstSeq, &b.LastSeq, &b.RootHash, &b.CreatedAt)
+		return &b, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read merkle batches: %w", err)
+	}
+	return batches, nil
+}
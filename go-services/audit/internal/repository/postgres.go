@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
 	"github.com/jackc/pgx/v5"
@@ -10,9 +13,68 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrLogNotFound is returned when a range refers to an entry that does not
+// exist.
+var ErrLogNotFound = errors.New("audit log not found")
+
+// ErrDuplicateLog is returned by AppendLog when an entry with the same
+// MsgID is already in the chain. The entry passed in is filled with it.
+var ErrDuplicateLog = errors.New("audit log already recorded")
+
+// IsRejected reports whether err is the database refusing an entry for
+// its content, such as a value too long for its column, rather than a
+// failure to write it that a retry could get past.
+func IsRejected(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || len(pgErr.Code) != 5 {
+		return false
+	}
+	// Data exceptions, integrity constraint violations and program limits
+	switch pgErr.Code[:2] {
+	case "22", "23", "54":
+		return true
+	}
+	return false
+}
+
+// iteratePageSize is how many entries IterateLogs reads per query.
+const iteratePageSize = 1000
+
+// LogRange selects a contiguous part of the chain. Zero fields are
+// unbounded; all bounds are inclusive. Timestamp bounds select from the
+// first entry at or after From to the last entry at or before To in chain
+// order, so entries with out-of-order timestamps in between are included.
+type LogRange struct {
+	FromSeq int64
+	ToSeq   int64
+	FromID  string
+	ToID    string
+	From    time.Time
+	To      time.Time
+}
+
+// IsZero reports whether rng selects the whole chain.
+func (rng LogRange) IsZero() bool {
+	return rng == LogRange{}
+}
+
 type Repository interface {
 	AppendLog(ctx context.Context, log *core.LogEntry, hasher core.Hasher) error
-	IterateLogs(ctx context.Context, callback func(*core.LogEntry) error) error
+	AppendLogs(ctx context.Context, logs []*core.LogEntry, hasher core.Hasher) ([]bool, error)
+	IterateLogs(ctx context.Context, rng LogRange, callback func(*core.LogEntry) error) error
+	CountLogs(ctx context.Context, rng LogRange) (int64, error)
+	LastLog(ctx context.Context) (*core.LogEntry, error)
+	LastLogIn(ctx context.Context, rng LogRange) (*core.LogEntry, error)
+	GetLog(ctx context.Context, id string) (*core.LogEntry, error)
+	SearchLogs(ctx context.Context, q LogQuery) ([]*core.LogEntry, error)
+	SaveCheckpoint(ctx context.Context, cp *core.Checkpoint) error
+	LatestCheckpoint(ctx context.Context) (*core.Checkpoint, error)
+	ListCheckpoints(ctx context.Context) ([]*core.Checkpoint, error)
+	SaveTimestamp(ctx context.Context, ts *core.CheckpointTimestamp) error
+	ListTimestamps(ctx context.Context) ([]*core.CheckpointTimestamp, error)
+	ListUntimestampedCheckpoints(ctx context.Context) ([]*core.Checkpoint, error)
+	SaveMerkleBatch(ctx context.Context, b *core.MerkleBatch) error
+	ListMerkleBatches(ctx context.Context, batchSize int64) ([]*core.MerkleBatch, error)
 	Close()
 }
 
@@ -38,6 +100,37 @@ func NewPostgresRepository(ctx context.Context, dbUrl string) (*PostgresReposito
 	);
 	CREATE INDEX IF NOT EXISTS idx_audit_logs_timestamp ON audit_logs(timestamp);
 	CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs(actor);
+	CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action text_pattern_ops);
+	ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS seq BIGINT;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_seq ON audit_logs(seq);
+	ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS hash_version SMALLINT NOT NULL DEFAULT 1;
+	ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS msg_id TEXT;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_msg_id ON audit_logs(msg_id);
+	CREATE TABLE IF NOT EXISTS audit_checkpoints (
+		seq BIGINT PRIMARY KEY,
+		head_hash VARCHAR(64) NOT NULL,
+		count BIGINT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		key_id VARCHAR(64) NOT NULL,
+		algorithm VARCHAR(32) NOT NULL,
+		signature TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS audit_merkle_batches (
+		batch_size INT NOT NULL,
+		batch_index BIGINT NOT NULL,
+		first_seq BIGINT NOT NULL,
+		last_seq BIGINT NOT NULL,
+		root_hash VARCHAR(64) NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (batch_size, batch_index)
+	);
+	CREATE TABLE IF NOT EXISTS audit_timestamps (
+		seq BIGINT PRIMARY KEY REFERENCES audit_checkpoints(seq),
+		tsa TEXT NOT NULL,
+		token BYTEA NOT NULL,
+		gen_time TIMESTAMPTZ NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	);
 	`
 
 	_, err = pool.Exec(ctx, migrationSQL)
@@ -46,41 +139,220 @@ func NewPostgresRepository(ctx context.Context, dbUrl string) (*PostgresReposito
 		return nil, fmt.Errorf("failed to run migration: %w", err)
 	}
 
-	return &PostgresRepository{pool: pool}, nil
+	repo := &PostgresRepository{pool: pool}
+	if err := repo.backfillSequence(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to migrate audit log sequence: %w", err)
+	}
+	return repo, nil
 }
 
 func (r *PostgresRepository) Close() {
 	r.pool.Close()
 }
 
-func (r *PostgresRepository) IterateLogs(ctx context.Context, callback func(*core.LogEntry) error) error {
-	// TODO: Add pagination support. For now, limit to 1000 to prevent DoS.
-	query := `SELECT id, timestamp, actor, action, details, prev_hash, curr_hash FROM audit_logs ORDER BY timestamp ASC LIMIT 1000`
-	rows, err := r.pool.Query(ctx, query)
+// IterateLogs streams the entries in rng in sequence order. Entries are
+// read a page at a time after the last sequence seen, so the whole chain can
+// be walked without holding a connection or loading it into memory.
+func (r *PostgresRepository) IterateLogs(ctx context.Context, rng LogRange, callback func(*core.LogEntry) error) error {
+	from, to, err := r.resolveRange(ctx, rng)
 	if err != nil {
 		return err
 	}
+
+	for from <= to {
+		page, err := r.queryLogs(ctx, `SELECT `+logColumns+` FROM audit_logs
+			WHERE seq >= $1 AND seq <= $2 ORDER BY seq ASC LIMIT $3`, from, to, iteratePageSize)
+		if err != nil {
+			return err
+		}
+		for _, log := range page {
+			if err := callback(log); err != nil {
+				return err
+			}
+		}
+		if len(page) < iteratePageSize {
+			return nil
+		}
+		from = page[len(page)-1].Seq + 1
+	}
+	return nil
+}
+
+// CountLogs returns the number of entries in rng.
+func (r *PostgresRepository) CountLogs(ctx context.Context, rng LogRange) (int64, error) {
+	from, to, err := r.resolveRange(ctx, rng)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	query := `SELECT COUNT(*) FROM audit_logs WHERE seq >= $1 AND seq <= $2`
+	if err := r.pool.QueryRow(ctx, query, from, to).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count logs: %w", err)
+	}
+	return count, nil
+}
+
+// LastLog returns the head of the chain, or nil when it is empty.
+func (r *PostgresRepository) LastLog(ctx context.Context) (*core.LogEntry, error) {
+	logs, err := r.queryLogs(ctx, `SELECT `+logColumns+` FROM audit_logs ORDER BY seq DESC LIMIT 1`)
+	if err != nil || len(logs) == 0 {
+		return nil, err
+	}
+	return logs[0], nil
+}
+
+// LastLogIn returns the last entry in rng, or nil when rng is empty.
+func (r *PostgresRepository) LastLogIn(ctx context.Context, rng LogRange) (*core.LogEntry, error) {
+	from, to, err := r.resolveRange(ctx, rng)
+	if err != nil || from > to {
+		return nil, err
+	}
+	logs, err := r.queryLogs(ctx, `SELECT `+logColumns+` FROM audit_logs
+		WHERE seq >= $1 AND seq <= $2 ORDER BY seq DESC LIMIT 1`, from, to)
+	if err != nil || len(logs) == 0 {
+		return nil, err
+	}
+	return logs[0], nil
+}
+
+// GetLog returns the entry with the given ID.
+func (r *PostgresRepository) GetLog(ctx context.Context, id string) (*core.LogEntry, error) {
+	logs, err := r.queryLogs(ctx, `SELECT `+logColumns+` FROM audit_logs WHERE id::text = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrLogNotFound, id)
+	}
+	return logs[0], nil
+}
+
+const logColumns = `seq, id, timestamp, actor, action, details, prev_hash, curr_hash, hash_version, msg_id`
+
+func (r *PostgresRepository) queryLogs(ctx context.Context, query string, args ...interface{}) ([]*core.LogEntry, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logs: %w", err)
+	}
 	defer rows.Close()
 
+	var logs []*core.LogEntry
 	for rows.Next() {
-		var log core.LogEntry
-		err := rows.Scan(&log.ID, &log.Timestamp, &log.Actor, &log.Action, &log.Details, &log.PrevHash, &log.CurrHash)
+		log, err := scanLog(rows)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if err := callback(&log); err != nil {
-			return err
+		logs = append(logs, log)
+	}
+	return logs, rows.Err()
+}
+
+func scanLog(row pgx.Row) (*core.LogEntry, error) {
+	var log core.LogEntry
+	var msgID *string
+	if err := row.Scan(&log.Seq, &log.ID, &log.Timestamp, &log.Actor, &log.Action, &log.Details, &log.PrevHash, &log.CurrHash, &log.HashVersion, &msgID); err != nil {
+		return nil, err
+	}
+	if msgID != nil {
+		log.MsgID = *msgID
+	}
+	return &log, nil
+}
+
+// resolveRange turns rng into inclusive sequence bounds. An empty range
+// comes back with from > to.
+func (r *PostgresRepository) resolveRange(ctx context.Context, rng LogRange) (int64, int64, error) {
+	from, to := int64(1), int64(math.MaxInt64)
+	narrow := func(lo, hi int64) {
+		from = max(from, lo)
+		to = min(to, hi)
+	}
+
+	if rng.FromSeq > 0 {
+		narrow(rng.FromSeq, math.MaxInt64)
+	}
+	if rng.ToSeq > 0 {
+		narrow(1, rng.ToSeq)
+	}
+	for _, id := range []struct {
+		id   string
+		from bool
+	}{{rng.FromID, true}, {rng.ToID, false}} {
+		if id.id == "" {
+			continue
+		}
+		seq, err := r.seqOf(ctx, id.id)
+		if err != nil {
+			return 0, 0, err
+		}
+		if id.from {
+			narrow(seq, math.MaxInt64)
+		} else {
+			narrow(1, seq)
 		}
 	}
-	return rows.Err()
+	if !rng.From.IsZero() {
+		var seq *int64
+		if err := r.pool.QueryRow(ctx, `SELECT MIN(seq) FROM audit_logs WHERE timestamp >= $1`, rng.From).Scan(&seq); err != nil {
+			return 0, 0, fmt.Errorf("failed to resolve range start: %w", err)
+		}
+		if seq == nil {
+			return 1, 0, nil
+		}
+		narrow(*seq, math.MaxInt64)
+	}
+	if !rng.To.IsZero() {
+		var seq *int64
+		if err := r.pool.QueryRow(ctx, `SELECT MAX(seq) FROM audit_logs WHERE timestamp <= $1`, rng.To).Scan(&seq); err != nil {
+			return 0, 0, fmt.Errorf("failed to resolve range end: %w", err)
+		}
+		if seq == nil {
+			return 1, 0, nil
+		}
+		narrow(1, *seq)
+	}
+	return from, to, nil
 }
 
+func (r *PostgresRepository) seqOf(ctx context.Context, id string) (int64, error) {
+	var seq int64
+	err := r.pool.QueryRow(ctx, `SELECT seq FROM audit_logs WHERE id::text = $1`, id).Scan(&seq)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("%w: %s", ErrLogNotFound, id)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up log %s: %w", id, err)
+	}
+	return seq, nil
+}
+
+// AppendLog appends one entry to the chain. It returns ErrDuplicateLog,
+// with log filled in, if an entry with the same MsgID is already in it.
 func (r *PostgresRepository) AppendLog(ctx context.Context, log *core.LogEntry, hasher core.Hasher) error {
+	duplicate, err := r.AppendLogs(ctx, []*core.LogEntry{log}, hasher)
+	if err != nil {
+		return err
+	}
+	if duplicate[0] {
+		return ErrDuplicateLog
+	}
+	return nil
+}
+
+// AppendLogs appends logs to the chain in order in one transaction, so
+// either all of them are written or none are. duplicate[i] reports that
+// logs[i] has the MsgID of an entry already in the chain, or earlier in
+// logs; it is filled in with that entry instead of being written.
+func (r *PostgresRepository) AppendLogs(ctx context.Context, logs []*core.LogEntry, hasher core.Hasher) (duplicate []bool, err error) {
+	if len(logs) == 0 {
+		return nil, nil
+	}
 	maxRetries := 5
 	for i := 0; i < maxRetries; i++ {
-		err := r.appendLogTx(ctx, log, hasher)
+		duplicate, err = r.appendLogsTx(ctx, logs, hasher)
 		if err == nil {
-			return nil
+			return duplicate, nil
 		}
 
 		// Check for serialization failure (SQLState 40001)
@@ -88,49 +360,118 @@ func (r *PostgresRepository) AppendLog(ctx context.Context, log *core.LogEntry,
 			// Exponential backoff could be added here, but simple retry is often enough for optimistic locking
 			continue
 		}
+		// A concurrent delivery of the same message got there first; the
+		// retry finds its entry.
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_audit_logs_msg_id" {
+			continue
+		}
 
-		return err
+		return nil, err
 	}
-	return fmt.Errorf("failed to append log after %d retries", maxRetries)
+	return nil, fmt.Errorf("failed to append log after %d retries", maxRetries)
 }
 
-func (r *PostgresRepository) appendLogTx(ctx context.Context, log *core.LogEntry, hasher core.Hasher) error {
+func (r *PostgresRepository) appendLogsTx(ctx context.Context, logs []*core.LogEntry, hasher core.Hasher) ([]bool, error) {
 	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// Get last log
+	// Redelivered messages are already in the chain.
+	var msgIDs []string
+	for _, log := range logs {
+		if log.MsgID != "" {
+			msgIDs = append(msgIDs, log.MsgID)
+		}
+	}
+	existing := make(map[string]*core.LogEntry)
+	if len(msgIDs) > 0 {
+		rows, err := tx.Query(ctx, `SELECT `+logColumns+` FROM audit_logs WHERE msg_id = ANY($1)`, msgIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up messages: %w", err)
+		}
+		for rows.Next() {
+			log, err := scanLog(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			existing[log.MsgID] = log
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to look up messages: %w", err)
+		}
+	}
+
+	// Get last log. The chain is ordered by seq, not timestamp: events such
+	// as alarms carry their own timestamps, which may be older than entries
+	// already written.
+	var lastSeq int64
 	var prevHash string
-	query := `SELECT curr_hash FROM audit_logs ORDER BY timestamp DESC LIMIT 1`
-	err = tx.QueryRow(ctx, query).Scan(&prevHash)
+	query := `SELECT seq, curr_hash FROM audit_logs ORDER BY seq DESC LIMIT 1`
+	err = tx.QueryRow(ctx, query).Scan(&lastSeq, &prevHash)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			prevHash = "0000000000000000000000000000000000000000000000000000000000000000" // Genesis hash
+			prevHash = core.GenesisHash
 		} else {
-			return fmt.Errorf("failed to get last log: %w", err)
+			return nil, fmt.Errorf("failed to get last log: %w", err)
 		}
 	}
 
-	// Calculate new hash (using the timestamp already set in log.Timestamp)
-	log.PrevHash = prevHash
-	log.CurrHash = hasher.Hash(prevHash, log)
+	// Chain the batch in memory and send the inserts in one round trip.
+	duplicate := make([]bool, len(logs))
+	firstOf := make(map[string]int)
+	var repeats []int
+	batch := &pgx.Batch{}
+	insertQuery := `INSERT INTO audit_logs (seq, timestamp, actor, action, details, prev_hash, curr_hash, hash_version, msg_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	for i, log := range logs {
+		if log.MsgID != "" {
+			if e, ok := existing[log.MsgID]; ok {
+				*log = *e
+				duplicate[i] = true
+				continue
+			}
+			if _, ok := firstOf[log.MsgID]; ok {
+				duplicate[i] = true
+				repeats = append(repeats, i)
+				continue
+			}
+			firstOf[log.MsgID] = i
+		}
 
-	// Debug: log what we're inserting
-	// fmt.Printf("DEBUG INSERT: timestamp=%v, actor=%s, action=%s, details=%s, hash=%s\n",
-	// 	log.Timestamp, log.Actor, log.Action, string(log.Details), log.CurrHash)
+		lastSeq++
+		log.Seq = lastSeq
+		log.HashVersion = hasher.Version()
+		// Calculate new hash (using the timestamp already set in log.Timestamp)
+		log.PrevHash = prevHash
+		log.CurrHash = hasher.Hash(prevHash, log)
+		prevHash = log.CurrHash
 
-	// Insert with explicit timestamp to match hash calculation
-	insertQuery := `INSERT INTO audit_logs (timestamp, actor, action, details, prev_hash, curr_hash) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
-	err = tx.QueryRow(ctx, insertQuery, log.Timestamp, log.Actor, log.Action, log.Details, log.PrevHash, log.CurrHash).Scan(&log.ID)
-	if err != nil {
-		return err // Let the caller handle wrapping/checking
+		// Insert with explicit timestamp to match hash calculation
+		var msgID *string
+		if log.MsgID != "" {
+			msgID = &log.MsgID
+		}
+		batch.Queue(insertQuery, log.Seq, log.Timestamp, log.Actor, log.Action, log.Details, log.PrevHash, log.CurrHash, log.HashVersion, msgID).
+			QueryRow(func(row pgx.Row) error {
+				return row.Scan(&log.ID)
+			})
+	}
+	if batch.Len() > 0 {
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return nil, err // Let the caller handle wrapping/checking
+		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	// Repeats within the batch resolve to the entry that was written.
+	for _, i := range repeats {
+		*logs[i] = *logs[firstOf[logs[i].MsgID]]
+	}
+	return duplicate, nil
 }
@@ -3,6 +3,8 @@ package repository_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"sync"
 	"testing"
@@ -25,7 +27,7 @@ func TestPostgresRepository_Integration(t *testing.T) {
 	}
 	defer repo.Close()
 
-	hasher := core.NewSHA256Hasher()
+	hasher := core.NewSHA256HasherV2()
 
 	// Test Concurrent Writes
 	var wg sync.WaitGroup
@@ -56,15 +58,19 @@ func TestPostgresRepository_Integration(t *testing.T) {
 	// The first log in DB should have 00...00 as prevHash.
 	
 	var prevHash string = "0000000000000000000000000000000000000000000000000000000000000000"
-	err = repo.IterateLogs(ctx, func(log *core.LogEntry) error {
+	err = repo.IterateLogs(ctx, repository.LogRange{}, func(log *core.LogEntry) error {
 		if log.PrevHash != prevHash {
 			// If this is the very first log ever, it should match.
 			// If we are appending to existing DB, we might start verification from middle?
-			// IterateLogs starts from ORDER BY timestamp ASC.
+			// IterateLogs starts from ORDER BY seq ASC.
 			// So it should be the first log.
 			t.Errorf("Chain broken at %s: prev %s != expected %s", log.ID, log.PrevHash, prevHash)
 		}
-		calculated := hasher.Hash(prevHash, log)
+		logHasher, err := core.NewHashers().ForVersion(log.HashVersion)
+		if err != nil {
+			t.Fatalf("Entry %s: %v", log.ID, err)
+		}
+		calculated := logHasher.Hash(prevHash, log)
 		if log.CurrHash != calculated {
 			t.Errorf("Hash mismatch at %s", log.ID)
 		}
@@ -75,3 +81,205 @@ func TestPostgresRepository_Integration(t *testing.T) {
 		t.Errorf("IterateLogs failed: %v", err)
 	}
 }
+
+func TestPostgresRepository_IterateLogsPastOnePage(t *testing.T) {
+	dbUrl := os.Getenv("DB_URL")
+	if dbUrl == "" {
+		t.Skip("Skipping integration test: DB_URL not set")
+	}
+
+	ctx := context.Background()
+	repo, err := repository.NewPostgresRepository(ctx, dbUrl)
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+	defer repo.Close()
+
+	hasher := core.NewSHA256HasherV2()
+	var ids []string
+	for i := 0; i < 1005; i++ {
+		log := &core.LogEntry{Timestamp: time.Now(), Actor: "tester", Action: "page_test", Details: json.RawMessage(`{}`)}
+		if err := repo.AppendLog(ctx, log, hasher); err != nil {
+			t.Fatalf("Failed to append log %d: %v", i, err)
+		}
+		ids = append(ids, log.ID)
+	}
+
+	total, err := repo.CountLogs(ctx, repository.LogRange{})
+	if err != nil {
+		t.Fatalf("CountLogs failed: %v", err)
+	}
+	verifier := core.NewChainVerifier(core.NewHashers())
+	if err := repo.IterateLogs(ctx, repository.LogRange{}, func(log *core.LogEntry) error {
+		verifier.Add(log)
+		return nil
+	}); err != nil {
+		t.Fatalf("IterateLogs failed: %v", err)
+	}
+	if result := verifier.Result(); result.Checked != total || !result.Valid {
+		t.Errorf("Expected %d valid entries, got %+v", total, result)
+	}
+
+	rng := repository.LogRange{FromID: ids[10], ToID: ids[999]}
+	count, err := repo.CountLogs(ctx, rng)
+	if err != nil || count != 990 {
+		t.Errorf("Expected 990 entries in range, got %d (%v)", count, err)
+	}
+	rangeVerifier := core.NewRangeVerifier(core.NewHashers())
+	repo.IterateLogs(ctx, rng, func(log *core.LogEntry) error {
+		rangeVerifier.Add(log)
+		return nil
+	})
+	if result := rangeVerifier.Result(); result.FirstID != ids[10] || result.LastID != ids[999] || !result.Valid {
+		t.Errorf("Unexpected range result %+v", result)
+	}
+	if last, err := repo.LastLogIn(ctx, rng); err != nil || last == nil || last.ID != ids[999] {
+		t.Errorf("Expected the range to end at %s, got %+v (%v)", ids[999], last, err)
+	}
+	if last, err := repo.LastLogIn(ctx, repository.LogRange{From: time.Now().Add(time.Hour)}); err != nil || last != nil {
+		t.Errorf("Expected no last entry in an empty range, got %+v (%v)", last, err)
+	}
+
+	if _, err := repo.CountLogs(ctx, repository.LogRange{FromID: "00000000-0000-0000-0000-000000000000"}); !errors.Is(err, repository.ErrLogNotFound) {
+		t.Errorf("Expected ErrLogNotFound, got %v", err)
+	}
+}
+
+func TestPostgresRepository_OutOfOrderTimestamps(t *testing.T) {
+	dbUrl := os.Getenv("DB_URL")
+	if dbUrl == "" {
+		t.Skip("Skipping integration test: DB_URL not set")
+	}
+
+	ctx := context.Background()
+	repo, err := repository.NewPostgresRepository(ctx, dbUrl)
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+	defer repo.Close()
+
+	hasher := core.NewSHA256HasherV2()
+	now := time.Now()
+	// An alarm event arriving late carries a timestamp older than entries
+	// already in the chain.
+	var appended []*core.LogEntry
+	for _, ts := range []time.Time{now, now.Add(-time.Hour), now.Add(time.Second)} {
+		log := &core.LogEntry{Timestamp: ts, Actor: "tester", Action: "late_event", Details: json.RawMessage(`{}`)}
+		if err := repo.AppendLog(ctx, log, hasher); err != nil {
+			t.Fatalf("Failed to append log: %v", err)
+		}
+		appended = append(appended, log)
+	}
+	for i := 1; i < len(appended); i++ {
+		if appended[i].Seq != appended[i-1].Seq+1 {
+			t.Errorf("Expected consecutive sequence numbers, got %d after %d", appended[i].Seq, appended[i-1].Seq)
+		}
+	}
+
+	verifier := core.NewRangeVerifier(core.NewHashers())
+	repo.IterateLogs(ctx, repository.LogRange{FromSeq: appended[0].Seq}, func(log *core.LogEntry) error {
+		verifier.Add(log)
+		return nil
+	})
+	if result := verifier.Result(); !result.Valid || result.Checked < 3 {
+		t.Errorf("Expected chain with a late event to verify, got %+v", result)
+	}
+}
+
+func TestPostgresRepository_DuplicateMsgID(t *testing.T) {
+	dbUrl := os.Getenv("DB_URL")
+	if dbUrl == "" {
+		t.Skip("Skipping integration test: DB_URL not set")
+	}
+
+	ctx := context.Background()
+	repo, err := repository.NewPostgresRepository(ctx, dbUrl)
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+	defer repo.Close()
+
+	hasher := core.NewSHA256HasherV2()
+	msgID := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	// Concurrent redeliveries of one message land once.
+	var wg sync.WaitGroup
+	entries := make([]*core.LogEntry, 5)
+	errs := make([]error, len(entries))
+	for i := range entries {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			entries[i] = &core.LogEntry{Timestamp: time.Now(), Actor: "tester", Action: "redelivered", Details: json.RawMessage(`{}`), MsgID: msgID}
+			errs[i] = repo.AppendLog(ctx, entries[i], hasher)
+		}(i)
+	}
+	wg.Wait()
+
+	written := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			written++
+		case !errors.Is(err, repository.ErrDuplicateLog):
+			t.Fatalf("Failed to append log: %v", err)
+		}
+		if entries[i].Seq != entries[0].Seq || entries[i].MsgID != msgID {
+			t.Errorf("Expected every delivery to resolve to one entry, got seq %d and %d", entries[i].Seq, entries[0].Seq)
+		}
+	}
+	if written != 1 {
+		t.Errorf("Expected the message to be written once, got %d", written)
+	}
+}
+
+func TestPostgresRepository_AppendLogs(t *testing.T) {
+	dbUrl := os.Getenv("DB_URL")
+	if dbUrl == "" {
+		t.Skip("Skipping integration test: DB_URL not set")
+	}
+
+	ctx := context.Background()
+	repo, err := repository.NewPostgresRepository(ctx, dbUrl)
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+	defer repo.Close()
+
+	hasher := core.NewSHA256HasherV2()
+	prefix := fmt.Sprintf("batch-%d-", time.Now().UnixNano())
+	already := &core.LogEntry{Timestamp: time.Now(), Actor: "tester", Action: "batch", Details: json.RawMessage(`{}`), MsgID: prefix + "0"}
+	if err := repo.AppendLog(ctx, already, hasher); err != nil {
+		t.Fatalf("Failed to append log: %v", err)
+	}
+
+	// A batch with an entry already in the chain and a repeat within it.
+	var batch []*core.LogEntry
+	for _, n := range []int{1, 0, 2, 1, 3} {
+		batch = append(batch, &core.LogEntry{Timestamp: time.Now(), Actor: "tester", Action: "batch", Details: json.RawMessage(`{}`), MsgID: fmt.Sprintf("%s%d", prefix, n)})
+	}
+	duplicate, err := repo.AppendLogs(ctx, batch, hasher)
+	if err != nil {
+		t.Fatalf("AppendLogs failed: %v", err)
+	}
+	want := []bool{false, true, false, true, false}
+	for i := range batch {
+		if duplicate[i] != want[i] {
+			t.Errorf("Entry %d: expected duplicate %v, got %v", i, want[i], duplicate[i])
+		}
+	}
+	if batch[1].Seq != already.Seq || batch[3].Seq != batch[0].Seq || batch[3].ID != batch[0].ID {
+		t.Errorf("Expected duplicates to resolve to the recorded entries, got %+v", batch)
+	}
+	if batch[2].Seq != batch[0].Seq+1 || batch[4].Seq != batch[2].Seq+1 || batch[2].PrevHash != batch[0].CurrHash {
+		t.Errorf("Expected the new entries chained in order, got %+v", batch)
+	}
+
+	verifier := core.NewRangeVerifier(core.NewHashers())
+	repo.IterateLogs(ctx, repository.LogRange{FromSeq: already.Seq}, func(log *core.LogEntry) error {
+		verifier.Add(log)
+		return nil
+	})
+	if result := verifier.Result(); !result.Valid || result.Checked < 4 {
+		t.Errorf("Expected batched entries to verify, got %+v", result)
+	}
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+)
+
+// Orders a search can be sorted by. Both break ties by seq, so pages never
+// skip or repeat an entry.
+const (
+	SortSeq       = "seq"
+	SortTimestamp = "timestamp"
+)
+
+// LogQuery filters and pages audit log entries. Zero fields don't filter.
+type LogQuery struct {
+	Actor string
+	// Action matches exactly, ActionPrefix matches the start of the action.
+	Action       string
+	ActionPrefix string
+	// From and To bound the timestamp, inclusively.
+	From time.Time
+	To   time.Time
+	// DetailsPath is a path of keys (or array indexes) into details; the
+	// value there, as text, must equal DetailsValue.
+	DetailsPath  []string
+	DetailsValue string
+
+	Sort       string
+	Descending bool
+	// After continues a previous search after the last entry it returned.
+	After *LogCursor
+	Limit int
+}
+
+// LogCursor is the position of an entry in a search's sort order.
+type LogCursor struct {
+	Seq       int64     `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CursorOf returns the cursor to continue a search after log.
+func CursorOf(log *core.LogEntry) *LogCursor {
+	return &LogCursor{Seq: log.Seq, Timestamp: log.Timestamp}
+}
+
+// SearchLogs returns up to q.Limit entries matching q in its sort order.
+func (r *PostgresRepository) SearchLogs(ctx context.Context, q LogQuery) ([]*core.LogEntry, error) {
+	query, args, err := buildSearchQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	return r.queryLogs(ctx, query, args...)
+}
+
+func buildSearchQuery(q LogQuery) (string, []interface{}, error) {
+	var where []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q.Actor != "" {
+		where = append(where, "actor = "+arg(q.Actor))
+	}
+	if q.Action != "" {
+		where = append(where, "action = "+arg(q.Action))
+	}
+	if q.ActionPrefix != "" {
+		where = append(where, "action LIKE "+arg(escapeLike(q.ActionPrefix)+"%"))
+	}
+	if !q.From.IsZero() {
+		where = append(where, "timestamp >= "+arg(q.From))
+	}
+	if !q.To.IsZero() {
+		where = append(where, "timestamp <= "+arg(q.To))
+	}
+	if len(q.DetailsPath) > 0 {
+		where = append(where, "details #>> "+arg(q.DetailsPath)+"::text[] = "+arg(q.DetailsValue))
+	}
+
+	cmp, dir := ">", "ASC"
+	if q.Descending {
+		cmp, dir = "<", "DESC"
+	}
+	var order string
+	switch q.Sort {
+	case SortSeq, "":
+		if q.After != nil {
+			where = append(where, "seq "+cmp+" "+arg(q.After.Seq))
+		}
+		order = "seq " + dir
+	case SortTimestamp:
+		if q.After != nil {
+			where = append(where, fmt.Sprintf("(timestamp, seq) %s (%s, %s)", cmp, arg(q.After.Timestamp), arg(q.After.Seq)))
+		}
+		order = fmt.Sprintf("timestamp %s, seq %s", dir, dir)
+	default:
+		return "", nil, fmt.Errorf("unknown sort %q", q.Sort)
+	}
+
+	query := `SELECT ` + logColumns + ` FROM audit_logs`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY ` + order + ` LIMIT ` + arg(q.Limit)
+	return query, args, nil
+}
+
+// escapeLike makes s match literally in a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
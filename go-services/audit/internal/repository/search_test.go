@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildSearchQuery(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	query, args, err := buildSearchQuery(LogQuery{
+		Actor:        "alice",
+		ActionPrefix: "ALARM_%",
+		From:         from,
+		DetailsPath:  []string{"tag", "name"},
+		DetailsValue: "TI-101",
+		Sort:         SortTimestamp,
+		Descending:   true,
+		After:        &LogCursor{Seq: 42, Timestamp: from},
+		Limit:        50,
+	})
+	if err != nil {
+		t.Fatalf("buildSearchQuery failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"actor = $1",
+		"action LIKE $2",
+		"timestamp >= $3",
+		"details #>> $4::text[] = $5",
+		"(timestamp, seq) < ($6, $7)",
+		"ORDER BY timestamp DESC, seq DESC LIMIT $8",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("Expected query to contain %q, got %s", want, query)
+		}
+	}
+	if len(args) != 8 || args[1] != `ALARM\_\%%` || args[7] != 50 {
+		t.Errorf("Unexpected args %v", args)
+	}
+}
+
+func TestBuildSearchQuery_Defaults(t *testing.T) {
+	query, args, err := buildSearchQuery(LogQuery{After: &LogCursor{Seq: 7}, Limit: 10})
+	if err != nil {
+		t.Fatalf("buildSearchQuery failed: %v", err)
+	}
+	if !strings.HasSuffix(query, "WHERE seq > $1 ORDER BY seq ASC LIMIT $2") || len(args) != 2 {
+		t.Errorf("Unexpected query %s %v", query, args)
+	}
+
+	if _, _, err := buildSearchQuery(LogQuery{Sort: "actor"}); err == nil {
+		t.Error("Expected unknown sort to be rejected")
+	}
+}
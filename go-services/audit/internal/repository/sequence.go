@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+// legacyLog is what the sequence backfill needs to know about an entry
+// written before audit_logs had a seq column.
+type legacyLog struct {
+	id        string
+	timestamp time.Time
+	prevHash  string
+	currHash  string
+}
+
+// backfillSequence numbers entries that have no seq yet, which is every
+// entry written before the column existed. Those entries were chained to
+// whichever entry had the latest timestamp when they were appended, so
+// their prev_hash links, not their timestamps, record the order they were
+// written in. The backfill follows the links from the end of the numbered
+// chain; entries that can't be reached that way were already broken and are
+// numbered after the rest by timestamp, where verification will report
+// them.
+func (r *PostgresRepository) backfillSequence(ctx context.Context) error {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `LOCK TABLE audit_logs IN EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("failed to lock audit_logs: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id::text, timestamp, prev_hash, curr_hash FROM audit_logs WHERE seq IS NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to read unsequenced logs: %w", err)
+	}
+	var logs []legacyLog
+	for rows.Next() {
+		var l legacyLog
+		if err := rows.Scan(&l.id, &l.timestamp, &l.prevHash, &l.currHash); err != nil {
+			rows.Close()
+			return err
+		}
+		logs = append(logs, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(logs) == 0 {
+		return requireSequence(ctx, tx)
+	}
+
+	var lastSeq int64
+	lastHash := core.GenesisHash
+	err = tx.QueryRow(ctx, `SELECT seq, curr_hash FROM audit_logs WHERE seq IS NOT NULL ORDER BY seq DESC LIMIT 1`).Scan(&lastSeq, &lastHash)
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("failed to get last sequenced log: %w", err)
+	}
+
+	ordered, linked := orderByLinks(lastHash, logs)
+	ids := make([]string, len(ordered))
+	seqs := make([]int64, len(ordered))
+	for i, l := range ordered {
+		ids[i] = l.id
+		seqs[i] = lastSeq + int64(i) + 1
+	}
+	_, err = tx.Exec(ctx, `UPDATE audit_logs AS a SET seq = v.seq
+		FROM unnest($1::text[], $2::bigint[]) AS v(id, seq) WHERE a.id = v.id::uuid`, ids, seqs)
+	if err != nil {
+		return fmt.Errorf("failed to number logs: %w", err)
+	}
+	if err := requireSequence(ctx, tx); err != nil {
+		return err
+	}
+
+	log.Printf("Numbered %d audit log entries by their hash chain", len(ordered))
+	if unlinked := len(ordered) - linked; unlinked > 0 {
+		log.Printf("Warning: %d audit log entries are not reachable from the chain and were numbered by timestamp", unlinked)
+	}
+	return nil
+}
+
+// requireSequence makes seq mandatory once every entry has one, so a writer
+// that doesn't know about it fails instead of forking the chain, and
+// commits tx.
+func requireSequence(ctx context.Context, tx pgx.Tx) error {
+	if _, err := tx.Exec(ctx, `ALTER TABLE audit_logs ALTER COLUMN seq SET NOT NULL`); err != nil {
+		return fmt.Errorf("failed to require seq: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// orderByLinks orders logs by following prev_hash links from start. Where
+// several entries share a predecessor, which only happens when entries were
+// appended with out-of-order timestamps, the earlier timestamp goes first.
+// Entries that can't be reached follow in timestamp order. It also returns
+// how many entries were reached.
+func orderByLinks(start string, logs []legacyLog) ([]legacyLog, int) {
+	sorted := append([]legacyLog(nil), logs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].timestamp.Equal(sorted[j].timestamp) {
+			return sorted[i].timestamp.Before(sorted[j].timestamp)
+		}
+		return sorted[i].id < sorted[j].id
+	})
+
+	children := make(map[string][]int)
+	for i, l := range sorted {
+		children[l.prevHash] = append(children[l.prevHash], i)
+	}
+
+	ordered := make([]legacyLog, 0, len(sorted))
+	visited := make([]bool, len(sorted))
+	// Depth-first, pushing children in reverse so the earliest is visited
+	// first.
+	var stack []int
+	push := func(hash string) {
+		next := children[hash]
+		for i := len(next) - 1; i >= 0; i-- {
+			stack = append(stack, next[i])
+		}
+	}
+	push(start)
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[i] {
+			continue
+		}
+		visited[i] = true
+		ordered = append(ordered, sorted[i])
+		push(sorted[i].currHash)
+	}
+	linked := len(ordered)
+
+	for i, l := range sorted {
+		if !visited[i] {
+			ordered = append(ordered, l)
+		}
+	}
+	return ordered, linked
+}
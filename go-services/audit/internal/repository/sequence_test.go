@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+)
+
+func TestOrderByLinks(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return base.Add(time.Duration(seconds) * time.Second) }
+
+	// b was appended after a with an older timestamp, so c, appended next,
+	// was chained to a (the latest timestamp) as well.
+	logs := []legacyLog{
+		{id: "c", timestamp: at(11), prevHash: "A", currHash: "C"},
+		{id: "a", timestamp: at(10), prevHash: core.GenesisHash, currHash: "A"},
+		{id: "orphan", timestamp: at(1), prevHash: "X", currHash: "O"},
+		{id: "b", timestamp: at(5), prevHash: "A", currHash: "B"},
+		{id: "d", timestamp: at(12), prevHash: "C", currHash: "D"},
+	}
+
+	ordered, linked := orderByLinks(core.GenesisHash, logs)
+	var got []string
+	for _, l := range ordered {
+		got = append(got, l.id)
+	}
+	want := []string{"a", "b", "c", "d", "orphan"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+	if linked != 4 {
+		t.Errorf("Expected 4 linked entries, got %d", linked)
+	}
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveTimestamp stores a checkpoint's timestamp token. A checkpoint keeps
+// the first token stored for it.
+func (r *PostgresRepository) SaveTimestamp(ctx context.Context, ts *core.CheckpointTimestamp) error {
+	_, err := r.pool.Exec(ctx, `INSERT INTO audit_timestamps (seq, tsa, token, gen_time, created_at)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (seq) DO NOTHING`,
+		ts.Seq, ts.TSA, ts.Token, ts.GenTime, ts.CreatedAt)
+	return err
+}
+
+// ListTimestamps returns every checkpoint timestamp in sequence order.
+func (r *PostgresRepository) ListTimestamps(ctx context.Context) ([]*core.CheckpointTimestamp, error) {
+	rows, err := r.pool.Query(ctx, `SELECT seq, tsa, token, gen_time, created_at FROM audit_timestamps ORDER BY seq ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query timestamps: %w", err)
+	}
+	timestamps, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*core.CheckpointTimestamp, error) {
+		var ts core.CheckpointTimestamp
+		err := row.Scan(&ts.Seq, &ts.TSA, &ts.Token, &ts.GenTime, &ts.CreatedAt)
+		ts.GenTime, ts.CreatedAt = ts.GenTime.UTC(), ts.CreatedAt.UTC()
+		return &ts, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read timestamps: %w", err)
+	}
+	return timestamps, nil
+}
+
+// ListUntimestampedCheckpoints returns the checkpoints that have no
+// timestamp yet, in sequence order.
+func (r *PostgresRepository) ListUntimestampedCheckpoints(ctx context.Context) ([]*core.Checkpoint, error) {
+	return r.queryCheckpoints(ctx, `SELECT `+checkpointColumns+` FROM audit_checkpoints c
+		WHERE NOT EXISTS (SELECT 1 FROM audit_timestamps t WHERE t.seq = c.seq) ORDER BY seq ASC`)
+}
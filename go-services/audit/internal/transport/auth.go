@@ -0,0 +1,127 @@
+package transport
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Roles from the auth service that may read the audit log.
+const (
+	RoleAdmin   = "ADMIN"
+	RoleAuditor = "AUDITOR"
+)
+
+type contextKey string
+
+const userKey contextKey = "user"
+
+// User is the authenticated caller of a request.
+type User struct {
+	ID       string
+	Username string
+	Role     string
+}
+
+// UserFromContext returns the user Authenticator.RequireRole let through.
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userKey).(*User)
+	return user, ok
+}
+
+// Authenticator accepts access tokens issued by the auth service's
+// /api/v1/login: RS256 JWTs with type "access".
+type Authenticator struct {
+	publicKey *rsa.PublicKey
+}
+
+// NewAuthenticator loads the auth service's RSA public key from a PEM
+// file, in PKIX ("PUBLIC KEY") or PKCS#1 ("RSA PUBLIC KEY") form.
+func NewAuthenticator(publicKeyPath string) (*Authenticator, error) {
+	data, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth public key: %w", err)
+	}
+	key, err := parseRSAPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse auth public key: %w", err)
+	}
+	return &Authenticator{publicKey: key}, nil
+}
+
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not RSA")
+		}
+		return rsaKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*User, error) {
+	header := r.Header.Get("Authorization")
+	tokenString, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || tokenString == "" {
+		return nil, errors.New("missing or invalid Authorization header")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return a.publicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if claims["type"] != "access" {
+		return nil, errors.New("not an access token")
+	}
+
+	user := &User{}
+	user.Username, _ = claims["username"].(string)
+	user.Role, _ = claims["role"].(string)
+	if sub, ok := claims["sub"]; ok {
+		user.ID = fmt.Sprint(sub)
+	}
+	return user, nil
+}
+
+// RequireRole only lets requests through with a valid access token for
+// one of roles.
+func (a *Authenticator) RequireRole(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := a.authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		for _, role := range roles {
+			if user.Role == role {
+				next(w, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+				return
+			}
+		}
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}
+}
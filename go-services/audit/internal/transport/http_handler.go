@@ -1,66 +1,669 @@
 package transport
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	stdlog "log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/checkpoint"
 	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/deadletter"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/export"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/proof"
 	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
 )
 
+// progressInterval is how many entries are verified between progress
+// reports.
+const progressInterval = 10000
+
 type HttpHandler struct {
-	repo   repository.Repository
-	hasher core.Hasher
+	repo        repository.Repository
+	hashers     *core.Hashers
+	checkpoints core.CheckpointVerifier
+	publicKey   *checkpoint.PublicKey
+	timestamps  core.TimestampVerifier
+	prover      *proof.Prover
+	signer      *checkpoint.Signer
+	exportKeys  []*checkpoint.PublicKey
+	deadLetters *deadletter.Queue
+	writer      LogWriter
+}
+
+// LogWriter appends entries the service records itself to the chain.
+// AuditConsumer is one, so they go through the chain's single writer.
+type LogWriter interface {
+	Append(ctx context.Context, log *core.LogEntry) error
+}
+
+func NewHttpHandler(repo repository.Repository, hashers *core.Hashers) *HttpHandler {
+	return &HttpHandler{repo: repo, hashers: hashers}
+}
+
+// SetCheckpoints makes verification check the chain against stored
+// checkpoints and publishes the key they are signed with.
+func (h *HttpHandler) SetCheckpoints(verifier core.CheckpointVerifier, publicKey *checkpoint.PublicKey) {
+	h.checkpoints = verifier
+	h.publicKey = publicKey
+}
+
+// SetTimestamps makes verification check the stored checkpoint timestamp
+// tokens. It only has an effect along with SetCheckpoints.
+func (h *HttpHandler) SetTimestamps(verifier core.TimestampVerifier) {
+	h.timestamps = verifier
+}
+
+// SetExport makes export bundles carry a checkpoint of their last entry
+// signed by signer, and the public keys their checkpoints verify with.
+func (h *HttpHandler) SetExport(signer *checkpoint.Signer, publicKeys []*checkpoint.PublicKey) {
+	h.signer = signer
+	h.exportKeys = publicKeys
+}
+
+// SetLogWriter sets where entries recording exports are written. Export
+// is refused without one.
+func (h *HttpHandler) SetLogWriter(w LogWriter) {
+	h.writer = w
+}
+
+// verifyProgress is streamed while a verification runs when the client
+// accepts application/x-ndjson.
+type verifyProgress struct {
+	Checked int64 `json:"checked"`
+	Total   int64 `json:"total"`
+	Broken  int   `json:"broken"`
 }
 
-func NewHttpHandler(repo repository.Repository, hasher core.Hasher) *HttpHandler {
-	return &HttpHandler{repo: repo, hasher: hasher}
+// verifyResponse keeps broken_id, the first broken entry, for existing
+// clients alongside every broken link.
+type verifyResponse struct {
+	*core.VerifyResult
+	BrokenID string `json:"broken_id,omitempty"`
 }
 
+// Verify walks the chain, or the part selected by from_seq/to_seq,
+// from_id/to_id or from/to (RFC 3339), and reports every broken link.
 func (h *HttpHandler) Verify(w http.ResponseWriter, r *http.Request) {
-	var prevHash string = "0000000000000000000000000000000000000000000000000000000000000000"
-	var brokenID string
-	valid := true
+	rng, err := parseLogRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	total, err := h.repo.CountLogs(r.Context(), rng)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
 
+	verifier := core.NewChainVerifier(h.hashers)
+	if !rng.IsZero() {
+		verifier = core.NewRangeVerifier(h.hashers)
+	}
 
-	err := h.repo.IterateLogs(r.Context(), func(log *core.LogEntry) error {
-		if !valid {
-			return nil 
-		}
-		
-		if log.PrevHash != prevHash {
-			valid = false
-			brokenID = log.ID
-			return nil 
+	if h.checkpoints != nil {
+		checkpoints, err := h.repo.ListCheckpoints(r.Context())
+		if err != nil {
+			writeRepoError(w, err)
+			return
 		}
+		verifier.UseCheckpoints(checkpoints, h.checkpoints)
 
-		calculated := h.hasher.Hash(prevHash, log)
-		if log.CurrHash != calculated {
-			valid = false
-			brokenID = log.ID
-			// Debug: stdlog the mismatch
-			stdlog.Printf("Hash mismatch at %s: expected=%s, actual=%s, timestamp=%v", 
-				log.ID, calculated, log.CurrHash, log.Timestamp)
-			return nil
+		if h.timestamps != nil {
+			timestamps, err := h.repo.ListTimestamps(r.Context())
+			if err != nil {
+				writeRepoError(w, err)
+				return
+			}
+			verifier.UseTimestamps(timestamps, h.timestamps)
 		}
+	}
 
-		prevHash = log.CurrHash
+	streaming := strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	if streaming {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+
+	started := time.Now()
+	err = h.repo.IterateLogs(r.Context(), rng, func(log *core.LogEntry) error {
+		verifier.Add(log)
+		if checked := verifier.Checked(); checked%progressInterval == 0 {
+			progress := verifyProgress{Checked: checked, Total: total, Broken: len(verifier.Result().Broken)}
+			stdlog.Printf("Verifying audit chain: %d/%d entries, %d broken", progress.Checked, progress.Total, progress.Broken)
+			if streaming {
+				if err := enc.Encode(map[string]verifyProgress{"progress": progress}); err != nil {
+					return err
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
+		}
 		return nil
 	})
+	if err != nil {
+		if streaming {
+			// Progress has already been sent, so the status can't change.
+			enc.Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		writeRepoError(w, err)
+		return
+	}
+
+	result := verifier.Finish()
+	for _, broken := range result.Broken {
+		stdlog.Printf("Audit chain broken at %s: %s, expected=%s, actual=%s", broken.ID, broken.Reason, broken.Expected, broken.Actual)
+	}
+	stdlog.Printf("Verified %d audit entries against %d checkpoints and %d timestamps in %v: %d broken links",
+		result.Checked, result.Checkpoints, result.Timestamps, time.Since(started), len(result.Broken))
+
+	response := verifyResponse{VerifyResult: result}
+	if len(result.Broken) > 0 {
+		response.BrokenID = result.Broken[0].ID
+	}
+	if !streaming {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	enc.Encode(response)
+}
+
+// ListCheckpoints returns the stored checkpoints in sequence order.
+func (h *HttpHandler) ListCheckpoints(w http.ResponseWriter, r *http.Request) {
+	checkpoints, err := h.repo.ListCheckpoints(r.Context())
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if checkpoints == nil {
+		checkpoints = []*core.Checkpoint{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(checkpoints)
+}
+
+// CheckpointPublicKey returns the key checkpoints are signed with, so they
+// can be verified independently of this service.
+func (h *HttpHandler) CheckpointPublicKey(w http.ResponseWriter, r *http.Request) {
+	if h.publicKey == nil {
+		http.Error(w, "checkpoint signing is not configured", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.publicKey)
+}
+
+// ListTimestamps returns the checkpoint timestamp tokens in sequence
+// order. Tokens are DER, base64 encoded.
+func (h *HttpHandler) ListTimestamps(w http.ResponseWriter, r *http.Request) {
+	timestamps, err := h.repo.ListTimestamps(r.Context())
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if timestamps == nil {
+		timestamps = []*core.CheckpointTimestamp{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timestamps)
+}
+
+// Export streams the entries in the range selected as for Verify as a zip
+// bundle that can be checked offline with audit-verify. The export is
+// recorded in the chain before the bundle is sent, with the range pinned
+// to the head it recorded.
+func (h *HttpHandler) Export(w http.ResponseWriter, r *http.Request) {
+	if h.writer == nil {
+		http.Error(w, "exports can't be audited", http.StatusServiceUnavailable)
+		return
+	}
+	rng, err := parseLogRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Errors can't be reported once the bundle is streaming, so an empty
+	// or unknown range is caught first.
+	count, err := h.repo.CountLogs(r.Context(), rng)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	head, err := h.repo.LastLogIn(r.Context(), rng)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if count == 0 || head == nil {
+		http.Error(w, export.ErrEmptyRange.Error(), http.StatusNotFound)
+		return
+	}
 
+	actor := "anonymous"
+	if user, ok := UserFromContext(r.Context()); ok {
+		actor = user.Username
+	}
+	record, err := exportRecord(actor, rng, count, head)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := h.writer.Append(r.Context(), record); err != nil {
+		stdlog.Printf("Failed to audit export for %s: %v", actor, err)
+		http.Error(w, "failed to audit export", http.StatusInternalServerError)
+		return
+	}
+	// The record is past the head when the range is open ended; it goes
+	// in the next export.
+	rng.ToSeq = head.Seq
+
+	opts := export.Options{Range: rng, Signer: h.signer, PublicKeys: h.exportKeys, Timestamps: h.timestamps != nil}
+	name := fmt.Sprintf("audit-export-%s.zip", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	manifest, err := export.Write(r.Context(), h.repo, opts, w)
+	if err != nil {
+		stdlog.Printf("Audit export failed: %v", err)
+		return
+	}
+	stdlog.Printf("Exported audit entries %d-%d (%d) for %s", manifest.FirstSeq, manifest.LastSeq, manifest.Count, actor)
+}
 
-	response := map[string]interface{}{
-		"valid": valid,
+// exportRecord is the entry recording that actor exported rng, which has
+// count entries up to head.
+func exportRecord(actor string, rng repository.LogRange, count int64, head *core.LogEntry) (*core.LogEntry, error) {
+	details := map[string]interface{}{
+		"count":     count,
+		"last_seq":  head.Seq,
+		"head_hash": head.CurrHash,
+	}
+	if rng.FromSeq > 0 {
+		details["from_seq"] = rng.FromSeq
+	}
+	if rng.ToSeq > 0 {
+		details["to_seq"] = rng.ToSeq
+	}
+	if rng.FromID != "" {
+		details["from_id"] = rng.FromID
+	}
+	if rng.ToID != "" {
+		details["to_id"] = rng.ToID
 	}
-	if !valid {
-		response["broken_id"] = brokenID
+	if !rng.From.IsZero() {
+		details["from"] = rng.From
+	}
+	if !rng.To.IsZero() {
+		details["to"] = rng.To
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+	return &core.LogEntry{
+		Timestamp: time.Now(),
+		Actor:     actor,
+		Action:    export.Action,
+		Details:   data,
+	}, nil
+}
+
+// Search page sizes.
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// searchCursor is the opaque cursor handed to clients. It records the
+// order it was made for, so it can't be reused with a different one.
+type searchCursor struct {
+	repository.LogCursor
+	Sort       string `json:"sort"`
+	Descending bool   `json:"desc"`
+}
+
+type searchResponse struct {
+	Logs       []*core.LogEntry `json:"logs"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// SearchLogs lists entries filtered by actor, action, action_prefix,
+// from/to (RFC 3339), and details_path (dot separated) with details_value.
+// Results are sorted by sort (seq or timestamp) in order (asc or desc,
+// the default) and paged by limit and the previous page's next_cursor.
+func (h *HttpHandler) SearchLogs(w http.ResponseWriter, r *http.Request) {
+	q, err := parseLogQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
+	// One extra entry tells whether there is another page.
+	limit := q.Limit
+	q.Limit++
+	logs, err := h.repo.SearchLogs(r.Context(), q)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+
+	response := searchResponse{Logs: logs}
+	if len(logs) > limit {
+		response.Logs = logs[:limit]
+		cursor := searchCursor{LogCursor: *repository.CursorOf(logs[limit-1]), Sort: q.Sort, Descending: q.Descending}
+		data, err := json.Marshal(cursor)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+	}
+	if response.Logs == nil {
+		response.Logs = []*core.LogEntry{}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+func parseLogQuery(r *http.Request) (repository.LogQuery, error) {
+	v := r.URL.Query()
+	q := repository.LogQuery{
+		Actor:        v.Get("actor"),
+		Action:       v.Get("action"),
+		ActionPrefix: v.Get("action_prefix"),
+		Sort:         repository.SortSeq,
+		Descending:   true,
+		Limit:        defaultSearchLimit,
+	}
+	for name, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		value := v.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return q, fmt.Errorf("invalid %s timestamp, expected RFC 3339: %w", name, err)
+		}
+		*dst = t
+	}
+
+	if path := v.Get("details_path"); path != "" {
+		if !v.Has("details_value") {
+			return q, fmt.Errorf("details_path requires details_value")
+		}
+		q.DetailsPath = strings.Split(path, ".")
+		q.DetailsValue = v.Get("details_value")
+	}
+
+	switch sort := v.Get("sort"); sort {
+	case "":
+	case repository.SortSeq, repository.SortTimestamp:
+		q.Sort = sort
+	default:
+		return q, fmt.Errorf("invalid sort, expected seq or timestamp")
+	}
+	switch order := v.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		q.Descending = false
+	default:
+		return q, fmt.Errorf("invalid order, expected asc or desc")
+	}
+
+	if value := v.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return q, fmt.Errorf("invalid limit, expected 1 to %d", maxSearchLimit)
+		}
+		q.Limit = limit
+	}
+
+	if value := v.Get("cursor"); value != "" {
+		var cursor searchCursor
+		data, err := base64.RawURLEncoding.DecodeString(value)
+		if err == nil {
+			err = json.Unmarshal(data, &cursor)
+		}
+		if err != nil {
+			return q, fmt.Errorf("invalid cursor")
+		}
+		if cursor.Sort != q.Sort || cursor.Descending != q.Descending {
+			return q, fmt.Errorf("cursor was made for a different sort order")
+		}
+		q.After = &cursor.LogCursor
+	}
+	return q, nil
+}
+
+// SetProver serves Merkle proofs from p.
+func (h *HttpHandler) SetProver(p *proof.Prover) {
+	h.prover = p
+}
+
+// InclusionProof proves that entry ?id= is in the log tree of size
+// ?tree_size=, or of the whole log.
+func (h *HttpHandler) InclusionProof(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	treeSize, err := parseTreeSize(r, "tree_size")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := h.prover.Inclusion(r.Context(), id, treeSize)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// ConsistencyProof proves that the log tree of size ?first= is a prefix of
+// the tree of size ?second=, or of the whole log.
+func (h *HttpHandler) ConsistencyProof(w http.ResponseWriter, r *http.Request) {
+	first, err := parseTreeSize(r, "first")
+	if err == nil && first == 0 {
+		err = fmt.Errorf("first is required")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	second, err := parseTreeSize(r, "second")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := h.prover.Consistency(r.Context(), first, second)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// MerkleBatches lists the sealed batch roots.
+func (h *HttpHandler) MerkleBatches(w http.ResponseWriter, r *http.Request) {
+	batches, err := h.prover.Batches(r.Context())
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	if batches == nil {
+		batches = []*core.MerkleBatch{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
+
+// SetDeadLetters serves the dead-letter admin API from q.
+func (h *HttpHandler) SetDeadLetters(q *deadletter.Queue) {
+	h.deadLetters = q
+}
+
+// deadLetterRequest is the body of a replay or discard. A discard must
+// give a reason, which is recorded in the audit chain.
+type deadLetterRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ListDeadLetters lists dead letters oldest first, limit at a time after
+// seq after.
+func (h *HttpHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var after uint64
+	if value := q.Get("after"); value != "" {
+		var err error
+		if after, err = strconv.ParseUint(value, 10, 64); err != nil {
+			http.Error(w, "invalid after, expected a sequence number", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := defaultSearchLimit
+	if value := q.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			http.Error(w, fmt.Sprintf("invalid limit, expected 1 to %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	letters, err := h.deadLetters.List(r.Context(), after, limit)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(letters)
+}
+
+// GetDeadLetter returns a dead letter with its data.
+func (h *HttpHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	seq, ok := deadLetterSeq(w, r)
+	if !ok {
+		return
+	}
+	letter, err := h.deadLetters.Get(r.Context(), seq)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(letter)
+}
+
+// ReplayDeadLetter sends a dead letter back to its original subject.
+func (h *HttpHandler) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	h.decideDeadLetter(w, r, h.deadLetters.Replay, false)
+}
+
+// DiscardDeadLetter drops a dead letter.
+func (h *HttpHandler) DiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	h.decideDeadLetter(w, r, h.deadLetters.Discard, true)
+}
+
+func (h *HttpHandler) decideDeadLetter(w http.ResponseWriter, r *http.Request, decide func(context.Context, uint64, string, string) (*deadletter.Letter, error), needReason bool) {
+	seq, ok := deadLetterSeq(w, r)
+	if !ok {
+		return
+	}
+	var req deadLetterRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if needReason && strings.TrimSpace(req.Reason) == "" {
+		http.Error(w, "a reason is required", http.StatusBadRequest)
+		return
+	}
+	user, ok := UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	letter, err := decide(r.Context(), seq, user.Username, req.Reason)
+	if err != nil {
+		writeRepoError(w, err)
+		return
+	}
+	letter.Data = nil
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(letter)
+}
+
+func deadLetterSeq(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	seq, err := strconv.ParseUint(r.PathValue("seq"), 10, 64)
+	if err != nil || seq == 0 {
+		http.Error(w, "invalid dead letter sequence", http.StatusBadRequest)
+		return 0, false
+	}
+	return seq, true
+}
+
+func parseTreeSize(r *http.Request, name string) (uint64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	size, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || size == 0 {
+		return 0, fmt.Errorf("invalid %s, expected a positive integer", name)
+	}
+	return size, nil
+}
+
+func parseLogRange(r *http.Request) (repository.LogRange, error) {
+	q := r.URL.Query()
+	rng := repository.LogRange{FromID: q.Get("from_id"), ToID: q.Get("to_id")}
+	for name, dst := range map[string]*int64{"from_seq": &rng.FromSeq, "to_seq": &rng.ToSeq} {
+		value := q.Get(name)
+		if value == "" {
+			continue
+		}
+		seq, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seq < 1 {
+			return rng, fmt.Errorf("invalid %s, expected a positive integer", name)
+		}
+		*dst = seq
+	}
+	for name, dst := range map[string]*time.Time{"from": &rng.From, "to": &rng.To} {
+		value := q.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return rng, fmt.Errorf("invalid %s timestamp, expected RFC 3339: %w", name, err)
+		}
+		*dst = t
+	}
+	return rng, nil
+}
+
+func writeRepoError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrLogNotFound) || errors.Is(err, deadletter.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, proof.ErrInvalidTreeSize) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
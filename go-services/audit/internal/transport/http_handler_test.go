@@ -0,0 +1,297 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/export"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newAuthenticator(t *testing.T) (*Authenticator, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	path := filepath.Join(t.TempDir(), "auth.pub")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+	auth, err := NewAuthenticator(path)
+	if err != nil {
+		t.Fatalf("NewAuthenticator failed: %v", err)
+	}
+	return auth, key
+}
+
+func token(t *testing.T, key *rsa.PrivateKey, role, tokenType string) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub":      "7",
+		"username": "alice",
+		"role":     role,
+		"type":     tokenType,
+		"exp":      time.Now().Add(time.Hour).Unix(),
+		"iat":      time.Now().Unix(),
+	}).SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestAuthenticator_RequireRole(t *testing.T) {
+	auth, key := newAuthenticator(t)
+	_, otherKey := newAuthenticator(t)
+	var seen *User
+	handler := auth.RequireRole(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = UserFromContext(r.Context())
+	}, RoleAuditor, RoleAdmin)
+
+	cases := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"auditor", token(t, key, RoleAuditor, "access"), http.StatusOK},
+		{"admin", token(t, key, RoleAdmin, "access"), http.StatusOK},
+		{"operator", token(t, key, "OPERATOR", "access"), http.StatusForbidden},
+		{"signing token", token(t, key, RoleAuditor, "signing"), http.StatusUnauthorized},
+		{"other issuer", token(t, otherKey, RoleAdmin, "access"), http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/audit/logs", nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+	if seen == nil || seen.Username != "alice" || seen.ID != "7" {
+		t.Errorf("Expected user in context, got %+v", seen)
+	}
+}
+
+type searchRepo struct {
+	repository.Repository
+	logs  []*core.LogEntry
+	query repository.LogQuery
+}
+
+// SearchLogs pages through logs by seq, ignoring filters.
+func (r *searchRepo) SearchLogs(ctx context.Context, q repository.LogQuery) ([]*core.LogEntry, error) {
+	r.query = q
+	var out []*core.LogEntry
+	for _, log := range r.logs {
+		if (q.After == nil || log.Seq > q.After.Seq) && len(out) < q.Limit {
+			out = append(out, log)
+		}
+	}
+	return out, nil
+}
+
+func search(t *testing.T, h *HttpHandler, query string) (int, searchResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.SearchLogs(rec, httptest.NewRequest(http.MethodGet, "/api/v1/audit/logs?"+query, nil))
+	var resp searchResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestHttpHandler_SearchLogs(t *testing.T) {
+	repo := &searchRepo{}
+	for seq := int64(1); seq <= 5; seq++ {
+		repo.logs = append(repo.logs, &core.LogEntry{Seq: seq, Actor: "alice", Action: "LOGIN"})
+	}
+	h := NewHttpHandler(repo, core.NewHashers())
+
+	code, page := search(t, h, "actor=alice&action_prefix=LOG&details_path=tag.name&details_value=TI-101&order=asc&limit=2")
+	if code != http.StatusOK || len(page.Logs) != 2 || page.NextCursor == "" {
+		t.Fatalf("Expected first page of 2 with a cursor, got %d %+v", code, page)
+	}
+	q := repo.query
+	if q.Actor != "alice" || q.ActionPrefix != "LOG" || len(q.DetailsPath) != 2 || q.DetailsValue != "TI-101" || q.Descending || q.Limit != 3 {
+		t.Errorf("Unexpected query %+v", q)
+	}
+
+	var seqs []int64
+	cursor := page.NextCursor
+	for _, log := range page.Logs {
+		seqs = append(seqs, log.Seq)
+	}
+	for cursor != "" {
+		code, page = search(t, h, "order=asc&limit=2&cursor="+cursor)
+		if code != http.StatusOK {
+			t.Fatalf("Expected next page, got %d", code)
+		}
+		for _, log := range page.Logs {
+			seqs = append(seqs, log.Seq)
+		}
+		cursor = page.NextCursor
+	}
+	if len(seqs) != 5 || seqs[4] != 5 {
+		t.Errorf("Expected to page through all 5 entries once, got %v", seqs)
+	}
+
+	_, page = search(t, h, "order=asc&limit=2")
+	if code, _ := search(t, h, "sort=timestamp&order=asc&limit=2&cursor="+page.NextCursor); code != http.StatusBadRequest {
+		t.Errorf("Expected cursor reused with another sort to be rejected, got %d", code)
+	}
+	for _, bad := range []string{"limit=0", "limit=5000", "sort=actor", "order=up", "from=yesterday", "details_path=tag", "cursor=not-a-cursor"} {
+		if code, _ := search(t, h, bad); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", bad, code)
+		}
+	}
+}
+
+type exportRepo struct {
+	repository.Repository
+	logs []*core.LogEntry
+}
+
+func (r *exportRepo) IterateLogs(ctx context.Context, rng repository.LogRange, callback func(*core.LogEntry) error) error {
+	for _, log := range r.logs {
+		if log.Seq >= rng.FromSeq && (rng.ToSeq == 0 || log.Seq <= rng.ToSeq) {
+			if err := callback(log); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *exportRepo) CountLogs(ctx context.Context, rng repository.LogRange) (int64, error) {
+	var count int64
+	r.IterateLogs(ctx, rng, func(*core.LogEntry) error { count++; return nil })
+	return count, nil
+}
+
+func (r *exportRepo) LastLogIn(ctx context.Context, rng repository.LogRange) (*core.LogEntry, error) {
+	var last *core.LogEntry
+	r.IterateLogs(ctx, rng, func(log *core.LogEntry) error { last = log; return nil })
+	return last, nil
+}
+
+// Append chains log onto the entries, as the consumer would.
+func (r *exportRepo) Append(ctx context.Context, log *core.LogEntry) error {
+	head := r.logs[len(r.logs)-1]
+	log.Seq, log.PrevHash, log.HashVersion = head.Seq+1, head.CurrHash, core.HashVersion2
+	log.CurrHash = core.NewSHA256HasherV2().Hash(log.PrevHash, log)
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func (r *exportRepo) ListCheckpoints(ctx context.Context) ([]*core.Checkpoint, error) {
+	return nil, nil
+}
+
+func TestHttpHandler_Export(t *testing.T) {
+	hashers := core.NewHashers()
+	hasher, _ := hashers.ForVersion(core.HashVersion2)
+	repo := &exportRepo{}
+	prevHash := core.GenesisHash
+	for seq := int64(1); seq <= 5; seq++ {
+		log := &core.LogEntry{Seq: seq, Actor: "alice", Action: "LOGIN", PrevHash: prevHash, HashVersion: core.HashVersion2}
+		log.CurrHash = hasher.Hash(prevHash, log)
+		prevHash = log.CurrHash
+		repo.logs = append(repo.logs, log)
+	}
+	h := NewHttpHandler(repo, hashers)
+
+	rec := httptest.NewRecorder()
+	h.Export(rec, httptest.NewRequest(http.MethodGet, "/api/v1/audit/export", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected export without a writer to be refused, got %d", rec.Code)
+	}
+	h.SetLogWriter(repo)
+
+	exportBundle := func(query string) *export.Report {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.Export(rec, httptest.NewRequest(http.MethodGet, "/api/v1/audit/export?"+query, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+			t.Fatalf("Expected a zip bundle, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		bundle := rec.Body.Bytes()
+		report, err := export.Verify(bytes.NewReader(bundle), int64(len(bundle)), export.VerifyOptions{Hashers: hashers})
+		if err != nil {
+			t.Fatalf("Failed to verify bundle: %v", err)
+		}
+		return report
+	}
+
+	if report := exportBundle("from_seq=2&to_seq=4"); !report.Valid || report.Chain.Checked != 3 {
+		t.Errorf("Expected a valid bundle of 3 entries, got %+v", report)
+	}
+	record := repo.logs[len(repo.logs)-1]
+	var details map[string]interface{}
+	json.Unmarshal(record.Details, &details)
+	if record.Action != export.Action || record.Actor != "anonymous" || details["from_seq"] != 2.0 || details["to_seq"] != 4.0 ||
+		details["count"] != 3.0 || details["head_hash"] != repo.logs[3].CurrHash {
+		t.Errorf("Expected the export to be recorded, got %+v %s", record, record.Details)
+	}
+
+	// An open range ends at the head the record names, before the record.
+	if report := exportBundle("from_seq=5"); !report.Valid || report.Manifest.LastSeq != 6 || report.Manifest.Head != record.CurrHash {
+		t.Errorf("Expected the bundle to end at seq 6, got %+v", report.Manifest)
+	}
+	if len(repo.logs) != 7 {
+		t.Errorf("Expected a record per export, got %d entries", len(repo.logs))
+	}
+
+	for query, want := range map[string]int{"from_seq=9": http.StatusNotFound, "from_seq=x": http.StatusBadRequest} {
+		rec := httptest.NewRecorder()
+		h.Export(rec, httptest.NewRequest(http.MethodGet, "/api/v1/audit/export?"+query, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", query, want, rec.Code)
+		}
+	}
+}
+
+func TestHttpHandler_DeadLetterRequests(t *testing.T) {
+	h := NewHttpHandler(&searchRepo{}, core.NewHashers())
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /dead-letters/{seq}/discard", h.DiscardDeadLetter)
+
+	// Requests rejected before the queue is touched.
+	for _, tc := range []struct{ path, body string }{
+		{"/dead-letters/x/discard", `{"reason":"junk"}`},
+		{"/dead-letters/0/discard", `{"reason":"junk"}`},
+		{"/dead-letters/3/discard", `{}`},
+		{"/dead-letters/3/discard", `{"reason":"  "}`},
+		{"/dead-letters/3/discard", `not json`},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tc.path, bytes.NewBufferString(tc.body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected 400, got %d", tc.path, tc.body, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/dead-letters/3/discard", bytes.NewBufferString(`{"reason":"junk"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a discard without a user to be refused, got %d", rec.Code)
+	}
+}
@@ -3,11 +3,13 @@ package transport
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/deadletter"
 	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
 	pb "github.com/ahmetsah/industrial-historian/go-services/pkg/proto"
 	"github.com/nats-io/nats.go"
@@ -15,14 +17,42 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// maxDeliver is how many times a message is tried before it is moved to
+// the dead-letter stream. redeliveryBackoff is the wait after each failed
+// try; the last one repeats.
+const maxDeliver = 5
+
+var redeliveryBackoff = []time.Duration{time.Second, 10 * time.Second, time.Minute, 5 * time.Minute}
+
+// writeRetryWait is the first wait before retrying a write the database
+// failed, doubling up to maxWriteRetryWait. That stays under JetStream's
+// default ack wait of 30s, so the messages held meanwhile aren't
+// redelivered.
+var writeRetryWait = time.Second
+
+const maxWriteRetryWait = 15 * time.Second
+
+// AuditConsumer is the audit chain's only writer: it pulls messages in
+// batches, appends each batch in one transaction in stream order, and acks
+// it after the commit. Entries the service records itself are handed to
+// it with Append and written between batches.
 type AuditConsumer struct {
-	nc     *nats.Conn
-	js     jetstream.JetStream
-	repo   repository.Repository
-	hasher core.Hasher
+	nc        *nats.Conn
+	js        jetstream.JetStream
+	repo      repository.Repository
+	hasher    core.Hasher
+	batchSize int
+	batchWait time.Duration
+	appends   chan appendRequest
 }
 
-func NewAuditConsumer(natsUrl string, repo repository.Repository, hasher core.Hasher) (*AuditConsumer, error) {
+// appendRequest is an entry passed to Append and where its result goes.
+type appendRequest struct {
+	log  *core.LogEntry
+	done chan error
+}
+
+func NewAuditConsumer(natsUrl string, repo repository.Repository, hasher core.Hasher, batchSize int, batchWait time.Duration) (*AuditConsumer, error) {
 	nc, err := nats.Connect(natsUrl)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
@@ -35,10 +65,13 @@ func NewAuditConsumer(natsUrl string, repo repository.Repository, hasher core.Ha
 	}
 
 	return &AuditConsumer{
-		nc:     nc,
-		js:     js,
-		repo:   repo,
-		hasher: hasher,
+		nc:        nc,
+		js:        js,
+		repo:      repo,
+		hasher:    hasher,
+		batchSize: batchSize,
+		batchWait: batchWait,
+		appends:   make(chan appendRequest),
 	}, nil
 }
 
@@ -53,6 +86,9 @@ func (c *AuditConsumer) Start(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to create stream: %w", err)
 	}
+	if _, err := c.js.CreateOrUpdateStream(ctx, deadletter.StreamConfig); err != nil {
+		return fmt.Errorf("failed to create dead-letter stream: %w", err)
+	}
 
 	cons, err := c.js.CreateOrUpdateConsumer(ctx, "AUDIT_EVENTS", jetstream.ConsumerConfig{
 		Durable:   "AuditServiceConsumer",
@@ -62,22 +98,212 @@ func (c *AuditConsumer) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to create consumer: %w", err)
 	}
 
-	_, err = cons.Consume(func(msg jetstream.Msg) {
-		if err := c.processMessage(ctx, msg); err != nil {
-			log.Printf("Failed to process message: %v", err)
-			msg.Nak()
-		} else {
-			msg.Ack()
+	go c.run(ctx, cons)
+	return nil
+}
+
+// run fetches and writes batches until ctx is done. Fetch returns as soon
+// as batchSize messages are available, or after batchWait with fewer, so
+// an Append waits at most about batchWait to be written.
+func (c *AuditConsumer) run(ctx context.Context, cons jetstream.Consumer) {
+	for ctx.Err() == nil {
+		c.writeAppends(ctx)
+		batch, err := cons.Fetch(c.batchSize, jetstream.FetchMaxWait(c.batchWait))
+		if err != nil {
+			log.Printf("Failed to fetch audit messages: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		var msgs []jetstream.Msg
+		for msg := range batch.Messages() {
+			msgs = append(msgs, msg)
+		}
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) && ctx.Err() == nil {
+			log.Printf("Audit message fetch ended early: %v", err)
+		}
+		if len(msgs) > 0 {
+			c.processBatch(ctx, msgs)
+		}
+	}
+}
+
+// processBatch appends msgs to the chain in order and acks them. Messages
+// that can't be parsed are retried or dead-lettered on their own.
+func (c *AuditConsumer) processBatch(ctx context.Context, msgs []jetstream.Msg) {
+	var parsed []jetstream.Msg
+	var entries []*core.LogEntry
+	for _, msg := range msgs {
+		entry, err := c.parseMessage(msg)
+		if err != nil {
+			c.handleFailure(ctx, msg, err)
+			continue
 		}
-	})
+		parsed = append(parsed, msg)
+		entries = append(entries, entry)
+	}
+	if len(entries) > 0 {
+		c.writeBatch(ctx, parsed, entries)
+	}
+}
+
+// writeBatch writes entries, parsed from msgs, in one transaction. A
+// batch that fails for a reason other than its content, such as the
+// database being down, is retried as a whole so the chain keeps stream
+// order. Only a batch the database rejects is written one entry at a
+// time, so the entry it rejects doesn't hold back the rest.
+func (c *AuditConsumer) writeBatch(ctx context.Context, msgs []jetstream.Msg, entries []*core.LogEntry) {
+	duplicate, err := c.repo.AppendLogs(ctx, entries, c.hasher)
 	if err != nil {
-		return fmt.Errorf("failed to start consuming: %w", err)
+		duplicate, err = c.retryAppend(ctx, msgs, entries, err)
 	}
+	switch {
+	case err == nil:
+		c.ack(msgs, entries, duplicate)
+	case ctx.Err() != nil:
+		// Shutting down; the messages are redelivered unacked.
+	case len(entries) == 1:
+		c.handleFailure(ctx, msgs[0], err)
+	default:
+		log.Printf("Database rejected batch of %d audit entries, writing them one at a time: %v", len(entries), err)
+		for i := range entries {
+			c.writeBatch(ctx, msgs[i:i+1], entries[i:i+1])
+		}
+	}
+}
 
-	return nil
+// retryAppend retries writing entries after err until it succeeds, the
+// database rejects them, or ctx is done. Fetching waits meanwhile, and
+// msgs are kept from being redelivered.
+func (c *AuditConsumer) retryAppend(ctx context.Context, msgs []jetstream.Msg, entries []*core.LogEntry, err error) ([]bool, error) {
+	wait := writeRetryWait
+	for err != nil && !repository.IsRejected(err) {
+		log.Printf("Failed to append %d audit entries, retrying in %s: %v", len(entries), wait, err)
+		for _, msg := range msgs {
+			msg.InProgress()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, maxWriteRetryWait)
+
+		var duplicate []bool
+		if duplicate, err = c.repo.AppendLogs(ctx, entries, c.hasher); err == nil {
+			return duplicate, nil
+		}
+	}
+	return nil, err
+}
+
+// ack acks msgs once their entries are written.
+func (c *AuditConsumer) ack(msgs []jetstream.Msg, entries []*core.LogEntry, duplicate []bool) {
+	for i, msg := range msgs {
+		if duplicate[i] {
+			// Acking a redelivery is all that's left to do.
+			log.Printf("Skipping duplicate message %s, already recorded as seq %d", entries[i].MsgID, entries[i].Seq)
+		}
+		msg.Ack()
+	}
+}
+
+// Append writes log to the chain through the writer and returns once it
+// is committed. It needs Start to have been called.
+func (c *AuditConsumer) Append(ctx context.Context, log *core.LogEntry) error {
+	req := appendRequest{log: log, done: make(chan error, 1)}
+	select {
+	case c.appends <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-req.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeAppends writes the entries waiting in Append, up to batchSize, in
+// one transaction. If it fails they are written one at a time, so each
+// caller gets its own entry's result.
+func (c *AuditConsumer) writeAppends(ctx context.Context) {
+	var reqs []appendRequest
+	var logs []*core.LogEntry
+pending:
+	for len(reqs) < c.batchSize {
+		select {
+		case req := <-c.appends:
+			reqs = append(reqs, req)
+			logs = append(logs, req.log)
+		default:
+			break pending
+		}
+	}
+	if len(reqs) == 0 {
+		return
+	}
+	_, err := c.repo.AppendLogs(ctx, logs, c.hasher)
+	if err != nil && len(reqs) > 1 {
+		log.Printf("Failed to append %d service audit entries, writing them one at a time: %v", len(reqs), err)
+		for _, req := range reqs {
+			_, err := c.repo.AppendLogs(ctx, []*core.LogEntry{req.log}, c.hasher)
+			req.done <- err
+		}
+		return
+	}
+	for _, req := range reqs {
+		req.done <- err
+	}
+}
+
+// handleFailure redelivers msg after a backoff, or moves it to the
+// dead-letter stream once it has failed maxDeliver times.
+func (c *AuditConsumer) handleFailure(ctx context.Context, msg jetstream.Msg, err error) {
+	meta, metaErr := msg.Metadata()
+	if metaErr != nil {
+		log.Printf("Failed to process message on %s: %v", msg.Subject(), err)
+		msg.NakWithDelay(redeliveryBackoff[0])
+		return
+	}
+	if meta.NumDelivered < maxDeliver {
+		log.Printf("Failed to process message %d on %s (delivery %d of %d): %v", meta.Sequence.Stream, msg.Subject(), meta.NumDelivered, maxDeliver, err)
+		msg.NakWithDelay(backoff(meta.NumDelivered))
+		return
+	}
+
+	if _, pubErr := c.js.PublishMsg(ctx, deadletter.NewMessage(msg, meta, err)); pubErr != nil {
+		log.Printf("Failed to dead-letter message %d on %s: %v", meta.Sequence.Stream, msg.Subject(), pubErr)
+		msg.NakWithDelay(backoff(meta.NumDelivered))
+		return
+	}
+	log.Printf("Moved message %d on %s to %s after %d deliveries: %v", meta.Sequence.Stream, msg.Subject(), deadletter.Stream, meta.NumDelivered, err)
+	msg.Term()
+}
+
+// backoff is the wait before redelivering a message delivered n times.
+func backoff(n uint64) time.Duration {
+	if n < 1 {
+		n = 1
+	}
+	return redeliveryBackoff[min(int(n), len(redeliveryBackoff))-1]
 }
 
-func (c *AuditConsumer) processMessage(ctx context.Context, msg jetstream.Msg) error {
+// JetStream returns the consumer's JetStream context.
+func (c *AuditConsumer) JetStream() jetstream.JetStream {
+	return c.js
+}
+
+// Publish sends data on the consumer's NATS connection.
+func (c *AuditConsumer) Publish(subject string, data []byte) error {
+	return c.nc.Publish(subject, data)
+}
+
+// parseMessage turns a message into the entry to append.
+func (c *AuditConsumer) parseMessage(msg jetstream.Msg) (*core.LogEntry, error) {
 	var actor, action string
 	var detailsBytes []byte
 	var timestamp time.Time
@@ -86,19 +312,41 @@ func (c *AuditConsumer) processMessage(ctx context.Context, msg jetstream.Msg) e
 		// Handle Protobuf AlarmEvent
 		var event pb.AlarmEvent
 		if err := proto.Unmarshal(msg.Data(), &event); err != nil {
-			return fmt.Errorf("invalid AlarmEvent proto: %w", err)
+			return nil, fmt.Errorf("invalid AlarmEvent proto: %w", err)
 		}
 
-		actor = "system" // Alarms are usually system generated or triggered by sensor
+		// Operator actions (ack, shelve) carry their actor; evaluator transitions are "system"
+		actor = event.Actor
+		if actor == "" {
+			actor = "system"
+		}
 		action = fmt.Sprintf("alarm_%s", event.State)
 		timestamp = time.UnixMilli(event.TimestampMs)
 
 		details := map[string]interface{}{
-			"alarm_id":      event.AlarmId,
-			"definition_id": event.DefinitionId,
-			"value":         event.Value,
-			"message":       event.Message,
-			"state":         event.State,
+			"alarm_id":       event.AlarmId,
+			"definition_id":  event.DefinitionId,
+			"tag":            event.Tag,
+			"priority":       event.Priority,
+			"alarm_type":     event.AlarmType,
+			"threshold":      event.Threshold,
+			"value":          event.Value,
+			"message":        event.Message,
+			"state":          event.State,
+			"previous_state": event.PreviousState,
+			"sequence":       event.Sequence,
+		}
+		if event.Comment != "" {
+			details["comment"] = event.Comment
+		}
+		if event.GroupId != 0 {
+			details["group_id"] = event.GroupId
+			details["first_out"] = event.FirstOut
+			details["consequential"] = event.Consequential
+		}
+		if event.SignedBy != "" {
+			details["signed_by"] = event.SignedBy
+			details["signature_meaning"] = event.SignatureMeaning
 		}
 		detailsBytes, _ = json.Marshal(details)
 
@@ -106,7 +354,7 @@ func (c *AuditConsumer) processMessage(ctx context.Context, msg jetstream.Msg) e
 		// Handle JSON (default)
 		var payload map[string]interface{}
 		if err := json.Unmarshal(msg.Data(), &payload); err != nil {
-			return fmt.Errorf("invalid JSON: %w", err)
+			return nil, fmt.Errorf("invalid JSON: %w", err)
 		}
 
 		actor, _ = payload["actor"].(string)
@@ -127,14 +375,26 @@ func (c *AuditConsumer) processMessage(ctx context.Context, msg jetstream.Msg) e
 		timestamp = time.Now()
 	}
 
-	logEntry := &core.LogEntry{
+	return &core.LogEntry{
 		Timestamp: timestamp,
 		Actor:     actor,
 		Action:    action,
 		Details:   json.RawMessage(detailsBytes),
-	}
+		MsgID:     messageID(msg),
+	}, nil
+}
 
-	return c.repo.AppendLog(ctx, logEntry, c.hasher)
+// messageID is the publisher's Nats-Msg-Id, or failing that the message's
+// stream and stream sequence, which stay the same across redeliveries.
+func messageID(msg jetstream.Msg) string {
+	if id := msg.Headers().Get(jetstream.MsgIDHeader); id != "" {
+		return id
+	}
+	meta, err := msg.Metadata()
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", meta.Stream, meta.Sequence.Stream)
 }
 
 func (c *AuditConsumer) Close() {
@@ -0,0 +1,302 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/deadletter"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type fakeMsg struct {
+	jetstream.Msg
+	subject string
+	data    []byte
+	headers nats.Header
+	seq     uint64
+
+	delivered  uint64
+	nakDelay   time.Duration
+	termed     bool
+	acked      bool
+	inProgress int
+}
+
+func (m *fakeMsg) Subject() string      { return m.subject }
+func (m *fakeMsg) Data() []byte         { return m.data }
+func (m *fakeMsg) Headers() nats.Header { return m.headers }
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{Stream: "AUDIT_EVENTS", Sequence: jetstream.SequencePair{Stream: m.seq}, NumDelivered: m.delivered}, nil
+}
+func (m *fakeMsg) NakWithDelay(delay time.Duration) error { m.nakDelay = delay; return nil }
+func (m *fakeMsg) Term() error                            { m.termed = true; return nil }
+
+func (m *fakeMsg) Ack() error        { m.acked = true; return nil }
+func (m *fakeMsg) InProgress() error { m.inProgress++; return nil }
+
+// dedupRepo records entries once per MsgID, like the unique index does.
+// It rejects entries by rejectActor, as the database rejects a bad row,
+// and fails the next outage writes as if the database were down.
+type dedupRepo struct {
+	repository.Repository
+	logs        []*core.LogEntry
+	batches     []int
+	rejectActor string
+	outage      int
+}
+
+func (r *dedupRepo) AppendLogs(ctx context.Context, logs []*core.LogEntry, hasher core.Hasher) ([]bool, error) {
+	if r.outage > 0 {
+		r.outage--
+		return nil, errors.New("failed to begin transaction: connection refused")
+	}
+	for _, log := range logs {
+		if r.rejectActor != "" && log.Actor == r.rejectActor {
+			return nil, &pgconn.PgError{Code: "22001", Message: "value too long for type character varying(255)"}
+		}
+	}
+	r.batches = append(r.batches, len(logs))
+	duplicate := make([]bool, len(logs))
+	for i, log := range logs {
+		for _, existing := range r.logs {
+			if existing.MsgID == log.MsgID {
+				*log = *existing
+				duplicate[i] = true
+			}
+		}
+		if !duplicate[i] {
+			log.Seq = int64(len(r.logs) + 1)
+			r.logs = append(r.logs, log)
+		}
+	}
+	return duplicate, nil
+}
+
+func jsonMsg(seq uint64, subject, data string) *fakeMsg {
+	return &fakeMsg{subject: subject, data: []byte(data), headers: nats.Header{}, seq: seq, delivered: 1}
+}
+
+func TestAuditConsumer_Redelivery(t *testing.T) {
+	repo := &dedupRepo{}
+	c := &AuditConsumer{repo: repo, hasher: core.NewSHA256HasherV2()}
+	ctx := context.Background()
+
+	withID := jsonMsg(1, "sys.auth.login", `{"actor":"alice"}`)
+	withID.headers.Set(jetstream.MsgIDHeader, "login-1")
+	withoutID := jsonMsg(2, "sys.audit.setpoint", `{"actor":"bob"}`)
+
+	for i := 0; i < 2; i++ {
+		withID.acked, withoutID.acked = false, false
+		c.processBatch(ctx, []jetstream.Msg{withID, withoutID})
+		if !withID.acked || !withoutID.acked {
+			t.Fatalf("Expected delivery %d to be acked", i+1)
+		}
+	}
+	if len(repo.logs) != 2 || repo.logs[0].MsgID != "login-1" || repo.logs[1].MsgID != "AUDIT_EVENTS:2" {
+		t.Errorf("Expected each message recorded once under its ID, got %+v", repo.logs)
+	}
+}
+
+func TestAuditConsumer_Batch(t *testing.T) {
+	repo := &dedupRepo{}
+	c := &AuditConsumer{js: &publishJS{}, repo: repo, hasher: core.NewSHA256HasherV2()}
+	ctx := context.Background()
+
+	var msgs []jetstream.Msg
+	var fakes []*fakeMsg
+	for seq := uint64(1); seq <= 10; seq++ {
+		data := fmt.Sprintf(`{"actor":"op","n":%d}`, seq)
+		if seq == 4 {
+			data = `{"actor":`
+		}
+		msg := jsonMsg(seq, "sys.audit.setpoint", data)
+		msgs, fakes = append(msgs, msg), append(fakes, msg)
+	}
+	c.processBatch(ctx, msgs)
+
+	// The parseable messages go in one write, in stream order.
+	if len(repo.batches) != 1 || repo.batches[0] != 9 {
+		t.Fatalf("Expected one write of 9 entries, got %v", repo.batches)
+	}
+	for i, seq := range []int{1, 2, 3, 5, 6, 7, 8, 9, 10} {
+		if want := fmt.Sprintf("AUDIT_EVENTS:%d", seq); repo.logs[i].MsgID != want {
+			t.Errorf("Expected entry %d from %s, got %s", i+1, want, repo.logs[i].MsgID)
+		}
+	}
+	for _, msg := range fakes {
+		if msg.seq == 4 {
+			if msg.acked || msg.nakDelay == 0 {
+				t.Errorf("Expected the bad message to be retried, got %+v", msg)
+			}
+		} else if !msg.acked {
+			t.Errorf("Expected message %d to be acked", msg.seq)
+		}
+	}
+}
+
+func TestAuditConsumer_BatchFallback(t *testing.T) {
+	repo := &dedupRepo{rejectActor: "x"}
+	c := &AuditConsumer{js: &publishJS{}, repo: repo, hasher: core.NewSHA256HasherV2()}
+
+	good1 := jsonMsg(1, "sys.audit.setpoint", `{"actor":"op"}`)
+	bad := jsonMsg(2, "sys.audit.setpoint", `{"actor":"x"}`)
+	good2 := jsonMsg(3, "sys.audit.setpoint", `{"actor":"op"}`)
+	c.processBatch(context.Background(), []jetstream.Msg{good1, bad, good2})
+
+	// The failed batch is written one entry at a time around the bad one.
+	if len(repo.logs) != 2 || repo.logs[0].MsgID != "AUDIT_EVENTS:1" || repo.logs[1].MsgID != "AUDIT_EVENTS:3" {
+		t.Errorf("Expected the good entries in order, got %+v", repo.logs)
+	}
+	if !good1.acked || !good2.acked || bad.acked || bad.nakDelay == 0 {
+		t.Errorf("Expected good messages acked and the bad one retried, got %+v %+v %+v", good1, bad, good2)
+	}
+}
+
+func TestAuditConsumer_Append(t *testing.T) {
+	repo := &dedupRepo{rejectActor: "x"}
+	c := &AuditConsumer{repo: repo, hasher: core.NewSHA256HasherV2(), batchSize: 10, appends: make(chan appendRequest)}
+	ctx := context.Background()
+
+	results := make(chan error, 3)
+	for _, actor := range []string{"alice", "x", "bob"} {
+		go func() {
+			results <- c.Append(ctx, &core.LogEntry{Actor: actor, Action: "audit_exported", MsgID: actor})
+		}()
+	}
+	// Stand in for the run loop until every Append has returned.
+	var done, failed int
+	for done < 3 {
+		c.writeAppends(ctx)
+		select {
+		case err := <-results:
+			done++
+			if err != nil {
+				failed++
+			}
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if failed != 1 || len(repo.logs) != 2 {
+		t.Errorf("Expected only the rejected entry to fail, got %d failures and %+v", failed, repo.logs)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := c.Append(canceled, &core.LogEntry{Actor: "alice"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Append to give up with its context, got %v", err)
+	}
+}
+
+func TestAuditConsumer_DatabaseOutage(t *testing.T) {
+	defer func(wait time.Duration) { writeRetryWait = wait }(writeRetryWait)
+	writeRetryWait = time.Millisecond
+
+	js := &publishJS{}
+	repo := &dedupRepo{outage: 2 * maxDeliver}
+	c := &AuditConsumer{js: js, repo: repo, hasher: core.NewSHA256HasherV2()}
+
+	msg := jsonMsg(1, "sys.audit.setpoint", `{"actor":"op"}`)
+	msg.delivered = maxDeliver
+	c.processBatch(context.Background(), []jetstream.Msg{msg})
+
+	// The outage is waited out: the message is written, not dead-lettered.
+	if len(js.published) != 0 || msg.termed || msg.nakDelay != 0 {
+		t.Errorf("Expected nothing dead-lettered or retried, got %+v", msg)
+	}
+	if !msg.acked || len(repo.logs) != 1 || msg.inProgress == 0 {
+		t.Errorf("Expected the message held and written once the database was back, got %+v %+v", msg, repo.logs)
+	}
+}
+
+func TestAuditConsumer_BatchRetriedAsUnit(t *testing.T) {
+	defer func(wait time.Duration) { writeRetryWait = wait }(writeRetryWait)
+	writeRetryWait = time.Millisecond
+
+	repo := &dedupRepo{outage: 3}
+	c := &AuditConsumer{js: &publishJS{}, repo: repo, hasher: core.NewSHA256HasherV2()}
+
+	var msgs []jetstream.Msg
+	for seq := uint64(1); seq <= 3; seq++ {
+		msgs = append(msgs, jsonMsg(seq, "sys.audit.setpoint", `{"actor":"op"}`))
+	}
+	c.processBatch(context.Background(), msgs)
+
+	// The batch is written whole once the database is back, in stream order.
+	if len(repo.batches) != 1 || repo.batches[0] != 3 {
+		t.Fatalf("Expected one write of the whole batch, got %v", repo.batches)
+	}
+	for i, msg := range msgs {
+		if want := fmt.Sprintf("AUDIT_EVENTS:%d", i+1); repo.logs[i].MsgID != want || !msg.(*fakeMsg).acked {
+			t.Errorf("Expected entry %d from %s and acked, got %s", i+1, want, repo.logs[i].MsgID)
+		}
+	}
+}
+
+type publishJS struct {
+	jetstream.JetStream
+	published []*nats.Msg
+	err       error
+}
+
+func (js *publishJS) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if js.err != nil {
+		return nil, js.err
+	}
+	js.published = append(js.published, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func TestAuditConsumer_DeadLetter(t *testing.T) {
+	js := &publishJS{}
+	c := &AuditConsumer{js: js, repo: &dedupRepo{}, hasher: core.NewSHA256HasherV2()}
+	ctx := context.Background()
+	cause := errors.New("invalid JSON")
+
+	// Early failures back off further each time.
+	var delays []time.Duration
+	for n := uint64(1); n < maxDeliver; n++ {
+		msg := &fakeMsg{subject: "sys.audit.setpoint", data: []byte(`{`), seq: 7, delivered: n}
+		c.handleFailure(ctx, msg, cause)
+		if msg.termed || msg.nakDelay == 0 {
+			t.Fatalf("Expected delivery %d to be retried, got %+v", n, msg)
+		}
+		delays = append(delays, msg.nakDelay)
+	}
+	for i := 1; i < len(delays); i++ {
+		if delays[i] <= delays[i-1] {
+			t.Errorf("Expected increasing backoff, got %v", delays)
+		}
+	}
+	if len(js.published) != 0 {
+		t.Fatalf("Expected nothing dead-lettered before %d deliveries", maxDeliver)
+	}
+
+	// The last delivery goes to the dead-letter stream, unless that fails.
+	js.err = errors.New("no responders")
+	msg := &fakeMsg{subject: "sys.audit.setpoint", data: []byte(`{`), headers: nats.Header{}, seq: 7, delivered: maxDeliver}
+	msg.headers.Set(jetstream.MsgIDHeader, "setpoint-1")
+	c.handleFailure(ctx, msg, cause)
+	if msg.termed || msg.nakDelay == 0 {
+		t.Errorf("Expected message to be kept when it can't be dead-lettered, got %+v", msg)
+	}
+
+	js.err = nil
+	msg.nakDelay = 0
+	c.handleFailure(ctx, msg, cause)
+	if !msg.termed || len(js.published) != 1 {
+		t.Fatalf("Expected message to be dead-lettered and terminated, got %+v", msg)
+	}
+	dl := js.published[0]
+	if dl.Subject != deadletter.Subject || dl.Header.Get(deadletter.HeaderSubject) != "sys.audit.setpoint" ||
+		dl.Header.Get(deadletter.HeaderError) != "invalid JSON" || dl.Header.Get(deadletter.HeaderMsgID) != "setpoint-1" ||
+		dl.Header.Get(jetstream.MsgIDHeader) != "" || string(dl.Data) != "{" {
+		t.Errorf("Unexpected dead letter %s %v %q", dl.Subject, dl.Header, dl.Data)
+	}
+}
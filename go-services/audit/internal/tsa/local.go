@@ -0,0 +1,186 @@
+package tsa
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/digitorus/timestamp"
+)
+
+// LocalName is the authority name stored for timestamps from the built-in
+// TSA.
+const LocalName = "local"
+
+// localPolicy is the policy the built-in TSA issues tokens under when the
+// request names none. It carries no meaning beyond identifying the
+// stand-in.
+var localPolicy = asn1.ObjectIdentifier{1, 2, 3, 4, 1}
+
+var (
+	oidExtKeyUsage  = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidTimeStamping = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}
+)
+
+// LocalTSA is a minimal RFC 3161 timestamping authority for testing and for
+// air-gapped sites without a TSA. Its tokens are only as trustworthy as its
+// key, so for evidence against the operator the key and certificate
+// should be held apart from the audit database, or a real TSA used.
+type LocalTSA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+	now  func() time.Time
+}
+
+// LoadLocalTSA reads the built-in TSA's PEM certificate and key.
+func LoadLocalTSA(certFile, keyFile string) (*LocalTSA, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load local TSA key pair: %w", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse local TSA certificate: %w", err)
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("local TSA key cannot sign")
+	}
+	return NewLocalTSA(cert, key)
+}
+
+// NewLocalTSA issues timestamps with cert, which must be for timestamping.
+func NewLocalTSA(cert *x509.Certificate, key crypto.Signer) (*LocalTSA, error) {
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageTimeStamping {
+		return nil, fmt.Errorf("local TSA certificate must have timeStamping as its only extended key usage")
+	}
+	return &LocalTSA{cert: cert, key: key, now: time.Now}, nil
+}
+
+// GenerateLocalTSA creates a TSA with a new self-signed certificate valid
+// for validity. Its tokens can't be verified once the process exits unless
+// the certificate is kept, so it is meant for tests.
+func GenerateLocalTSA(validity time.Duration) (*LocalTSA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate local TSA key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	// RFC 3161 requires the timeStamping usage to be critical, which
+	// crypto/x509 doesn't do on its own.
+	eku, err := asn1.Marshal([]asn1.ObjectIdentifier{oidTimeStamping})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Audit Service Local TSA"},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		ExtraExtensions:       []pkix.Extension{{Id: oidExtKeyUsage, Critical: true, Value: eku}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local TSA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return NewLocalTSA(cert, key)
+}
+
+// Certificate returns the TSA certificate, which verifiers trust as a
+// root.
+func (l *LocalTSA) Certificate() *x509.Certificate {
+	return l.cert
+}
+
+// CertificatePEM returns the TSA certificate in PEM form.
+func (l *LocalTSA) CertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: l.cert.Raw})
+}
+
+func (l *LocalTSA) Name() string {
+	return LocalName
+}
+
+// Timestamp issues a token in-process, without going through HTTP.
+func (l *LocalTSA) Timestamp(ctx context.Context, digest []byte) ([]byte, error) {
+	resp, err := l.respond(&timestamp.Request{HashAlgorithm: crypto.SHA256, HashedMessage: digest, Certificates: true})
+	if err != nil {
+		return nil, err
+	}
+	ts, err := timestamp.ParseResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	return ts.RawToken, nil
+}
+
+// ServeHTTP answers RFC 3161 requests posted as
+// application/timestamp-query.
+func (l *LocalTSA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxResponseSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := timestamp.ParseRequest(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid timestamp request: %v", err), http.StatusBadRequest)
+		return
+	}
+	resp, err := l.respond(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", replyContentType)
+	w.Write(resp)
+}
+
+func (l *LocalTSA) respond(req *timestamp.Request) ([]byte, error) {
+	policy := req.TSAPolicyOID
+	if policy == nil {
+		policy = localPolicy
+	}
+	ts := &timestamp.Timestamp{
+		HashAlgorithm:     req.HashAlgorithm,
+		HashedMessage:     req.HashedMessage,
+		Time:              l.now().UTC().Truncate(time.Second),
+		Accuracy:          time.Second,
+		Policy:            policy,
+		Nonce:             req.Nonce,
+		AddTSACertificate: req.Certificates,
+	}
+	resp, err := ts.CreateResponseWithOpts(l.cert, l.key, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create timestamp: %w", err)
+	}
+	return resp, nil
+}
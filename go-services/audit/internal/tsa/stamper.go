@@ -0,0 +1,81 @@
+package tsa
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+)
+
+// stampInterval is how often the stamper looks for new checkpoints.
+const stampInterval = 10 * time.Second
+
+// Stamper gets a timestamp token for every stored checkpoint. Checkpoints
+// are stamped after they are stored rather than when they are signed, so
+// one made while the TSA is unreachable is stamped once it is back.
+type Stamper struct {
+	repo      repository.Repository
+	authority Authority
+	verifier  core.TimestampVerifier
+	now       func() time.Time
+}
+
+func NewStamper(repo repository.Repository, authority Authority, verifier core.TimestampVerifier) *Stamper {
+	return &Stamper{repo: repo, authority: authority, verifier: verifier, now: time.Now}
+}
+
+// Run stamps checkpoints until ctx is done.
+func (s *Stamper) Run(ctx context.Context) {
+	ticker := time.NewTicker(stampInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Stamp(ctx); err != nil {
+			log.Printf("Failed to timestamp audit checkpoint: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stamp timestamps every checkpoint that has no token yet and returns how
+// many it stamped. Each token is verified before it is stored, so a TSA
+// that doesn't chain to the trusted roots is caught now rather than at
+// the next verification.
+func (s *Stamper) Stamp(ctx context.Context) (int, error) {
+	checkpoints, err := s.repo.ListUntimestampedCheckpoints(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	stamped := 0
+	for _, cp := range checkpoints {
+		digest := cp.Digest()
+		token, err := s.authority.Timestamp(ctx, digest)
+		if err != nil {
+			return stamped, fmt.Errorf("failed to timestamp checkpoint %d: %w", cp.Seq, err)
+		}
+		genTime, err := s.verifier.VerifyTimestamp(token, digest)
+		if err != nil {
+			return stamped, fmt.Errorf("timestamp for checkpoint %d does not verify: %w", cp.Seq, err)
+		}
+		ts := &core.CheckpointTimestamp{
+			Seq:       cp.Seq,
+			TSA:       s.authority.Name(),
+			Token:     token,
+			GenTime:   genTime,
+			CreatedAt: s.now().Truncate(time.Microsecond).UTC(),
+		}
+		if err := s.repo.SaveTimestamp(ctx, ts); err != nil {
+			return stamped, fmt.Errorf("failed to save timestamp: %w", err)
+		}
+		log.Printf("Audit checkpoint %d timestamped by %s at %s", cp.Seq, ts.TSA, genTime.Format(time.RFC3339))
+		stamped++
+	}
+	return stamped, nil
+}
@@ -0,0 +1,174 @@
+package tsa
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/digitorus/pkcs7"
+	"github.com/digitorus/timestamp"
+)
+
+// Media types of RFC 3161 requests and responses over HTTP.
+const (
+	queryContentType = "application/timestamp-query"
+	replyContentType = "application/timestamp-reply"
+)
+
+// maxResponseSize bounds a TSA response; tokens are a few kilobytes.
+const maxResponseSize = 1 << 20
+
+// Authority issues RFC 3161 timestamp tokens over SHA-256 digests.
+type Authority interface {
+	// Name identifies the authority in stored timestamps.
+	Name() string
+	// Timestamp returns a DER timestamp token over digest.
+	Timestamp(ctx context.Context, digest []byte) ([]byte, error)
+}
+
+// Client asks a remote TSA for timestamps over HTTP.
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+func NewClient(url string) *Client {
+	return &Client{url: url, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (c *Client) Name() string {
+	return c.url
+}
+
+// Timestamp requests a token with the TSA's certificates included, so it
+// can be verified later with only the trusted roots.
+func (c *Client) Timestamp(ctx context.Context, digest []byte) ([]byte, error) {
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	req := &timestamp.Request{
+		HashAlgorithm: crypto.SHA256,
+		HashedMessage: digest,
+		Certificates:  true,
+		Nonce:         nonce,
+	}
+	body, err := req.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode timestamp request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", queryContentType)
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach TSA: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TSA response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TSA returned %s", resp.Status)
+	}
+
+	ts, err := timestamp.ParseResponse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TSA response: %w", err)
+	}
+	if !bytes.Equal(ts.HashedMessage, digest) {
+		return nil, errors.New("TSA timestamped a different digest")
+	}
+	if ts.Nonce == nil || ts.Nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("TSA response nonce does not match the request")
+	}
+	return ts.RawToken, nil
+}
+
+// Verifier checks timestamp tokens against trusted TSA root certificates.
+type Verifier struct {
+	roots *x509.CertPool
+}
+
+func NewVerifier(roots *x509.CertPool) *Verifier {
+	return &Verifier{roots: roots}
+}
+
+// LoadRoots reads PEM certificates to trust as TSA roots.
+func LoadRoots(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TSA roots: %w", err)
+	}
+	roots := x509.NewCertPool()
+	count := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse TSA root: %w", err)
+		}
+		roots.AddCert(cert)
+		count++
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("no certificates in %s", path)
+	}
+	return roots, nil
+}
+
+// VerifyTimestamp checks that token is signed by a TSA chaining to a
+// trusted root, by a certificate for timestamping that was valid at the
+// time it vouches for, and that it covers digest. It returns that time.
+func (v *Verifier) VerifyTimestamp(token, digest []byte) (time.Time, error) {
+	ts, err := timestamp.Parse(token)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp token: %w", err)
+	}
+	if ts.HashAlgorithm != crypto.SHA256 || !bytes.Equal(ts.HashedMessage, digest) {
+		return time.Time{}, errors.New("timestamp token covers a different digest")
+	}
+
+	p7, err := pkcs7.Parse(token)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp token: %w", err)
+	}
+	if len(p7.Certificates) == 0 {
+		return time.Time{}, errors.New("timestamp token does not include the TSA certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range p7.Certificates {
+		intermediates.AddCert(cert)
+	}
+	err = p7.VerifyWithOpts(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   ts.Time,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp token is not from a trusted TSA: %w", err)
+	}
+	return ts.Time.UTC(), nil
+}
@@ -0,0 +1,154 @@
+package tsa
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/core"
+	"github.com/ahmetsah/industrial-historian/go-services/audit/internal/repository"
+)
+
+func newLocal(t *testing.T) (*LocalTSA, *Verifier) {
+	t.Helper()
+	local, err := GenerateLocalTSA(time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateLocalTSA failed: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(local.Certificate())
+	return local, NewVerifier(roots)
+}
+
+func TestLocalTSA_TimestampVerifies(t *testing.T) {
+	local, verifier := newLocal(t)
+	digest := sha256.Sum256([]byte("checkpoint"))
+
+	token, err := local.Timestamp(context.Background(), digest[:])
+	if err != nil {
+		t.Fatalf("Timestamp failed: %v", err)
+	}
+	genTime, err := verifier.VerifyTimestamp(token, digest[:])
+	if err != nil {
+		t.Fatalf("Expected token to verify, got %v", err)
+	}
+	if time.Since(genTime) > time.Minute {
+		t.Errorf("Unexpected token time %v", genTime)
+	}
+
+	other := sha256.Sum256([]byte("another checkpoint"))
+	if _, err := verifier.VerifyTimestamp(token, other[:]); err == nil {
+		t.Error("Expected token to be rejected for a different digest")
+	}
+
+	tampered := append([]byte(nil), token...)
+	tampered[len(tampered)-10] ^= 0xff
+	if _, err := verifier.VerifyTimestamp(tampered, digest[:]); err == nil {
+		t.Error("Expected tampered token to be rejected")
+	}
+}
+
+func TestVerifier_UntrustedTSA(t *testing.T) {
+	local, _ := newLocal(t)
+	_, otherVerifier := newLocal(t)
+	digest := sha256.Sum256([]byte("checkpoint"))
+
+	token, _ := local.Timestamp(context.Background(), digest[:])
+	if _, err := otherVerifier.VerifyTimestamp(token, digest[:]); err == nil {
+		t.Error("Expected token from an untrusted TSA to be rejected")
+	}
+}
+
+func TestClient_AgainstLocalTSA(t *testing.T) {
+	local, verifier := newLocal(t)
+	server := httptest.NewServer(local)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	digest := sha256.Sum256([]byte("checkpoint"))
+	token, err := client.Timestamp(context.Background(), digest[:])
+	if err != nil {
+		t.Fatalf("Timestamp over HTTP failed: %v", err)
+	}
+	if _, err := verifier.VerifyTimestamp(token, digest[:]); err != nil {
+		t.Errorf("Expected token to verify, got %v", err)
+	}
+}
+
+func TestLoadLocalTSA(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadLocalTSA(filepath.Join(dir, "missing.pem"), filepath.Join(dir, "missing.key")); err == nil {
+		t.Error("Expected missing key pair to fail")
+	}
+
+	local, _ := newLocal(t)
+	certFile := filepath.Join(dir, "tsa.pem")
+	os.WriteFile(certFile, local.CertificatePEM(), 0o600)
+	roots, err := LoadRoots(certFile)
+	if err != nil {
+		t.Fatalf("LoadRoots failed: %v", err)
+	}
+	digest := sha256.Sum256([]byte("checkpoint"))
+	token, _ := local.Timestamp(context.Background(), digest[:])
+	if _, err := NewVerifier(roots).VerifyTimestamp(token, digest[:]); err != nil {
+		t.Errorf("Expected token to verify against loaded roots, got %v", err)
+	}
+}
+
+type fakeRepo struct {
+	repository.Repository
+	checkpoints []*core.Checkpoint
+	timestamps  []*core.CheckpointTimestamp
+}
+
+func (r *fakeRepo) ListUntimestampedCheckpoints(ctx context.Context) ([]*core.Checkpoint, error) {
+	var out []*core.Checkpoint
+	for _, cp := range r.checkpoints {
+		stamped := false
+		for _, ts := range r.timestamps {
+			stamped = stamped || ts.Seq == cp.Seq
+		}
+		if !stamped {
+			out = append(out, cp)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeRepo) SaveTimestamp(ctx context.Context, ts *core.CheckpointTimestamp) error {
+	r.timestamps = append(r.timestamps, ts)
+	return nil
+}
+
+func TestStamper_StampsNewCheckpoints(t *testing.T) {
+	local, verifier := newLocal(t)
+	repo := &fakeRepo{checkpoints: []*core.Checkpoint{
+		{Seq: 10, HeadHash: "aa", Count: 10, CreatedAt: time.Now().UTC()},
+		{Seq: 20, HeadHash: "bb", Count: 20, CreatedAt: time.Now().UTC()},
+	}}
+	stamper := NewStamper(repo, local, verifier)
+
+	stamped, err := stamper.Stamp(context.Background())
+	if err != nil || stamped != 2 {
+		t.Fatalf("Expected 2 checkpoints stamped, got %d (%v)", stamped, err)
+	}
+	if stamped, _ := stamper.Stamp(context.Background()); stamped != 0 {
+		t.Errorf("Expected stamped checkpoints to be skipped, stamped %d", stamped)
+	}
+
+	v := core.NewChainVerifier(core.NewHashers())
+	v.UseCheckpoints(repo.checkpoints, acceptAll{})
+	v.UseTimestamps(repo.timestamps, verifier)
+	if result := v.Finish(); result.Timestamps != 2 {
+		t.Errorf("Expected both timestamps to verify, got %+v", result)
+	}
+}
+
+type acceptAll struct{}
+
+func (acceptAll) VerifyCheckpoint(*core.Checkpoint) error { return nil }
@@ -0,0 +1,6 @@
+-- The chain is ordered by seq rather than timestamp, since events carry
+-- their own timestamps. Existing entries are numbered by the service on
+-- startup by following their prev_hash links, after which seq is made
+-- NOT NULL.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS seq BIGINT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_seq ON audit_logs(seq);
@@ -0,0 +1,3 @@
+-- Entries record the hash format they were written with so verification
+-- can pick the right algorithm. Existing entries were hashed with version 1.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS hash_version SMALLINT NOT NULL DEFAULT 1;
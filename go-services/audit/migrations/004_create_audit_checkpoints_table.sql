@@ -0,0 +1,10 @@
+-- Signed checkpoints of the chain head, kept apart from the chain itself.
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    seq BIGINT PRIMARY KEY,
+    head_hash VARCHAR(64) NOT NULL,
+    count BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    key_id VARCHAR(64) NOT NULL,
+    algorithm VARCHAR(32) NOT NULL,
+    signature TEXT NOT NULL
+);
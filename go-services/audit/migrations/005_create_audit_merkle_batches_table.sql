@@ -0,0 +1,10 @@
+-- Merkle roots of full, fixed-size batches of entries in seq order.
+CREATE TABLE IF NOT EXISTS audit_merkle_batches (
+    batch_size INT NOT NULL,
+    batch_index BIGINT NOT NULL,
+    first_seq BIGINT NOT NULL,
+    last_seq BIGINT NOT NULL,
+    root_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (batch_size, batch_index)
+);
@@ -0,0 +1,9 @@
+-- RFC 3161 timestamp tokens over signed checkpoints, from a trusted
+-- timestamping authority.
+CREATE TABLE IF NOT EXISTS audit_timestamps (
+    seq BIGINT PRIMARY KEY REFERENCES audit_checkpoints(seq),
+    tsa TEXT NOT NULL,
+    token BYTEA NOT NULL,
+    gen_time TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,2 @@
+-- Exact and prefix (LIKE 'x%') searches on action.
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action text_pattern_ops);
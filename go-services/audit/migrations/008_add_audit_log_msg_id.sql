@@ -0,0 +1,4 @@
+-- The message each entry was ingested from, so redeliveries are recorded
+-- once. Entries written before this have none.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS msg_id TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_msg_id ON audit_logs(msg_id);
@@ -0,0 +1,326 @@
+// Package merkle implements the Merkle tree hashing, inclusion proofs and
+// consistency proofs of RFC 6962 over SHA-256. It has no dependency on the
+// audit service, so auditors can check proofs offline.
+//
+// The leaves of the audit log tree are the entries' curr_hash values,
+// hex-decoded, in sequence order: leaf i is the entry with seq i+1.
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// ErrInvalidProof is returned when a proof does not verify.
+var ErrInvalidProof = errors.New("invalid merkle proof")
+
+// LeafHash is the hash of a leaf: SHA-256(0x00 || data).
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// NodeHash is the hash of an interior node: SHA-256(0x01 || left || right).
+func NodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// EmptyRoot is the root of a tree with no leaves.
+func EmptyRoot() []byte {
+	sum := sha256.Sum256(nil)
+	return sum[:]
+}
+
+// Split is where RFC 6962 divides a tree of n > 1 leaves: the largest
+// power of two smaller than n.
+func Split(n uint64) uint64 {
+	return 1 << (bits.Len64(n-1) - 1)
+}
+
+// SubtreeHasher returns the root of the subtree over leaves [lo, hi). It
+// lets proofs be built from stored subtree roots instead of every leaf.
+type SubtreeHasher func(lo, hi uint64) ([]byte, error)
+
+// FromLeafHashes is a SubtreeHasher over leaf hashes held in memory.
+func FromLeafHashes(leaves [][]byte) SubtreeHasher {
+	var mth SubtreeHasher
+	mth = func(lo, hi uint64) ([]byte, error) {
+		if hi > uint64(len(leaves)) || lo > hi {
+			return nil, fmt.Errorf("subtree [%d, %d) outside %d leaves", lo, hi, len(leaves))
+		}
+		switch hi - lo {
+		case 0:
+			return EmptyRoot(), nil
+		case 1:
+			return leaves[lo], nil
+		}
+		k := Split(hi - lo)
+		left, err := mth(lo, lo+k)
+		if err != nil {
+			return nil, err
+		}
+		right, err := mth(lo+k, hi)
+		if err != nil {
+			return nil, err
+		}
+		return NodeHash(left, right), nil
+	}
+	return mth
+}
+
+// ProveInclusion returns the audit path for leaf index in a tree of size
+// leaves (RFC 6962 section 2.1.1).
+func ProveInclusion(index, size uint64, mth SubtreeHasher) ([][]byte, error) {
+	if index >= size {
+		return nil, fmt.Errorf("leaf %d is outside a tree of size %d", index, size)
+	}
+	var path [][]byte
+	var walk func(m, lo, hi uint64) error
+	walk = func(m, lo, hi uint64) error {
+		if hi-lo <= 1 {
+			return nil
+		}
+		k := Split(hi - lo)
+		var sibling []byte
+		var err error
+		if m < k {
+			err = walk(m, lo, lo+k)
+			if err == nil {
+				sibling, err = mth(lo+k, hi)
+			}
+		} else {
+			err = walk(m-k, lo+k, hi)
+			if err == nil {
+				sibling, err = mth(lo, lo+k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		path = append(path, sibling)
+		return nil
+	}
+	if err := walk(index, 0, size); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// ProveConsistency proves that the tree of size first is a prefix of the
+// tree of size second (RFC 6962 section 2.1.2).
+func ProveConsistency(first, second uint64, mth SubtreeHasher) ([][]byte, error) {
+	if first == 0 || first > second {
+		return nil, fmt.Errorf("no consistency proof from size %d to %d", first, second)
+	}
+	var proof [][]byte
+	var sub func(m, lo, hi uint64, whole bool) error
+	sub = func(m, lo, hi uint64, whole bool) error {
+		n := hi - lo
+		if m == n {
+			if whole {
+				return nil
+			}
+			root, err := mth(lo, hi)
+			if err != nil {
+				return err
+			}
+			proof = append(proof, root)
+			return nil
+		}
+		k := Split(n)
+		var node []byte
+		var err error
+		if m <= k {
+			err = sub(m, lo, lo+k, whole)
+			if err == nil {
+				node, err = mth(lo+k, hi)
+			}
+		} else {
+			err = sub(m-k, lo+k, hi, false)
+			if err == nil {
+				node, err = mth(lo, lo+k)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		proof = append(proof, node)
+		return nil
+	}
+	if err := sub(first, 0, second, true); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// VerifyInclusion checks that leafHash is leaf index of the tree of size
+// leaves with the given root (RFC 9162 section 2.1.3.2).
+func VerifyInclusion(leafHash []byte, index, size uint64, proof [][]byte, root []byte) error {
+	if index >= size {
+		return fmt.Errorf("%w: leaf %d is outside a tree of size %d", ErrInvalidProof, index, size)
+	}
+	fn, sn := index, size-1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return fmt.Errorf("%w: proof is too long", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			r = NodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = NodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return fmt.Errorf("%w: proof is too short", ErrInvalidProof)
+	}
+	if !bytes.Equal(r, root) {
+		return fmt.Errorf("%w: root mismatch", ErrInvalidProof)
+	}
+	return nil
+}
+
+// VerifyConsistency checks that the tree of size first with firstRoot is a
+// prefix of the tree of size second with secondRoot (RFC 9162 section
+// 2.1.4.2).
+func VerifyConsistency(first, second uint64, firstRoot, secondRoot []byte, proof [][]byte) error {
+	switch {
+	case first > second:
+		return fmt.Errorf("%w: size %d is larger than %d", ErrInvalidProof, first, second)
+	case first == second:
+		if len(proof) != 0 || !bytes.Equal(firstRoot, secondRoot) {
+			return fmt.Errorf("%w: equal sizes need equal roots and no proof", ErrInvalidProof)
+		}
+		return nil
+	case first == 0:
+		// The empty tree is a prefix of every tree.
+		if len(proof) != 0 {
+			return fmt.Errorf("%w: proof from an empty tree must be empty", ErrInvalidProof)
+		}
+		return nil
+	case len(proof) == 0:
+		return fmt.Errorf("%w: proof is empty", ErrInvalidProof)
+	}
+
+	if first&(first-1) == 0 {
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+	fn, sn := first-1, second-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return fmt.Errorf("%w: proof is too long", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = NodeHash(c, fr)
+			sr = NodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = NodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return fmt.Errorf("%w: proof is too short", ErrInvalidProof)
+	}
+	if !bytes.Equal(fr, firstRoot) || !bytes.Equal(sr, secondRoot) {
+		return fmt.Errorf("%w: root mismatch", ErrInvalidProof)
+	}
+	return nil
+}
+
+// InclusionProof is an inclusion proof as served by the audit service.
+// Hashes are hex encoded.
+type InclusionProof struct {
+	LeafIndex uint64   `json:"leaf_index"`
+	TreeSize  uint64   `json:"tree_size"`
+	RootHash  string   `json:"root_hash"`
+	AuditPath []string `json:"audit_path"`
+}
+
+// Verify checks that an entry with the given curr_hash (hex) is included
+// in the tree. Compare RootHash with a root obtained independently, e.g.
+// from a signed checkpoint, before relying on it.
+func (p *InclusionProof) Verify(currHash string) error {
+	data, err := hex.DecodeString(currHash)
+	if err != nil {
+		return fmt.Errorf("invalid curr_hash: %w", err)
+	}
+	root, path, err := decodeHashes(p.RootHash, p.AuditPath)
+	if err != nil {
+		return err
+	}
+	return VerifyInclusion(LeafHash(data), p.LeafIndex, p.TreeSize, path, root)
+}
+
+// ConsistencyProof is a consistency proof as served by the audit service.
+// Hashes are hex encoded.
+type ConsistencyProof struct {
+	First      uint64   `json:"first"`
+	Second     uint64   `json:"second"`
+	FirstRoot  string   `json:"first_root"`
+	SecondRoot string   `json:"second_root"`
+	Proof      []string `json:"proof"`
+}
+
+// Verify checks that the tree with SecondRoot extends the one with
+// FirstRoot. Compare FirstRoot with a root recorded earlier before relying
+// on it.
+func (p *ConsistencyProof) Verify() error {
+	firstRoot, proof, err := decodeHashes(p.FirstRoot, p.Proof)
+	if err != nil {
+		return err
+	}
+	secondRoot, err := hex.DecodeString(p.SecondRoot)
+	if err != nil {
+		return fmt.Errorf("invalid second_root: %w", err)
+	}
+	return VerifyConsistency(p.First, p.Second, firstRoot, secondRoot, proof)
+}
+
+// EncodeHashes hex encodes proof hashes.
+func EncodeHashes(hashes [][]byte) []string {
+	encoded := make([]string, len(hashes))
+	for i, h := range hashes {
+		encoded[i] = hex.EncodeToString(h)
+	}
+	return encoded
+}
+
+func decodeHashes(root string, hashes []string) ([]byte, [][]byte, error) {
+	r, err := hex.DecodeString(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid root: %w", err)
+	}
+	decoded := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		if decoded[i], err = hex.DecodeString(h); err != nil {
+			return nil, nil, fmt.Errorf("invalid proof hash %d: %w", i, err)
+		}
+	}
+	return r, decoded, nil
+}
@@ -0,0 +1,137 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func testLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("entry-%d", i)))
+		leaves[i] = LeafHash(sum[:])
+	}
+	return leaves
+}
+
+func root(t *testing.T, leaves [][]byte) []byte {
+	t.Helper()
+	r, err := FromLeafHashes(leaves)(0, uint64(len(leaves)))
+	if err != nil {
+		t.Fatalf("root: %v", err)
+	}
+	return r
+}
+
+func TestRoot_KnownValues(t *testing.T) {
+	if got := hex.EncodeToString(EmptyRoot()); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("Unexpected empty root %s", got)
+	}
+	// Three leaves split as (a, b) and c.
+	a, b, c := LeafHash([]byte("a")), LeafHash([]byte("b")), LeafHash([]byte("c"))
+	want := NodeHash(NodeHash(a, b), c)
+	if got := root(t, [][]byte{a, b, c}); !bytes.Equal(got, want) {
+		t.Errorf("Expected unbalanced tree root %x, got %x", want, got)
+	}
+}
+
+func TestInclusionProofs(t *testing.T) {
+	all := testLeaves(40)
+	for size := 1; size <= len(all); size++ {
+		leaves := all[:size]
+		r := root(t, leaves)
+		for i := 0; i < size; i++ {
+			proof, err := ProveInclusion(uint64(i), uint64(size), FromLeafHashes(leaves))
+			if err != nil {
+				t.Fatalf("ProveInclusion(%d, %d): %v", i, size, err)
+			}
+			if err := VerifyInclusion(leaves[i], uint64(i), uint64(size), proof, r); err != nil {
+				t.Fatalf("VerifyInclusion(%d, %d): %v", i, size, err)
+			}
+			other := (i + 1) % size
+			if size > 1 && VerifyInclusion(leaves[other], uint64(i), uint64(size), proof, r) == nil {
+				t.Fatalf("Proof for leaf %d of %d accepted the wrong leaf", i, size)
+			}
+			if len(proof) > 0 && VerifyInclusion(leaves[i], uint64(i), uint64(size), proof[:len(proof)-1], r) == nil {
+				t.Fatalf("Truncated proof for leaf %d of %d accepted", i, size)
+			}
+		}
+	}
+}
+
+func TestConsistencyProofs(t *testing.T) {
+	all := testLeaves(40)
+	mth := FromLeafHashes(all)
+	for second := 1; second <= len(all); second++ {
+		secondRoot := root(t, all[:second])
+		for first := 1; first <= second; first++ {
+			firstRoot := root(t, all[:first])
+			proof, err := ProveConsistency(uint64(first), uint64(second), mth)
+			if err != nil {
+				t.Fatalf("ProveConsistency(%d, %d): %v", first, second, err)
+			}
+			if err := VerifyConsistency(uint64(first), uint64(second), firstRoot, secondRoot, proof); err != nil {
+				t.Fatalf("VerifyConsistency(%d, %d): %v", first, second, err)
+			}
+			if first < second {
+				forked := append([][]byte{}, all[:second]...)
+				forked[first-1] = LeafHash([]byte("rewritten"))
+				if VerifyConsistency(uint64(first), uint64(second), firstRoot, root(t, forked), proof) == nil {
+					t.Fatalf("Consistency %d -> %d accepted a rewritten history", first, second)
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyConsistency_Edges(t *testing.T) {
+	leaves := testLeaves(4)
+	r := root(t, leaves)
+	if err := VerifyConsistency(4, 4, r, r, nil); err != nil {
+		t.Errorf("Expected equal trees to be consistent: %v", err)
+	}
+	if err := VerifyConsistency(4, 3, r, r, nil); err == nil {
+		t.Error("Expected shrinking tree to be rejected")
+	}
+	if err := VerifyConsistency(2, 4, root(t, leaves[:2]), r, nil); err == nil {
+		t.Error("Expected empty proof to be rejected")
+	}
+}
+
+func TestProofJSON_Verify(t *testing.T) {
+	currHashes := make([]string, 7)
+	leaves := make([][]byte, 7)
+	for i := range currHashes {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("entry-%d", i)))
+		currHashes[i] = hex.EncodeToString(sum[:])
+		leaves[i] = LeafHash(sum[:])
+	}
+	mth := FromLeafHashes(leaves)
+
+	path, _ := ProveInclusion(5, 7, mth)
+	inclusion := &InclusionProof{LeafIndex: 5, TreeSize: 7, RootHash: hex.EncodeToString(root(t, leaves)), AuditPath: EncodeHashes(path)}
+	if err := inclusion.Verify(currHashes[5]); err != nil {
+		t.Errorf("Expected inclusion proof to verify: %v", err)
+	}
+	if err := inclusion.Verify(currHashes[4]); err == nil {
+		t.Error("Expected inclusion proof for another entry to fail")
+	}
+
+	proof, _ := ProveConsistency(3, 7, mth)
+	consistency := &ConsistencyProof{
+		First: 3, Second: 7,
+		FirstRoot:  hex.EncodeToString(root(t, leaves[:3])),
+		SecondRoot: hex.EncodeToString(root(t, leaves)),
+		Proof:      EncodeHashes(proof),
+	}
+	if err := consistency.Verify(); err != nil {
+		t.Errorf("Expected consistency proof to verify: %v", err)
+	}
+	consistency.FirstRoot = hex.EncodeToString(root(t, leaves[:4]))
+	if err := consistency.Verify(); err == nil {
+		t.Error("Expected consistency proof with the wrong first root to fail")
+	}
+}
@@ -58,7 +58,7 @@ fi
 # 7. Query database
 echo -e "\n📊 Audit logs in database:"
 docker exec ops-postgres-1 psql -U postgres -d historian -c \
-  "SELECT id, timestamp, actor, action, LEFT(prev_hash, 8) as prev, LEFT(curr_hash, 8) as curr FROM audit_logs ORDER BY timestamp;"
+  "SELECT seq, id, timestamp, actor, action, LEFT(prev_hash, 8) as prev, LEFT(curr_hash, 8) as curr FROM audit_logs ORDER BY seq;"
 
 echo -e "\n✅ Test completed!"
 echo -e "\n💡 Useful commands:"
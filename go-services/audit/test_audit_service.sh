@@ -75,7 +75,7 @@ fi
 
 # 7. Query database to see logs
 echo -e "\n${BLUE}7. Querying audit logs from database...${NC}"
-docker exec -it ops-postgres-1 psql -U postgres -d historian -c "SELECT id, timestamp, actor, action, prev_hash, curr_hash FROM audit_logs ORDER BY timestamp;"
+docker exec -it ops-postgres-1 psql -U postgres -d historian -c "SELECT seq, id, timestamp, actor, action, prev_hash, curr_hash FROM audit_logs ORDER BY seq;"
 
 # 8. Cleanup
 echo -e "\n${BLUE}8. Cleanup...${NC}"
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -62,21 +63,34 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Publish event to NATS
-	event := map[string]interface{}{
+	h.publishEvent(SubjectLoginSuccess, map[string]interface{}{
 		"user_id":   user.ID,
 		"username":  user.Username,
 		"timestamp": time.Now(),
 		"event":     "login_success",
-	}
-	eventBytes, _ := json.Marshal(event)
-	if h.NatsConn != nil {
-		h.NatsConn.Publish(SubjectLoginSuccess, eventBytes)
-	}
+	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(LoginResponse{Token: token})
 }
 
+// newEventMsg builds an event message with a unique Nats-Msg-Id, which
+// the audit service records so a redelivered event is stored once.
+func newEventMsg(subject string, event map[string]interface{}) *nats.Msg {
+	id := make([]byte, 16)
+	rand.Read(id)
+	msg := nats.NewMsg(subject)
+	msg.Header.Set(nats.MsgIdHdr, hex.EncodeToString(id))
+	msg.Data, _ = json.Marshal(event)
+	return msg
+}
+
+func (h *AuthHandler) publishEvent(subject string, event map[string]interface{}) {
+	if h.NatsConn != nil {
+		h.NatsConn.PublishMsg(newEventMsg(subject, event))
+	}
+}
+
 type ReAuthRequest struct {
 	Password string `json:"password"`
 }
@@ -144,16 +158,12 @@ func (h *AuthHandler) ReAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 6. Publish Event
-	event := map[string]interface{}{
+	h.publishEvent(SubjectSignatureIssued, map[string]interface{}{
 		"user_id":   user.ID,
 		"username":  user.Username,
 		"timestamp": time.Now(),
 		"event":     "signature_issued",
-	}
-	eventBytes, _ := json.Marshal(event)
-	if h.NatsConn != nil {
-		h.NatsConn.Publish(SubjectSignatureIssued, eventBytes)
-	}
+	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(ReAuthResponse{SigningToken: signingToken})
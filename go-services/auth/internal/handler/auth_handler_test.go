@@ -10,6 +10,7 @@ import (
 
 	"github.com/ahmetsah/industrial-historian/go-services/auth/internal/repository"
 	"github.com/ahmetsah/industrial-historian/go-services/auth/internal/service"
+	"github.com/nats-io/nats.go"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -211,3 +212,18 @@ func TestCreateServiceAccount(t *testing.T) {
 		t.Errorf("Expected role SERVICE, got %s", user.Role)
 	}
 }
+
+func TestNewEventMsg(t *testing.T) {
+	event := map[string]interface{}{"username": "testuser", "event": "login_success"}
+	first := newEventMsg(SubjectLoginSuccess, event)
+	second := newEventMsg(SubjectLoginSuccess, event)
+
+	id := first.Header.Get(nats.MsgIdHdr)
+	if id == "" || id == second.Header.Get(nats.MsgIdHdr) {
+		t.Errorf("Expected a unique Nats-Msg-Id per event, got %q and %q", id, second.Header.Get(nats.MsgIdHdr))
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(first.Data, &decoded); err != nil || decoded["username"] != "testuser" || first.Subject != SubjectLoginSuccess {
+		t.Errorf("Unexpected event message %s %s: %v", first.Subject, first.Data, err)
+	}
+}
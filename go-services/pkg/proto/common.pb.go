@@ -233,8 +233,30 @@ type AlarmEvent struct {
 	Value         float64                `protobuf:"fixed64,4,opt,name=value,proto3" json:"value,omitempty"`
 	TimestampMs   int64                  `protobuf:"varint,5,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`
 	Message       string                 `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	Tag           string                 `protobuf:"bytes,7,opt,name=tag,proto3" json:"tag,omitempty"`
+	Priority      string                 `protobuf:"bytes,8,opt,name=priority,proto3" json:"priority,omitempty"`
+	AlarmType     string                 `protobuf:"bytes,9,opt,name=alarm_type,json=alarmType,proto3" json:"alarm_type,omitempty"`
+	Threshold     float64                `protobuf:"fixed64,10,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	PreviousState string                 `protobuf:"bytes,11,opt,name=previous_state,json=previousState,proto3" json:"previous_state,omitempty"`
+	Actor         string                 `protobuf:"bytes,12,opt,name=actor,proto3" json:"actor,omitempty"` // "system" for evaluator-driven transitions
+	Comment       string                 `protobuf:"bytes,13,opt,name=comment,proto3" json:"comment,omitempty"`
+	Sequence      uint64                 `protobuf:"varint,14,opt,name=sequence,proto3" json:"sequence,omitempty"` // Monotonic per alarm service instance
+	// Operator guidance from the definition's rationalization
+	Cause               string `protobuf:"bytes,15,opt,name=cause,proto3" json:"cause,omitempty"`
+	Consequence         string `protobuf:"bytes,16,opt,name=consequence,proto3" json:"consequence,omitempty"`
+	CorrectiveAction    string `protobuf:"bytes,17,opt,name=corrective_action,json=correctiveAction,proto3" json:"corrective_action,omitempty"`
+	ResponseTimeSeconds int32  `protobuf:"varint,18,opt,name=response_time_seconds,json=responseTimeSeconds,proto3" json:"response_time_seconds,omitempty"`
+	AlarmClass          string `protobuf:"bytes,19,opt,name=alarm_class,json=alarmClass,proto3" json:"alarm_class,omitempty"`
+	// Alarm grouping for cause analysis
+	GroupId           int32 `protobuf:"varint,20,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	FirstOut          bool  `protobuf:"varint,21,opt,name=first_out,json=firstOut,proto3" json:"first_out,omitempty"`
+	Consequential     bool  `protobuf:"varint,22,opt,name=consequential,proto3" json:"consequential,omitempty"`                                    // Raised while the group parent was active
+	SourceTimestampMs int64 `protobuf:"varint,23,opt,name=source_timestamp_ms,json=sourceTimestampMs,proto3" json:"source_timestamp_ms,omitempty"` // SensorData timestamp that caused the transition
+	// Electronic signature on signed operator actions
+	SignedBy         string `protobuf:"bytes,24,opt,name=signed_by,json=signedBy,proto3" json:"signed_by,omitempty"`
+	SignatureMeaning string `protobuf:"bytes,25,opt,name=signature_meaning,json=signatureMeaning,proto3" json:"signature_meaning,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *AlarmEvent) Reset() {
@@ -309,6 +331,139 @@ func (x *AlarmEvent) GetMessage() string {
 	return ""
 }
 
+func (x *AlarmEvent) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetAlarmType() string {
+	if x != nil {
+		return x.AlarmType
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *AlarmEvent) GetPreviousState() string {
+	if x != nil {
+		return x.PreviousState
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetComment() string {
+	if x != nil {
+		return x.Comment
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *AlarmEvent) GetCause() string {
+	if x != nil {
+		return x.Cause
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetConsequence() string {
+	if x != nil {
+		return x.Consequence
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetCorrectiveAction() string {
+	if x != nil {
+		return x.CorrectiveAction
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetResponseTimeSeconds() int32 {
+	if x != nil {
+		return x.ResponseTimeSeconds
+	}
+	return 0
+}
+
+func (x *AlarmEvent) GetAlarmClass() string {
+	if x != nil {
+		return x.AlarmClass
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetGroupId() int32 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *AlarmEvent) GetFirstOut() bool {
+	if x != nil {
+		return x.FirstOut
+	}
+	return false
+}
+
+func (x *AlarmEvent) GetConsequential() bool {
+	if x != nil {
+		return x.Consequential
+	}
+	return false
+}
+
+func (x *AlarmEvent) GetSourceTimestampMs() int64 {
+	if x != nil {
+		return x.SourceTimestampMs
+	}
+	return 0
+}
+
+func (x *AlarmEvent) GetSignedBy() string {
+	if x != nil {
+		return x.SignedBy
+	}
+	return ""
+}
+
+func (x *AlarmEvent) GetSignatureMeaning() string {
+	if x != nil {
+		return x.SignatureMeaning
+	}
+	return ""
+}
+
 var File_common_proto protoreflect.FileDescriptor
 
 const file_common_proto_rawDesc = "" +
@@ -330,7 +485,7 @@ const file_common_proto_rawDesc = "" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
 	"\x06action\x18\x02 \x01(\tR\x06action\x12\x1a\n" +
 	"\bresource\x18\x03 \x01(\tR\bresource\x12!\n" +
-	"\ftimestamp_ms\x18\x04 \x01(\x03R\vtimestampMs\"\xb5\x01\n" +
+	"\ftimestamp_ms\x18\x04 \x01(\x03R\vtimestampMs\"\xa5\x06\n" +
 	"\n" +
 	"AlarmEvent\x12\x19\n" +
 	"\balarm_id\x18\x01 \x01(\x05R\aalarmId\x12#\n" +
@@ -338,7 +493,29 @@ const file_common_proto_rawDesc = "" +
 	"\x05state\x18\x03 \x01(\tR\x05state\x12\x14\n" +
 	"\x05value\x18\x04 \x01(\x01R\x05value\x12!\n" +
 	"\ftimestamp_ms\x18\x05 \x01(\x03R\vtimestampMs\x12\x18\n" +
-	"\amessage\x18\x06 \x01(\tR\amessageB@Z>github.com/ahmetsah/industrial-historian/go-services/pkg/protob\x06proto3"
+	"\amessage\x18\x06 \x01(\tR\amessage\x12\x10\n" +
+	"\x03tag\x18\a \x01(\tR\x03tag\x12\x1a\n" +
+	"\bpriority\x18\b \x01(\tR\bpriority\x12\x1d\n" +
+	"\n" +
+	"alarm_type\x18\t \x01(\tR\talarmType\x12\x1c\n" +
+	"\tthreshold\x18\n" +
+	" \x01(\x01R\tthreshold\x12%\n" +
+	"\x0eprevious_state\x18\v \x01(\tR\rpreviousState\x12\x14\n" +
+	"\x05actor\x18\f \x01(\tR\x05actor\x12\x18\n" +
+	"\acomment\x18\r \x01(\tR\acomment\x12\x1a\n" +
+	"\bsequence\x18\x0e \x01(\x04R\bsequence\x12\x14\n" +
+	"\x05cause\x18\x0f \x01(\tR\x05cause\x12 \n" +
+	"\vconsequence\x18\x10 \x01(\tR\vconsequence\x12+\n" +
+	"\x11corrective_action\x18\x11 \x01(\tR\x10correctiveAction\x122\n" +
+	"\x15response_time_seconds\x18\x12 \x01(\x05R\x13responseTimeSeconds\x12\x1f\n" +
+	"\valarm_class\x18\x13 \x01(\tR\n" +
+	"alarmClass\x12\x19\n" +
+	"\bgroup_id\x18\x14 \x01(\x05R\agroupId\x12\x1b\n" +
+	"\tfirst_out\x18\x15 \x01(\bR\bfirstOut\x12$\n" +
+	"\rconsequential\x18\x16 \x01(\bR\rconsequential\x12.\n" +
+	"\x13source_timestamp_ms\x18\x17 \x01(\x03R\x11sourceTimestampMs\x12\x1b\n" +
+	"\tsigned_by\x18\x18 \x01(\tR\bsignedBy\x12+\n" +
+	"\x11signature_meaning\x18\x19 \x01(\tR\x10signatureMeaningB@Z>github.com/ahmetsah/industrial-historian/go-services/pkg/protob\x06proto3"
 
 var (
 	file_common_proto_rawDescOnce sync.Once
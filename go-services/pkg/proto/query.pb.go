@@ -22,11 +22,13 @@ const (
 )
 
 type QueryRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SensorId      string                 `protobuf:"bytes,1,opt,name=sensor_id,json=sensorId,proto3" json:"sensor_id,omitempty"`
-	StartTs       int64                  `protobuf:"varint,2,opt,name=start_ts,json=startTs,proto3" json:"start_ts,omitempty"`
-	EndTs         int64                  `protobuf:"varint,3,opt,name=end_ts,json=endTs,proto3" json:"end_ts,omitempty"`
-	MaxPoints     int32                  `protobuf:"varint,4,opt,name=max_points,json=maxPoints,proto3" json:"max_points,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SensorId  string                 `protobuf:"bytes,1,opt,name=sensor_id,json=sensorId,proto3" json:"sensor_id,omitempty"`
+	StartTs   int64                  `protobuf:"varint,2,opt,name=start_ts,json=startTs,proto3" json:"start_ts,omitempty"`
+	EndTs     int64                  `protobuf:"varint,3,opt,name=end_ts,json=endTs,proto3" json:"end_ts,omitempty"`
+	MaxPoints int32                  `protobuf:"varint,4,opt,name=max_points,json=maxPoints,proto3" json:"max_points,omitempty"`
+	// Only the newest point in the range is returned.
+	LatestOnly    bool `protobuf:"varint,5,opt,name=latest_only,json=latestOnly,proto3" json:"latest_only,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -89,17 +91,26 @@ func (x *QueryRequest) GetMaxPoints() int32 {
 	return 0
 }
 
+func (x *QueryRequest) GetLatestOnly() bool {
+	if x != nil {
+		return x.LatestOnly
+	}
+	return false
+}
+
 var File_query_proto protoreflect.FileDescriptor
 
 const file_query_proto_rawDesc = "" +
 	"\n" +
-	"\vquery.proto\x12\fhistorian.v1\x1a\fcommon.proto\"|\n" +
+	"\vquery.proto\x12\fhistorian.v1\x1a\fcommon.proto\"\x9d\x01\n" +
 	"\fQueryRequest\x12\x1b\n" +
 	"\tsensor_id\x18\x01 \x01(\tR\bsensorId\x12\x19\n" +
 	"\bstart_ts\x18\x02 \x01(\x03R\astartTs\x12\x15\n" +
 	"\x06end_ts\x18\x03 \x01(\x03R\x05endTs\x12\x1d\n" +
 	"\n" +
-	"max_points\x18\x04 \x01(\x05R\tmaxPoints2Q\n" +
+	"max_points\x18\x04 \x01(\x05R\tmaxPoints\x12\x1f\n" +
+	"\vlatest_only\x18\x05 \x01(\bR\n" +
+	"latestOnly2Q\n" +
 	"\x0eHistorianQuery\x12?\n" +
 	"\x05Query\x12\x1a.historian.v1.QueryRequest\x1a\x18.historian.v1.SensorData0\x01B@Z>github.com/ahmetsah/industrial-historian/go-services/pkg/protob\x06proto3"
 
@@ -86,7 +86,10 @@ impl HistorianQuery for QueryService {
             1000
         };
 
-        let final_points = if points.len() > max_points {
+        let final_points = if req.latest_only {
+            // Points are in ascending time order; only the newest is wanted
+            points.into_iter().last().into_iter().collect()
+        } else if points.len() > max_points {
             crate::downsample::downsample(points, max_points)
         } else {
             points
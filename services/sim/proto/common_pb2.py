@@ -14,7 +14,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0c\x63ommon.proto\x12\x0chistorian.v1\"U\n\nSensorData\x12\x11\n\tsensor_id\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01\x12\x14\n\x0ctimestamp_ms\x18\x03 \x01(\x03\x12\x0f\n\x07quality\x18\x04 \x01(\x05\"Q\n\x08LogEntry\x12\r\n\x05level\x18\x01 \x01(\t\x12\x0f\n\x07message\x18\x02 \x01(\t\x12\x14\n\x0ctimestamp_ms\x18\x03 \x01(\x03\x12\x0f\n\x07service\x18\x04 \x01(\t\"U\n\nUserAction\x12\x0f\n\x07user_id\x18\x01 \x01(\t\x12\x0e\n\x06\x61\x63tion\x18\x02 \x01(\t\x12\x10\n\x08resource\x18\x03 \x01(\t\x12\x14\n\x0ctimestamp_ms\x18\x04 \x01(\x03\"z\n\nAlarmEvent\x12\x10\n\x08\x61larm_id\x18\x01 \x01(\x05\x12\x15\n\rdefinition_id\x18\x02 \x01(\x05\x12\r\n\x05state\x18\x03 \x01(\t\x12\r\n\x05value\x18\x04 \x01(\x01\x12\x14\n\x0ctimestamp_ms\x18\x05 \x01(\x03\x12\x0f\n\x07message\x18\x06 \x01(\tB@Z>github.com/ahmetsah/industrial-historian/go-services/pkg/protob\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0c\x63ommon.proto\x12\x0chistorian.v1\"U\n\nSensorData\x12\x11\n\tsensor_id\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01\x12\x14\n\x0ctimestamp_ms\x18\x03 \x01(\x03\x12\x0f\n\x07quality\x18\x04 \x01(\x05\"Q\n\x08LogEntry\x12\r\n\x05level\x18\x01 \x01(\t\x12\x0f\n\x07message\x18\x02 \x01(\t\x12\x14\n\x0ctimestamp_ms\x18\x03 \x01(\x03\x12\x0f\n\x07service\x18\x04 \x01(\t\"U\n\nUserAction\x12\x0f\n\x07user_id\x18\x01 \x01(\t\x12\x0e\n\x06\x61\x63tion\x18\x02 \x01(\t\x12\x10\n\x08resource\x18\x03 \x01(\t\x12\x14\n\x0ctimestamp_ms\x18\x04 \x01(\x03\"\x84\x04\n\nAlarmEvent\x12\x10\n\x08\x61larm_id\x18\x01 \x01(\x05\x12\x15\n\rdefinition_id\x18\x02 \x01(\x05\x12\r\n\x05state\x18\x03 \x01(\t\x12\r\n\x05value\x18\x04 \x01(\x01\x12\x14\n\x0ctimestamp_ms\x18\x05 \x01(\x03\x12\x0f\n\x07message\x18\x06 \x01(\t\x12\x0b\n\x03tag\x18\x07 \x01(\t\x12\x10\n\x08priority\x18\x08 \x01(\t\x12\x12\n\nalarm_type\x18\t \x01(\t\x12\x11\n\tthreshold\x18\n \x01(\x01\x12\x16\n\x0eprevious_state\x18\x0b \x01(\t\x12\r\n\x05\x61\x63tor\x18\x0c \x01(\t\x12\x0f\n\x07\x63omment\x18\r \x01(\t\x12\x10\n\x08sequence\x18\x0e \x01(\x04\x12\r\n\x05\x63\x61use\x18\x0f \x01(\t\x12\x13\n\x0b\x63onsequence\x18\x10 \x01(\t\x12\x19\n\x11\x63orrective_action\x18\x11 \x01(\t\x12\x1d\n\x15response_time_seconds\x18\x12 \x01(\x05\x12\x13\n\x0b\x61larm_class\x18\x13 \x01(\t\x12\x10\n\x08group_id\x18\x14 \x01(\x05\x12\x11\n\tfirst_out\x18\x15 \x01(\x08\x12\x15\n\rconsequential\x18\x16 \x01(\x08\x12\x1b\n\x13source_timestamp_ms\x18\x17 \x01(\x03\x12\x11\n\tsigned_by\x18\x18 \x01(\t\x12\x19\n\x11signature_meaning\x18\x19 \x01(\tB@Z>github.com/ahmetsah/industrial-historian/go-services/pkg/protob\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -28,6 +28,6 @@ if _descriptor._USE_C_DESCRIPTORS == False:
   _globals['_LOGENTRY']._serialized_end=198
   _globals['_USERACTION']._serialized_start=200
   _globals['_USERACTION']._serialized_end=285
-  _globals['_ALARMEVENT']._serialized_start=287
-  _globals['_ALARMEVENT']._serialized_end=409
+  _globals['_ALARMEVENT']._serialized_start=288
+  _globals['_ALARMEVENT']._serialized_end=804
 # @@protoc_insertion_point(module_scope)
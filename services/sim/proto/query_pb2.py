@@ -15,7 +15,7 @@ _sym_db = _symbol_database.Default()
 import common_pb2 as common__pb2
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0bquery.proto\x12\x0chistorian.v1\x1a\x0c\x63ommon.proto\"W\n\x0cQueryRequest\x12\x11\n\tsensor_id\x18\x01 \x01(\t\x12\x10\n\x08start_ts\x18\x02 \x01(\x03\x12\x0e\n\x06\x65nd_ts\x18\x03 \x01(\x03\x12\x12\n\nmax_points\x18\x04 \x01(\x05\x32Q\n\x0eHistorianQuery\x12?\n\x05Query\x12\x1a.historian.v1.QueryRequest\x1a\x18.historian.v1.SensorData0\x01\x42@Z>github.com/ahmetsah/industrial-historian/go-services/pkg/protob\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0bquery.proto\x12\x0chistorian.v1\x1a\x0c\x63ommon.proto\"l\n\x0cQueryRequest\x12\x11\n\tsensor_id\x18\x01 \x01(\t\x12\x10\n\x08start_ts\x18\x02 \x01(\x03\x12\x0e\n\x06\x65nd_ts\x18\x03 \x01(\x03\x12\x12\n\nmax_points\x18\x04 \x01(\x05\x12\x13\n\x0blatest_only\x18\x05 \x01(\x08\x32Q\n\x0eHistorianQuery\x12?\n\x05Query\x12\x1a.historian.v1.QueryRequest\x1a\x18.historian.v1.SensorData0\x01\x42@Z>github.com/ahmetsah/industrial-historian/go-services/pkg/protob\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -24,7 +24,7 @@ if _descriptor._USE_C_DESCRIPTORS == False:
   _globals['DESCRIPTOR']._options = None
   _globals['DESCRIPTOR']._serialized_options = b'Z>github.com/ahmetsah/industrial-historian/go-services/pkg/proto'
   _globals['_QUERYREQUEST']._serialized_start=43
-  _globals['_QUERYREQUEST']._serialized_end=130
-  _globals['_HISTORIANQUERY']._serialized_start=132
-  _globals['_HISTORIANQUERY']._serialized_end=213
+  _globals['_QUERYREQUEST']._serialized_end=151
+  _globals['_HISTORIANQUERY']._serialized_start=153
+  _globals['_HISTORIANQUERY']._serialized_end=234
 # @@protoc_insertion_point(module_scope)